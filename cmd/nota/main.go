@@ -1,12 +1,26 @@
 package main
 
 import (
+	"fmt"
 	"log"
+	"os"
+	"strings"
 
 	"github.com/curtisnewbie/nota/internal/app"
+	"github.com/curtisnewbie/nota/internal/cli"
 )
 
 func main() {
+	args, err := cli.ParseGlobalFlags(os.Args[1:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "nota: %v\n", err)
+		os.Exit(2)
+	}
+	// Any other argument is a mistyped command, e.g., "nota lsit", Run rejects it instead of opening the window
+	if len(args) > 0 && (cli.IsCommand(args[0]) || !strings.HasPrefix(args[0], "-")) {
+		os.Exit(cli.Run(args))
+	}
+
 	notaApp, err := app.NewApp()
 	if err != nil {
 		log.Fatalf("Failed to initialize application: %v", err)
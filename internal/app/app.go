@@ -1,8 +1,11 @@
 package app
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
+	"strings"
+	"time"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
@@ -16,8 +19,24 @@ import (
 	"github.com/curtisnewbie/nota/internal/i18n"
 	"github.com/curtisnewbie/nota/internal/infrastructure"
 	"github.com/curtisnewbie/nota/internal/repository"
+	"github.com/curtisnewbie/nota/internal/server"
 	"github.com/curtisnewbie/nota/internal/service"
 	"github.com/curtisnewbie/nota/internal/ui"
+	"gorm.io/gorm"
+)
+
+const (
+	// trashRetention is how long notes stay in the trash before they are purged
+	trashRetention = 30 * 24 * time.Hour
+
+	// trashPurgeInterval is how often the trash is checked for notes to purge
+	trashPurgeInterval = 6 * time.Hour
+
+	// draftInterval is how long after an unsaved edit a draft of the note is saved
+	draftInterval = 3 * time.Second
+
+	// reminderInterval is how often the reminders are checked for the ones that came due
+	reminderInterval = 30 * time.Second
 )
 
 // App represents the main application
@@ -27,12 +46,47 @@ type App struct {
 	noteService         service.NoteService
 	importExportService service.ImportExportService
 	configService       service.ConfigService
+	draftService        service.DraftService
+	diagnosticsService  service.DiagnosticsService
 	mainUI              *ui.MainUI
+	unlockView          *ui.UnlockView
 	currentNote         *domain.Note
 	hasUnsavedChanges   bool
+	conflictNote        *domain.Note
+	unlocked            *unlockedNote
+	autosaving          *pendingAutosave
+	trashPurgeDone      chan struct{}
+	snapshotDone        chan struct{}
+	reminderDone        chan struct{}
+	apiServer           *server.Server
+	autosaveDelay       time.Duration
+	autosaveTimer       *time.Timer
+	draftTimer          *time.Timer
+}
+
+// unlockedNote is the locked note being edited, with the key derived from its passphrase when it was unlocked and the
+// plaintext of the version last saved, so that saving it again neither derives the key nor decrypts the note
+type unlockedNote struct {
+	noteID  string
+	key     *domain.NoteKey
+	version int
+	title   string
+	content string
+}
+
+// pendingAutosave is an autosave running off the UI thread, finishAutosave applies its result on the UI thread
+type pendingAutosave struct {
+	done     chan struct{}
+	base     *domain.Note  // the current note when the autosave started
+	note     domain.Note   // the note being saved, updated by the save
+	unlocked *unlockedNote // a copy of a.unlocked when the autosave started
+	latest   *domain.Note  // the note loaded again once it's saved
+	err      error
 }
 
 // NewApp creates a new application instance
+//
+// When the database is encrypted, the window asks for its password and the application is initialized once it's unlocked.
 func NewApp() (*App, error) {
 	rail := flow.EmptyRail()
 
@@ -47,23 +101,8 @@ func NewApp() (*App, error) {
 		return nil, err
 	}
 
-	db, err := infrastructure.InitializeDatabase()
-	if err != nil {
-		rail.Errorf("Failed to initialize database: %v", err)
-		return nil, err
-	}
-
-	noteRepo := repository.NewSQLiteNoteRepository(db)
-	noteService := service.NewNoteService(noteRepo)
-	importExportService := service.NewImportExportService(noteRepo)
-	configRepo := repository.NewSQLiteConfigRepository(db)
-	configService := service.NewConfigService(configRepo)
-
 	appInstance := &App{
-		fyneApp:             fyneApp,
-		noteService:         noteService,
-		importExportService: importExportService,
-		configService:       configService,
+		fyneApp: fyneApp,
 	}
 
 	window := fyneApp.NewWindow("Nota")
@@ -74,30 +113,98 @@ func NewApp() (*App, error) {
 
 	appInstance.window = window
 
+	db, err := infrastructure.InitializeDatabase()
+	if errors.Is(err, infrastructure.ErrDatabaseEncrypted) {
+		appInstance.showUnlockView()
+		return appInstance, nil
+	}
+	if err != nil {
+		rail.Errorf("Failed to initialize database: %v", err)
+		return nil, err
+	}
+
+	appInstance.initialize(db)
+	return appInstance, nil
+}
+
+// showUnlockView asks for the password of the encrypted database instead of showing the notes
+func (a *App) showUnlockView() {
+	flow.EmptyRail().Infof("Database is encrypted, waiting for the password")
+	a.unlockView = ui.NewUnlockView(a)
+	a.window.SetContent(a.unlockView.Build())
+	a.unlockView.Focus(a.window)
+}
+
+// initialize creates the services and the main UI on the opened database
+func (a *App) initialize(db *gorm.DB) {
+	rail := flow.EmptyRail()
+
+	noteRepo := repository.NewSQLiteNoteRepository(db)
+	revisionRepo := repository.NewSQLiteNoteRevisionRepository(db)
+	tagRepo := repository.NewSQLiteTagRepository(db)
+	notebookRepo := repository.NewSQLiteNotebookRepository(db)
+	linkRepo := repository.NewSQLiteNoteLinkRepository(db)
+	taskRepo := repository.NewSQLiteNoteTaskRepository(db)
+	a.noteService = service.NewNoteService(noteRepo, revisionRepo, tagRepo, notebookRepo, linkRepo, taskRepo)
+	a.importExportService = service.NewImportExportService(noteRepo, tagRepo, a.noteService)
+	configRepo := repository.NewSQLiteConfigRepository(db)
+	a.configService = service.NewConfigService(configRepo)
+	a.draftService = service.NewDraftService(repository.NewSQLiteDraftRepository(db))
+	a.diagnosticsService = service.NewDiagnosticsService(repository.NewSQLiteDiagnosticsRepository(db))
+	a.autosaveDelay = a.configService.GetAutosaveDelay(rail)
+
 	// Load language preference
-	lang, err := configService.GetLanguage(rail)
+	lang, err := a.configService.GetLanguage(rail)
 	if err == nil {
 		i18n.SetLanguage(lang)
 		rail.Infof("Loaded language preference: %s", lang)
 	}
 
-	mainUI := ui.NewMainUI(window, noteService, importExportService, appInstance)
-	appInstance.mainUI = mainUI
+	mainUI := ui.NewMainUI(a.window, a.noteService, a.importExportService, a)
+	a.mainUI = mainUI
 
-	window.SetContent(mainUI.Build())
+	a.window.SetContent(mainUI.Build())
 
-	// Refresh the note list on startup
+	// Refresh the notebooks, the tags and the note list on startup
+	mainUI.RefreshNotebooks()
+	mainUI.RefreshTags()
 	mainUI.RefreshNoteList()
 
-	err = appInstance.loadLastNote()
+	err = a.loadLastNote()
 	if err != nil {
 		rail.Infof("No existing notes, ready to create new note")
 		mainUI.ShowEmptyState()
 	}
 
+	// Offer the edits left behind when nota was not closed properly
+	a.recoverDraft()
+
 	rail.Infof("Application initialized successfully")
+}
 
-	return appInstance, nil
+// onUnlockDatabase is called when user enters the password of the encrypted database
+func (a *App) onUnlockDatabase(password string) {
+	if a.mainUI != nil {
+		return
+	}
+
+	db, err := infrastructure.UnlockDatabase(password)
+	if errors.Is(err, infrastructure.ErrWrongPassword) || errors.Is(err, infrastructure.ErrEmptyPassword) {
+		a.unlockView.ShowError(i18n.T().Database.WrongPassword)
+		return
+	}
+	if err != nil {
+		flow.EmptyRail().Errorf("Failed to unlock database: %v", err)
+		a.unlockView.ShowError(err.Error())
+		return
+	}
+
+	a.unlockView = nil
+	a.initialize(db)
+	a.startTrashPurge()
+	a.startSnapshots()
+	a.startReminders()
+	a.startAPIServer()
 }
 
 // Run starts the application
@@ -112,6 +219,24 @@ func (a *App) Run() {
 		a.saveCurrentNote()
 	})
 
+	// Cmd+T / Ctrl+T opens today's daily note
+	canvas.AddShortcut(&desktop.CustomShortcut{
+		KeyName:  fyne.KeyT,
+		Modifier: fyne.KeyModifierShortcutDefault,
+	}, func(shortcut fyne.Shortcut) {
+		if a.mainUI != nil {
+			a.onOpenToday()
+		}
+	})
+
+	// An encrypted database is started once it's unlocked
+	if a.mainUI != nil {
+		a.startTrashPurge()
+		a.startSnapshots()
+		a.startReminders()
+		a.startAPIServer()
+	}
+
 	a.window.ShowAndRun()
 }
 
@@ -123,6 +248,13 @@ func (a *App) onClose() {
 			func(save bool) {
 				if save {
 					a.saveCurrentNote()
+					if a.hasUnsavedChanges {
+						// The save failed or ran into a conflict, the edits would be lost
+						return
+					}
+				} else {
+					a.deleteDraft(a.currentNote.ID)
+					a.hasUnsavedChanges = false
 				}
 				a.cleanup()
 				a.fyneApp.Quit()
@@ -137,9 +269,26 @@ func (a *App) onClose() {
 
 // cleanup cleans up resources before quitting
 func (a *App) cleanup() {
+	a.waitAutosave()
+	a.stopTrashPurge()
+	a.stopSnapshots()
+	a.stopReminders()
+	a.stopAPIServer()
+	if a.autosaveTimer != nil {
+		a.autosaveTimer.Stop()
+	}
+	if a.draftTimer != nil {
+		// The edits made since the last draft are saved now, the pending draft would never be written
+		a.draftTimer.Stop()
+		a.draftTimer = nil
+		a.saveDraft()
+	}
 	if a.mainUI != nil {
 		a.mainUI.Close()
 	}
+	if err := infrastructure.CloseDatabase(); err != nil {
+		flow.EmptyRail().Errorf("Failed to close database: %v", err)
+	}
 }
 
 // loadLastNote loads the last modified note
@@ -157,17 +306,20 @@ func (a *App) loadLastNote() error {
 	return nil
 }
 
-// isNoteEmpty checks if the current note is a new, empty note (no content)
+// isNoteEmpty checks if the current note is a new note that hasn't been edited, e.g., a blank note or a daily note
+// with only its title
 func (a *App) isNoteEmpty() bool {
 	return a.currentNote != nil &&
 		a.currentNote.ID == "" &&
-		a.mainUI.GetTitle() == "" &&
-		a.mainUI.GetContent() == ""
+		a.mainUI.GetTitle() == a.currentNote.Title &&
+		a.mainUI.GetContent() == a.currentNote.Content
 }
 
 // saveCurrentNote saves the current note
 func (a *App) saveCurrentNote() {
-	if a.currentNote == nil {
+	// The note is saved on top of a running autosave
+	a.waitAutosave()
+	if a.currentNote == nil || a.mainUI.IsLocked() || a.conflictNote != nil {
 		return
 	}
 
@@ -186,21 +338,31 @@ func (a *App) saveCurrentNote() {
 		err = a.noteService.CreateNote(rail, a.currentNote)
 	} else {
 		// Update existing note
-		err = a.noteService.UpdateNote(rail, a.currentNote)
+		err = a.updateNote(rail, a.currentNote, a.unlockedFor(a.currentNote.ID))
 	}
 
+	if errors.Is(err, service.ErrVersionConflict) {
+		a.onVersionConflict(a.currentNote)
+		return
+	}
 	if err != nil {
 		// Check if it's an empty title error and show translated message
 		if err == service.ErrEmptyTitle {
-			dialog.ShowError(fmt.Errorf(i18n.T().Dialog.TitleCannotBeEmpty), a.window)
+			dialog.ShowError(errors.New(i18n.T().Dialog.TitleCannotBeEmpty), a.window)
 		} else {
 			dialog.ShowError(err, a.window)
 		}
 		return
 	}
+	a.rememberSaved(a.currentNote)
+
+	if isNewNote {
+		a.deleteDraft("")
+	}
+	a.deleteDraft(a.currentNote.ID)
 
 	// Fetch latest note from database to get updated timestamps and other fields
-	latestNote, fetchErr := a.noteService.GetNote(rail, a.currentNote.ID)
+	latestNote, fetchErr := a.reloadNote(rail, a.currentNote)
 	if fetchErr != nil {
 		dialog.ShowError(fetchErr, a.window)
 		return
@@ -217,6 +379,168 @@ func (a *App) saveCurrentNote() {
 	a.mainUI.RefreshNoteList()
 }
 
+// updateNote saves the edits of an existing note, locked notes are encrypted again with the key of unlocked
+//
+// The edits of a locked note are compared with the plaintext last saved, unchanged notes aren't encrypted and saved
+// again. updateNote doesn't change the state of App so that autosave can run it off the UI thread, the caller
+// remembers the saved note with rememberSaved.
+func (a *App) updateNote(rail flow.Rail, note *domain.Note, unlocked *unlockedNote) error {
+	if !note.Encrypted {
+		return a.noteService.UpdateNote(rail, note)
+	}
+	if unlocked == nil || unlocked.noteID != note.ID {
+		return service.ErrNoteLocked
+	}
+	if note.Version == unlocked.version && note.Title == unlocked.title && note.Content == unlocked.content {
+		return nil
+	}
+	return a.noteService.UpdateLockedNote(rail, note, unlocked.key)
+}
+
+// unlockedFor returns the state of the locked note when it's unlocked, nil otherwise
+func (a *App) unlockedFor(noteID string) *unlockedNote {
+	if a.unlocked == nil || a.unlocked.noteID != noteID {
+		return nil
+	}
+	return a.unlocked
+}
+
+// rememberSaved remembers the plaintext of a locked note that was just saved, for the next save to compare with
+func (a *App) rememberSaved(note *domain.Note) {
+	unlocked := a.unlockedFor(note.ID)
+	if unlocked == nil {
+		return
+	}
+	if !note.Encrypted {
+		// The lock was removed elsewhere, the note was saved as plaintext
+		a.unlocked = nil
+		return
+	}
+	unlocked.version = note.Version
+	unlocked.title = note.Title
+	unlocked.content = note.Content
+}
+
+// reloadNote loads a note that was just saved, a locked note keeps the plaintext it was saved with so that it stays unlocked
+func (a *App) reloadNote(rail flow.Rail, saved *domain.Note) (*domain.Note, error) {
+	latest, err := a.noteService.GetNote(rail, saved.ID)
+	if err != nil {
+		return nil, err
+	}
+	if latest.Encrypted && latest.Version == saved.Version {
+		latest.Content = saved.Content
+	}
+	return latest, nil
+}
+
+// unlockNote decrypts a locked note with the key of the note being edited, the note is returned as is when it
+// can't be decrypted, and is then shown as locked
+func (a *App) unlockNote(note *domain.Note) *domain.Note {
+	unlocked := a.unlockedFor(note.ID)
+	if !note.Encrypted || unlocked == nil {
+		return note
+	}
+	content, err := unlocked.key.Decrypt(note.Content)
+	if err != nil {
+		return note
+	}
+	decrypted := *note
+	decrypted.Content = content
+	return &decrypted
+}
+
+// onVersionConflict is called when the edited note was saved elsewhere since it was loaded
+func (a *App) onVersionConflict(edited *domain.Note) {
+	rail := flow.EmptyRail()
+	latest, err := a.noteService.GetNote(rail, edited.ID)
+	if err != nil {
+		dialog.ShowError(err, a.window)
+		return
+	}
+	latest = a.unlockNote(latest)
+
+	// The edits are kept aside, the current note may change before the conflict is resolved
+	conflictNote := *edited
+	a.conflictNote = &conflictNote
+	a.mainUI.ShowConflict(a.conflictNote, latest)
+}
+
+// onResolveConflict is called when user chooses how to resolve a version conflict
+func (a *App) onResolveConflict(resolution ui.ConflictResolution) {
+	edited := a.conflictNote
+	a.conflictNote = nil
+	if edited == nil {
+		return
+	}
+
+	rail := flow.EmptyRail()
+	latest, err := a.noteService.GetNote(rail, edited.ID)
+	if err != nil {
+		dialog.ShowError(err, a.window)
+		return
+	}
+
+	resolved := latest
+	switch resolution {
+	case ui.ConflictOverwrite:
+		edited.Version = latest.Version
+		err = a.updateNote(rail, edited, a.unlockedFor(edited.ID))
+		if errors.Is(err, service.ErrVersionConflict) {
+			a.onVersionConflict(edited)
+			return
+		}
+		if err == nil {
+			a.rememberSaved(edited)
+		}
+		resolved = edited
+	case ui.ConflictSaveAsNew:
+		now := atom.Now()
+		resolved = &domain.Note{
+			Title:      edited.Title + i18n.T().Conflict.CopySuffix,
+			Content:    edited.Content,
+			NotebookID: edited.NotebookID,
+			Version:    1,
+			Metadata:   make(map[string]interface{}),
+			CreatedAt:  now,
+			UpdatedAt:  now,
+		}
+		err = a.noteService.CreateNote(rail, resolved)
+		if err == nil && edited.Encrypted {
+			// The copy is locked with the same key as the note it was edited from
+			if unlocked := a.unlockedFor(edited.ID); unlocked != nil {
+				err = a.noteService.LockNote(rail, resolved, unlocked.key)
+			} else {
+				err = service.ErrNoteLocked
+			}
+		}
+	}
+	if err != nil {
+		dialog.ShowError(err, a.window)
+		return
+	}
+
+	// Only replace the editor content when the conflicting note is still being edited
+	if a.currentNote != nil && a.currentNote.ID == edited.ID {
+		if unlocked := a.unlockedFor(edited.ID); unlocked != nil && resolved.ID != edited.ID {
+			// The locked copy is edited further with the same key
+			a.unlocked = &unlockedNote{noteID: resolved.ID, key: unlocked.key}
+		}
+		note, err := a.noteService.GetNote(rail, resolved.ID)
+		if err != nil {
+			dialog.ShowError(err, a.window)
+			return
+		}
+		note = a.unlockNote(note)
+		a.mainUI.StartSaving()
+		a.currentNote = note
+		a.mainUI.DisplayNote(note)
+		a.mainUI.EndSaving()
+		a.hasUnsavedChanges = false
+		a.mainUI.MarkAsSaved()
+	}
+	a.mainUI.RefreshNoteList()
+}
+
 // onNoteSelected is called when a note is selected from the list
 func (a *App) onNoteSelected(note *domain.Note) {
 	if a.hasUnsavedChanges && !a.isNoteEmpty() {
@@ -225,6 +549,8 @@ func (a *App) onNoteSelected(note *domain.Note) {
 			func(save bool) {
 				if save {
 					a.saveCurrentNote()
+				} else {
+					a.deleteDraft(a.currentNote.ID)
 				}
 				// Always load the selected note as the current note, regardless of save choice
 				rail := flow.EmptyRail()
@@ -236,6 +562,7 @@ func (a *App) onNoteSelected(note *domain.Note) {
 				a.mainUI.StartSaving()
 				defer a.mainUI.EndSaving()
 				a.currentNote = latestNote
+				a.unlocked = nil
 				a.hasUnsavedChanges = false
 				a.mainUI.DisplayNote(latestNote)
 				a.mainUI.MarkAsSaved()
@@ -253,6 +580,7 @@ func (a *App) onNoteSelected(note *domain.Note) {
 		a.mainUI.StartSaving()
 		defer a.mainUI.EndSaving()
 		a.currentNote = latestNote
+		a.unlocked = nil
 		a.hasUnsavedChanges = false
 		a.mainUI.DisplayNote(latestNote)
 		a.mainUI.MarkAsSaved()
@@ -263,36 +591,294 @@ func (a *App) onNoteSelected(note *domain.Note) {
 func (a *App) onContentChanged() {
 	a.hasUnsavedChanges = true
 	a.mainUI.MarkAsUnsaved()
+	a.scheduleDraft()
+	a.scheduleAutosave()
+}
+
+// scheduleDraft saves a draft of the edits draftInterval after the first unsaved edit
+func (a *App) scheduleDraft() {
+	if a.draftTimer != nil {
+		return
+	}
+	a.draftTimer = time.AfterFunc(draftInterval, func() {
+		fyne.Do(func() {
+			a.draftTimer = nil
+			a.saveDraft()
+		})
+	})
+}
+
+// saveDraft saves the unsaved edits of the current note, so that they survive a crash
+func (a *App) saveDraft() {
+	if !a.hasUnsavedChanges || a.currentNote == nil || a.isNoteEmpty() {
+		return
+	}
+	if a.currentNote.Encrypted {
+		// Drafts are saved as plaintext, the edits of locked notes are only kept in memory
+		return
+	}
+
+	draft := &domain.Draft{
+		NoteID:      a.currentNote.ID,
+		Title:       a.mainUI.GetTitle(),
+		Content:     a.mainUI.GetContent(),
+		NotebookID:  a.currentNote.NotebookID,
+		BaseVersion: a.currentNote.Version,
+	}
+	_ = a.draftService.SaveDraft(flow.EmptyRail(), draft)
+}
+
+// deleteDraft deletes the draft of a note once its edits are saved or discarded
+func (a *App) deleteDraft(noteID string) {
+	_ = a.draftService.DeleteDraft(flow.EmptyRail(), noteID)
+}
+
+// recoverDraft offers to restore the most recent draft, the other drafts are dropped
+func (a *App) recoverDraft() {
+	t := i18n.T()
+	rail := flow.EmptyRail()
+	drafts, err := a.draftService.ListDrafts(rail)
+	if err != nil || len(drafts) == 0 {
+		return
+	}
+
+	draft := drafts[0]
+	title := draft.Title
+	if title == "" {
+		title = t.Dialog.Untitled
+	}
+	rail.Infof("Found draft of note %q from %s", draft.NoteID, draft.UpdatedAt.Format(time.RFC3339))
+
+	dialog.ShowConfirm(t.Dialog.RecoverDraft,
+		fmt.Sprintf(t.Dialog.SureRecoverDraft, title, draft.UpdatedAt.Format("2006/01/02 15:04:05")),
+		func(recover bool) {
+			_ = a.draftService.DeleteAllDrafts(rail)
+			if recover {
+				a.restoreDraft(draft)
+			}
+		},
+		a.window,
+	)
+}
+
+// restoreDraft loads the edits of a draft into the editor as unsaved changes
+func (a *App) restoreDraft(draft *domain.Draft) {
+	rail := flow.EmptyRail()
+	now := atom.Now()
+	note := &domain.Note{
+		Version:    1,
+		NotebookID: draft.NotebookID,
+		Metadata:   make(map[string]interface{}),
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if draft.NoteID != "" {
+		if existing, err := a.noteService.GetNote(rail, draft.NoteID); err == nil {
+			// Saving is checked against the version the edits started from
+			note = existing
+			note.Version = draft.BaseVersion
+		} else {
+			rail.Warnf("Note %s of the draft no longer exists, recovering it as a new note", draft.NoteID)
+		}
+	}
+	note.Title = draft.Title
+	note.Content = draft.Content
+
+	a.mainUI.StartSaving()
+	a.currentNote = note
+	a.mainUI.DisplayNote(note)
+	a.mainUI.EndSaving()
+	a.hasUnsavedChanges = true
+	a.mainUI.MarkAsUnsaved()
+	a.saveDraft()
+}
+
+// scheduleAutosave saves the current note once there are no edits for the autosave delay
+func (a *App) scheduleAutosave() {
+	if a.autosaveDelay <= 0 {
+		return
+	}
+	if a.autosaveTimer != nil {
+		a.autosaveTimer.Stop()
+	}
+	a.autosaveTimer = time.AfterFunc(a.autosaveDelay, func() {
+		fyne.Do(func() {
+			a.autosave()
+		})
+	})
+}
+
+// autosave saves the current note without interrupting editing, errors other than conflicts are only logged
+//
+// The note is saved off the UI thread since encrypting a locked note takes a while, editing goes on meanwhile and
+// finishAutosave applies the result once it's saved.
+func (a *App) autosave() {
+	if !a.hasUnsavedChanges || a.currentNote == nil || a.conflictNote != nil || a.mainUI.IsLocked() {
+		return
+	}
+	if a.autosaving != nil {
+		// The edits made since the running autosave started are saved after it
+		a.scheduleAutosave()
+		return
+	}
+
+	title := a.mainUI.GetTitle()
+	content := a.mainUI.GetContent()
+	if strings.TrimSpace(title) == "" {
+		// Notes can't be saved without a title, the draft keeps the edits meanwhile
+		return
+	}
+
+	p := &pendingAutosave{done: make(chan struct{}), base: a.currentNote, note: *a.currentNote}
+	p.note.Title = title
+	p.note.Content = content
+	// The metadata of the current note may change while saving
+	p.note.Metadata = maps.Clone(a.currentNote.Metadata)
+	if unlocked := a.unlockedFor(p.note.ID); unlocked != nil {
+		copied := *unlocked
+		p.unlocked = &copied
+	}
+	a.autosaving = p
+
+	go func() {
+		rail := flow.EmptyRail()
+		if p.note.ID == "" {
+			p.err = a.noteService.CreateNote(rail, &p.note)
+		} else {
+			p.err = a.updateNote(rail, &p.note, p.unlocked)
+		}
+		if p.err == nil {
+			latest, err := a.reloadNote(rail, &p.note)
+			if err != nil {
+				rail.Warnf("Failed to load autosaved note: %v", err)
+				latest = &p.note
+			}
+			p.latest = latest
+		}
+		close(p.done)
+		fyne.Do(func() {
+			a.finishAutosave(p)
+		})
+	}()
+}
+
+// finishAutosave applies the result of an autosave on the UI thread, the editor is only updated when it still shows
+// the note that was saved
+func (a *App) finishAutosave(p *pendingAutosave) {
+	if a.autosaving != p {
+		// Already finished by waitAutosave
+		return
+	}
+	a.autosaving = nil
+
+	current := a.currentNote == p.base
+	if errors.Is(p.err, service.ErrVersionConflict) {
+		if current {
+			a.onVersionConflict(&p.note)
+		}
+		return
+	}
+	if p.err != nil {
+		flow.EmptyRail().Warnf("Failed to autosave note: %v", p.err)
+		return
+	}
+	a.rememberSaved(&p.note)
+	a.mainUI.RefreshNoteList()
+	if !current {
+		return
+	}
+
+	a.currentNote = p.latest
+	if p.base.ID == "" {
+		a.deleteDraft("")
+	}
+	if a.mainUI.GetTitle() != p.note.Title || a.mainUI.GetContent() != p.note.Content {
+		// Edited while saving, the draft of the edits is based on the saved version from now on
+		a.mainUI.RefreshNoteInfo(p.latest)
+		a.saveDraft()
+		return
+	}
+
+	a.deleteDraft(p.note.ID)
+	a.hasUnsavedChanges = false
+	if p.latest.Title != p.note.Title || p.latest.Content != p.note.Content {
+		// Saving rewrote the content, e.g., wiki links were resolved
+		a.mainUI.StartSaving()
+		a.mainUI.DisplayNote(p.latest)
+		a.mainUI.EndSaving()
+	} else {
+		a.mainUI.RefreshNoteInfo(p.latest)
+	}
+	a.mainUI.MarkAsSaved()
+}
+
+// waitAutosave waits for the running autosave and applies its result, before the current note is saved by other means
+func (a *App) waitAutosave() {
+	if p := a.autosaving; p != nil {
+		<-p.done
+		a.finishAutosave(p)
+	}
+}
+
+// onAutosaveDelayChanged is called when user changes the autosave delay, 0 turns autosave off
+func (a *App) onAutosaveDelayChanged(delay time.Duration) {
+	if err := a.configService.SaveAutosaveDelay(flow.EmptyRail(), delay); err != nil {
+		dialog.ShowError(err, a.window)
+		return
+	}
+	a.autosaveDelay = delay
+	if a.autosaveTimer != nil {
+		a.autosaveTimer.Stop()
+		a.autosaveTimer = nil
+	}
+	if a.hasUnsavedChanges {
+		a.scheduleAutosave()
+	}
 }
 
 // onCreateNote is called when user wants to create a new note
 func (a *App) onCreateNote() {
+	a.beforeNewNote(func() {
+		a.createNewNote(nil)
+	})
+}
+
+// beforeNewNote asks whether to save the unsaved changes of the current note before creating a new note with create
+func (a *App) beforeNewNote(create func()) {
 	if a.hasUnsavedChanges && !a.isNoteEmpty() {
 		dialog.ShowConfirm("Unsaved Changes",
 			"You have unsaved changes. Do you want to save them before creating a new note?",
 			func(save bool) {
 				if save {
 					a.saveCurrentNote()
+				} else {
+					a.deleteDraft(a.currentNote.ID)
 				}
-				a.createNewNote()
+				create()
 			},
 			a.window,
 		)
 	} else {
-		a.createNewNote()
+		create()
 	}
 }
 
 // createNewNote creates a new note in memory (not saved to database yet)
-func (a *App) createNewNote() {
-	newNote := &domain.Note{
-		Title:     "",
-		Content:   "",
-		Version:   1,
-		Metadata:  make(map[string]interface{}),
-		CreatedAt: atom.Now(),
-		UpdatedAt: atom.Now(),
+//
+// newNote is the note to start from, e.g., one created from a template or the daily note of a day, a blank note is
+// created when it's nil.
+func (a *App) createNewNote(newNote *domain.Note) {
+	if newNote == nil {
+		newNote = &domain.Note{
+			Title:    "",
+			Content:  "",
+			Version:  1,
+			Metadata: make(map[string]interface{}),
+		}
 	}
+	newNote.NotebookID = a.mainUI.GetSelectedNotebookID()
+	newNote.CreatedAt = atom.Now()
+	newNote.UpdatedAt = atom.Now()
 
 	a.mainUI.StartSaving()
 	defer a.mainUI.EndSaving()
@@ -302,30 +888,303 @@ func (a *App) createNewNote() {
 	a.mainUI.MarkAsUnsaved()
 }
 
-// onDeleteNote is called when user wants to delete the current note
-func (a *App) onDeleteNote() {
-	if a.currentNote == nil {
-		dialog.ShowInformation("No Note Selected", "Please select a note to delete", a.window)
+// onShowTemplates is called when user wants to create a new note from a template
+func (a *App) onShowTemplates() {
+	templates, err := a.noteService.ListTemplates(flow.EmptyRail())
+	if err != nil {
+		dialog.ShowError(err, a.window)
 		return
 	}
+	a.mainUI.ShowTemplates(templates)
+}
 
-	if a.currentNote.ID == "" {
-		t := i18n.T()
-		dialog.ShowInformation(t.Dialog.UnsavedNote, t.Dialog.CannotDelete, a.window)
+// onCreateFromTemplate is called when user has picked a template and filled in its custom fields
+func (a *App) onCreateFromTemplate(templateID string, fields map[string]string) {
+	template, err := a.noteService.GetNote(flow.EmptyRail(), templateID)
+	if err != nil {
+		dialog.ShowError(err, a.window)
 		return
 	}
 
-	dialog.ShowConfirm(i18n.T().Dialog.DeleteNote,
-		i18n.T().Dialog.SureDelete,
-		func(confirmed bool) {
-			if confirmed {
-				rail := flow.EmptyRail()
-				err := a.noteService.DeleteNote(rail, a.currentNote.ID)
-				if err != nil {
-					dialog.ShowError(err, a.window)
+	// The clipboard is only read when it's used, it may hold something large or private
+	clipboard := ""
+	if domain.UsesTemplateVar(domain.TemplateVarClipboard, template.Title, template.Content) {
+		clipboard = a.fyneApp.Clipboard().Content()
+	}
+	values := domain.BuiltinTemplateValues(time.Now(), clipboard)
+	for name, value := range fields {
+		values[name] = value
+	}
+
+	a.beforeNewNote(func() {
+		a.createNewNote(domain.NewNoteFromTemplate(template, values))
+	})
+}
+
+// onOpenToday is called when user wants to open today's daily note
+func (a *App) onOpenToday() {
+	a.onOpenDay(time.Now())
+}
+
+// onOpenAdjacentDay is called when user wants to open the daily note offset days from the day of the current note,
+// or from today when the current note is not a daily note
+func (a *App) onOpenAdjacentDay(offset int) {
+	date := time.Now()
+	if a.currentNote != nil {
+		if day, ok := a.currentNote.DailyDate(); ok {
+			date = day
+		}
+	}
+	a.onOpenDay(date.AddDate(0, 0, offset))
+}
+
+// onOpenDay opens the daily note of a day, it's created in memory when the day doesn't have one yet
+func (a *App) onOpenDay(date time.Time) {
+	rail := flow.EmptyRail()
+	format := a.configService.GetDailyTitleFormat(rail)
+	note, err := a.noteService.GetDailyNote(rail, date, format)
+	if err == nil {
+		if a.currentNote != nil && a.currentNote.ID == note.ID {
+			return
+		}
+		a.onNoteSelected(note)
+		return
+	}
+	if !errors.Is(err, service.ErrNoteNotFound) {
+		dialog.ShowError(err, a.window)
+		return
+	}
+
+	if a.currentNote != nil && a.currentNote.ID == "" {
+		if day, ok := a.currentNote.DailyDate(); ok && day.Format(domain.DailyDateLayout) == date.Format(domain.DailyDateLayout) {
+			// The new daily note of the day is already open
+			return
+		}
+	}
+	a.beforeNewNote(func() {
+		a.createNewNote(domain.NewDailyNote(date, format))
+	})
+}
+
+// onShowCalendar is called when user wants to pick the day to open the daily note of
+func (a *App) onShowCalendar() {
+	date := time.Now()
+	if a.currentNote != nil {
+		if day, ok := a.currentNote.DailyDate(); ok {
+			date = day
+		}
+	}
+	a.mainUI.ShowCalendar(date)
+}
+
+// onDailyTitleFormatChanged is called when user picks the layout that new daily notes are titled with
+func (a *App) onDailyTitleFormatChanged(format string) {
+	if err := a.configService.SaveDailyTitleFormat(flow.EmptyRail(), format); err != nil {
+		dialog.ShowError(err, a.window)
+	}
+}
+
+// isTemplate checks whether the current note is used as a template
+func (a *App) isTemplate() bool {
+	return a.currentNote != nil && a.currentNote.ID != "" && a.currentNote.IsTemplate()
+}
+
+// onSetTemplate is called when user wants to use the current note as a template, or to stop using it as one
+func (a *App) onSetTemplate(template bool) {
+	if a.currentNote == nil || a.currentNote.ID == "" {
+		t := i18n.T()
+		dialog.ShowInformation(t.Dialog.UnsavedNote, t.Template.SaveBefore, a.window)
+		return
+	}
+
+	if err := a.noteService.SetTemplate(flow.EmptyRail(), a.currentNote.ID, template); err != nil {
+		dialog.ShowError(err, a.window)
+		return
+	}
+	if a.currentNote.Metadata == nil {
+		a.currentNote.Metadata = make(map[string]interface{})
+	}
+	if template {
+		a.currentNote.Metadata[domain.TemplateMetadataKey] = true
+	} else {
+		delete(a.currentNote.Metadata, domain.TemplateMetadataKey)
+	}
+}
+
+// onShowReminder is called when user wants to set when the reminder of the current note is due
+func (a *App) onShowReminder() {
+	if a.currentNote == nil || a.currentNote.ID == "" {
+		t := i18n.T()
+		dialog.ShowInformation(t.Dialog.UnsavedNote, t.Reminder.SaveBefore, a.window)
+		return
+	}
+
+	var dueAt *time.Time
+	if a.currentNote.DueAt != nil {
+		due := a.currentNote.DueAt.Unwrap()
+		dueAt = &due
+	}
+	a.mainUI.ShowReminderEditor(dueAt)
+}
+
+// onSetReminder is called when user sets when the reminder of the current note is due, nil completes the reminder
+func (a *App) onSetReminder(dueAt *time.Time) {
+	if a.currentNote == nil || a.currentNote.ID == "" {
+		return
+	}
+	if err := a.noteService.SetReminder(flow.EmptyRail(), a.currentNote.ID, dueAt); err != nil {
+		dialog.ShowError(err, a.window)
+		return
+	}
+	a.refreshReminder(a.currentNote.ID)
+}
+
+// onOpenReminder is called when user wants to open the note of a reminder that came due
+func (a *App) onOpenReminder(noteID string) {
+	if a.currentNote != nil && a.currentNote.ID == noteID {
+		return
+	}
+	note, err := a.noteService.GetNote(flow.EmptyRail(), noteID)
+	if err != nil {
+		dialog.ShowError(err, a.window)
+		return
+	}
+	a.onNoteSelected(note)
+}
+
+// onSnoozeReminder is called when user wants to be reminded of a note again after d
+func (a *App) onSnoozeReminder(noteID string, d time.Duration) {
+	if _, err := a.noteService.SnoozeReminder(flow.EmptyRail(), noteID, d); err != nil {
+		dialog.ShowError(err, a.window)
+		return
+	}
+	a.refreshReminder(noteID)
+}
+
+// onCompleteReminder is called when user is done with the reminder of a note, the reminder is removed
+func (a *App) onCompleteReminder(noteID string) {
+	if err := a.noteService.SetReminder(flow.EmptyRail(), noteID, nil); err != nil {
+		dialog.ShowError(err, a.window)
+		return
+	}
+	a.refreshReminder(noteID)
+}
+
+// refreshReminder shows the reminder of a note after it's changed, in the note list and in the editor if it's the current note
+func (a *App) refreshReminder(noteID string) {
+	if a.currentNote != nil && a.currentNote.ID == noteID {
+		if note, err := a.noteService.GetNote(flow.EmptyRail(), noteID); err == nil {
+			a.currentNote.DueAt = note.DueAt
+			a.currentNote.RemindedAt = note.RemindedAt
+			a.mainUI.RefreshNoteInfo(a.currentNote)
+		}
+	}
+	a.mainUI.RefreshNoteList()
+}
+
+// onSetStarred is called when user stars or unstars a note, starred notes are listed first
+func (a *App) onSetStarred(noteID string, starred bool) {
+	if err := a.noteService.SetStarred(flow.EmptyRail(), noteID, starred); err != nil {
+		dialog.ShowError(err, a.window)
+		return
+	}
+	if a.currentNote != nil && a.currentNote.ID == noteID {
+		a.currentNote.Starred = starred
+		a.mainUI.RefreshNoteInfo(a.currentNote)
+	}
+	a.mainUI.RefreshNoteList()
+}
+
+// isArchived checks whether the current note is archived
+func (a *App) isArchived() bool {
+	return a.currentNote != nil && a.currentNote.ID != "" && a.currentNote.ArchivedAt != nil
+}
+
+// onSetArchived is called when user archives the current note or unarchives it, the note stays open
+func (a *App) onSetArchived(archived bool) {
+	if a.currentNote == nil || a.currentNote.ID == "" {
+		t := i18n.T()
+		dialog.ShowInformation(t.Dialog.UnsavedNote, t.Archive.SaveBefore, a.window)
+		return
+	}
+
+	rail := flow.EmptyRail()
+	if err := a.noteService.SetArchived(rail, a.currentNote.ID, archived); err != nil {
+		dialog.ShowError(err, a.window)
+		return
+	}
+	if note, err := a.noteService.GetNote(rail, a.currentNote.ID); err == nil {
+		a.currentNote.ArchivedAt = note.ArchivedAt
+	}
+	a.mainUI.RefreshNoteList()
+	a.mainUI.RefreshTasks()
+}
+
+// onSetTaskDone is called when user ticks or unticks a task, the task is rewritten in its note
+func (a *App) onSetTaskDone(task *domain.NoteTask, done bool) {
+	t := i18n.T()
+	current := a.currentNote != nil && a.currentNote.ID == task.NoteID
+	if current && a.hasUnsavedChanges {
+		dialog.ShowInformation(t.Dialog.UnsavedNote, t.Task.SaveBefore, a.window)
+		a.mainUI.RefreshTasks()
+		return
+	}
+
+	note, err := a.noteService.SetTaskDone(flow.EmptyRail(), task.NoteID, task.Line, done)
+	if err != nil {
+		dialog.ShowError(err, a.window)
+		a.mainUI.RefreshTasks()
+		return
+	}
+	if current {
+		a.mainUI.StartSaving()
+		a.currentNote = note
+		a.mainUI.DisplayNote(note)
+		a.mainUI.MarkAsSaved()
+		a.mainUI.EndSaving()
+	}
+	a.mainUI.RefreshNoteList()
+	a.mainUI.RefreshTasks()
+}
+
+// onOpenTask is called when user wants to open the note of a task
+func (a *App) onOpenTask(task *domain.NoteTask) {
+	if a.currentNote != nil && a.currentNote.ID == task.NoteID {
+		return
+	}
+	note, err := a.noteService.GetNote(flow.EmptyRail(), task.NoteID)
+	if err != nil {
+		dialog.ShowError(err, a.window)
+		return
+	}
+	a.onNoteSelected(note)
+}
+
+// onDeleteNote is called when user wants to delete the current note
+func (a *App) onDeleteNote() {
+	if a.currentNote == nil {
+		dialog.ShowInformation("No Note Selected", "Please select a note to delete", a.window)
+		return
+	}
+
+	if a.currentNote.ID == "" {
+		t := i18n.T()
+		dialog.ShowInformation(t.Dialog.UnsavedNote, t.Dialog.CannotDelete, a.window)
+		return
+	}
+
+	dialog.ShowConfirm(i18n.T().Dialog.DeleteNote,
+		i18n.T().Dialog.SureDelete,
+		func(confirmed bool) {
+			if confirmed {
+				rail := flow.EmptyRail()
+				err := a.noteService.DeleteNote(rail, a.currentNote.ID)
+				if err != nil {
+					dialog.ShowError(err, a.window)
 					return
 				}
 
+				a.deleteDraft(a.currentNote.ID)
 				a.currentNote = nil
 				a.hasUnsavedChanges = false
 				a.mainUI.RefreshNoteList()
@@ -382,10 +1241,49 @@ func (a *App) onImportNote() {
 	fd.Show()
 }
 
+// onImportMarkdown is called when user wants to import a directory of markdown files
+func (a *App) onImportMarkdown() {
+	t := i18n.T()
+	fd := dialog.NewFolderOpen(func(dir fyne.ListableURI, err error) {
+		if err != nil || dir == nil {
+			return
+		}
+
+		dialog.ShowConfirm(t.Dialog.DuplicateNotes, t.Dialog.OverwriteDuplicates,
+			func(overwrite bool) {
+				rail := flow.EmptyRail()
+				results, err := a.importExportService.ImportMarkdown(rail, dir.Path(), func(note *domain.Note) bool {
+					return overwrite
+				})
+				if err != nil {
+					dialog.ShowError(err, a.window)
+					return
+				}
+
+				counts := map[service.ImportStatus]int{}
+				var lines []string
+				for _, result := range results {
+					counts[result.Status]++
+					if result.Status != service.ImportStatusImported {
+						lines = append(lines, fmt.Sprintf("[%s] %s: %s", result.Status, result.Path, result.Reason))
+					}
+				}
+
+				a.mainUI.RefreshTags()
+				a.mainUI.RefreshNoteList()
+				a.mainUI.ShowImportReport(fmt.Sprintf(t.Dialog.ImportSummary, counts[service.ImportStatusImported],
+					counts[service.ImportStatusSkipped], counts[service.ImportStatusFailed]), lines)
+			},
+			a.window,
+		)
+	}, a.window)
+	fd.Show()
+}
+
 // onExportNote is called when user wants to export all notes
 func (a *App) onExportNote() {
 	rail := flow.EmptyRail()
-	notes, err := a.noteService.ListNotes(rail)
+	notes, err := a.noteService.ListAllNotes(rail)
 	if err != nil {
 		dialog.ShowError(err, a.window)
 		return
@@ -402,40 +1300,48 @@ func (a *App) onExportNote() {
 		}
 		defer writer.Close()
 
-		// Create export structure with all notes
-		type ExportData struct {
-			Version int               `json:"version"`
-			Notes   []domain.NoteJSON `json:"notes"`
-			Count   int               `json:"count"`
+		err = a.importExportService.ExportNotesTo(rail, notes, writer)
+		if err != nil {
+			dialog.ShowError(err, a.window)
+			return
 		}
 
-		exportData := ExportData{
-			Version: 1,
-			Notes:   make([]domain.NoteJSON, len(notes)),
-			Count:   len(notes),
-		}
+		dialog.ShowInformation("Export Successful", fmt.Sprintf("Successfully exported %d notes", len(notes)), a.window)
+	}, a.window)
 
-		for i, note := range notes {
-			exportData.Notes[i] = note.ToJSON()
-		}
+	fd.SetFileName("nota_export.json")
+	fd.SetFilter(storage.NewExtensionFileFilter([]string{".json"}))
+	fd.Show()
+}
 
-		data, err := json.MarshalIndent(exportData, "", "  ")
-		if err != nil {
-			dialog.ShowError(err, a.window)
+// onExportMarkdown is called when user wants to export all notes as markdown files
+func (a *App) onExportMarkdown() {
+	t := i18n.T()
+	rail := flow.EmptyRail()
+	notes, err := a.noteService.ListAllNotes(rail)
+	if err != nil {
+		dialog.ShowError(err, a.window)
+		return
+	}
+
+	if len(notes) == 0 {
+		dialog.ShowInformation(t.Dialog.NoNotes, t.Dialog.NoNotesToExport, a.window)
+		return
+	}
+
+	fd := dialog.NewFolderOpen(func(dir fyne.ListableURI, err error) {
+		if err != nil || dir == nil {
 			return
 		}
 
-		_, err = writer.Write(data)
+		err = a.importExportService.ExportNotesAsMarkdown(rail, notes, dir.Path())
 		if err != nil {
 			dialog.ShowError(err, a.window)
 			return
 		}
 
-		dialog.ShowInformation("Export Successful", fmt.Sprintf("Successfully exported %d notes", len(notes)), a.window)
+		dialog.ShowInformation(t.Dialog.ExportSuccessful, fmt.Sprintf(t.Dialog.ExportedNotes, len(notes), dir.Path()), a.window)
 	}, a.window)
-
-	fd.SetFileName("nota_export.json")
-	fd.SetFilter(storage.NewExtensionFileFilter([]string{".json"}))
 	fd.Show()
 }
 
@@ -446,9 +1352,9 @@ func (a *App) onSearch(query string) {
 	if query == "" {
 		// Not searching
 		if noteList.GetCurrentQuery() == "" && noteList.HasMore() && noteList.IsLoading() {
-			// Loading more pages of all notes (Load More button clicked)
+			// Loading more pages of all notes, or of the notes in the selected notebook or with selected tags (Load More button clicked)
 			rail := flow.EmptyRail()
-			notes, err := a.noteService.ListNotesPaginated(rail, noteList.GetOffset(), noteList.GetPageSize())
+			notes, err := a.noteService.SearchNotesPaginated(rail, a.mainUI.NoteFilter(""), noteList.GetOffset(), noteList.GetPageSize())
 			if err != nil {
 				dialog.ShowError(err, a.window)
 				return
@@ -470,7 +1376,7 @@ func (a *App) onSearch(query string) {
 		// New query - reset and load first page
 		noteList.SetCurrentQuery(query)
 		rail := flow.EmptyRail()
-		notes, err := a.noteService.SearchNotesPaginated(rail, query, 0, noteList.GetPageSize())
+		notes, err := a.noteService.SearchNotesPaginated(rail, a.mainUI.NoteFilter(query), 0, noteList.GetPageSize())
 		if err != nil {
 			dialog.ShowError(err, a.window)
 			return
@@ -479,7 +1385,7 @@ func (a *App) onSearch(query string) {
 	} else if noteList.HasMore() && noteList.IsLoading() {
 		// Same query - load next page (Load More button clicked)
 		rail := flow.EmptyRail()
-		notes, err := a.noteService.SearchNotesPaginated(rail, query, noteList.GetOffset(), noteList.GetPageSize())
+		notes, err := a.noteService.SearchNotesPaginated(rail, a.mainUI.NoteFilter(query), noteList.GetOffset(), noteList.GetPageSize())
 		if err != nil {
 			dialog.ShowError(err, a.window)
 			return
@@ -509,33 +1415,1047 @@ func (a *App) onPinNote(pin bool) {
 	}
 }
 
-// GetDatabaseLocation returns the database location
-func (a *App) GetDatabaseLocation() string {
-	return infrastructure.GetDatabaseLocation()
-}
+// onShowHistory is called when user wants to see the revision history of the current note
+func (a *App) onShowHistory() {
+	if a.currentNote == nil || a.currentNote.ID == "" {
+		t := i18n.T()
+		dialog.ShowInformation(t.History.Title, t.History.NoRevisions, a.window)
+		return
+	}
+
+	if a.currentNote.Encrypted {
+		t := i18n.T()
+		dialog.ShowInformation(t.History.Title, t.Lock.NoHistory, a.window)
+		return
+	}
 
-// ListNotes returns all notes
-func (a *App) ListNotes() ([]*domain.Note, error) {
 	rail := flow.EmptyRail()
-	return a.noteService.ListNotes(rail)
+	revisions, err := a.noteService.ListRevisions(rail, a.currentNote.ID)
+	if err != nil {
+		dialog.ShowError(err, a.window)
+		return
+	}
+	a.mainUI.ShowHistory(a.currentNote, revisions)
 }
 
-// OnNoteSelected implements NoteSelectionHandler interface
-func (a *App) OnNoteSelected(note *domain.Note) {
-	a.onNoteSelected(note)
-}
+// onRestoreRevision is called when user wants to restore the current note to a previous version
+func (a *App) onRestoreRevision(version int) {
+	if a.currentNote == nil || a.currentNote.ID == "" {
+		return
+	}
 
-// OnContentChanged implements NoteEditHandler interface
-func (a *App) OnContentChanged() {
-	a.onContentChanged()
+	if a.hasUnsavedChanges {
+		t := i18n.T()
+		dialog.ShowConfirm(t.Dialog.UnsavedChanges, t.Dialog.SaveBeforeRestore,
+			func(save bool) {
+				if save {
+					a.saveCurrentNote()
+				} else {
+					a.deleteDraft(a.currentNote.ID)
+				}
+				a.restoreRevision(version)
+			},
+			a.window,
+		)
+	} else {
+		a.restoreRevision(version)
+	}
 }
 
-// OnSave implements NoteEditHandler interface
-func (a *App) OnSave() {
-	a.saveCurrentNote()
+// restoreRevision restores the current note to a previous version and displays it
+func (a *App) restoreRevision(version int) {
+	rail := flow.EmptyRail()
+	note, err := a.noteService.RestoreRevision(rail, a.currentNote.ID, version)
+	if err != nil {
+		dialog.ShowError(err, a.window)
+		return
+	}
+
+	a.mainUI.StartSaving()
+	defer a.mainUI.EndSaving()
+	a.currentNote = note
+	a.hasUnsavedChanges = false
+	a.mainUI.DisplayNote(note)
+	a.mainUI.MarkAsSaved()
+	a.mainUI.RefreshNoteList()
 }
 
-// OnCreateNote implements AppActionsHandler interface
+// onShowLockNote is called when user wants to lock the current note with a passphrase
+func (a *App) onShowLockNote() {
+	t := i18n.T()
+	if a.currentNote == nil || a.currentNote.ID == "" || a.hasUnsavedChanges {
+		dialog.ShowInformation(t.Dialog.UnsavedNote, t.Lock.SaveBeforeLocking, a.window)
+		return
+	}
+	if a.currentNote.Encrypted {
+		dialog.ShowError(service.ErrNoteAlreadyLocked, a.window)
+		return
+	}
+	a.mainUI.ShowLockNoteDialog()
+}
+
+// onLockNote is called when user has entered the passphrase that the current note is locked with
+func (a *App) onLockNote(passphrase string) {
+	if a.currentNote == nil || a.currentNote.ID == "" || a.currentNote.Encrypted {
+		return
+	}
+
+	key, err := domain.NewNoteKey(passphrase)
+	if err != nil {
+		dialog.ShowError(err, a.window)
+		return
+	}
+	rail := flow.EmptyRail()
+	note := *a.currentNote
+	if err := a.noteService.LockNote(rail, &note, key); err != nil {
+		dialog.ShowError(err, a.window)
+		return
+	}
+	a.deleteDraft(note.ID)
+
+	// The note is shown locked right away, so that it's clear that it needs the passphrase from now on
+	locked, err := a.noteService.GetNote(rail, note.ID)
+	if err != nil {
+		dialog.ShowError(err, a.window)
+		return
+	}
+	a.mainUI.StartSaving()
+	defer a.mainUI.EndSaving()
+	a.currentNote = locked
+	a.unlocked = nil
+	a.hasUnsavedChanges = false
+	a.mainUI.DisplayNote(locked)
+	a.mainUI.MarkAsSaved()
+	a.mainUI.RefreshNoteList()
+}
+
+// onUnlockNote is called when user enters the passphrase of the locked note being displayed
+func (a *App) onUnlockNote(passphrase string) {
+	if a.currentNote == nil || !a.currentNote.Encrypted {
+		return
+	}
+
+	rail := flow.EmptyRail()
+	note, key, err := a.noteService.UnlockNote(rail, a.currentNote.ID, passphrase)
+	if err != nil {
+		if errors.Is(err, service.ErrWrongPassphrase) {
+			dialog.ShowError(errors.New(i18n.T().Lock.WrongPassphrase), a.window)
+		} else {
+			dialog.ShowError(err, a.window)
+		}
+		return
+	}
+
+	a.mainUI.StartSaving()
+	defer a.mainUI.EndSaving()
+	a.currentNote = note
+	a.unlocked = nil
+	if key != nil {
+		a.unlocked = &unlockedNote{noteID: note.ID, key: key, version: note.Version, title: note.Title, content: note.Content}
+	}
+	a.hasUnsavedChanges = false
+	a.mainUI.DisplayNote(note)
+	a.mainUI.MarkAsSaved()
+}
+
+// onRemoveLock is called when user wants to save the unlocked note as plaintext again, unsaved edits are saved with it
+func (a *App) onRemoveLock() {
+	a.waitAutosave()
+	if a.currentNote == nil || !a.currentNote.Encrypted {
+		return
+	}
+	if a.mainUI.IsLocked() {
+		dialog.ShowInformation(i18n.T().Lock.RemoveLock, i18n.T().Lock.Locked, a.window)
+		return
+	}
+
+	rail := flow.EmptyRail()
+	note := *a.currentNote
+	note.Title = a.mainUI.GetTitle()
+	note.Content = a.mainUI.GetContent()
+	var key *domain.NoteKey
+	if unlocked := a.unlockedFor(note.ID); unlocked != nil {
+		key = unlocked.key
+	}
+	err := a.noteService.RemoveLock(rail, &note, key)
+	if errors.Is(err, service.ErrVersionConflict) {
+		a.onVersionConflict(&note)
+		return
+	}
+	if err != nil {
+		dialog.ShowError(err, a.window)
+		return
+	}
+
+	latestNote, err := a.noteService.GetNote(rail, note.ID)
+	if err != nil {
+		dialog.ShowError(err, a.window)
+		return
+	}
+	a.mainUI.StartSaving()
+	defer a.mainUI.EndSaving()
+	a.currentNote = latestNote
+	a.unlocked = nil
+	a.hasUnsavedChanges = false
+	a.mainUI.DisplayNote(latestNote)
+	a.mainUI.MarkAsSaved()
+	a.mainUI.RefreshNoteList()
+}
+
+// startTrashPurge periodically purges notes that have been in the trash for longer than trashRetention
+func (a *App) startTrashPurge() {
+	if a.trashPurgeDone != nil {
+		return
+	}
+
+	a.trashPurgeDone = make(chan struct{})
+	done := a.trashPurgeDone
+
+	go func() {
+		ticker := time.NewTicker(trashPurgeInterval)
+		defer ticker.Stop()
+		for {
+			rail := flow.EmptyRail()
+			if _, err := a.noteService.EmptyTrash(rail, trashRetention); err != nil {
+				rail.Errorf("Failed to purge expired notes in trash: %v", err)
+			}
+
+			select {
+			case <-ticker.C:
+			case <-done:
+				return
+			}
+		}
+	}()
+}
+
+// stopTrashPurge stops the periodic trash purge
+func (a *App) stopTrashPurge() {
+	if a.trashPurgeDone != nil {
+		close(a.trashPurgeDone)
+		a.trashPurgeDone = nil
+	}
+}
+
+// startSnapshots periodically takes a snapshot of the database as scheduled, only the latest snapshots are kept
+func (a *App) startSnapshots() {
+	if a.snapshotDone != nil {
+		return
+	}
+	interval, keep := a.configService.GetSnapshotSchedule(flow.EmptyRail())
+	if interval <= 0 {
+		return
+	}
+
+	a.snapshotDone = make(chan struct{})
+	done := a.snapshotDone
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			// The snapshot is skipped when nothing has changed since the latest one
+			rail := flow.EmptyRail()
+			if _, err := infrastructure.SnapshotDatabase(rail, keep); err != nil {
+				rail.Errorf("Failed to take snapshot of database: %v", err)
+			}
+
+			select {
+			case <-ticker.C:
+			case <-done:
+				return
+			}
+		}
+	}()
+}
+
+// stopSnapshots stops the periodic snapshots
+func (a *App) stopSnapshots() {
+	if a.snapshotDone != nil {
+		close(a.snapshotDone)
+		a.snapshotDone = nil
+	}
+}
+
+// startReminders periodically checks the reminders and shows the ones that came due, including the ones that came due
+// while the app was closed
+func (a *App) startReminders() {
+	if a.reminderDone != nil {
+		return
+	}
+
+	a.reminderDone = make(chan struct{})
+	done := a.reminderDone
+
+	go func() {
+		ticker := time.NewTicker(reminderInterval)
+		defer ticker.Stop()
+		for {
+			rail := flow.EmptyRail()
+			notes, err := a.noteService.TakeDueReminders(rail, time.Now())
+			if err != nil {
+				rail.Errorf("Failed to check reminders: %v", err)
+			}
+			if len(notes) > 0 {
+				fyne.Do(func() {
+					select {
+					case <-done:
+						// The database was closed in the meantime
+						return
+					default:
+					}
+					a.showDueReminders(notes)
+				})
+			}
+
+			select {
+			case <-ticker.C:
+			case <-done:
+				return
+			}
+		}
+	}()
+}
+
+// stopReminders stops checking the reminders
+func (a *App) stopReminders() {
+	if a.reminderDone != nil {
+		close(a.reminderDone)
+		a.reminderDone = nil
+	}
+}
+
+// showDueReminders sends a notification for each reminder that came due and shows it with the actions to take
+func (a *App) showDueReminders(notes []*domain.Note) {
+	t := i18n.T()
+	for _, note := range notes {
+		a.fyneApp.SendNotification(fyne.NewNotification(fmt.Sprintf(t.Reminder.Notification, note.Title), note.DueAt.Format("2006/01/02 15:04")))
+		a.mainUI.ShowDueReminder(note)
+		if a.currentNote != nil && a.currentNote.ID == note.ID {
+			a.currentNote.RemindedAt = note.RemindedAt
+			a.mainUI.RefreshNoteInfo(a.currentNote)
+		}
+	}
+	a.mainUI.RefreshNoteList()
+}
+
+// startAPIServer starts the API server when it was enabled the last time the app was used
+func (a *App) startAPIServer() {
+	rail := flow.EmptyRail()
+	enabled, addr := a.configService.GetAPIServer(rail)
+	if !enabled {
+		return
+	}
+	if err := a.runAPIServer(addr); err != nil {
+		rail.Errorf("Failed to start API server on %s: %v", addr, err)
+	}
+}
+
+// runAPIServer starts the API server on addr, allowing the configured origin
+func (a *App) runAPIServer(addr string) error {
+	rail := flow.EmptyRail()
+	token, err := a.configService.GetAPIToken(rail)
+	if err != nil {
+		return err
+	}
+	if a.apiServer == nil {
+		a.apiServer = server.NewServer(a.noteService, token, func() {
+			fyne.Do(func() {
+				a.mainUI.RefreshNoteList()
+			})
+		})
+	}
+	a.apiServer.SetToken(token)
+	a.apiServer.SetAllowedOrigin(a.configService.GetAPIAllowedOrigin(rail))
+	return a.apiServer.Start(addr)
+}
+
+// stopAPIServer stops the API server if it is running
+func (a *App) stopAPIServer() {
+	if a.apiServer == nil {
+		return
+	}
+	if err := a.apiServer.Stop(); err != nil {
+		flow.EmptyRail().Errorf("Failed to stop API server: %v", err)
+	}
+}
+
+// apiServerAddr returns the address the API server listens on, empty when it is not running
+func (a *App) apiServerAddr() string {
+	if a.apiServer == nil {
+		return ""
+	}
+	return a.apiServer.Addr()
+}
+
+// onShowServer is called when user wants to see the API server settings
+func (a *App) onShowServer() {
+	rail := flow.EmptyRail()
+	token, err := a.configService.GetAPIToken(rail)
+	if err != nil {
+		dialog.ShowError(err, a.window)
+		return
+	}
+	_, addr := a.configService.GetAPIServer(rail)
+	a.mainUI.ShowServer(addr, a.configService.GetAPIAllowedOrigin(rail), a.apiServerAddr(), token)
+}
+
+// onToggleServer is called when user starts or stops the API server
+func (a *App) onToggleServer(enabled bool, addr string, origin string) {
+	rail := flow.EmptyRail()
+	addr = strings.TrimSpace(addr)
+	if addr == "" {
+		addr = service.DefaultAPIServerAddr
+	}
+
+	origin, err := server.NormalizeOrigin(origin)
+	if err != nil {
+		dialog.ShowError(errors.New(i18n.T().Server.InvalidOrigin), a.window)
+		token, _ := a.configService.GetAPIToken(rail)
+		a.mainUI.UpdateServer(addr, a.configService.GetAPIAllowedOrigin(rail), a.apiServerAddr(), token)
+		return
+	}
+	if err := a.configService.SaveAPIAllowedOrigin(rail, origin); err != nil {
+		dialog.ShowError(err, a.window)
+		return
+	}
+
+	if enabled {
+		if err := a.runAPIServer(addr); err != nil {
+			rail.Errorf("Failed to start API server on %s: %v", addr, err)
+			dialog.ShowError(err, a.window)
+			enabled = false
+		}
+	} else {
+		a.stopAPIServer()
+	}
+
+	if err := a.configService.SaveAPIServer(rail, enabled, addr); err != nil {
+		dialog.ShowError(err, a.window)
+	}
+	token, _ := a.configService.GetAPIToken(rail)
+	a.mainUI.UpdateServer(addr, origin, a.apiServerAddr(), token)
+}
+
+// onResetServerToken is called when user wants to replace the API server token
+func (a *App) onResetServerToken() {
+	rail := flow.EmptyRail()
+	token, err := a.configService.ResetAPIToken(rail)
+	if err != nil {
+		dialog.ShowError(err, a.window)
+		return
+	}
+	if a.apiServer != nil {
+		a.apiServer.SetToken(token)
+	}
+	_, addr := a.configService.GetAPIServer(rail)
+	a.mainUI.UpdateServer(addr, a.configService.GetAPIAllowedOrigin(rail), a.apiServerAddr(), token)
+}
+
+// onShowTrash is called when user wants to see the deleted notes
+func (a *App) onShowTrash() {
+	rail := flow.EmptyRail()
+	notes, err := a.noteService.ListDeleted(rail)
+	if err != nil {
+		dialog.ShowError(err, a.window)
+		return
+	}
+	a.mainUI.ShowTrash(notes)
+}
+
+// refreshTrash reloads the deleted notes shown in the trash panel
+func (a *App) refreshTrash() {
+	rail := flow.EmptyRail()
+	notes, err := a.noteService.ListDeleted(rail)
+	if err != nil {
+		dialog.ShowError(err, a.window)
+		return
+	}
+	a.mainUI.UpdateTrash(notes)
+}
+
+// onRestoreNote is called when user wants to restore a note from the trash
+func (a *App) onRestoreNote(id string) {
+	rail := flow.EmptyRail()
+	err := a.noteService.RestoreNote(rail, id)
+	if err != nil {
+		dialog.ShowError(err, a.window)
+		return
+	}
+
+	a.refreshTrash()
+	a.mainUI.RefreshNoteList()
+
+	// Nothing was displayed since every note had been deleted
+	if a.currentNote == nil {
+		note, err := a.noteService.GetNote(rail, id)
+		if err == nil {
+			a.mainUI.StartSaving()
+			defer a.mainUI.EndSaving()
+			a.currentNote = note
+			a.mainUI.DisplayNote(note)
+			a.mainUI.MarkAsSaved()
+		}
+	}
+}
+
+// onPurgeNote is called when user wants to permanently delete a note in the trash
+func (a *App) onPurgeNote(id string) {
+	rail := flow.EmptyRail()
+	err := a.noteService.PurgeNote(rail, id)
+	if err != nil {
+		dialog.ShowError(err, a.window)
+		return
+	}
+	a.refreshTrash()
+}
+
+// onEmptyTrash is called when user wants to permanently delete every note in the trash
+func (a *App) onEmptyTrash() {
+	rail := flow.EmptyRail()
+	_, err := a.noteService.EmptyTrash(rail, 0)
+	if err != nil {
+		dialog.ShowError(err, a.window)
+		return
+	}
+	a.refreshTrash()
+}
+
+// onAddTag is called when user wants to add a tag to the current note
+func (a *App) onAddTag(name string) {
+	if a.currentNote == nil || a.currentNote.ID == "" {
+		t := i18n.T()
+		dialog.ShowInformation(t.Dialog.UnsavedNote, t.Dialog.SaveBeforeTagging, a.window)
+		return
+	}
+
+	rail := flow.EmptyRail()
+	_, err := a.noteService.AddTag(rail, a.currentNote.ID, name)
+	if err != nil {
+		dialog.ShowError(err, a.window)
+		return
+	}
+	a.mainUI.RefreshTags()
+}
+
+// onRemoveTag is called when user wants to remove a tag from the current note
+func (a *App) onRemoveTag(tagID string) {
+	if a.currentNote == nil || a.currentNote.ID == "" {
+		return
+	}
+
+	rail := flow.EmptyRail()
+	err := a.noteService.RemoveTag(rail, a.currentNote.ID, tagID)
+	if err != nil {
+		dialog.ShowError(err, a.window)
+		return
+	}
+	a.mainUI.RefreshTags()
+	a.mainUI.RefreshNoteList()
+}
+
+// onRenameTag is called when user wants to rename a tag
+func (a *App) onRenameTag(tagID string, name string) {
+	rail := flow.EmptyRail()
+	err := a.noteService.RenameTag(rail, tagID, name)
+	if err != nil {
+		if err == service.ErrTagExists {
+			dialog.ShowError(errors.New(i18n.T().Dialog.TagExists), a.window)
+		} else {
+			dialog.ShowError(err, a.window)
+		}
+		return
+	}
+	a.mainUI.RefreshTags()
+	a.mainUI.RefreshNoteList()
+}
+
+// onMergeTags is called when user wants to merge a tag into another
+func (a *App) onMergeTags(sourceID string, targetID string) {
+	rail := flow.EmptyRail()
+	err := a.noteService.MergeTags(rail, sourceID, targetID)
+	if err != nil {
+		dialog.ShowError(err, a.window)
+		return
+	}
+	a.mainUI.RefreshTags()
+	a.mainUI.RefreshNoteList()
+}
+
+// onDeleteTag is called when user wants to delete a tag
+func (a *App) onDeleteTag(tagID string) {
+	rail := flow.EmptyRail()
+	err := a.noteService.DeleteTag(rail, tagID)
+	if err != nil {
+		dialog.ShowError(err, a.window)
+		return
+	}
+	a.mainUI.RefreshTags()
+	a.mainUI.RefreshNoteList()
+}
+
+// onCreateNotebook is called when user wants to create a notebook
+func (a *App) onCreateNotebook(name string, parentID string) {
+	rail := flow.EmptyRail()
+	_, err := a.noteService.CreateNotebook(rail, name, parentID)
+	if err != nil {
+		dialog.ShowError(err, a.window)
+		return
+	}
+	a.mainUI.RefreshNotebooks()
+}
+
+// onRenameNotebook is called when user wants to rename a notebook
+func (a *App) onRenameNotebook(notebookID string, name string) {
+	rail := flow.EmptyRail()
+	err := a.noteService.RenameNotebook(rail, notebookID, name)
+	if err != nil {
+		dialog.ShowError(err, a.window)
+		return
+	}
+	a.mainUI.RefreshNotebooks()
+}
+
+// onMoveNotebook is called when user wants to move a notebook under another notebook
+func (a *App) onMoveNotebook(notebookID string, parentID string) {
+	rail := flow.EmptyRail()
+	err := a.noteService.MoveNotebook(rail, notebookID, parentID)
+	if err != nil {
+		if err == service.ErrNotebookCycle {
+			dialog.ShowError(errors.New(i18n.T().Dialog.NotebookCycle), a.window)
+		} else {
+			dialog.ShowError(err, a.window)
+		}
+		return
+	}
+	a.mainUI.RefreshNotebooks()
+	a.mainUI.RefreshNoteList()
+}
+
+// onDeleteNotebook is called when user wants to delete a notebook, its notes are moved to the trash
+func (a *App) onDeleteNotebook(notebookID string) {
+	rail := flow.EmptyRail()
+	err := a.noteService.DeleteNotebook(rail, notebookID)
+	if err != nil {
+		dialog.ShowError(err, a.window)
+		return
+	}
+	a.mainUI.RefreshNotebooks()
+	a.mainUI.RefreshNoteList()
+
+	// The current note may have been moved to the trash with the notebook
+	if a.currentNote == nil || a.currentNote.ID == "" {
+		return
+	}
+	if _, err := a.noteService.GetNote(rail, a.currentNote.ID); err == nil {
+		return
+	}
+
+	a.currentNote = nil
+	a.hasUnsavedChanges = false
+	a.mainUI.StartSaving()
+	defer a.mainUI.EndSaving()
+	lastNote, err := a.noteService.GetLastModifiedNote(rail)
+	if err != nil {
+		a.mainUI.ShowEmptyState()
+	} else {
+		a.currentNote = lastNote
+		a.mainUI.DisplayNote(lastNote)
+	}
+	a.mainUI.MarkAsSaved()
+}
+
+// onShowMoveNote is called when user wants to move the current note to another notebook
+func (a *App) onShowMoveNote() {
+	if a.currentNote == nil || a.currentNote.ID == "" {
+		t := i18n.T()
+		dialog.ShowInformation(t.Dialog.UnsavedNote, t.Dialog.SaveBeforeMoving, a.window)
+		return
+	}
+	a.mainUI.ShowMoveNoteDialog()
+}
+
+// onMoveNote is called when user has picked the notebook that the current note is moved into
+func (a *App) onMoveNote(notebookID string) {
+	if a.currentNote == nil || a.currentNote.ID == "" {
+		return
+	}
+
+	rail := flow.EmptyRail()
+	err := a.noteService.MoveNote(rail, a.currentNote.ID, notebookID)
+	if err != nil {
+		dialog.ShowError(err, a.window)
+		return
+	}
+	a.currentNote.NotebookID = notebookID
+	a.mainUI.RefreshNoteList()
+}
+
+// onLinkSelected is called when user clicks a wiki link, the target is either a note ID or a note title
+func (a *App) onLinkSelected(target string) {
+	rail := flow.EmptyRail()
+	note, err := a.noteService.ResolveLink(rail, target)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf(i18n.T().Dialog.LinkNotFound, target), a.window)
+		return
+	}
+	a.onNoteSelected(note)
+}
+
+// GetDatabaseLocation returns the database location
+func (a *App) GetDatabaseLocation() string {
+	return infrastructure.GetDatabaseLocation()
+}
+
+// onSwitchDatabase is called when user switches to another database, the edits are saved first if user wants to
+func (a *App) onSwitchDatabase(path string) {
+	if path == infrastructure.GetDatabaseLocation() {
+		return
+	}
+
+	if a.hasUnsavedChanges && !a.isNoteEmpty() {
+		t := i18n.T()
+		dialog.ShowConfirm(t.Dialog.UnsavedChanges, t.Database.SaveBefore,
+			func(save bool) {
+				if save {
+					a.saveCurrentNote()
+					if a.hasUnsavedChanges {
+						// The save failed or ran into a conflict, the edits would be lost
+						return
+					}
+				}
+				// Without saving, the draft is kept and offered when the database is opened again
+				a.switchDatabase(path)
+			},
+			a.window,
+		)
+		return
+	}
+	a.switchDatabase(path)
+}
+
+// switchDatabase closes the current database and reopens the app against the database at path, the current
+// database is opened again when it fails
+func (a *App) switchDatabase(path string) {
+	rail := flow.EmptyRail()
+	previous := infrastructure.GetDatabaseLocation()
+	rail.Infof("Switching database from %s to %s", previous, path)
+
+	a.closeDatabase()
+	err := a.openDatabase(path)
+	if err == nil {
+		return
+	}
+
+	rail.Errorf("Failed to open database %s: %v", path, err)
+	if reopenErr := a.openDatabase(previous); reopenErr != nil {
+		rail.Errorf("Failed to reopen database %s: %v", previous, reopenErr)
+	}
+	dialog.ShowError(fmt.Errorf(i18n.T().Database.OpenFailed, path, err), a.window)
+}
+
+// openDatabase opens the database at path and initializes the app, an encrypted database is initialized once it's unlocked
+func (a *App) openDatabase(path string) error {
+	if err := infrastructure.SetDatabasePath(path); err != nil {
+		return err
+	}
+	if err := infrastructure.EnsureDatabaseDir(); err != nil {
+		return err
+	}
+	db, err := infrastructure.InitializeDatabase()
+	if errors.Is(err, infrastructure.ErrDatabaseEncrypted) {
+		a.showUnlockView()
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	a.initialize(db)
+	a.startTrashPurge()
+	a.startSnapshots()
+	a.startReminders()
+	a.startAPIServer()
+	return nil
+}
+
+// closeDatabase stops everything that uses the database, closes it and forgets the state of the closed database
+func (a *App) closeDatabase() {
+	a.cleanup()
+	a.mainUI = nil
+	a.unlockView = nil
+	a.apiServer = nil
+	a.autosaveTimer = nil
+	a.draftTimer = nil
+	a.currentNote = nil
+	a.conflictNote = nil
+	a.unlocked = nil
+	a.hasUnsavedChanges = false
+}
+
+// onOpenDatabase is called when user wants to open an existing database file
+func (a *App) onOpenDatabase() {
+	fd := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+		if err != nil || reader == nil {
+			return
+		}
+		path := reader.URI().Path()
+		reader.Close()
+		a.onSwitchDatabase(path)
+	}, a.window)
+
+	fd.SetFilter(storage.NewExtensionFileFilter([]string{".sqlite", ".db"}))
+	fd.Show()
+}
+
+// onNewDatabase is called when user wants to create a new database file and switch to it
+func (a *App) onNewDatabase() {
+	fd := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+		if err != nil || writer == nil {
+			return
+		}
+		// The empty file is a valid database, its schema is created when it's opened
+		path := writer.URI().Path()
+		writer.Close()
+		a.onSwitchDatabase(path)
+	}, a.window)
+
+	fd.SetFileName("nota.sqlite")
+	fd.SetFilter(storage.NewExtensionFileFilter([]string{".sqlite", ".db"}))
+	fd.Show()
+}
+
+// onSetDefaultDatabase is called when user changes the database opened on startup, empty for the default database
+func (a *App) onSetDefaultDatabase(path string) {
+	if err := infrastructure.SetDefaultDatabase(path); err != nil {
+		dialog.ShowError(err, a.window)
+	}
+}
+
+// onShowBackups is called when user wants to see the backups of the database
+func (a *App) onShowBackups() {
+	backups, err := a.listBackups()
+	if err != nil {
+		dialog.ShowError(err, a.window)
+		return
+	}
+	interval, keep := a.configService.GetSnapshotSchedule(flow.EmptyRail())
+	a.mainUI.ShowBackups(backups, interval, keep)
+}
+
+// listBackups lists the backups of the database for the backup panel, newest first
+func (a *App) listBackups() ([]ui.BackupEntry, error) {
+	backups, err := infrastructure.ListBackups()
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]ui.BackupEntry, 0, len(backups))
+	for _, b := range backups {
+		entries = append(entries, ui.BackupEntry{Path: b.Path, Kind: b.Kind, CreatedAt: b.CreatedAt, Size: b.Size, Encrypted: b.Encrypted})
+	}
+	return entries, nil
+}
+
+// refreshBackups updates the backups shown in the backup panel
+func (a *App) refreshBackups() {
+	backups, err := a.listBackups()
+	if err != nil {
+		flow.EmptyRail().Errorf("Failed to list backups: %v", err)
+		return
+	}
+	a.mainUI.UpdateBackups(backups)
+}
+
+// onBackupNow is called when user wants to back up the database
+func (a *App) onBackupNow() {
+	rail := flow.EmptyRail()
+	path, err := infrastructure.BackupDatabase(rail, infrastructure.BackupKindManual)
+	if err != nil {
+		rail.Errorf("Failed to back up database: %v", err)
+		dialog.ShowError(err, a.window)
+		return
+	}
+	rail.Infof("Backed up database to %s", path)
+	a.refreshBackups()
+	t := i18n.T()
+	dialog.ShowInformation(t.Backup.Title, fmt.Sprintf(t.Backup.BackedUp, path), a.window)
+}
+
+// onSnapshotScheduleChanged is called when user changes how often snapshots are taken and how many are kept
+func (a *App) onSnapshotScheduleChanged(interval time.Duration, keep int) {
+	if err := a.configService.SaveSnapshotSchedule(flow.EmptyRail(), interval, keep); err != nil {
+		dialog.ShowError(err, a.window)
+		return
+	}
+	a.stopSnapshots()
+	a.startSnapshots()
+}
+
+// onRestoreBackup is called when user restores a backup, the edits are saved first if user wants to
+//
+// The password is empty unless user has entered the password of an encrypted backup.
+func (a *App) onRestoreBackup(path string, password string) {
+	if a.hasUnsavedChanges && !a.isNoteEmpty() {
+		t := i18n.T()
+		dialog.ShowConfirm(t.Dialog.UnsavedChanges, t.Backup.SaveBefore,
+			func(save bool) {
+				if save {
+					a.saveCurrentNote()
+					if a.hasUnsavedChanges {
+						// The save failed or ran into a conflict, the edits would be lost
+						return
+					}
+				}
+				a.restoreBackup(path, password)
+			},
+			a.window,
+		)
+		return
+	}
+	a.restoreBackup(path, password)
+}
+
+// restoreBackup replaces the notes with the backup and shows them, the database is backed up first
+func (a *App) restoreBackup(path string, password string) {
+	rail := flow.EmptyRail()
+	t := i18n.T()
+
+	// The pending saves belong to the notes being replaced
+	if a.autosaveTimer != nil {
+		a.autosaveTimer.Stop()
+		a.autosaveTimer = nil
+	}
+	if a.draftTimer != nil {
+		a.draftTimer.Stop()
+		a.draftTimer = nil
+	}
+
+	preRestore, err := infrastructure.RestoreBackup(rail, path, password)
+	if err != nil {
+		rail.Errorf("Failed to restore backup %s: %v", path, err)
+		switch {
+		case errors.Is(err, infrastructure.ErrDatabaseEncrypted):
+			a.mainUI.AskBackupPassword(path)
+		case errors.Is(err, infrastructure.ErrWrongPassword):
+			a.mainUI.AskBackupPassword(path)
+			dialog.ShowError(errors.New(t.Database.WrongPassword), a.window)
+		case errors.Is(err, infrastructure.ErrInvalidBackup):
+			dialog.ShowError(fmt.Errorf(t.Backup.Invalid, err), a.window)
+		default:
+			dialog.ShowError(err, a.window)
+		}
+		return
+	}
+
+	rail.Infof("Restored backup %s", path)
+	a.reload()
+	a.refreshBackups()
+	dialog.ShowInformation(t.Backup.Title, fmt.Sprintf(t.Backup.Restored, preRestore), a.window)
+}
+
+// reload shows the notes again after the content of the database is replaced, the state of the replaced notes is forgotten
+func (a *App) reload() {
+	rail := flow.EmptyRail()
+	a.currentNote = nil
+	a.conflictNote = nil
+	a.unlocked = nil
+	a.hasUnsavedChanges = false
+	a.autosaveDelay = a.configService.GetAutosaveDelay(rail)
+	a.stopSnapshots()
+	a.startSnapshots()
+
+	a.mainUI.RefreshNotebooks()
+	a.mainUI.RefreshTags()
+	a.mainUI.RefreshNoteList()
+	if err := a.loadLastNote(); err != nil {
+		a.mainUI.ShowEmptyState()
+	}
+}
+
+// onShowDiagnostics is called when user wants to check the database for problems
+func (a *App) onShowDiagnostics() {
+	issues, err := a.diagnosticsService.Diagnose(flow.EmptyRail())
+	if err != nil {
+		dialog.ShowError(err, a.window)
+		return
+	}
+	a.mainUI.ShowDiagnostics(issues)
+}
+
+// onRepairIssues is called when user repairs the problems found in the database, the edits are saved first if user wants to
+func (a *App) onRepairIssues(issues []domain.Issue) {
+	if a.hasUnsavedChanges && !a.isNoteEmpty() {
+		t := i18n.T()
+		dialog.ShowConfirm(t.Dialog.UnsavedChanges, t.Diagnostics.SaveBefore,
+			func(save bool) {
+				if save {
+					a.saveCurrentNote()
+					if a.hasUnsavedChanges {
+						// The save failed or ran into a conflict, the edits would be lost
+						return
+					}
+				}
+				a.repairIssues(issues)
+			},
+			a.window,
+		)
+		return
+	}
+	a.repairIssues(issues)
+}
+
+// repairIssues backs up the database, repairs the problems and checks the database again
+func (a *App) repairIssues(issues []domain.Issue) {
+	rail := flow.EmptyRail()
+	backupPath, err := infrastructure.BackupDatabase(rail, infrastructure.BackupKindPreRepair)
+	if err != nil {
+		rail.Errorf("Failed to back up database before repairing: %v", err)
+		dialog.ShowError(err, a.window)
+		return
+	}
+
+	repaired, err := a.diagnosticsService.Repair(rail, issues)
+	if err != nil {
+		dialog.ShowError(err, a.window)
+	}
+	if repaired > 0 {
+		a.reload()
+	}
+
+	remaining, derr := a.diagnosticsService.Diagnose(rail)
+	if derr != nil {
+		dialog.ShowError(derr, a.window)
+		return
+	}
+	a.mainUI.ShowDiagnostics(remaining)
+	if err == nil {
+		t := i18n.T()
+		dialog.ShowInformation(t.Diagnostics.Title, fmt.Sprintf(t.Diagnostics.Repaired, repaired, backupPath), a.window)
+	}
+}
+
+// ListNotes returns all notes
+func (a *App) ListNotes() ([]*domain.Note, error) {
+	rail := flow.EmptyRail()
+	return a.noteService.ListNotes(rail)
+}
+
+// OnNoteSelected implements NoteSelectionHandler interface
+func (a *App) OnNoteSelected(note *domain.Note) {
+	a.onNoteSelected(note)
+}
+
+// OnContentChanged implements NoteEditHandler interface
+func (a *App) OnContentChanged() {
+	a.onContentChanged()
+}
+
+// OnSave implements NoteEditHandler interface
+func (a *App) OnSave() {
+	a.saveCurrentNote()
+}
+
+// OnCreateNote implements AppActionsHandler interface
 func (a *App) OnCreateNote() {
 	a.onCreateNote()
 }
@@ -550,11 +2470,71 @@ func (a *App) OnImportNote() {
 	a.onImportNote()
 }
 
+// OnImportMarkdown implements AppActionsHandler interface
+func (a *App) OnImportMarkdown() {
+	a.onImportMarkdown()
+}
+
+// OnResolveConflict implements ConflictHandler interface
+func (a *App) OnResolveConflict(resolution ui.ConflictResolution) {
+	a.onResolveConflict(resolution)
+}
+
+// GetAutosaveDelay implements AutosaveHandler interface
+func (a *App) GetAutosaveDelay() time.Duration {
+	return a.autosaveDelay
+}
+
+// OnAutosaveDelayChanged implements AutosaveHandler interface
+func (a *App) OnAutosaveDelayChanged(delay time.Duration) {
+	a.onAutosaveDelayChanged(delay)
+}
+
+// OnShowLockNote implements LockHandler interface
+func (a *App) OnShowLockNote() {
+	a.onShowLockNote()
+}
+
+// OnLockNote implements LockHandler interface
+func (a *App) OnLockNote(passphrase string) {
+	a.onLockNote(passphrase)
+}
+
+// OnUnlockNote implements LockHandler interface
+func (a *App) OnUnlockNote(passphrase string) {
+	a.onUnlockNote(passphrase)
+}
+
+// OnRemoveLock implements LockHandler interface
+func (a *App) OnRemoveLock() {
+	a.onRemoveLock()
+}
+
+// OnShowServer implements ServerHandler interface
+func (a *App) OnShowServer() {
+	a.onShowServer()
+}
+
+// OnToggleServer implements ServerHandler interface
+func (a *App) OnToggleServer(enabled bool, addr string, origin string) {
+	a.onToggleServer(enabled, addr, origin)
+}
+
+// OnResetServerToken implements ServerHandler interface
+func (a *App) OnResetServerToken() {
+	a.onResetServerToken()
+}
+
 // OnExportNote implements AppActionsHandler interface
 func (a *App) OnExportNote() {
 	a.onExportNote()
 }
 
+// OnExportMarkdown implements AppActionsHandler interface
+func (a *App) OnExportMarkdown() {
+	a.onExportMarkdown()
+}
+
 // OnSearch implements SearchHandler interface
 func (a *App) OnSearch(query string) {
 	a.onSearch(query)
@@ -565,6 +2545,117 @@ func (a *App) OnPinNote(pin bool) {
 	a.onPinNote(pin)
 }
 
+// OnShowHistory implements HistoryHandler interface
+func (a *App) OnShowHistory() {
+	a.onShowHistory()
+}
+
+// DiffRevision implements HistoryHandler interface, diffing a previous version against the current one
+func (a *App) DiffRevision(version int) ([]domain.DiffLine, error) {
+	rail := flow.EmptyRail()
+	return a.noteService.DiffRevisions(rail, a.currentNote.ID, version, a.currentNote.Version)
+}
+
+// OnRestoreRevision implements HistoryHandler interface
+func (a *App) OnRestoreRevision(version int) {
+	a.onRestoreRevision(version)
+}
+
+// OnShowTrash implements TrashHandler interface
+func (a *App) OnShowTrash() {
+	a.onShowTrash()
+}
+
+// OnRestoreNote implements TrashHandler interface
+func (a *App) OnRestoreNote(id string) {
+	a.onRestoreNote(id)
+}
+
+// OnPurgeNote implements TrashHandler interface
+func (a *App) OnPurgeNote(id string) {
+	a.onPurgeNote(id)
+}
+
+// OnEmptyTrash implements TrashHandler interface
+func (a *App) OnEmptyTrash() {
+	a.onEmptyTrash()
+}
+
+// OnAddTag implements TagHandler interface
+func (a *App) OnAddTag(name string) {
+	a.onAddTag(name)
+}
+
+// OnRemoveTag implements TagHandler interface
+func (a *App) OnRemoveTag(tagID string) {
+	a.onRemoveTag(tagID)
+}
+
+// OnTagFilterChanged implements TagHandler interface
+func (a *App) OnTagFilterChanged() {
+	a.mainUI.RefreshNoteList()
+}
+
+// OnManageTags implements TagHandler interface
+func (a *App) OnManageTags() {
+	a.mainUI.ShowTagManager()
+}
+
+// OnRenameTag implements TagHandler interface
+func (a *App) OnRenameTag(tagID string, name string) {
+	a.onRenameTag(tagID, name)
+}
+
+// OnMergeTags implements TagHandler interface
+func (a *App) OnMergeTags(sourceID string, targetID string) {
+	a.onMergeTags(sourceID, targetID)
+}
+
+// OnDeleteTag implements TagHandler interface
+func (a *App) OnDeleteTag(tagID string) {
+	a.onDeleteTag(tagID)
+}
+
+// OnNotebookSelected implements NotebookHandler interface
+func (a *App) OnNotebookSelected(notebookID string) {
+	a.mainUI.RefreshNoteList()
+}
+
+// OnCreateNotebook implements NotebookHandler interface
+func (a *App) OnCreateNotebook(name string, parentID string) {
+	a.onCreateNotebook(name, parentID)
+}
+
+// OnRenameNotebook implements NotebookHandler interface
+func (a *App) OnRenameNotebook(notebookID string, name string) {
+	a.onRenameNotebook(notebookID, name)
+}
+
+// OnMoveNotebook implements NotebookHandler interface
+func (a *App) OnMoveNotebook(notebookID string, parentID string) {
+	a.onMoveNotebook(notebookID, parentID)
+}
+
+// OnDeleteNotebook implements NotebookHandler interface
+func (a *App) OnDeleteNotebook(notebookID string) {
+	a.onDeleteNotebook(notebookID)
+}
+
+// OnShowMoveNote implements NotebookHandler interface
+func (a *App) OnShowMoveNote() {
+	a.onShowMoveNote()
+}
+
+// OnMoveNote implements NotebookHandler interface
+func (a *App) OnMoveNote(notebookID string) {
+	a.onMoveNote(notebookID)
+}
+
+// OnLinkSelected implements LinkHandler interface
+func (a *App) OnLinkSelected(target string) {
+	a.onLinkSelected(target)
+}
+
 // OnLanguageChanged implements LanguageHandler interface
 func (a *App) OnLanguageChanged(lang i18n.Language) {
 	rail := flow.EmptyRail()
@@ -584,3 +2675,193 @@ func (a *App) OnLanguageChanged(lang i18n.Language) {
 	// Refresh menu bar
 	a.mainUI.GetMenuBar().Refresh()
 }
+
+// OnUnlockDatabase implements UnlockHandler interface
+func (a *App) OnUnlockDatabase(password string) {
+	a.onUnlockDatabase(password)
+}
+
+// RecentDatabases implements VaultHandler interface
+func (a *App) RecentDatabases() []string {
+	return infrastructure.RecentDatabases()
+}
+
+// GetDefaultDatabase implements VaultHandler interface
+func (a *App) GetDefaultDatabase() string {
+	return infrastructure.LoadVaultConfig().Default
+}
+
+// OnSwitchDatabase implements VaultHandler interface
+func (a *App) OnSwitchDatabase(path string) {
+	a.onSwitchDatabase(path)
+}
+
+// OnOpenDatabase implements VaultHandler interface
+func (a *App) OnOpenDatabase() {
+	a.onOpenDatabase()
+}
+
+// OnNewDatabase implements VaultHandler interface
+func (a *App) OnNewDatabase() {
+	a.onNewDatabase()
+}
+
+// OnSetDefaultDatabase implements VaultHandler interface
+func (a *App) OnSetDefaultDatabase(path string) {
+	a.onSetDefaultDatabase(path)
+}
+
+// OnShowBackups implements BackupHandler interface
+func (a *App) OnShowBackups() {
+	a.onShowBackups()
+}
+
+// OnBackupNow implements BackupHandler interface
+func (a *App) OnBackupNow() {
+	a.onBackupNow()
+}
+
+// OnRestoreBackup implements BackupHandler interface
+func (a *App) OnRestoreBackup(path string, password string) {
+	a.onRestoreBackup(path, password)
+}
+
+// OnSnapshotScheduleChanged implements BackupHandler interface
+func (a *App) OnSnapshotScheduleChanged(interval time.Duration, keep int) {
+	a.onSnapshotScheduleChanged(interval, keep)
+}
+
+// OnShowDiagnostics implements DiagnosticsHandler interface
+func (a *App) OnShowDiagnostics() {
+	a.onShowDiagnostics()
+}
+
+// OnRepairIssues implements DiagnosticsHandler interface
+func (a *App) OnRepairIssues(issues []domain.Issue) {
+	a.onRepairIssues(issues)
+}
+
+// OnShowTemplates implements TemplateHandler interface
+func (a *App) OnShowTemplates() {
+	a.onShowTemplates()
+}
+
+// OnCreateFromTemplate implements TemplateHandler interface
+func (a *App) OnCreateFromTemplate(templateID string, fields map[string]string) {
+	a.onCreateFromTemplate(templateID, fields)
+}
+
+// IsTemplate implements TemplateHandler interface
+func (a *App) IsTemplate() bool {
+	return a.isTemplate()
+}
+
+// OnSetTemplate implements TemplateHandler interface
+func (a *App) OnSetTemplate(template bool) {
+	a.onSetTemplate(template)
+}
+
+// OnOpenToday implements DailyHandler interface
+func (a *App) OnOpenToday() {
+	a.onOpenToday()
+}
+
+// OnOpenDay implements DailyHandler interface
+func (a *App) OnOpenDay(date time.Time) {
+	a.onOpenDay(date)
+}
+
+// OnOpenAdjacentDay implements DailyHandler interface
+func (a *App) OnOpenAdjacentDay(offset int) {
+	a.onOpenAdjacentDay(offset)
+}
+
+// OnShowCalendar implements DailyHandler interface
+func (a *App) OnShowCalendar() {
+	a.onShowCalendar()
+}
+
+// ListDailyDates implements DailyHandler interface
+func (a *App) ListDailyDates(from time.Time, to time.Time) ([]time.Time, error) {
+	return a.noteService.ListDailyDates(flow.EmptyRail(), from, to)
+}
+
+// GetDailyTitleFormat implements DailyHandler interface
+func (a *App) GetDailyTitleFormat() string {
+	return a.configService.GetDailyTitleFormat(flow.EmptyRail())
+}
+
+// OnDailyTitleFormatChanged implements DailyHandler interface
+func (a *App) OnDailyTitleFormatChanged(format string) {
+	a.onDailyTitleFormatChanged(format)
+}
+
+// OnShowReminder implements ReminderHandler interface
+func (a *App) OnShowReminder() {
+	a.onShowReminder()
+}
+
+// OnSetReminder implements ReminderHandler interface
+func (a *App) OnSetReminder(dueAt *time.Time) {
+	a.onSetReminder(dueAt)
+}
+
+// OnOpenReminder implements ReminderHandler interface
+func (a *App) OnOpenReminder(noteID string) {
+	a.onOpenReminder(noteID)
+}
+
+// OnSnoozeReminder implements ReminderHandler interface
+func (a *App) OnSnoozeReminder(noteID string, d time.Duration) {
+	a.onSnoozeReminder(noteID, d)
+}
+
+// OnCompleteReminder implements ReminderHandler interface
+func (a *App) OnCompleteReminder(noteID string) {
+	a.onCompleteReminder(noteID)
+}
+
+// OnDueSoonFilterChanged implements ReminderHandler interface
+func (a *App) OnDueSoonFilterChanged() {
+	a.mainUI.RefreshNoteList()
+}
+
+// OnShowTasks implements TaskHandler interface
+func (a *App) OnShowTasks() {
+	a.mainUI.ShowTasks()
+}
+
+// ListTasks implements TaskHandler interface
+func (a *App) ListTasks(filter domain.TaskFilter) ([]*domain.NoteTask, error) {
+	return a.noteService.ListTasks(flow.EmptyRail(), filter)
+}
+
+// OnSetTaskDone implements TaskHandler interface
+func (a *App) OnSetTaskDone(task *domain.NoteTask, done bool) {
+	a.onSetTaskDone(task, done)
+}
+
+// OnOpenTask implements TaskHandler interface
+func (a *App) OnOpenTask(task *domain.NoteTask) {
+	a.onOpenTask(task)
+}
+
+// OnSetStarred implements StarHandler interface
+func (a *App) OnSetStarred(noteID string, starred bool) {
+	a.onSetStarred(noteID, starred)
+}
+
+// IsArchived implements ArchiveHandler interface
+func (a *App) IsArchived() bool {
+	return a.isArchived()
+}
+
+// OnSetArchived implements ArchiveHandler interface
+func (a *App) OnSetArchived(archived bool) {
+	a.onSetArchived(archived)
+}
+
+// OnArchivedFilterChanged implements ArchiveHandler interface
+func (a *App) OnArchivedFilterChanged() {
+	a.mainUI.RefreshNoteList()
+}
@@ -0,0 +1,1243 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/curtisnewbie/miso/flow"
+	"github.com/curtisnewbie/miso/util/atom"
+	"github.com/curtisnewbie/nota/internal/domain"
+	"github.com/curtisnewbie/nota/internal/infrastructure"
+	"github.com/curtisnewbie/nota/internal/repository"
+	"github.com/curtisnewbie/nota/internal/server"
+	"github.com/curtisnewbie/nota/internal/service"
+	"gorm.io/gorm"
+)
+
+// errUsage is returned when a command is called with invalid arguments
+var errUsage = errors.New("invalid usage")
+
+// command is a nota subcommand
+type command struct {
+	name    string
+	usage   string
+	summary string
+	run     func(c *CLI, args []string) error
+}
+
+var commands = []command{
+	{"list", "list [--limit n] [--archived] [--json]", "List notes, starred notes first and then the most recently updated", (*CLI).list},
+	{"show", "show <id> [--json]", "Show a note", (*CLI).show},
+	{"new", "new --title <title> [--content <content>] | --template <template> [--field name=value]... [--json]", "Create a note, the content is read from stdin when piped", (*CLI).create},
+	{"today", "today [--date <yyyy-mm-dd>] [--json]", "Show the daily note of today or the given day, it's created when the day doesn't have one yet", (*CLI).today},
+	{"remind", "remind [--limit n] | <id> --at <yyyy-mm-dd hh:mm> | <id> --done [--json]", "Set or complete the reminder of a note, or list the reminders due soon", (*CLI).remind},
+	{"tasks", "tasks [--all] [--tag <tag>] | --done <id:line> | --undo <id:line> [--json]", "List the open tasks across notes, or tick and untick a task in its note", (*CLI).tasks},
+	{"star", "star <id>... [--off] [--json]", "Star notes so that they are listed first, or unstar them with --off", (*CLI).star},
+	{"archive", "archive <id>... [--off] [--json]", "Archive notes so that they are left out of lists and searches, or unarchive them with --off", (*CLI).archive},
+	{"edit", "edit <id> [--title <title>] [--json]", "Edit the content of a note in $EDITOR", (*CLI).edit},
+	{"search", "search <query> [--tag <tag>]... [--limit n] [--json]", "Search notes by title and content", (*CLI).search},
+	{"rm", "rm <id>... [--purge] [--json]", "Move notes to the trash, or delete them permanently with --purge", (*CLI).rm},
+	{"import", "import <file> | --markdown <dir> [--overwrite] [--json]", "Import notes from a JSON export file, or from a directory of markdown files", (*CLI).importNotes},
+	{"export", "export [--output <file>] [--markdown <dir>]", "Export every note as JSON to stdout by default, or as markdown files", (*CLI).export},
+	{"serve", "serve [--addr " + service.DefaultAPIServerAddr + "] [--origin <url>] [--reset-token]", "Serve notes over a JSON REST API until interrupted", (*CLI).serve},
+	{"migrations", "migrations [--json]", "List the schema migrations and whether they have been applied", (*CLI).migrations},
+	{"backup", "backup [--list] [--json]", "Back up the database into the backups directory next to it, or list the backups", (*CLI).backup},
+	{"restore", "restore <file>", "Replace the notes with a backup, the database is backed up first", (*CLI).restore},
+	{"check", "check [--repair] [--json]", "Check the database for problems, and repair them with --repair", (*CLI).check},
+}
+
+// databaseCommands work on the database file itself, they run without opening the database
+var databaseCommands = []command{
+	{"encrypt", "encrypt", "Encrypt the database with a password, it's asked for whenever nota opens the database", (*CLI).encrypt},
+	{"passwd", "passwd", "Change the password of the encrypted database", (*CLI).passwd},
+	{"decrypt", "decrypt", "Decrypt the database back to plain storage", (*CLI).decrypt},
+}
+
+const (
+	// passwordEnv is the environment variable read for the database password instead of prompting for it
+	passwordEnv = "NOTA_PASSWORD"
+
+	// newPasswordEnv is the environment variable read for the new database password of encrypt and passwd
+	newPasswordEnv = "NOTA_NEW_PASSWORD"
+)
+
+// CLI runs nota subcommands against the nota database
+type CLI struct {
+	db                  *gorm.DB
+	noteService         service.NoteService
+	importExportService service.ImportExportService
+	configService       service.ConfigService
+	diagnosticsService  service.DiagnosticsService
+	stdin               io.Reader
+	stdout              io.Writer
+	stderr              io.Writer
+	input               *bufio.Reader
+}
+
+// IsCommand checks whether the argument is a nota subcommand
+func IsCommand(name string) bool {
+	if name == "help" || name == "-h" || name == "--help" {
+		return true
+	}
+	for _, cmd := range append(commands, databaseCommands...) {
+		if cmd.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseGlobalFlags applies the flags given before the subcommand, e.g., --db, and returns the remaining arguments
+func ParseGlobalFlags(args []string) ([]string, error) {
+	for len(args) > 0 {
+		var path string
+		if value, ok := strings.CutPrefix(args[0], "--db="); ok {
+			path = value
+			args = args[1:]
+		} else if args[0] == "--db" {
+			if len(args) < 2 {
+				return nil, errors.New("--db needs the path of the database")
+			}
+			path = args[1]
+			args = args[2:]
+		} else {
+			break
+		}
+		if err := infrastructure.SetDatabasePath(path); err != nil {
+			return nil, err
+		}
+	}
+	return args, nil
+}
+
+// Run runs the subcommand in args and returns the exit code
+func Run(args []string) int {
+	// Logs would be mixed up with the command output
+	flow.SetLogLevel("error")
+	flow.SetLogOutput(os.Stderr)
+
+	c := &CLI{stdin: os.Stdin, stdout: os.Stdout, stderr: os.Stderr}
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		c.printUsage(c.stdout)
+		return 0
+	}
+
+	for _, cmd := range databaseCommands {
+		if cmd.name == args[0] {
+			return c.runCommand(cmd, args[1:])
+		}
+	}
+
+	for _, cmd := range commands {
+		if cmd.name != args[0] {
+			continue
+		}
+		if err := c.init(); err != nil {
+			fmt.Fprintf(c.stderr, "nota: %v\n", err)
+			return 1
+		}
+		code := c.runCommand(cmd, args[1:])
+
+		// An encrypted database is written back to the database file one last time when it's closed
+		if err := infrastructure.CloseDatabase(); err != nil {
+			fmt.Fprintf(c.stderr, "nota: %v\n", err)
+			return 1
+		}
+		return code
+	}
+
+	fmt.Fprintf(c.stderr, "nota: unknown command %q\n", args[0])
+	c.printUsage(c.stderr)
+	return 2
+}
+
+// runCommand runs the command and returns the exit code
+func (c *CLI) runCommand(cmd command, args []string) int {
+	err := cmd.run(c, args)
+	if errors.Is(err, errUsage) {
+		fmt.Fprintf(c.stderr, "usage: nota %s\n", cmd.usage)
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintf(c.stderr, "nota %s: %v\n", cmd.name, err)
+		return 1
+	}
+	return 0
+}
+
+// init opens the database and creates the services
+func (c *CLI) init() error {
+	if err := infrastructure.EnsureDatabaseDir(); err != nil {
+		return err
+	}
+	db, err := infrastructure.InitializeDatabase()
+	if errors.Is(err, infrastructure.ErrDatabaseEncrypted) {
+		password, perr := c.readPassword(passwordEnv, "Password: ")
+		if perr != nil {
+			return perr
+		}
+		db, err = infrastructure.UnlockDatabase(password)
+	}
+	if err != nil {
+		return err
+	}
+	c.db = db
+
+	noteRepo := repository.NewSQLiteNoteRepository(db)
+	tagRepo := repository.NewSQLiteTagRepository(db)
+	c.noteService = service.NewNoteService(
+		noteRepo,
+		repository.NewSQLiteNoteRevisionRepository(db),
+		tagRepo,
+		repository.NewSQLiteNotebookRepository(db),
+		repository.NewSQLiteNoteLinkRepository(db),
+		repository.NewSQLiteNoteTaskRepository(db),
+	)
+	c.importExportService = service.NewImportExportService(noteRepo, tagRepo, c.noteService)
+	c.configService = service.NewConfigService(repository.NewSQLiteConfigRepository(db))
+	c.diagnosticsService = service.NewDiagnosticsService(repository.NewSQLiteDiagnosticsRepository(db))
+	return nil
+}
+
+// printUsage prints the available subcommands to out
+func (c *CLI) printUsage(out io.Writer) {
+	fmt.Fprintln(out, "Usage: nota [--db <path>] [command]")
+	fmt.Fprintf(out, "\nWithout a command, the nota window is opened. The database is %s,\nchange it with --db or $%s.\n\nCommands:\n", infrastructure.GetDatabaseLocation(), infrastructure.DatabaseEnv)
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	for _, cmd := range append(commands, databaseCommands...) {
+		fmt.Fprintf(w, "  %s\t%s\n", cmd.usage, cmd.summary)
+	}
+	w.Flush()
+	fmt.Fprintf(out, "\nThe password of an encrypted database is read from $%s, and the new one from $%s, when they are set.\n", passwordEnv, newPasswordEnv)
+}
+
+// list lists notes
+func (c *CLI) list(args []string) error {
+	fs := newFlagSet("list")
+	limit := fs.Int("limit", 0, "maximum number of notes, 0 for all")
+	archived := fs.Bool("archived", false, "list the archived notes instead")
+	asJSON := fs.Bool("json", false, "print as JSON")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+
+	rail := flow.EmptyRail()
+	var notes []*domain.Note
+	var err error
+	if *archived {
+		n := *limit
+		if n <= 0 {
+			n = -1 // no limit
+		}
+		notes, err = c.noteService.SearchNotesPaginated(rail, domain.NoteFilter{Archived: true}, 0, n)
+	} else if *limit > 0 {
+		notes, err = c.noteService.ListNotesPaginated(rail, 0, *limit)
+	} else {
+		notes, err = c.noteService.ListNotes(rail)
+	}
+	if err != nil {
+		return err
+	}
+	return c.printNotes(notes, *asJSON)
+}
+
+// show prints a note
+func (c *CLI) show(args []string) error {
+	fs := newFlagSet("show")
+	asJSON := fs.Bool("json", false, "print as JSON")
+	positional, err := parseArgs(fs, args)
+	if err != nil || len(positional) != 1 {
+		return errUsage
+	}
+
+	note, err := c.noteService.GetNote(flow.EmptyRail(), positional[0])
+	if err != nil {
+		return err
+	}
+	return c.printNote(note, *asJSON)
+}
+
+// create creates a note, or a note from a template with its variables expanded
+func (c *CLI) create(args []string) error {
+	fs := newFlagSet("new")
+	title := fs.String("title", "", "title of the note")
+	content := fs.String("content", "", "content of the note")
+	template := fs.String("template", "", "ID or title of the template the note is created from")
+	var fields stringList
+	fs.Var(&fields, "field", "value of a custom template field as name=value, can be repeated")
+	asJSON := fs.Bool("json", false, "print as JSON")
+	positional, err := parseArgs(fs, args)
+	if err != nil || len(positional) != 0 {
+		return errUsage
+	}
+
+	var note *domain.Note
+	if *template != "" {
+		if isFlagSet(fs, "content") {
+			return errUsage
+		}
+		note, err = c.noteFromTemplate(*template, fields)
+		if err != nil {
+			return err
+		}
+		if *title != "" {
+			note.Title = *title
+		}
+	} else {
+		if *title == "" || len(fields) > 0 {
+			return errUsage
+		}
+		if !isFlagSet(fs, "content") && isPiped(c.stdin) {
+			data, err := io.ReadAll(c.stdin)
+			if err != nil {
+				return err
+			}
+			*content = string(data)
+		}
+		note = &domain.Note{
+			Title:    *title,
+			Content:  *content,
+			Version:  1,
+			Metadata: make(map[string]interface{}),
+		}
+	}
+
+	note.CreatedAt = atom.Now()
+	note.UpdatedAt = atom.Now()
+	if err := c.noteService.CreateNote(flow.EmptyRail(), note); err != nil {
+		return err
+	}
+	if *asJSON {
+		return c.printJSON(note.ToJSON())
+	}
+	fmt.Fprintln(c.stdout, note.ID)
+	return nil
+}
+
+// noteFromTemplate creates a note in memory from the template with the ID or title, fields are name=value pairs
+//
+// There is no clipboard in the terminal, {{clipboard}} is replaced with the content read from stdin when piped.
+func (c *CLI) noteFromTemplate(idOrTitle string, fields []string) (*domain.Note, error) {
+	templates, err := c.noteService.ListTemplates(flow.EmptyRail())
+	if err != nil {
+		return nil, err
+	}
+	var template *domain.Note
+	for _, t := range templates {
+		if t.ID == idOrTitle || (template == nil && t.Title == idOrTitle) {
+			template = t
+		}
+	}
+	if template == nil {
+		return nil, fmt.Errorf("template not found: %s", idOrTitle)
+	}
+
+	clipboard := ""
+	if domain.UsesTemplateVar(domain.TemplateVarClipboard, template.Title, template.Content) && isPiped(c.stdin) {
+		data, err := io.ReadAll(c.stdin)
+		if err != nil {
+			return nil, err
+		}
+		clipboard = strings.TrimRight(string(data), "\r\n")
+	}
+	values := domain.BuiltinTemplateValues(time.Now(), clipboard)
+	for _, field := range fields {
+		name, value, ok := strings.Cut(field, "=")
+		if !ok {
+			return nil, errUsage
+		}
+		values[strings.TrimSpace(name)] = value
+	}
+	for _, name := range domain.TemplateFields(template.Title, template.Content) {
+		if _, ok := values[name]; !ok {
+			return nil, fmt.Errorf("template field %q needs a value, pass it with --field %q", name, name+"=...")
+		}
+	}
+	return domain.NewNoteFromTemplate(template, values), nil
+}
+
+// today shows the daily note of a day, creating it first when needed
+func (c *CLI) today(args []string) error {
+	fs := newFlagSet("today")
+	day := fs.String("date", "", "the day, today by default")
+	asJSON := fs.Bool("json", false, "print as JSON")
+	positional, err := parseArgs(fs, args)
+	if err != nil || len(positional) != 0 {
+		return errUsage
+	}
+
+	date := time.Now()
+	if *day != "" {
+		if date, err = time.ParseInLocation(domain.DailyDateLayout, *day, time.Local); err != nil {
+			return fmt.Errorf("invalid date %q, expected yyyy-mm-dd", *day)
+		}
+	}
+
+	rail := flow.EmptyRail()
+	format := c.configService.GetDailyTitleFormat(rail)
+	note, err := c.noteService.GetDailyNote(rail, date, format)
+	if errors.Is(err, service.ErrNoteNotFound) {
+		note = domain.NewDailyNote(date, format)
+		note.CreatedAt = atom.Now()
+		note.UpdatedAt = atom.Now()
+		err = c.noteService.CreateNote(rail, note)
+	}
+	if err != nil {
+		return err
+	}
+	return c.printNote(note, *asJSON)
+}
+
+// remind sets or completes the reminder of a note, or lists the reminders due soon without a note
+func (c *CLI) remind(args []string) error {
+	fs := newFlagSet("remind")
+	at := fs.String("at", "", "when the reminder is due")
+	done := fs.Bool("done", false, "complete the reminder")
+	limit := fs.Int("limit", 50, "maximum number of reminders")
+	asJSON := fs.Bool("json", false, "print as JSON")
+	positional, err := parseArgs(fs, args)
+	if err != nil || len(positional) > 1 {
+		return errUsage
+	}
+
+	rail := flow.EmptyRail()
+	if len(positional) == 0 {
+		if *at != "" || *done {
+			return errUsage
+		}
+		notes, err := c.noteService.SearchNotesPaginated(rail, domain.NoteFilter{DueSoon: true}, 0, *limit)
+		if err != nil {
+			return err
+		}
+		return c.printReminders(notes, *asJSON)
+	}
+	if (*at == "") == !*done {
+		return errUsage
+	}
+
+	var dueAt *time.Time
+	if *at != "" {
+		due, err := time.ParseInLocation("2006-01-02 15:04", *at, time.Local)
+		if err != nil {
+			return fmt.Errorf("invalid time %q, expected yyyy-mm-dd hh:mm", *at)
+		}
+		dueAt = &due
+	}
+	if err := c.noteService.SetReminder(rail, positional[0], dueAt); err != nil {
+		return err
+	}
+	note, err := c.noteService.GetNote(rail, positional[0])
+	if err != nil {
+		return err
+	}
+	return c.printReminders([]*domain.Note{note}, *asJSON)
+}
+
+// star stars or unstars notes
+func (c *CLI) star(args []string) error {
+	fs := newFlagSet("star")
+	off := fs.Bool("off", false, "unstar the notes")
+	asJSON := fs.Bool("json", false, "print as JSON")
+	positional, err := parseArgs(fs, args)
+	if err != nil || len(positional) == 0 {
+		return errUsage
+	}
+
+	rail := flow.EmptyRail()
+	notes := make([]*domain.Note, 0, len(positional))
+	for _, id := range positional {
+		if err := c.noteService.SetStarred(rail, id, !*off); err != nil {
+			return err
+		}
+		note, err := c.noteService.GetNote(rail, id)
+		if err != nil {
+			return err
+		}
+		notes = append(notes, note)
+	}
+	return c.printNotes(notes, *asJSON)
+}
+
+// archive archives or unarchives notes
+func (c *CLI) archive(args []string) error {
+	fs := newFlagSet("archive")
+	off := fs.Bool("off", false, "unarchive the notes")
+	asJSON := fs.Bool("json", false, "print as JSON")
+	positional, err := parseArgs(fs, args)
+	if err != nil || len(positional) == 0 {
+		return errUsage
+	}
+
+	rail := flow.EmptyRail()
+	notes := make([]*domain.Note, 0, len(positional))
+	for _, id := range positional {
+		if err := c.noteService.SetArchived(rail, id, !*off); err != nil {
+			return err
+		}
+		note, err := c.noteService.GetNote(rail, id)
+		if err != nil {
+			return err
+		}
+		notes = append(notes, note)
+	}
+	return c.printNotes(notes, *asJSON)
+}
+
+// tasks lists the tasks across notes, or ticks or unticks a task
+func (c *CLI) tasks(args []string) error {
+	fs := newFlagSet("tasks")
+	all := fs.Bool("all", false, "also list the tasks that are done")
+	tag := fs.String("tag", "", "only tasks with the #tag")
+	done := fs.String("done", "", "tick the task at line of the note")
+	undo := fs.String("undo", "", "untick the task at line of the note")
+	asJSON := fs.Bool("json", false, "print as JSON")
+	positional, err := parseArgs(fs, args)
+	if err != nil || len(positional) != 0 || (*done != "" && *undo != "") {
+		return errUsage
+	}
+
+	rail := flow.EmptyRail()
+	if *done != "" || *undo != "" {
+		ref, tick := *done, true
+		if *undo != "" {
+			ref, tick = *undo, false
+		}
+		noteID, lineText, ok := strings.Cut(ref, ":")
+		line, err := strconv.Atoi(lineText)
+		if !ok || err != nil {
+			return fmt.Errorf("invalid task %q, expected <id>:<line>", ref)
+		}
+		if _, err := c.noteService.SetTaskDone(rail, noteID, line, tick); err != nil {
+			return err
+		}
+		*all = true
+	}
+
+	tasks, err := c.noteService.ListTasks(rail, domain.TaskFilter{IncludeDone: *all, Tag: strings.TrimPrefix(*tag, "#")})
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		if tasks == nil {
+			tasks = []*domain.NoteTask{}
+		}
+		return c.printJSON(tasks)
+	}
+	w := tabwriter.NewWriter(c.stdout, 0, 4, 2, ' ', 0)
+	for _, task := range tasks {
+		mark := " "
+		if task.Done {
+			mark = "x"
+		}
+		fmt.Fprintf(w, "%s:%d\t[%s] %s\t%s\n", task.NoteID, task.Line, mark, task.Text, task.NoteTitle)
+	}
+	return w.Flush()
+}
+
+// edit opens the content of a note in $EDITOR and saves it when changed
+func (c *CLI) edit(args []string) error {
+	fs := newFlagSet("edit")
+	title := fs.String("title", "", "new title of the note")
+	asJSON := fs.Bool("json", false, "print as JSON")
+	positional, err := parseArgs(fs, args)
+	if err != nil || len(positional) != 1 {
+		return errUsage
+	}
+
+	rail := flow.EmptyRail()
+	note, err := c.noteService.GetNote(rail, positional[0])
+	if err != nil {
+		return err
+	}
+	if note.Encrypted {
+		return fmt.Errorf("%w, unlock it in nota to edit it", service.ErrNoteLocked)
+	}
+
+	content, err := c.editInEditor(note.Content)
+	if err != nil {
+		return err
+	}
+
+	changed := content != note.Content
+	note.Content = content
+	if *title != "" && *title != note.Title {
+		note.Title = *title
+		changed = true
+	}
+	if changed {
+		err := c.noteService.UpdateNote(rail, note)
+		if errors.Is(err, service.ErrVersionConflict) {
+			return c.keepConflictingEdit(note, err)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if *asJSON {
+		return c.printJSON(note.ToJSON())
+	}
+	if changed {
+		fmt.Fprintf(c.stdout, "Saved %s (version %d)\n", note.ID, note.Version)
+	} else {
+		fmt.Fprintln(c.stdout, "No changes")
+	}
+	return nil
+}
+
+// keepConflictingEdit writes the edited content to a file so that it isn't lost when the note was updated elsewhere
+func (c *CLI) keepConflictingEdit(note *domain.Note, conflict error) error {
+	f, err := os.CreateTemp("", "nota-"+note.ID+"-*.md")
+	if err != nil {
+		return conflict
+	}
+	defer f.Close()
+	if _, err := f.WriteString(note.Content); err != nil {
+		return conflict
+	}
+	return fmt.Errorf("%w, your changes were saved to %s", conflict, f.Name())
+}
+
+// editInEditor writes the content to a temporary file, opens it in $EDITOR and returns the edited content
+func (c *CLI) editInEditor(content string) (string, error) {
+	f, err := os.CreateTemp("", "nota-*.md")
+	if err != nil {
+		return "", err
+	}
+	path := f.Name()
+	defer os.Remove(path)
+
+	if _, err := f.WriteString(content); err != nil {
+		f.Close()
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+
+	// $EDITOR may contain arguments, e.g. "code --wait"
+	parts := strings.Fields(editor)
+	cmd := exec.Command(parts[0], append(parts[1:], path)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("editor %s failed: %w", editor, err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// search searches notes
+func (c *CLI) search(args []string) error {
+	fs := newFlagSet("search")
+	var tags stringList
+	fs.Var(&tags, "tag", "only notes with the tag, can be repeated")
+	limit := fs.Int("limit", 50, "maximum number of notes")
+	asJSON := fs.Bool("json", false, "print as JSON")
+	positional, err := parseArgs(fs, args)
+	if err != nil || (len(positional) == 0 && len(tags) == 0) {
+		return errUsage
+	}
+
+	filter := domain.NoteFilter{Query: strings.Join(positional, " "), Tags: tags}
+	notes, err := c.noteService.SearchNotesPaginated(flow.EmptyRail(), filter, 0, *limit)
+	if err != nil {
+		return err
+	}
+	return c.printNotes(notes, *asJSON)
+}
+
+// rm moves notes to the trash, or deletes them permanently
+func (c *CLI) rm(args []string) error {
+	fs := newFlagSet("rm")
+	purge := fs.Bool("purge", false, "delete permanently instead of moving to the trash")
+	asJSON := fs.Bool("json", false, "print as JSON")
+	positional, err := parseArgs(fs, args)
+	if err != nil || len(positional) == 0 {
+		return errUsage
+	}
+
+	rail := flow.EmptyRail()
+	deleted := []string{}
+	for _, id := range positional {
+		// Notes already in the trash can still be purged
+		err := c.noteService.DeleteNote(rail, id)
+		if err != nil && !(*purge && errors.Is(err, service.ErrNoteNotFound)) {
+			return fmt.Errorf("%s: %w", id, err)
+		}
+		if *purge {
+			if err := c.noteService.PurgeNote(rail, id); err != nil {
+				return fmt.Errorf("%s: %w", id, err)
+			}
+		}
+		deleted = append(deleted, id)
+	}
+
+	if *asJSON {
+		return c.printJSON(map[string]any{"deleted": deleted, "purged": *purge})
+	}
+	for _, id := range deleted {
+		fmt.Fprintln(c.stdout, id)
+	}
+	return nil
+}
+
+// importNotes imports notes from a JSON export file or a directory of markdown files
+func (c *CLI) importNotes(args []string) error {
+	fs := newFlagSet("import")
+	overwrite := fs.Bool("overwrite", false, "overwrite notes with the same ID")
+	markdownDir := fs.String("markdown", "", "directory to import markdown files from")
+	asJSON := fs.Bool("json", false, "print as JSON")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return errUsage
+	}
+
+	if *markdownDir != "" {
+		if len(positional) != 0 {
+			return errUsage
+		}
+		return c.importMarkdown(*markdownDir, *overwrite, *asJSON)
+	}
+
+	if len(positional) != 1 {
+		return errUsage
+	}
+
+	notes, err := c.importExportService.ImportNotesFromFile(flow.EmptyRail(), positional[0], func(note *domain.Note) bool {
+		return *overwrite
+	})
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		return c.printNotes(notes, true)
+	}
+	fmt.Fprintf(c.stdout, "Imported %d notes\n", len(notes))
+	return nil
+}
+
+// importMarkdown imports markdown files and prints what happened to each of them
+func (c *CLI) importMarkdown(dir string, overwrite bool, asJSON bool) error {
+	results, err := c.importExportService.ImportMarkdown(flow.EmptyRail(), dir, func(note *domain.Note) bool {
+		return overwrite
+	})
+	if err != nil {
+		return err
+	}
+
+	if asJSON {
+		if results == nil {
+			results = []service.ImportResult{}
+		}
+		return c.printJSON(results)
+	}
+
+	counts := map[service.ImportStatus]int{}
+	w := tabwriter.NewWriter(c.stdout, 0, 4, 2, ' ', 0)
+	for _, result := range results {
+		counts[result.Status]++
+		detail := result.Title
+		if result.Reason != "" {
+			detail = result.Reason
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", result.Status, result.Path, detail)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintf(c.stderr, "Imported %d, skipped %d, failed %d\n",
+		counts[service.ImportStatusImported], counts[service.ImportStatusSkipped], counts[service.ImportStatusFailed])
+	return nil
+}
+
+// export exports every note as JSON
+func (c *CLI) export(args []string) error {
+	fs := newFlagSet("export")
+	output := fs.String("output", "", "file to write to, stdout by default")
+	markdownDir := fs.String("markdown", "", "directory to write markdown files to")
+	positional, err := parseArgs(fs, args)
+	if err != nil || len(positional) != 0 || (*output != "" && *markdownDir != "") {
+		return errUsage
+	}
+
+	rail := flow.EmptyRail()
+	notes, err := c.noteService.ListAllNotes(rail)
+	if err != nil {
+		return err
+	}
+
+	if *markdownDir != "" {
+		if err := c.importExportService.ExportNotesAsMarkdown(rail, notes, *markdownDir); err != nil {
+			return err
+		}
+		fmt.Fprintf(c.stderr, "Exported %d notes to %s\n", len(notes), *markdownDir)
+		return nil
+	}
+
+	if *output == "" {
+		return c.importExportService.ExportNotesTo(rail, notes, c.stdout)
+	}
+
+	f, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+	if err := c.importExportService.ExportNotesTo(rail, notes, f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintf(c.stderr, "Exported %d notes to %s\n", len(notes), *output)
+	return nil
+}
+
+// serve serves notes over the REST API until the process is interrupted
+func (c *CLI) serve(args []string) error {
+	rail := flow.EmptyRail()
+	_, configuredAddr := c.configService.GetAPIServer(rail)
+	fs := newFlagSet("serve")
+	addr := fs.String("addr", configuredAddr, "address to listen on")
+	origin := fs.String("origin", c.configService.GetAPIAllowedOrigin(rail), "origin that web pages may call the API from")
+	resetToken := fs.Bool("reset-token", false, "replace the token with a new one")
+	positional, err := parseArgs(fs, args)
+	if err != nil || len(positional) != 0 {
+		return errUsage
+	}
+	allowedOrigin, err := server.NormalizeOrigin(*origin)
+	if err != nil {
+		return err
+	}
+
+	var token string
+	if *resetToken {
+		token, err = c.configService.ResetAPIToken(rail)
+	} else {
+		token, err = c.configService.GetAPIToken(rail)
+	}
+	if err != nil {
+		return err
+	}
+
+	apiServer := server.NewServer(c.noteService, token, nil)
+	apiServer.SetAllowedOrigin(allowedOrigin)
+	if err := apiServer.Start(*addr); err != nil {
+		return err
+	}
+	fmt.Fprintf(c.stderr, "Serving on http://%s/api/v1\nToken: %s\n", apiServer.Addr(), token)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	<-ctx.Done()
+	return apiServer.Stop()
+}
+
+// migrations lists the schema migrations of the database
+func (c *CLI) migrations(args []string) error {
+	fs := newFlagSet("migrations")
+	asJSON := fs.Bool("json", false, "print as JSON")
+	positional, err := parseArgs(fs, args)
+	if err != nil || len(positional) != 0 {
+		return errUsage
+	}
+
+	statuses, err := infrastructure.ListMigrations(flow.EmptyRail(), c.db)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		type migrationJSON struct {
+			Version   int        `json:"version"`
+			Name      string     `json:"name"`
+			Available bool       `json:"available"`
+			AppliedAt *atom.Time `json:"applied_at,omitempty"`
+		}
+		result := make([]migrationJSON, len(statuses))
+		for i, st := range statuses {
+			result[i] = migrationJSON{Version: st.Version, Name: st.Name, Available: st.Available, AppliedAt: st.AppliedAt}
+		}
+		return c.printJSON(result)
+	}
+
+	w := tabwriter.NewWriter(c.stdout, 0, 4, 2, ' ', 0)
+	for _, st := range statuses {
+		status := "pending"
+		if st.AppliedAt != nil {
+			status = "applied " + st.AppliedAt.Format("2006/01/02 15:04")
+		} else if !st.Available {
+			status = "not available in this build"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\n", st.Version, st.Name, status)
+	}
+	return w.Flush()
+}
+
+// backup backs up the database, or lists the backups with --list
+func (c *CLI) backup(args []string) error {
+	fs := newFlagSet("backup")
+	list := fs.Bool("list", false, "list the backups instead")
+	asJSON := fs.Bool("json", false, "print as JSON")
+	positional, err := parseArgs(fs, args)
+	if err != nil || len(positional) != 0 {
+		return errUsage
+	}
+
+	if !*list {
+		path, err := infrastructure.BackupDatabase(flow.EmptyRail(), infrastructure.BackupKindManual)
+		if err != nil {
+			return err
+		}
+		if *asJSON {
+			return c.printJSON(map[string]string{"path": path})
+		}
+		fmt.Fprintln(c.stdout, path)
+		return nil
+	}
+
+	backups, err := infrastructure.ListBackups()
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		type backupJSON struct {
+			Path      string    `json:"path"`
+			Kind      string    `json:"kind"`
+			CreatedAt atom.Time `json:"created_at"`
+			Size      int64     `json:"size"`
+			Encrypted bool      `json:"encrypted"`
+		}
+		result := make([]backupJSON, len(backups))
+		for i, b := range backups {
+			result[i] = backupJSON{Path: b.Path, Kind: b.Kind, CreatedAt: atom.WrapTime(b.CreatedAt), Size: b.Size, Encrypted: b.Encrypted}
+		}
+		return c.printJSON(result)
+	}
+
+	w := tabwriter.NewWriter(c.stdout, 0, 4, 2, ' ', 0)
+	for _, b := range backups {
+		kind := b.Kind
+		if b.Encrypted {
+			kind += " (encrypted)"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", b.CreatedAt.Format("2006/01/02 15:04:05"), kind, b.Size, b.Path)
+	}
+	return w.Flush()
+}
+
+// restore replaces the content of the database with a backup
+func (c *CLI) restore(args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+	rail := flow.EmptyRail()
+
+	// A backup taken while the database had another password is decrypted with that one
+	preRestore, err := infrastructure.RestoreBackup(rail, args[0], "")
+	if errors.Is(err, infrastructure.ErrDatabaseEncrypted) {
+		password, perr := c.readPassword(passwordEnv, "Password of the backup: ")
+		if perr != nil {
+			return perr
+		}
+		preRestore, err = infrastructure.RestoreBackup(rail, args[0], password)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(c.stderr, "Restored %s, the database before restoring is backed up at %s\n", args[0], preRestore)
+	return nil
+}
+
+// check checks the database for problems and repairs them with --repair, it fails when problems remain
+func (c *CLI) check(args []string) error {
+	fs := newFlagSet("check")
+	repair := fs.Bool("repair", false, "repair the problems, the database is backed up first")
+	asJSON := fs.Bool("json", false, "print as JSON")
+	positional, err := parseArgs(fs, args)
+	if err != nil || len(positional) != 0 {
+		return errUsage
+	}
+
+	rail := flow.EmptyRail()
+	issues, err := c.diagnosticsService.Diagnose(rail)
+	if err != nil {
+		return err
+	}
+	if *repair && len(issues) > 0 {
+		backupPath, err := infrastructure.BackupDatabase(rail, infrastructure.BackupKindPreRepair)
+		if err != nil {
+			return fmt.Errorf("failed to back up database before repairing: %w", err)
+		}
+		repaired, err := c.diagnosticsService.Repair(rail, issues)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(c.stderr, "Repaired %d problems, the database before repairing is backed up at %s\n", repaired, backupPath)
+		if issues, err = c.diagnosticsService.Diagnose(rail); err != nil {
+			return err
+		}
+	}
+
+	if *asJSON {
+		if issues == nil {
+			issues = []domain.Issue{}
+		}
+		if err := c.printJSON(issues); err != nil {
+			return err
+		}
+	} else {
+		w := tabwriter.NewWriter(c.stdout, 0, 4, 2, ' ', 0)
+		for _, issue := range issues {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%q\n", issue.Kind, issue.NoteID, issue.Title, issue.Column, issue.Value)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+	if len(issues) > 0 {
+		return fmt.Errorf("%d problems found", len(issues))
+	}
+	return nil
+}
+
+// encrypt encrypts the database with a new password
+func (c *CLI) encrypt(args []string) error {
+	if len(args) != 0 {
+		return errUsage
+	}
+	password, err := c.readNewPassword()
+	if err != nil {
+		return err
+	}
+	if err := infrastructure.EncryptDatabase(password); err != nil {
+		return err
+	}
+	fmt.Fprintf(c.stderr, "Encrypted %s\n", infrastructure.GetDatabaseLocation())
+	return nil
+}
+
+// passwd changes the password of the encrypted database
+func (c *CLI) passwd(args []string) error {
+	if len(args) != 0 {
+		return errUsage
+	}
+	encrypted, err := infrastructure.IsDatabaseEncrypted()
+	if err != nil {
+		return err
+	}
+	if !encrypted {
+		return infrastructure.ErrDatabaseNotEncrypted
+	}
+	oldPassword, err := c.readPassword(passwordEnv, "Current password: ")
+	if err != nil {
+		return err
+	}
+	newPassword, err := c.readNewPassword()
+	if err != nil {
+		return err
+	}
+	if err := infrastructure.ChangeDatabasePassword(oldPassword, newPassword); err != nil {
+		return err
+	}
+	fmt.Fprintln(c.stderr, "Password changed")
+	return nil
+}
+
+// decrypt decrypts the database back to plain storage
+func (c *CLI) decrypt(args []string) error {
+	if len(args) != 0 {
+		return errUsage
+	}
+	encrypted, err := infrastructure.IsDatabaseEncrypted()
+	if err != nil {
+		return err
+	}
+	if !encrypted {
+		return infrastructure.ErrDatabaseNotEncrypted
+	}
+	password, err := c.readPassword(passwordEnv, "Password: ")
+	if err != nil {
+		return err
+	}
+	if err := infrastructure.DecryptDatabase(password); err != nil {
+		return err
+	}
+	fmt.Fprintf(c.stderr, "Decrypted %s\n", infrastructure.GetDatabaseLocation())
+	return nil
+}
+
+// readPassword reads a password from the environment variable, or prompts for it on stderr and reads a line from stdin
+func (c *CLI) readPassword(env string, prompt string) (string, error) {
+	if password, ok := os.LookupEnv(env); ok {
+		return password, nil
+	}
+	if c.input == nil {
+		c.input = bufio.NewReader(c.stdin)
+	}
+	fmt.Fprint(c.stderr, prompt)
+	line, err := c.input.ReadString('\n')
+	if err != nil && (!errors.Is(err, io.EOF) || line == "") {
+		return "", fmt.Errorf("failed to read password: %w", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// readNewPassword reads a new password, it has to be entered twice when prompted for
+func (c *CLI) readNewPassword() (string, error) {
+	if password, ok := os.LookupEnv(newPasswordEnv); ok {
+		return password, nil
+	}
+	password, err := c.readPassword(newPasswordEnv, "New password: ")
+	if err != nil {
+		return "", err
+	}
+	confirmed, err := c.readPassword(newPasswordEnv, "Confirm new password: ")
+	if err != nil {
+		return "", err
+	}
+	if password != confirmed {
+		return "", errors.New("passwords don't match")
+	}
+	return password, nil
+}
+
+// printNotes prints notes as a table or as a JSON array
+func (c *CLI) printNotes(notes []*domain.Note, asJSON bool) error {
+	if asJSON {
+		result := make([]domain.NoteJSON, len(notes))
+		for i, note := range notes {
+			result[i] = note.ToJSON()
+		}
+		return c.printJSON(result)
+	}
+
+	w := tabwriter.NewWriter(c.stdout, 0, 4, 2, ' ', 0)
+	for _, note := range notes {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", note.ID, note.UpdatedAt.Format("2006/01/02 15:04"), note.Title)
+	}
+	return w.Flush()
+}
+
+// printReminders prints when the reminders of notes are due, or the notes as JSON
+func (c *CLI) printReminders(notes []*domain.Note, asJSON bool) error {
+	if asJSON {
+		return c.printNotes(notes, true)
+	}
+
+	w := tabwriter.NewWriter(c.stdout, 0, 4, 2, ' ', 0)
+	for _, note := range notes {
+		due := "-"
+		if note.DueAt != nil {
+			due = note.DueAt.Format("2006/01/02 15:04")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", note.ID, due, note.Title)
+	}
+	return w.Flush()
+}
+
+// printNote prints a note with its title as a heading, or as JSON
+func (c *CLI) printNote(note *domain.Note, asJSON bool) error {
+	if asJSON {
+		return c.printJSON(note.ToJSON())
+	}
+	if note.Encrypted {
+		fmt.Fprintf(c.stdout, "# %s\n\n(locked)\n", note.Title)
+		return nil
+	}
+	fmt.Fprintf(c.stdout, "# %s\n\n%s\n", note.Title, note.Content)
+	return nil
+}
+
+// printJSON prints the value as indented JSON
+func (c *CLI) printJSON(v any) error {
+	encoder := json.NewEncoder(c.stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// newFlagSet creates a flag set that reports errors instead of exiting
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	return fs
+}
+
+// parseArgs parses flags placed anywhere between the positional arguments
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	positional := []string{}
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, errUsage
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// isFlagSet checks whether the flag was given on the command line
+func isFlagSet(fs *flag.FlagSet, name string) bool {
+	found := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			found = true
+		}
+	})
+	return found
+}
+
+// isPiped checks whether the reader is a pipe or a file rather than a terminal
+func isPiped(r io.Reader) bool {
+	f, ok := r.(*os.File)
+	if !ok {
+		return true
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice == 0
+}
+
+// stringList is a flag that can be repeated
+type stringList []string
+
+func (s *stringList) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringList) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
@@ -0,0 +1,39 @@
+package domain
+
+import "time"
+
+const (
+	// DailyMetadataKey is the metadata key that marks a note as the daily note of a day, e.g., "daily": "2006-01-02"
+	DailyMetadataKey = "daily"
+
+	// DailyDateLayout is the layout of the day kept under DailyMetadataKey, whatever the title of the note looks like
+	DailyDateLayout = "2006-01-02"
+
+	// DefaultDailyTitleFormat is the default layout of the title of daily notes
+	DefaultDailyTitleFormat = "2006-01-02"
+)
+
+// DailyDate returns the day of a daily note, false when the note is not a daily note
+func (n *Note) DailyDate() (time.Time, bool) {
+	day, ok := n.Metadata[DailyMetadataKey].(string)
+	if !ok {
+		return time.Time{}, false
+	}
+	date, err := time.ParseInLocation(DailyDateLayout, day, time.Local)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return date, true
+}
+
+// NewDailyNote creates the daily note of a day in memory, titled by the date in titleFormat
+func NewDailyNote(date time.Time, titleFormat string) *Note {
+	if titleFormat == "" {
+		titleFormat = DefaultDailyTitleFormat
+	}
+	return &Note{
+		Title:    date.Format(titleFormat),
+		Version:  1,
+		Metadata: map[string]interface{}{DailyMetadataKey: date.Format(DailyDateLayout)},
+	}
+}
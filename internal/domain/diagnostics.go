@@ -0,0 +1,23 @@
+package domain
+
+// IssueKind is the kind of problem found by the database diagnostics
+type IssueKind string
+
+const (
+	IssueIntegrity        IssueKind = "integrity"         // reported by PRAGMA integrity_check
+	IssueInvalidMetadata  IssueKind = "invalid_metadata"  // the metadata of a note is not a JSON object
+	IssueEmptyTitle       IssueKind = "empty_title"       // the title of a note is blank
+	IssueInvalidTimestamp IssueKind = "invalid_timestamp" // a timestamp of a note is missing or can't be parsed
+)
+
+// InvalidMetadataKey is the metadata key that keeps the malformed metadata of a note once it's repaired
+const InvalidMetadataKey = "invalid_metadata"
+
+// Issue is a problem found in the database
+type Issue struct {
+	Kind   IssueKind `json:"kind"`
+	NoteID string    `json:"note_id,omitempty"`
+	Title  string    `json:"title,omitempty"`
+	Column string    `json:"column,omitempty"` // the column of the note with the problem, e.g., created_at
+	Value  string    `json:"value,omitempty"`  // the offending value, or the message of integrity_check
+}
@@ -0,0 +1,71 @@
+package domain
+
+import "strings"
+
+// DiffOp represents the kind of change a DiffLine describes
+type DiffOp int
+
+const (
+	DiffEqual DiffOp = iota
+	DiffInsert
+	DiffDelete
+)
+
+// DiffLine represents a single line in a line-based diff
+type DiffLine struct {
+	Op   DiffOp `json:"op"`
+	Text string `json:"text"`
+}
+
+// DiffLines computes a line-based diff turning a into b, based on the longest common subsequence of lines
+func DiffLines(a, b string) []DiffLine {
+	as := splitLines(a)
+	bs := splitLines(b)
+
+	// lcs[i][j] is the length of the longest common subsequence of as[i:] and bs[j:]
+	lcs := make([][]int, len(as)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(bs)+1)
+	}
+	for i := len(as) - 1; i >= 0; i-- {
+		for j := len(bs) - 1; j >= 0; j-- {
+			if as[i] == bs[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	lines := make([]DiffLine, 0, max(len(as), len(bs)))
+	i, j := 0, 0
+	for i < len(as) && j < len(bs) {
+		switch {
+		case as[i] == bs[j]:
+			lines = append(lines, DiffLine{Op: DiffEqual, Text: as[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, DiffLine{Op: DiffDelete, Text: as[i]})
+			i++
+		default:
+			lines = append(lines, DiffLine{Op: DiffInsert, Text: bs[j]})
+			j++
+		}
+	}
+	for ; i < len(as); i++ {
+		lines = append(lines, DiffLine{Op: DiffDelete, Text: as[i]})
+	}
+	for ; j < len(bs); j++ {
+		lines = append(lines, DiffLine{Op: DiffInsert, Text: bs[j]})
+	}
+	return lines
+}
+
+// splitLines splits s into lines, an empty string has no lines
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
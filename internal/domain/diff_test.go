@@ -0,0 +1,58 @@
+package domain
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiffLines(t *testing.T) {
+	eq := func(s string) DiffLine { return DiffLine{Op: DiffEqual, Text: s} }
+	ins := func(s string) DiffLine { return DiffLine{Op: DiffInsert, Text: s} }
+	del := func(s string) DiffLine { return DiffLine{Op: DiffDelete, Text: s} }
+
+	tests := []struct {
+		name string
+		a    string
+		b    string
+		want []DiffLine
+	}{
+		{"both empty", "", "", []DiffLine{}},
+		{"equal", "a\nb", "a\nb", []DiffLine{eq("a"), eq("b")}},
+		{"trailing newline ignored", "a\n", "a", []DiffLine{eq("a")}},
+		{"from empty", "", "a\nb", []DiffLine{ins("a"), ins("b")}},
+		{"to empty", "a\nb", "", []DiffLine{del("a"), del("b")}},
+		{"insert in middle", "a\nc", "a\nb\nc", []DiffLine{eq("a"), ins("b"), eq("c")}},
+		{"delete in middle", "a\nb\nc", "a\nc", []DiffLine{eq("a"), del("b"), eq("c")}},
+		{"replace deletes first", "a\nb\nc", "a\nx\nc", []DiffLine{eq("a"), del("b"), ins("x"), eq("c")}},
+		{"empty lines", "a\n\nb", "a\nb", []DiffLine{eq("a"), del(""), eq("b")}},
+		{"moved line", "a\nb\nc", "b\nc\na", []DiffLine{del("a"), eq("b"), eq("c"), ins("a")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DiffLines(tt.a, tt.b); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DiffLines(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffLinesRebuildsBothSides(t *testing.T) {
+	a := "# plan\n- one\n- two\n- three\n\nend"
+	b := "# plan v2\n- one\n- three\n- four\n\nend\n"
+	var before, after []string
+	for _, line := range DiffLines(a, b) {
+		if line.Op != DiffInsert {
+			before = append(before, line.Text)
+		}
+		if line.Op != DiffDelete {
+			after = append(after, line.Text)
+		}
+	}
+	if got := strings.Join(before, "\n"); got != a {
+		t.Errorf("old side = %q, want %q", got, a)
+	}
+	if got := strings.Join(after, "\n"); got != strings.TrimSuffix(b, "\n") {
+		t.Errorf("new side = %q, want %q", got, b)
+	}
+}
@@ -0,0 +1,18 @@
+package domain
+
+import "github.com/curtisnewbie/miso/util/atom"
+
+// Draft holds the unsaved edits of a note, so that they can be recovered when nota was not closed properly
+type Draft struct {
+	NoteID      string    `gorm:"primaryKey" json:"note_id"` // empty for a note that was never saved
+	Title       string    `gorm:"not null;default:''" json:"title"`
+	Content     string    `gorm:"type:text" json:"content"`
+	NotebookID  string    `gorm:"not null;default:''" json:"notebook_id"`
+	BaseVersion int       `gorm:"not null;default:0" json:"base_version"` // version of the note the edits started from
+	UpdatedAt   atom.Time `gorm:"not null" json:"updated_at"`
+}
+
+// TableName specifies the table name for GORM
+func (Draft) TableName() string {
+	return "draft"
+}
@@ -12,14 +12,20 @@ import (
 
 // Note represents a note in the system
 type Note struct {
-	ID        string                 `gorm:"primaryKey" json:"id"`
-	Title     string                 `gorm:"not null" json:"title"`
-	Content   string                 `gorm:"type:text" json:"content"`
-	Version   int                    `gorm:"not null;default:1" json:"version"`
-	CreatedAt atom.Time              `gorm:"not null" json:"created_at"`
-	UpdatedAt atom.Time              `gorm:"not null" json:"updated_at"`
-	DeletedAt *atom.Time             `gorm:"index" json:"deleted_at,omitempty"`
-	Metadata  map[string]interface{} `gorm:"type:text;serializer:json" json:"metadata"`
+	ID         string                 `gorm:"primaryKey" json:"id"`
+	Title      string                 `gorm:"not null" json:"title"`
+	Content    string                 `gorm:"type:text" json:"content"`
+	Version    int                    `gorm:"not null;default:1" json:"version"`
+	NotebookID string                 `gorm:"not null;default:'';index" json:"notebook_id"`
+	Encrypted  bool                   `gorm:"not null;default:false" json:"encrypted"`
+	CreatedAt  atom.Time              `gorm:"not null" json:"created_at"`
+	UpdatedAt  atom.Time              `gorm:"not null" json:"updated_at"`
+	DeletedAt  *atom.Time             `gorm:"index" json:"deleted_at,omitempty"`
+	Metadata   map[string]interface{} `gorm:"type:text;serializer:json" json:"metadata"`
+	DueAt      *atom.Time             `gorm:"index" json:"due_at,omitempty"` // when the reminder of the note is due
+	RemindedAt *atom.Time             `json:"reminded_at,omitempty"`         // when the reminder was last sent
+	Starred    bool                   `gorm:"not null;default:false;index" json:"starred"`
+	ArchivedAt *atom.Time             `gorm:"index" json:"archived_at,omitempty"` // when the note was archived, nil if not archived
 }
 
 // TableName specifies the table name for GORM
@@ -46,42 +52,61 @@ func (n *Note) BeforeUpdate(tx *gorm.DB) error {
 
 // NoteJSON represents the JSON format for import/export
 type NoteJSON struct {
-	ID        string                 `json:"id"`
-	Title     string                 `json:"title"`
-	Content   string                 `json:"content"`
-	Version   int                    `json:"version"`
-	CreatedAt string                 `json:"created_at"`
-	UpdatedAt string                 `json:"updated_at"`
-	DeletedAt *string                `json:"deleted_at,omitempty"`
-	Metadata  map[string]interface{} `json:"metadata"`
+	ID         string                 `json:"id"`
+	Title      string                 `json:"title"`
+	Content    string                 `json:"content"`
+	Version    int                    `json:"version"`
+	NotebookID string                 `json:"notebook_id,omitempty"`
+	Encrypted  bool                   `json:"encrypted,omitempty"`
+	CreatedAt  string                 `json:"created_at"`
+	UpdatedAt  string                 `json:"updated_at"`
+	DeletedAt  *string                `json:"deleted_at,omitempty"`
+	DueAt      *string                `json:"due_at,omitempty"`
+	Starred    bool                   `json:"starred,omitempty"`
+	ArchivedAt *string                `json:"archived_at,omitempty"`
+	Metadata   map[string]interface{} `json:"metadata"`
 }
 
 // ToJSON converts Note to NoteJSON for export
 func (n *Note) ToJSON() NoteJSON {
 	result := NoteJSON{
-		ID:        n.ID,
-		Title:     n.Title,
-		Content:   n.Content,
-		Version:   n.Version,
-		CreatedAt: n.CreatedAt.Format(time.RFC3339),
-		UpdatedAt: n.UpdatedAt.Format(time.RFC3339),
-		Metadata:  n.Metadata,
+		ID:         n.ID,
+		Title:      n.Title,
+		Content:    n.Content,
+		Version:    n.Version,
+		NotebookID: n.NotebookID,
+		Encrypted:  n.Encrypted,
+		Starred:    n.Starred,
+		CreatedAt:  n.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:  n.UpdatedAt.Format(time.RFC3339),
+		Metadata:   n.Metadata,
 	}
 	if n.DeletedAt != nil {
 		deletedAt := n.DeletedAt.Format(time.RFC3339)
 		result.DeletedAt = &deletedAt
 	}
+	if n.DueAt != nil {
+		dueAt := n.DueAt.Format(time.RFC3339)
+		result.DueAt = &dueAt
+	}
+	if n.ArchivedAt != nil {
+		archivedAt := n.ArchivedAt.Format(time.RFC3339)
+		result.ArchivedAt = &archivedAt
+	}
 	return result
 }
 
 // FromJSON creates Note from NoteJSON for import
 func FromJSON(json NoteJSON) (*Note, error) {
 	note := &Note{
-		ID:       json.ID,
-		Title:    json.Title,
-		Content:  json.Content,
-		Version:  json.Version,
-		Metadata: json.Metadata,
+		ID:         json.ID,
+		Title:      json.Title,
+		Content:    json.Content,
+		Version:    json.Version,
+		NotebookID: json.NotebookID,
+		Encrypted:  json.Encrypted && IsEncryptedContent(json.Content),
+		Starred:    json.Starred,
+		Metadata:   json.Metadata,
 	}
 
 	if json.CreatedAt != "" {
@@ -111,6 +136,20 @@ func FromJSON(json NoteJSON) (*Note, error) {
 		}
 	}
 
+	if json.DueAt != nil && *json.DueAt != "" {
+		if t, err := time.Parse(time.RFC3339, *json.DueAt); err == nil {
+			dueAt := atom.WrapTime(t)
+			note.DueAt = &dueAt
+		}
+	}
+
+	if json.ArchivedAt != nil && *json.ArchivedAt != "" {
+		if t, err := time.Parse(time.RFC3339, *json.ArchivedAt); err == nil {
+			archivedAt := atom.WrapTime(t)
+			note.ArchivedAt = &archivedAt
+		}
+	}
+
 	return note, nil
 }
 
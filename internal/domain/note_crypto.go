@@ -0,0 +1,175 @@
+package domain
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	// encryptedContentPrefix marks note content encrypted with EncryptContent, followed by the base64 encoded salt, nonce and ciphertext
+	encryptedContentPrefix = "nota-encrypted:v1:"
+
+	// SaltSize is the size of the random salt that a key is derived from a passphrase with
+	SaltSize = 16
+
+	// Argon2id parameters of the key derivation, changing them requires a new version of encryptedContentPrefix
+	argon2Time    = 3
+	argon2Memory  = 64 * 1024
+	argon2Threads = 4
+	argon2KeySize = 32
+)
+
+var (
+	// ErrEmptyPassphrase is returned when a note is locked with an empty passphrase
+	ErrEmptyPassphrase = errors.New("passphrase cannot be empty")
+
+	// ErrWrongPassphrase is returned when encrypted content can't be decrypted with the given passphrase
+	ErrWrongPassphrase = errors.New("wrong passphrase")
+
+	// ErrInvalidEncryptedContent is returned when content marked as encrypted is malformed
+	ErrInvalidEncryptedContent = errors.New("invalid encrypted content")
+)
+
+// NoteKey is the key that the content of a locked note is encrypted with, derived from its passphrase
+//
+// Deriving the key is deliberately slow, so it's derived once when a note is locked or unlocked and kept while the
+// note is edited, instead of the passphrase.
+type NoteKey struct {
+	salt []byte
+	gcm  cipher.AEAD
+}
+
+// NewNoteKey derives a key from the passphrase with a random salt, for locking a note
+func NewNoteKey(passphrase string) (*NoteKey, error) {
+	if passphrase == "" {
+		return nil, ErrEmptyPassphrase
+	}
+	salt, err := NewSalt()
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := NewPassphraseCipher(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	return &NoteKey{salt: salt, gcm: gcm}, nil
+}
+
+// Encrypt encrypts the content with AES-256-GCM and a random nonce, the content can be decrypted with the passphrase
+// of the key
+func (k *NoteKey) Encrypt(content string) (string, error) {
+	nonce := make([]byte, k.gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := make([]byte, 0, len(k.salt)+len(nonce)+len(content)+k.gcm.Overhead())
+	sealed = append(sealed, k.salt...)
+	sealed = append(sealed, nonce...)
+	sealed = k.gcm.Seal(sealed, nonce, []byte(content), nil)
+	return encryptedContentPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt decrypts content encrypted with the key, ErrWrongPassphrase is returned when it was encrypted with another key
+func (k *NoteKey) Decrypt(encrypted string) (string, error) {
+	salt, sealed, err := splitEncryptedContent(encrypted)
+	if err != nil {
+		return "", err
+	}
+	if !bytes.Equal(salt, k.salt) {
+		return "", ErrWrongPassphrase
+	}
+	return k.open(sealed)
+}
+
+// open decrypts the nonce and ciphertext of encrypted content
+func (k *NoteKey) open(sealed []byte) (string, error) {
+	if len(sealed) < k.gcm.NonceSize()+k.gcm.Overhead() {
+		return "", ErrInvalidEncryptedContent
+	}
+	content, err := k.gcm.Open(nil, sealed[:k.gcm.NonceSize()], sealed[k.gcm.NonceSize():], nil)
+	if err != nil {
+		return "", ErrWrongPassphrase
+	}
+	return string(content), nil
+}
+
+// UnlockContent decrypts content encrypted with EncryptContent or a NoteKey, and returns the key derived from the
+// passphrase for encrypting the edits of the content
+//
+// ErrWrongPassphrase is returned when the passphrase doesn't match.
+func UnlockContent(encrypted string, passphrase string) (string, *NoteKey, error) {
+	salt, sealed, err := splitEncryptedContent(encrypted)
+	if err != nil {
+		return "", nil, err
+	}
+	gcm, err := NewPassphraseCipher(passphrase, salt)
+	if err != nil {
+		return "", nil, err
+	}
+	key := &NoteKey{salt: salt, gcm: gcm}
+	content, err := key.open(sealed)
+	if err != nil {
+		return "", nil, err
+	}
+	return content, key, nil
+}
+
+// EncryptContent encrypts the content with AES-256-GCM, using a key derived from the passphrase with Argon2id and a random salt
+func EncryptContent(content string, passphrase string) (string, error) {
+	key, err := NewNoteKey(passphrase)
+	if err != nil {
+		return "", err
+	}
+	return key.Encrypt(content)
+}
+
+// DecryptContent decrypts content encrypted with EncryptContent, ErrWrongPassphrase is returned when the passphrase doesn't match
+func DecryptContent(encrypted string, passphrase string) (string, error) {
+	content, _, err := UnlockContent(encrypted, passphrase)
+	return content, err
+}
+
+// splitEncryptedContent decodes encrypted content into the salt and the sealed nonce and ciphertext
+func splitEncryptedContent(encrypted string) ([]byte, []byte, error) {
+	encoded, ok := strings.CutPrefix(encrypted, encryptedContentPrefix)
+	if !ok {
+		return nil, nil, ErrInvalidEncryptedContent
+	}
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < SaltSize {
+		return nil, nil, ErrInvalidEncryptedContent
+	}
+	return sealed[:SaltSize], sealed[SaltSize:], nil
+}
+
+// IsEncryptedContent checks whether the content was encrypted with EncryptContent
+func IsEncryptedContent(content string) bool {
+	return strings.HasPrefix(content, encryptedContentPrefix)
+}
+
+// NewSalt generates a random salt for NewPassphraseCipher
+func NewSalt() ([]byte, error) {
+	salt := make([]byte, SaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	return salt, nil
+}
+
+// NewPassphraseCipher creates the AES-256-GCM cipher keyed by the passphrase and the salt with Argon2id
+func NewPassphraseCipher(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key := argon2.IDKey([]byte(passphrase), salt, argon2Time, argon2Memory, argon2Threads, argon2KeySize)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
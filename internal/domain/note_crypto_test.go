@@ -0,0 +1,233 @@
+package domain
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestEncryptContentRoundTrip(t *testing.T) {
+	for _, content := range []string{"", "plain text", "# 标题\n\n- [ ] unicode ✓\n"} {
+		encrypted, err := EncryptContent(content, "secret")
+		if err != nil {
+			t.Fatalf("EncryptContent(%q) failed: %v", content, err)
+		}
+		if !IsEncryptedContent(encrypted) {
+			t.Errorf("EncryptContent(%q) = %q, want it marked as encrypted", content, encrypted)
+		}
+		if content != "" && strings.Contains(encrypted, content) {
+			t.Errorf("EncryptContent(%q) = %q, contains the plaintext", content, encrypted)
+		}
+		decrypted, err := DecryptContent(encrypted, "secret")
+		if err != nil || decrypted != content {
+			t.Errorf("DecryptContent() = %q, %v, want %q", decrypted, err, content)
+		}
+	}
+}
+
+func TestEncryptContentUsesNewSaltAndNonce(t *testing.T) {
+	a, err := EncryptContent("same", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := EncryptContent("same", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a == b {
+		t.Error("EncryptContent() returned the same ciphertext twice")
+	}
+}
+
+func TestDecryptContentWrongPassphrase(t *testing.T) {
+	encrypted, err := EncryptContent("content", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, passphrase := range []string{"Secret", "secret ", ""} {
+		if _, err := DecryptContent(encrypted, passphrase); !errors.Is(err, ErrWrongPassphrase) {
+			t.Errorf("DecryptContent() with passphrase %q error = %v, want ErrWrongPassphrase", passphrase, err)
+		}
+	}
+}
+
+func TestDecryptContentTampered(t *testing.T) {
+	encrypted, err := EncryptContent("pay alice 10", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(encrypted, encryptedContentPrefix))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Flipping a bit of the salt, the nonce, the ciphertext or the tag fails authentication
+	for _, i := range []int{0, 20, len(sealed) - 20, len(sealed) - 1} {
+		tampered := append([]byte(nil), sealed...)
+		tampered[i] ^= 0x01
+		content := encryptedContentPrefix + base64.StdEncoding.EncodeToString(tampered)
+		if got, err := DecryptContent(content, "secret"); !errors.Is(err, ErrWrongPassphrase) {
+			t.Errorf("DecryptContent() of content tampered at byte %d = %q, %v, want ErrWrongPassphrase", i, got, err)
+		}
+	}
+}
+
+func TestDecryptContentInvalid(t *testing.T) {
+	encrypted, err := EncryptContent("content", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded := strings.TrimPrefix(encrypted, encryptedContentPrefix)
+
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"plaintext", "content"},
+		{"missing prefix", encoded},
+		{"unknown version", "nota-encrypted:v2:" + encoded},
+		{"not base64", encryptedContentPrefix + "!!!"},
+		{"empty", encryptedContentPrefix},
+		{"only salt", encryptedContentPrefix + base64.StdEncoding.EncodeToString(make([]byte, 16))},
+		{"truncated", encryptedContentPrefix + base64.StdEncoding.EncodeToString(make([]byte, 16+12+15))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := DecryptContent(tt.content, "secret"); !errors.Is(err, ErrInvalidEncryptedContent) {
+				t.Errorf("DecryptContent(%q) error = %v, want ErrInvalidEncryptedContent", tt.content, err)
+			}
+		})
+	}
+}
+
+func TestIsEncryptedContent(t *testing.T) {
+	tests := []struct {
+		content string
+		want    bool
+	}{
+		{"nota-encrypted:v1:abc", true},
+		{"nota-encrypted:v1:", true},
+		{"nota-encrypted:v2:abc", false},
+		{" nota-encrypted:v1:abc", false},
+		{"# nota-encrypted:v1:abc", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := IsEncryptedContent(tt.content); got != tt.want {
+			t.Errorf("IsEncryptedContent(%q) = %v, want %v", tt.content, got, tt.want)
+		}
+	}
+}
+
+func TestNewPassphraseCipher(t *testing.T) {
+	salt, err := NewSalt()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(salt) != SaltSize {
+		t.Fatalf("NewSalt() returned %d bytes, want %d", len(salt), SaltSize)
+	}
+	otherSalt, err := NewSalt()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(salt, otherSalt) {
+		t.Fatal("NewSalt() returned the same salt twice")
+	}
+
+	gcm, err := NewPassphraseCipher("secret", salt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	sealed := gcm.Seal(nil, nonce, []byte("database"), nil)
+
+	tests := []struct {
+		name       string
+		passphrase string
+		salt       []byte
+		opens      bool
+	}{
+		{"same passphrase and salt", "secret", salt, true},
+		{"other passphrase", "Secret", salt, false},
+		{"other salt", "secret", otherSalt, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			other, err := NewPassphraseCipher(tt.passphrase, tt.salt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			plain, err := other.Open(nil, nonce, sealed, nil)
+			if opens := err == nil && string(plain) == "database"; opens != tt.opens {
+				t.Errorf("opened = %v, want %v", opens, tt.opens)
+			}
+		})
+	}
+}
+
+func TestNoteKey(t *testing.T) {
+	if _, err := NewNoteKey(""); !errors.Is(err, ErrEmptyPassphrase) {
+		t.Errorf("NewNoteKey(\"\") error = %v, want ErrEmptyPassphrase", err)
+	}
+	key, err := NewNoteKey("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, err := key.Encrypt("first")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := key.Encrypt("first")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first == second {
+		t.Error("Encrypt() returned the same ciphertext twice")
+	}
+
+	// Content encrypted with the key is decrypted with the key or the passphrase
+	if content, err := key.Decrypt(second); err != nil || content != "first" {
+		t.Errorf("Decrypt() = %q, %v, want %q", content, err, "first")
+	}
+	content, unlocked, err := UnlockContent(first, "secret")
+	if err != nil || content != "first" {
+		t.Fatalf("UnlockContent() = %q, %v, want %q", content, err, "first")
+	}
+	if _, _, err := UnlockContent(first, "wrong"); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("UnlockContent() with a wrong passphrase error = %v, want ErrWrongPassphrase", err)
+	}
+
+	// The key of unlocked content encrypts the edits without the passphrase
+	edited, err := unlocked.Encrypt("edited")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content, err := DecryptContent(edited, "secret"); err != nil || content != "edited" {
+		t.Errorf("DecryptContent() of the edits = %q, %v, want %q", content, err, "edited")
+	}
+	if content, err := key.Decrypt(edited); err != nil || content != "edited" {
+		t.Errorf("Decrypt() of the edits = %q, %v, want %q", content, err, "edited")
+	}
+}
+
+func TestNoteKeyDecryptOtherKey(t *testing.T) {
+	key, err := NewNoteKey("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, passphrase := range []string{"secret", "other"} {
+		encrypted, err := EncryptContent("content", passphrase)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := key.Decrypt(encrypted); !errors.Is(err, ErrWrongPassphrase) {
+			t.Errorf("Decrypt() of content locked with %q again error = %v, want ErrWrongPassphrase", passphrase, err)
+		}
+	}
+	if _, err := key.Decrypt("plain"); !errors.Is(err, ErrInvalidEncryptedContent) {
+		t.Errorf("Decrypt() of plaintext error = %v, want ErrInvalidEncryptedContent", err)
+	}
+}
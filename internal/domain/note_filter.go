@@ -0,0 +1,46 @@
+package domain
+
+import (
+	"regexp"
+	"strings"
+)
+
+// archivedQueryPattern matches the archived: term of a search query
+var archivedQueryPattern = regexp.MustCompile(`(?i)(^|\s)archived:(\s|$)`)
+
+// NoteFilter narrows down the notes returned by a search
+type NoteFilter struct {
+	Query      string   // full-text search query
+	Tags       []string // names of the tags a note must all have
+	NotebookID string   // notebook (including its sub-notebooks) the notes belong to, empty for every notebook
+	DueSoon    bool     // only notes with a reminder due within DueSoonWindow or overdue, the earliest first
+	Archived   bool     // only the archived notes instead of the ones not archived
+}
+
+// IsEmpty checks whether the filter matches every note
+func (f NoteFilter) IsEmpty() bool {
+	return f.Query == "" && len(f.Tags) == 0 && f.NotebookID == "" && !f.DueSoon && !f.Archived
+}
+
+// WithQueryTerms applies the filters written in the query and takes them out of it
+//
+// E.g., "archived: budget" searches the archived notes for budget.
+func (f NoteFilter) WithQueryTerms() NoteFilter {
+	if !archivedQueryPattern.MatchString(f.Query) {
+		return f
+	}
+	f.Archived = true
+	query := archivedQueryPattern.ReplaceAllString(f.Query, " ")
+	if archivedQueryPattern.MatchString(query) {
+		query = archivedQueryPattern.ReplaceAllString(query, " ") // adjacent terms share the space between them
+	}
+	// The query keeps its trailing space, which tells whether the last term is still being typed
+	if strings.HasSuffix(strings.ToLower(strings.TrimSpace(f.Query)), "archived:") {
+		query = strings.TrimSpace(query)
+	}
+	f.Query = strings.TrimLeft(query, " ")
+	if strings.TrimSpace(f.Query) == "" {
+		f.Query = ""
+	}
+	return f
+}
@@ -0,0 +1,79 @@
+package domain
+
+import (
+	"regexp"
+	"strings"
+)
+
+// linkPattern matches [[target]] and [[target|label]] wiki links
+var linkPattern = regexp.MustCompile(`\[\[([^\[\]|\n]+)(?:\|([^\[\]\n]*))?\]\]`)
+
+// NoteLink represents a wiki link from one note to another
+type NoteLink struct {
+	SourceID string `gorm:"primaryKey" json:"source_id"`
+	TargetID string `gorm:"primaryKey;index" json:"target_id"`
+}
+
+// TableName specifies the table name for GORM
+func (NoteLink) TableName() string {
+	return "note_link"
+}
+
+// Link is a wiki link found in note content
+type Link struct {
+	Target string // note ID or note title
+	Label  string // text shown for the link, empty when the link has no label
+	Start  int    // byte offset of the opening brackets
+	End    int    // byte offset right after the closing brackets
+}
+
+// Text returns the text shown for the link
+func (l Link) Text() string {
+	if l.Label != "" {
+		return l.Label
+	}
+	return l.Target
+}
+
+// ParseLinks finds the wiki links in note content, [[Note Title]] links by title and [[note_id|label]] links by ID
+func ParseLinks(content string) []Link {
+	matches := linkPattern.FindAllStringSubmatchIndex(content, -1)
+	links := make([]Link, 0, len(matches))
+	for _, m := range matches {
+		link := Link{
+			Target: strings.TrimSpace(content[m[2]:m[3]]),
+			Start:  m[0],
+			End:    m[1],
+		}
+		if m[4] >= 0 {
+			link.Label = strings.TrimSpace(content[m[4]:m[5]])
+		}
+		if link.Target == "" {
+			continue
+		}
+		links = append(links, link)
+	}
+	return links
+}
+
+// RewriteLinks replaces every link with the result of rewrite, links are kept as is when rewrite returns false
+func RewriteLinks(content string, rewrite func(link Link) (string, bool)) string {
+	links := ParseLinks(content)
+	if len(links) == 0 {
+		return content
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, link := range links {
+		b.WriteString(content[last:link.Start])
+		if text, ok := rewrite(link); ok {
+			b.WriteString(text)
+		} else {
+			b.WriteString(content[link.Start:link.End])
+		}
+		last = link.End
+	}
+	b.WriteString(content[last:])
+	return b.String()
+}
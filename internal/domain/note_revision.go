@@ -0,0 +1,21 @@
+package domain
+
+import (
+	"github.com/curtisnewbie/miso/util/atom"
+)
+
+// NoteRevision represents a previous version of a note, recorded every time the note is saved
+type NoteRevision struct {
+	ID        string    `gorm:"primaryKey" json:"id"`
+	NoteID    string    `gorm:"not null;index" json:"note_id"`
+	Version   int       `gorm:"not null" json:"version"`
+	Title     string    `gorm:"not null" json:"title"`
+	Content   string    `gorm:"type:text" json:"content"`
+	UpdatedAt atom.Time `gorm:"not null" json:"updated_at"` // when this version of the note was saved
+	CreatedAt atom.Time `gorm:"not null" json:"created_at"` // when this version was replaced and moved into history
+}
+
+// TableName specifies the table name for GORM
+func (NoteRevision) TableName() string {
+	return "note_revision"
+}
@@ -0,0 +1,110 @@
+package domain
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// taskPattern matches markdown checkboxes, e.g., "- [ ] call Alice" and "  * [x] done"
+	taskPattern = regexp.MustCompile(`^(\s*[-*+]\s+\[)([ xX])(\]\s+)(.*\S)\s*$`)
+
+	// taskDuePattern matches the @due(2006-01-02) annotation of a task
+	taskDuePattern = regexp.MustCompile(`@due\((\d{4}-\d{2}-\d{2})\)`)
+
+	// taskTagPattern matches the #tag annotations of a task
+	taskTagPattern = regexp.MustCompile(`(?:^|\s)#([\p{L}\p{N}_/-]+)`)
+)
+
+// NoteTask is a markdown checkbox found in the content of a note
+type NoteTask struct {
+	NoteID    string `gorm:"primaryKey" json:"note_id"`
+	Line      int    `gorm:"primaryKey" json:"line"` // line of the task in the content, starting at 1
+	Text      string `json:"text"`                   // text after the checkbox, with its annotations
+	Done      bool   `json:"done"`
+	DueDate   string `json:"due_date,omitempty"`   // day of the @due annotation in DailyDateLayout, empty without one
+	Tags      string `json:"tags,omitempty"`       // names of the #tag annotations separated by spaces
+	NoteTitle string `gorm:"->" json:"note_title"` // title of the note, only loaded when listing tasks
+}
+
+// TableName specifies the table name for GORM
+func (NoteTask) TableName() string {
+	return "note_task"
+}
+
+// TagNames returns the names of the #tag annotations of the task
+func (t *NoteTask) TagNames() []string {
+	return strings.Fields(t.Tags)
+}
+
+// TaskFilter specifies which tasks are listed
+type TaskFilter struct {
+	IncludeDone bool   // also list the tasks that are done
+	Tag         string // only tasks with the #tag annotation, empty for every task
+}
+
+// ParseTasks finds the markdown checkboxes in note content, checkboxes in fenced code blocks are ignored
+func ParseTasks(content string) []*NoteTask {
+	var tasks []*NoteTask
+	fence := "" // marker of the fenced code block the line is in, ``` or ~~~, empty outside of code blocks
+	for i, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if fence == "" {
+			if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+				fence = trimmed[:3]
+				continue
+			}
+		} else {
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+			continue
+		}
+		m := taskPattern.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+
+		task := &NoteTask{
+			Line: i + 1,
+			Text: m[4],
+			Done: m[2] != " ",
+		}
+		if due := taskDuePattern.FindStringSubmatch(task.Text); due != nil {
+			task.DueDate = due[1]
+		}
+		var tags []string
+		seen := make(map[string]bool)
+		for _, tag := range taskTagPattern.FindAllStringSubmatch(task.Text, -1) {
+			if !seen[tag[1]] {
+				seen[tag[1]] = true
+				tags = append(tags, tag[1])
+			}
+		}
+		task.Tags = strings.Join(tags, " ")
+		tasks = append(tasks, task)
+	}
+	return tasks
+}
+
+// SetTaskDone ticks or unticks the checkbox of the task at line (starting at 1) of note content
+//
+// False is returned when the line is not a task with the given text, e.g., when the content has changed since the
+// task was found.
+func SetTaskDone(content string, line int, text string, done bool) (string, bool) {
+	lines := strings.Split(content, "\n")
+	if line < 1 || line > len(lines) {
+		return content, false
+	}
+	m := taskPattern.FindStringSubmatchIndex(lines[line-1])
+	if m == nil || lines[line-1][m[8]:m[9]] != text {
+		return content, false
+	}
+
+	mark := " "
+	if done {
+		mark = "x"
+	}
+	lines[line-1] = lines[line-1][:m[4]] + mark + lines[line-1][m[5]:]
+	return strings.Join(lines, "\n"), true
+}
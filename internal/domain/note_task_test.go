@@ -0,0 +1,93 @@
+package domain
+
+import (
+	"testing"
+)
+
+func TestParseTasks(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []NoteTask
+	}{
+		{"empty", "", nil},
+		{"open and done", "- [ ] open\n* [x] done\n+ [X] also done", []NoteTask{
+			{Line: 1, Text: "open"},
+			{Line: 2, Text: "done", Done: true},
+			{Line: 3, Text: "also done", Done: true},
+		}},
+		{"nested", "- [ ] parent\n  - [ ] child", []NoteTask{
+			{Line: 1, Text: "parent"},
+			{Line: 2, Text: "child"},
+		}},
+		{"not checkboxes", "[ ] no bullet\n- [] no space\n- [ ]\n-[ ] no gap", nil},
+		{"annotations", "- [ ] pay rent @due(2024-03-01) #home #money #home", []NoteTask{
+			{Line: 1, Text: "pay rent @due(2024-03-01) #home #money #home", DueDate: "2024-03-01", Tags: "home money"},
+		}},
+		{"hash inside word is not a tag", "- [ ] issue a#1", []NoteTask{
+			{Line: 1, Text: "issue a#1"},
+		}},
+		{"backtick fence", "```\n- [ ] code\n```\n- [ ] real", []NoteTask{
+			{Line: 4, Text: "real"},
+		}},
+		{"tilde fence", "~~~go\n- [ ] code\n~~~\n- [ ] real", []NoteTask{
+			{Line: 4, Text: "real"},
+		}},
+		{"tilde inside backtick fence", "```\n~~~\n- [ ] code\n```\n- [ ] real", []NoteTask{
+			{Line: 5, Text: "real"},
+		}},
+		{"backtick inside tilde fence", "~~~\n```\n- [ ] code\n~~~\n- [ ] real", []NoteTask{
+			{Line: 5, Text: "real"},
+		}},
+		{"unterminated fence", "- [ ] real\n```\n- [ ] code", []NoteTask{
+			{Line: 1, Text: "real"},
+		}},
+		{"crlf", "- [ ] one\r\n- [x] two\r\n", []NoteTask{
+			{Line: 1, Text: "one"},
+			{Line: 2, Text: "two", Done: true},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseTasks(tt.content)
+			if len(got) != len(tt.want) {
+				t.Fatalf("ParseTasks(%q) found %d tasks, want %d: %+v", tt.content, len(got), len(tt.want), got)
+			}
+			for i := range got {
+				if *got[i] != tt.want[i] {
+					t.Errorf("ParseTasks(%q)[%d] = %+v, want %+v", tt.content, i, *got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSetTaskDone(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		line    int
+		text    string
+		done    bool
+		want    string
+		ok      bool
+	}{
+		{"tick", "# todo\n- [ ] a\n- [ ] b", 2, "a", true, "# todo\n- [x] a\n- [ ] b", true},
+		{"untick", "  * [X] a", 1, "a", false, "  * [ ] a", true},
+		{"already done", "- [x] a", 1, "a", true, "- [x] a", true},
+		{"keeps crlf", "- [ ] a\r\n- [ ] b\r\n", 2, "b", true, "- [ ] a\r\n- [x] b\r\n", true},
+		{"text changed", "- [ ] a2", 1, "a", true, "- [ ] a2", false},
+		{"not a task", "# a", 1, "a", true, "# a", false},
+		{"line out of range", "- [ ] a", 2, "a", true, "- [ ] a", false},
+		{"line zero", "- [ ] a", 0, "a", true, "- [ ] a", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := SetTaskDone(tt.content, tt.line, tt.text, tt.done)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("SetTaskDone(%q, %d, %q, %v) = %q, %v, want %q, %v",
+					tt.content, tt.line, tt.text, tt.done, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,19 @@
+package domain
+
+import (
+	"github.com/curtisnewbie/miso/util/atom"
+)
+
+// Notebook represents a folder of notes, notebooks can be nested
+type Notebook struct {
+	ID        string    `gorm:"primaryKey" json:"id"`
+	Name      string    `gorm:"not null" json:"name"`
+	ParentID  string    `gorm:"not null;default:'';index" json:"parent_id"` // empty for top-level notebooks
+	CreatedAt atom.Time `gorm:"not null" json:"created_at"`
+	UpdatedAt atom.Time `gorm:"not null" json:"updated_at"`
+}
+
+// TableName specifies the table name for GORM
+func (Notebook) TableName() string {
+	return "notebook"
+}
@@ -0,0 +1,11 @@
+package domain
+
+import "time"
+
+// DueSoonWindow is how far ahead the due soon filter looks for reminders, overdue reminders are always included
+const DueSoonWindow = 7 * 24 * time.Hour
+
+// IsOverdue checks whether the reminder of the note is due at the given time
+func (n *Note) IsOverdue(now time.Time) bool {
+	return n.DueAt != nil && !n.DueAt.Unwrap().After(now)
+}
@@ -0,0 +1,28 @@
+package domain
+
+import (
+	"github.com/curtisnewbie/miso/util/atom"
+)
+
+// Tag represents a tag that can be attached to notes
+type Tag struct {
+	ID        string    `gorm:"primaryKey" json:"id"`
+	Name      string    `gorm:"not null;uniqueIndex" json:"name"`
+	CreatedAt atom.Time `gorm:"not null" json:"created_at"`
+}
+
+// TableName specifies the table name for GORM
+func (Tag) TableName() string {
+	return "tag"
+}
+
+// NoteTag represents the association between a note and a tag
+type NoteTag struct {
+	NoteID string `gorm:"primaryKey" json:"note_id"`
+	TagID  string `gorm:"primaryKey;index" json:"tag_id"`
+}
+
+// TableName specifies the table name for GORM
+func (NoteTag) TableName() string {
+	return "note_tag"
+}
@@ -0,0 +1,104 @@
+package domain
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// TemplateMetadataKey is the metadata key that marks a note as a template
+const TemplateMetadataKey = "template"
+
+// Built-in template variables, the other variables are custom fields that user is asked for
+const (
+	TemplateVarDate      = "date"      // current date, e.g., 2006-01-02
+	TemplateVarTime      = "time"      // current time, e.g., 15:04
+	TemplateVarWeekday   = "weekday"   // current day of the week, e.g., Monday
+	TemplateVarClipboard = "clipboard" // text in the clipboard
+)
+
+// templateVarPattern matches {{name}} template variables, spaces around the name are ignored
+var templateVarPattern = regexp.MustCompile(`\{\{\s*([^{}\n]+?)\s*\}\}`)
+
+// IsTemplate checks whether the note is a template
+func (n *Note) IsTemplate() bool {
+	template, _ := n.Metadata[TemplateMetadataKey].(bool)
+	return template
+}
+
+// IsBuiltinTemplateVar checks whether the variable is filled in without asking user
+func IsBuiltinTemplateVar(name string) bool {
+	switch name {
+	case TemplateVarDate, TemplateVarTime, TemplateVarWeekday, TemplateVarClipboard:
+		return true
+	}
+	return false
+}
+
+// TemplateFields finds the custom fields used in the texts of a template, in the order they first appear
+func TemplateFields(texts ...string) []string {
+	var fields []string
+	seen := make(map[string]bool)
+	for _, text := range texts {
+		for _, m := range templateVarPattern.FindAllStringSubmatch(text, -1) {
+			name := m[1]
+			if IsBuiltinTemplateVar(name) || seen[name] {
+				continue
+			}
+			seen[name] = true
+			fields = append(fields, name)
+		}
+	}
+	return fields
+}
+
+// UsesTemplateVar checks whether the variable is used in any of the texts of a template
+func UsesTemplateVar(name string, texts ...string) bool {
+	for _, text := range texts {
+		for _, m := range templateVarPattern.FindAllStringSubmatch(text, -1) {
+			if m[1] == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// BuiltinTemplateValues returns the values of the built-in variables at the given time
+func BuiltinTemplateValues(now time.Time, clipboard string) map[string]string {
+	return map[string]string{
+		TemplateVarDate:      now.Format("2006-01-02"),
+		TemplateVarTime:      now.Format("15:04"),
+		TemplateVarWeekday:   now.Weekday().String(),
+		TemplateVarClipboard: clipboard,
+	}
+}
+
+// ExpandTemplate replaces the variables in text with their values, variables without a value are kept as is
+func ExpandTemplate(text string, values map[string]string) string {
+	return templateVarPattern.ReplaceAllStringFunc(text, func(match string) string {
+		name := strings.TrimSpace(match[2 : len(match)-2])
+		if value, ok := values[name]; ok {
+			return value
+		}
+		return match
+	})
+}
+
+// NewNoteFromTemplate creates a new note in memory from a template, with its title and content expanded
+//
+// The metadata of the template is copied except for the template flag, so that the new note is not a template itself.
+func NewNoteFromTemplate(template *Note, values map[string]string) *Note {
+	metadata := make(map[string]interface{}, len(template.Metadata))
+	for k, v := range template.Metadata {
+		if k != TemplateMetadataKey {
+			metadata[k] = v
+		}
+	}
+	return &Note{
+		Title:    ExpandTemplate(template.Title, values),
+		Content:  ExpandTemplate(template.Content, values),
+		Version:  1,
+		Metadata: metadata,
+	}
+}
@@ -0,0 +1,100 @@
+package domain
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestExpandTemplate(t *testing.T) {
+	values := map[string]string{"date": "2024-03-01", "project": "Nota", "empty": ""}
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"no variables", "plain text", "plain text"},
+		{"variables", "{{date}} {{project}}", "2024-03-01 Nota"},
+		{"spaces around name", "{{ project  }}", "Nota"},
+		{"repeated", "{{project}}/{{project}}", "Nota/Nota"},
+		{"empty value", "[{{empty}}]", "[]"},
+		{"unknown kept", "{{ unknown }} {{project}}", "{{ unknown }} Nota"},
+		{"single braces", "{project} {{project}", "{project} {{project}"},
+		{"no newline in name", "{{pro\nject}}", "{{pro\nject}}"},
+		{"nested braces", "{{{project}}}", "{Nota}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExpandTemplate(tt.text, values); got != tt.want {
+				t.Errorf("ExpandTemplate(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpandTemplateDoesNotExpandValues(t *testing.T) {
+	values := map[string]string{"clipboard": "{{date}}", "date": "2024-03-01"}
+	if got := ExpandTemplate("{{clipboard}} {{date}}", values); got != "{{date}} 2024-03-01" {
+		t.Errorf("ExpandTemplate() = %q, want values inserted as is", got)
+	}
+}
+
+func TestTemplateFields(t *testing.T) {
+	got := TemplateFields("{{ client }} - {{date}}", "Hi {{client}}, about {{topic}} at {{time}} {{clipboard}}")
+	want := []string{"client", "topic"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("TemplateFields() = %v, want %v", got, want)
+	}
+	if got := TemplateFields("no fields"); got != nil {
+		t.Errorf("TemplateFields() = %v, want nil", got)
+	}
+}
+
+func TestUsesTemplateVar(t *testing.T) {
+	if !UsesTemplateVar(TemplateVarClipboard, "title", "paste: {{ clipboard }}") {
+		t.Error("UsesTemplateVar() = false, want true for a variable in the content")
+	}
+	if UsesTemplateVar(TemplateVarClipboard, "{clipboard}", "clipboard") {
+		t.Error("UsesTemplateVar() = true, want false without a variable")
+	}
+}
+
+func TestBuiltinTemplateValues(t *testing.T) {
+	now := time.Date(2024, 3, 1, 9, 5, 0, 0, time.UTC)
+	got := BuiltinTemplateValues(now, "copied")
+	want := map[string]string{
+		TemplateVarDate:      "2024-03-01",
+		TemplateVarTime:      "09:05",
+		TemplateVarWeekday:   "Friday",
+		TemplateVarClipboard: "copied",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("BuiltinTemplateValues() = %v, want %v", got, want)
+	}
+}
+
+func TestNewNoteFromTemplate(t *testing.T) {
+	template := &Note{
+		ID:       "tpl",
+		Title:    "Meeting {{date}}",
+		Content:  "# {{client}}",
+		Version:  3,
+		Metadata: map[string]interface{}{TemplateMetadataKey: true, "kind": "meeting"},
+	}
+	note := NewNoteFromTemplate(template, map[string]string{"date": "2024-03-01", "client": "Acme"})
+	if note.ID != "" || note.Version != 1 {
+		t.Errorf("NewNoteFromTemplate() ID = %q, Version = %d, want a new note", note.ID, note.Version)
+	}
+	if note.Title != "Meeting 2024-03-01" || note.Content != "# Acme" {
+		t.Errorf("NewNoteFromTemplate() = %q, %q, want expanded title and content", note.Title, note.Content)
+	}
+	if note.IsTemplate() {
+		t.Error("NewNoteFromTemplate() created a template")
+	}
+	if note.Metadata["kind"] != "meeting" {
+		t.Errorf("NewNoteFromTemplate() metadata = %v, want the other metadata copied", note.Metadata)
+	}
+	if !template.IsTemplate() {
+		t.Error("NewNoteFromTemplate() changed the metadata of the template")
+	}
+}
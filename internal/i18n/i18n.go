@@ -13,17 +13,40 @@ const (
 // Translation holds all translatable strings
 type Translation struct {
 	Menu struct {
-		Note          string
-		File          string
-		View          string
-		Language      string
-		NewNote       string
-		Import        string
-		Export        string
-		MinimizedMode string
-		English       string
-		Chinese       string
-		Delete        string
+		Note           string
+		File           string
+		View           string
+		Language       string
+		NewNote        string
+		FromTemplate   string
+		UseAsTemplate  string
+		Today          string
+		PreviousDay    string
+		NextDay        string
+		Calendar       string
+		DailyTitle     string
+		Reminder       string
+		Tasks          string
+		Archive        string
+		Import         string
+		ImportMarkdown string
+		Export         string
+		ExportMarkdown string
+		MinimizedMode  string
+		English        string
+		Chinese        string
+		Delete         string
+		Trash          string
+		ManageTags     string
+		MoveNote       string
+		LockNote       string
+		RemoveLock     string
+		APIServer      string
+		Backups        string
+		CheckDatabase  string
+		Autosave       string
+		AutosaveOff    string
+		AutosaveAfter  string
 	}
 	Dialog struct {
 		NoNoteSelected      string
@@ -36,6 +59,9 @@ type Translation struct {
 		NoteExported        string
 		ImportSuccessful    string
 		ImportedNotes       string
+		ImportReport        string
+		ImportSummary       string
+		ExportedNotes       string
 		DeleteNote          string
 		SureDelete          string
 		UnsavedNote         string
@@ -50,6 +76,26 @@ type Translation struct {
 		Saved               string
 		UnsavedChangesText  string
 		NoNotesAvailable    string
+		RestoreRevision     string
+		SureRestore         string
+		SaveBeforeRestore   string
+		PurgeNote           string
+		SurePurge           string
+		EmptyTrash          string
+		SureEmptyTrash      string
+		SaveBeforeTagging   string
+		DeleteTag           string
+		SureDeleteTag       string
+		TagExists           string
+		SaveBeforeMoving    string
+		DeleteNotebook      string
+		SureDeleteNotebook  string
+		NotebookCycle       string
+		LinkNotFound        string
+		SureResetToken      string
+		RecoverDraft        string
+		SureRecoverDraft    string
+		Untitled            string
 	}
 	Editor struct {
 		TitlePlaceholder   string
@@ -57,13 +103,191 @@ type Translation struct {
 		PlaceholderSearch  string
 		Save               string
 		Exit               string
+		History            string
+		ModeEdit           string
+		ModePreview        string
+		ModeSplit          string
+	}
+	History struct {
+		Title       string
+		Current     string
+		Version     string
+		Restore     string
+		NoRevisions string
+	}
+	Trash struct {
+		Title      string
+		Restore    string
+		Purge      string
+		EmptyTrash string
+		Empty      string
+		DeletedAt  string
+	}
+	Tags struct {
+		Title           string
+		Add             string
+		NamePlaceholder string
+		Rename          string
+		Merge           string
+		MergeInto       string
+		Delete          string
+		NoTags          string
+	}
+	Links struct {
+		Links     string
+		Backlinks string
+	}
+	Notebooks struct {
+		Title           string
+		AllNotes        string
+		TopLevel        string
+		New             string
+		NewSub          string
+		NamePlaceholder string
+		Rename          string
+		Move            string
+		MoveInto        string
+		Delete          string
+	}
+	Conflict struct {
+		Title       string
+		Message     string
+		Legend      string
+		Overwrite   string
+		Reload      string
+		SaveAsNew   string
+		KeepEditing string
+		CopySuffix  string
+	}
+	Lock struct {
+		Lock               string
+		Unlock             string
+		RemoveLock         string
+		Locked             string
+		Passphrase         string
+		ConfirmPassphrase  string
+		PassphraseMismatch string
+		WrongPassphrase    string
+		SureLock           string
+		SaveBeforeLocking  string
+		NoHistory          string
+	}
+	Server struct {
+		Title             string
+		Enabled           string
+		Address           string
+		Origin            string
+		OriginPlaceholder string
+		InvalidOrigin     string
+		Token             string
+		Copy              string
+		ResetToken        string
+		Running           string
+		Stopped           string
 	}
 	Status struct {
 		Saved          string
 		UnsavedChanges string
 	}
 	Database struct {
-		Location string
+		Location      string
+		Locked        string
+		Password      string
+		Unlock        string
+		WrongPassword string
+		Open          string
+		New           string
+		OpenOnStartup string
+		SaveBefore    string
+		OpenFailed    string
+	}
+	Backup struct {
+		Title          string
+		BackupNow      string
+		Restore        string
+		Empty          string
+		Snapshots      string
+		SnapshotsOff   string
+		SnapshotsEvery string
+		Keep           string
+		Manual         string
+		Snapshot       string
+		PreMigration   string
+		PreRestore     string
+		PreRepair      string
+		Encrypted      string
+		BackedUp       string
+		SureRestore    string
+		SaveBefore     string
+		Restored       string
+		Password       string
+		Invalid        string
+	}
+	Diagnostics struct {
+		Title            string
+		NoIssues         string
+		Found            string
+		Repair           string
+		RepairAll        string
+		CheckAgain       string
+		Integrity        string
+		InvalidMetadata  string
+		EmptyTitle       string
+		InvalidTimestamp string
+		FixIntegrity     string
+		FixMetadata      string
+		FixTitle         string
+		FixTimestamp     string
+		SureRepair       string
+		SaveBefore       string
+		Repaired         string
+	}
+	Template struct {
+		Title       string
+		Create      string
+		NoTemplates string
+		Fields      string
+		SaveBefore  string
+	}
+	Daily struct {
+		Calendar    string
+		Today       string
+		Weekdays    string
+		MonthFormat string
+	}
+	Reminder struct {
+		Title         string
+		Set           string
+		Due           string
+		Overdue       string
+		Date          string
+		Time          string
+		InvalidTime   string
+		InOneHour     string
+		Tomorrow      string
+		NextWeek      string
+		Complete      string
+		Open          string
+		Snooze        string
+		SnoozeMinutes string
+		SnoozeHour    string
+		SnoozeDay     string
+		DueSoon       string
+		Notification  string
+		SaveBefore    string
+	}
+	Task struct {
+		Title      string
+		NoTasks    string
+		ShowDone   string
+		AllTags    string
+		Due        string
+		Open       string
+		SaveBefore string
+	}
+	Archive struct {
+		Archived   string
+		SaveBefore string
 	}
 }
 
@@ -88,12 +312,35 @@ func getEnglishTranslation() *Translation {
 	t.Menu.View = "View"
 	t.Menu.Language = "Language"
 	t.Menu.NewNote = "New Note"
+	t.Menu.FromTemplate = "New Note from Template"
+	t.Menu.UseAsTemplate = "Use as Template"
+	t.Menu.Today = "Today's Note"
+	t.Menu.PreviousDay = "Previous Day"
+	t.Menu.NextDay = "Next Day"
+	t.Menu.Calendar = "Calendar"
+	t.Menu.DailyTitle = "Daily Note Title"
+	t.Menu.Reminder = "Set Reminder"
+	t.Menu.Tasks = "Tasks"
+	t.Menu.Archive = "Archived"
 	t.Menu.Import = "Import"
+	t.Menu.ImportMarkdown = "Import Markdown Folder"
 	t.Menu.Export = "Export"
 	t.Menu.MinimizedMode = "Minimized Mode"
 	t.Menu.English = "English"
 	t.Menu.Chinese = "中文"
 	t.Menu.Delete = "Delete"
+	t.Menu.Trash = "Trash"
+	t.Menu.ManageTags = "Manage Tags"
+	t.Menu.ExportMarkdown = "Export as Markdown"
+	t.Menu.MoveNote = "Move to Notebook"
+	t.Menu.LockNote = "Lock Note"
+	t.Menu.RemoveLock = "Remove Lock"
+	t.Menu.APIServer = "API Server"
+	t.Menu.Backups = "Backups"
+	t.Menu.CheckDatabase = "Check Database"
+	t.Menu.Autosave = "Autosave"
+	t.Menu.AutosaveOff = "Off"
+	t.Menu.AutosaveAfter = "After %d seconds"
 
 	t.Dialog.NoNoteSelected = "No Note Selected"
 	t.Dialog.PleaseSelectNote = "Please select a note"
@@ -105,6 +352,9 @@ func getEnglishTranslation() *Translation {
 	t.Dialog.NoteExported = "Note exported successfully"
 	t.Dialog.ImportSuccessful = "Import Successful"
 	t.Dialog.ImportedNotes = "Successfully imported %d notes"
+	t.Dialog.ImportReport = "Import Report"
+	t.Dialog.ImportSummary = "Imported %d, skipped %d, failed %d"
+	t.Dialog.ExportedNotes = "Successfully exported %d notes to %s"
 	t.Dialog.DeleteNote = "Delete Note"
 	t.Dialog.SureDelete = "Are you sure you want to delete this note?"
 	t.Dialog.UnsavedNote = "Unsaved Note"
@@ -120,17 +370,200 @@ func getEnglishTranslation() *Translation {
 	t.Dialog.Saved = "Saved"
 	t.Dialog.UnsavedChangesText = "Unsaved changes"
 	t.Dialog.NoNotesAvailable = "No notes available. Click 'New Note' to create one."
+	t.Dialog.RestoreRevision = "Restore Version"
+	t.Dialog.SureRestore = "Restore this version? The current version will be kept in history."
+	t.Dialog.SaveBeforeRestore = "You have unsaved changes. Do you want to save them before restoring?"
+	t.Dialog.PurgeNote = "Delete Permanently"
+	t.Dialog.SurePurge = "Are you sure you want to permanently delete this note? This cannot be undone."
+	t.Dialog.EmptyTrash = "Empty Trash"
+	t.Dialog.SureEmptyTrash = "Are you sure you want to permanently delete all notes in the trash? This cannot be undone."
+	t.Dialog.SaveBeforeTagging = "Please save the note before adding tags"
+	t.Dialog.DeleteTag = "Delete Tag"
+	t.Dialog.SureDeleteTag = "Are you sure you want to delete this tag? It will be removed from all notes."
+	t.Dialog.TagExists = "A tag with this name already exists, merge the tags instead"
+	t.Dialog.SaveBeforeMoving = "Please save the note before moving it"
+	t.Dialog.DeleteNotebook = "Delete Notebook"
+	t.Dialog.SureDeleteNotebook = "Are you sure you want to delete this notebook and its sub-notebooks? Their notes will be moved to the trash."
+	t.Dialog.NotebookCycle = "A notebook cannot be moved into itself or one of its sub-notebooks"
+	t.Dialog.LinkNotFound = "No note found for link: %s"
+	t.Dialog.SureResetToken = "Scripts and plugins using the current token will no longer be able to connect. Continue?"
+	t.Dialog.RecoverDraft = "Recover Unsaved Changes"
+	t.Dialog.SureRecoverDraft = "Unsaved changes to '%s' from %s were found, nota may not have been closed properly. Do you want to recover them?"
+	t.Dialog.Untitled = "Untitled"
 
 	t.Editor.TitlePlaceholder = "Note Title"
 	t.Editor.ContentPlaceholder = "Note content..."
 	t.Editor.PlaceholderSearch = "Search notes..."
 	t.Editor.Save = "Save"
 	t.Editor.Exit = "Exit"
+	t.Editor.History = "History"
+	t.Editor.ModeEdit = "Edit"
+	t.Editor.ModePreview = "Preview"
+	t.Editor.ModeSplit = "Split"
+
+	t.History.Title = "History"
+	t.History.Current = "Current"
+	t.History.Version = "v%d"
+	t.History.Restore = "Restore"
+	t.History.NoRevisions = "This note has no previous versions"
+
+	t.Trash.Title = "Trash"
+	t.Trash.Restore = "Restore"
+	t.Trash.Purge = "Delete Permanently"
+	t.Trash.EmptyTrash = "Empty Trash"
+	t.Trash.Empty = "Trash is empty"
+	t.Trash.DeletedAt = "Deleted: %s"
+
+	t.Tags.Title = "Tags"
+	t.Tags.Add = "Add Tag"
+	t.Tags.NamePlaceholder = "Tag name"
+	t.Tags.Rename = "Rename"
+	t.Tags.Merge = "Merge"
+	t.Tags.MergeInto = "Merge '%s' into"
+	t.Tags.Delete = "Delete"
+	t.Tags.NoTags = "No tags yet"
+
+	t.Links.Links = "Links (%d)"
+	t.Links.Backlinks = "Backlinks (%d)"
+
+	t.Notebooks.Title = "Notebooks"
+	t.Notebooks.AllNotes = "All Notes"
+	t.Notebooks.TopLevel = "(Top level)"
+	t.Notebooks.New = "New Notebook"
+	t.Notebooks.NewSub = "New Sub-notebook"
+	t.Notebooks.NamePlaceholder = "Notebook name"
+	t.Notebooks.Rename = "Rename"
+	t.Notebooks.Move = "Move"
+	t.Notebooks.MoveInto = "Move '%s' into"
+	t.Notebooks.Delete = "Delete"
+
+	t.Conflict.Title = "Note Changed Elsewhere"
+	t.Conflict.Message = "This note was saved elsewhere while you were editing it, the latest version is %d from %s."
+	t.Conflict.Legend = "Lines starting with - are only in the latest version, lines starting with + are only in your version."
+	t.Conflict.Overwrite = "Overwrite"
+	t.Conflict.Reload = "Reload"
+	t.Conflict.SaveAsNew = "Save as New Note"
+	t.Conflict.KeepEditing = "Keep Editing"
+	t.Conflict.CopySuffix = " (conflicted copy)"
+
+	t.Lock.Lock = "Lock"
+	t.Lock.Unlock = "Unlock"
+	t.Lock.RemoveLock = "Remove Lock"
+	t.Lock.Locked = "This note is locked, enter its passphrase to unlock it."
+	t.Lock.Passphrase = "Passphrase"
+	t.Lock.ConfirmPassphrase = "Confirm"
+	t.Lock.PassphraseMismatch = "The passphrases don't match"
+	t.Lock.WrongPassphrase = "Wrong passphrase"
+	t.Lock.SureLock = "The content will be encrypted and the history of the note will be deleted. The note can't be recovered without the passphrase."
+	t.Lock.SaveBeforeLocking = "Please save the note before locking it"
+	t.Lock.NoHistory = "The history of a locked note is encrypted, remove the lock to see it."
+
+	t.Server.Title = "API Server"
+	t.Server.Enabled = "Serve notes over HTTP"
+	t.Server.Address = "Address"
+	t.Server.Origin = "Allowed Origin"
+	t.Server.OriginPlaceholder = "e.g., https://example.com, empty allows no web page"
+	t.Server.InvalidOrigin = "Invalid origin, use a scheme and a host, e.g., https://example.com"
+	t.Server.Token = "Token"
+	t.Server.Copy = "Copy Token"
+	t.Server.ResetToken = "New Token"
+	t.Server.Running = "Listening on http://%s/api/v1"
+	t.Server.Stopped = "Not running"
 
 	t.Status.Saved = "Saved"
 	t.Status.UnsavedChanges = "Unsaved changes"
 
 	t.Database.Location = "DB: %s"
+	t.Database.Locked = "The notes are encrypted, enter the password to open them."
+	t.Database.Password = "Password"
+	t.Database.Unlock = "Unlock"
+	t.Database.WrongPassword = "Wrong password"
+	t.Database.Open = "Open Database..."
+	t.Database.New = "New Database..."
+	t.Database.OpenOnStartup = "Open on Startup"
+	t.Database.SaveBefore = "You have unsaved changes. Do you want to save them before switching databases?"
+	t.Database.OpenFailed = "Failed to open %s: %v"
+
+	t.Backup.Title = "Backups"
+	t.Backup.BackupNow = "Backup Now"
+	t.Backup.Restore = "Restore"
+	t.Backup.Empty = "No backups yet"
+	t.Backup.Snapshots = "Snapshots"
+	t.Backup.SnapshotsOff = "Off"
+	t.Backup.SnapshotsEvery = "Every %s"
+	t.Backup.Keep = "Keep the latest"
+	t.Backup.Manual = "Backup"
+	t.Backup.Snapshot = "Snapshot"
+	t.Backup.PreMigration = "Before upgrade"
+	t.Backup.PreRestore = "Before restore"
+	t.Backup.PreRepair = "Before repair"
+	t.Backup.Encrypted = "encrypted"
+	t.Backup.BackedUp = "Database backed up to %s"
+	t.Backup.SureRestore = "Are you sure you want to restore the backup taken at %s? All notes will be replaced, the current database is backed up first."
+	t.Backup.SaveBefore = "You have unsaved changes. Do you want to save them before restoring the backup?"
+	t.Backup.Restored = "Backup restored, the database before restoring is backed up to %s"
+	t.Backup.Password = "Enter the password the backup was encrypted with"
+	t.Backup.Invalid = "The backup is damaged or not a Nota database: %v"
+
+	t.Diagnostics.Title = "Check Database"
+	t.Diagnostics.NoIssues = "No problems found"
+	t.Diagnostics.Found = "%d problems found"
+	t.Diagnostics.Repair = "Repair"
+	t.Diagnostics.RepairAll = "Repair All"
+	t.Diagnostics.CheckAgain = "Check Again"
+	t.Diagnostics.Integrity = "Database file: %s"
+	t.Diagnostics.InvalidMetadata = "%s: metadata is not valid JSON"
+	t.Diagnostics.EmptyTitle = "Note without a title"
+	t.Diagnostics.InvalidTimestamp = "%s: invalid %s %q"
+	t.Diagnostics.FixIntegrity = "Rebuilds the indexes, restore a backup if the problem remains"
+	t.Diagnostics.FixMetadata = "Keeps the metadata as text under \"invalid_metadata\""
+	t.Diagnostics.FixTitle = "Uses the first line of the content as the title"
+	t.Diagnostics.FixTimestamp = "Uses the other timestamps of the note, or the current time"
+	t.Diagnostics.SureRepair = "Repair the selected problems? The database is backed up first."
+	t.Diagnostics.SaveBefore = "You have unsaved changes. Do you want to save them before repairing the database?"
+	t.Diagnostics.Repaired = "Repaired %d problems, the database before repairing is backed up to %s"
+
+	t.Template.Title = "New Note from Template"
+	t.Template.Create = "Create"
+	t.Template.NoTemplates = "No templates yet, open a note and choose Note > Use as Template to make it a template.\n\nTemplates may use {{date}}, {{time}}, {{weekday}} and {{clipboard}}, any other {{name}} is asked for when a note is created."
+	t.Template.Fields = "Fill in the template"
+	t.Template.SaveBefore = "Please save the note before using it as a template"
+
+	t.Daily.Calendar = "Daily Notes"
+	t.Daily.Today = "Today"
+	t.Daily.Weekdays = "Mon Tue Wed Thu Fri Sat Sun"
+	t.Daily.MonthFormat = "January 2006"
+
+	t.Reminder.Title = "Reminder"
+	t.Reminder.Set = "Remind Me"
+	t.Reminder.Due = "Due %s"
+	t.Reminder.Overdue = "Overdue since %s"
+	t.Reminder.Date = "Date"
+	t.Reminder.Time = "Time (HH:MM)"
+	t.Reminder.InvalidTime = "Invalid time, use HH:MM, e.g., 09:30"
+	t.Reminder.InOneHour = "In 1 Hour"
+	t.Reminder.Tomorrow = "Tomorrow 09:00"
+	t.Reminder.NextWeek = "Next Week"
+	t.Reminder.Complete = "Complete"
+	t.Reminder.Open = "Open"
+	t.Reminder.Snooze = "Snooze"
+	t.Reminder.SnoozeMinutes = "10 Minutes"
+	t.Reminder.SnoozeHour = "1 Hour"
+	t.Reminder.SnoozeDay = "1 Day"
+	t.Reminder.DueSoon = "Due soon"
+	t.Reminder.Notification = "Reminder: %s"
+	t.Reminder.SaveBefore = "Please save the note before setting a reminder"
+
+	t.Task.Title = "Tasks"
+	t.Task.NoTasks = "No open tasks. Tasks are the checkboxes in notes, e.g., \"- [ ] call Alice @due(2026-11-01) #work\"."
+	t.Task.ShowDone = "Show done"
+	t.Task.AllTags = "All tags"
+	t.Task.Due = "due %s"
+	t.Task.Open = "Open"
+	t.Task.SaveBefore = "Please save the note before ticking its tasks"
+
+	t.Archive.Archived = "Archived"
+	t.Archive.SaveBefore = "Please save the note before archiving it"
 
 	return t
 }
@@ -143,12 +576,35 @@ func getChineseTranslation() *Translation {
 	t.Menu.View = "视图"
 	t.Menu.Language = "语言"
 	t.Menu.NewNote = "新建笔记"
+	t.Menu.FromTemplate = "从模板新建笔记"
+	t.Menu.UseAsTemplate = "用作模板"
+	t.Menu.Today = "今日笔记"
+	t.Menu.PreviousDay = "前一天"
+	t.Menu.NextDay = "后一天"
+	t.Menu.Calendar = "日历"
+	t.Menu.DailyTitle = "每日笔记标题"
+	t.Menu.Reminder = "设置提醒"
+	t.Menu.Tasks = "任务"
+	t.Menu.Archive = "已归档"
 	t.Menu.Import = "导入"
+	t.Menu.ImportMarkdown = "导入 Markdown 文件夹"
 	t.Menu.Export = "导出"
 	t.Menu.MinimizedMode = "最小化模式"
 	t.Menu.English = "English"
 	t.Menu.Chinese = "中文"
 	t.Menu.Delete = "删除"
+	t.Menu.Trash = "回收站"
+	t.Menu.ManageTags = "管理标签"
+	t.Menu.ExportMarkdown = "导出为 Markdown"
+	t.Menu.MoveNote = "移动到笔记本"
+	t.Menu.LockNote = "锁定笔记"
+	t.Menu.RemoveLock = "移除锁定"
+	t.Menu.APIServer = "API 服务"
+	t.Menu.Backups = "备份"
+	t.Menu.CheckDatabase = "检查数据库"
+	t.Menu.Autosave = "自动保存"
+	t.Menu.AutosaveOff = "关闭"
+	t.Menu.AutosaveAfter = "%d 秒后"
 
 	t.Dialog.NoNoteSelected = "未选择笔记"
 	t.Dialog.PleaseSelectNote = "请选择一个笔记"
@@ -160,6 +616,9 @@ func getChineseTranslation() *Translation {
 	t.Dialog.NoteExported = "笔记导出成功"
 	t.Dialog.ImportSuccessful = "导入成功"
 	t.Dialog.ImportedNotes = "成功导入 %d 条笔记"
+	t.Dialog.ImportReport = "导入报告"
+	t.Dialog.ImportSummary = "导入 %d，跳过 %d，失败 %d"
+	t.Dialog.ExportedNotes = "成功导出 %d 条笔记到 %s"
 	t.Dialog.DeleteNote = "删除笔记"
 	t.Dialog.SureDelete = "确定要删除此笔记吗？"
 	t.Dialog.UnsavedNote = "未保存的笔记"
@@ -175,17 +634,200 @@ func getChineseTranslation() *Translation {
 	t.Dialog.Saved = "已保存"
 	t.Dialog.UnsavedChangesText = "未保存的更改"
 	t.Dialog.NoNotesAvailable = "没有可用的笔记。点击'新建笔记'创建一个。"
+	t.Dialog.RestoreRevision = "恢复版本"
+	t.Dialog.SureRestore = "确定要恢复此版本吗？当前版本将保留在历史记录中。"
+	t.Dialog.SaveBeforeRestore = "您有未保存的更改。要在恢复前保存吗？"
+	t.Dialog.PurgeNote = "永久删除"
+	t.Dialog.SurePurge = "确定要永久删除此笔记吗？此操作无法撤销。"
+	t.Dialog.EmptyTrash = "清空回收站"
+	t.Dialog.SureEmptyTrash = "确定要永久删除回收站中的所有笔记吗？此操作无法撤销。"
+	t.Dialog.SaveBeforeTagging = "请先保存笔记再添加标签"
+	t.Dialog.DeleteTag = "删除标签"
+	t.Dialog.SureDeleteTag = "确定要删除此标签吗？它将从所有笔记中移除。"
+	t.Dialog.TagExists = "已存在同名标签，请改为合并标签"
+	t.Dialog.SaveBeforeMoving = "请先保存笔记再移动"
+	t.Dialog.DeleteNotebook = "删除笔记本"
+	t.Dialog.SureDeleteNotebook = "确定要删除此笔记本及其子笔记本吗？其中的笔记将被移到回收站。"
+	t.Dialog.NotebookCycle = "笔记本不能移动到自身或其子笔记本中"
+	t.Dialog.LinkNotFound = "找不到链接对应的笔记: %s"
+	t.Dialog.SureResetToken = "使用当前令牌的脚本和插件将无法再连接，确定继续吗？"
+	t.Dialog.RecoverDraft = "恢复未保存的更改"
+	t.Dialog.SureRecoverDraft = "发现 %[2]s 对“%[1]s”的未保存更改，nota 可能未正常关闭。是否恢复？"
+	t.Dialog.Untitled = "无标题"
 
 	t.Editor.TitlePlaceholder = "笔记标题"
 	t.Editor.ContentPlaceholder = "笔记内容..."
 	t.Editor.PlaceholderSearch = "搜索笔记..."
 	t.Editor.Save = "保存"
 	t.Editor.Exit = "退出"
+	t.Editor.History = "历史"
+	t.Editor.ModeEdit = "编辑"
+	t.Editor.ModePreview = "预览"
+	t.Editor.ModeSplit = "分屏"
+
+	t.History.Title = "历史记录"
+	t.History.Current = "当前"
+	t.History.Version = "v%d"
+	t.History.Restore = "恢复"
+	t.History.NoRevisions = "此笔记没有历史版本"
+
+	t.Trash.Title = "回收站"
+	t.Trash.Restore = "恢复"
+	t.Trash.Purge = "永久删除"
+	t.Trash.EmptyTrash = "清空回收站"
+	t.Trash.Empty = "回收站为空"
+	t.Trash.DeletedAt = "删除于: %s"
+
+	t.Tags.Title = "标签"
+	t.Tags.Add = "添加标签"
+	t.Tags.NamePlaceholder = "标签名"
+	t.Tags.Rename = "重命名"
+	t.Tags.Merge = "合并"
+	t.Tags.MergeInto = "将 '%s' 合并到"
+	t.Tags.Delete = "删除"
+	t.Tags.NoTags = "暂无标签"
+
+	t.Links.Links = "链接 (%d)"
+	t.Links.Backlinks = "反向链接 (%d)"
+
+	t.Notebooks.Title = "笔记本"
+	t.Notebooks.AllNotes = "全部笔记"
+	t.Notebooks.TopLevel = "(顶层)"
+	t.Notebooks.New = "新建笔记本"
+	t.Notebooks.NewSub = "新建子笔记本"
+	t.Notebooks.NamePlaceholder = "笔记本名称"
+	t.Notebooks.Rename = "重命名"
+	t.Notebooks.Move = "移动"
+	t.Notebooks.MoveInto = "将 '%s' 移动到"
+	t.Notebooks.Delete = "删除"
+
+	t.Conflict.Title = "笔记已在别处修改"
+	t.Conflict.Message = "编辑期间此笔记已在别处保存，最新版本为 %d，保存于 %s。"
+	t.Conflict.Legend = "以 - 开头的行仅存在于最新版本，以 + 开头的行仅存在于您的版本。"
+	t.Conflict.Overwrite = "覆盖"
+	t.Conflict.Reload = "重新加载"
+	t.Conflict.SaveAsNew = "另存为新笔记"
+	t.Conflict.KeepEditing = "继续编辑"
+	t.Conflict.CopySuffix = "（冲突副本）"
+
+	t.Lock.Lock = "锁定"
+	t.Lock.Unlock = "解锁"
+	t.Lock.RemoveLock = "移除锁定"
+	t.Lock.Locked = "此笔记已锁定，请输入密码解锁。"
+	t.Lock.Passphrase = "密码"
+	t.Lock.ConfirmPassphrase = "确认密码"
+	t.Lock.PassphraseMismatch = "两次输入的密码不一致"
+	t.Lock.WrongPassphrase = "密码错误"
+	t.Lock.SureLock = "笔记内容将被加密，历史版本将被删除。忘记密码后将无法恢复此笔记。"
+	t.Lock.SaveBeforeLocking = "请先保存笔记再锁定"
+	t.Lock.NoHistory = "已锁定笔记的历史版本已加密，请移除锁定后查看。"
+
+	t.Server.Title = "API 服务"
+	t.Server.Enabled = "通过 HTTP 提供笔记"
+	t.Server.Address = "地址"
+	t.Server.Origin = "允许的来源"
+	t.Server.OriginPlaceholder = "例如 https://example.com，留空则不允许任何网页"
+	t.Server.InvalidOrigin = "来源无效，请使用协议和主机，例如 https://example.com"
+	t.Server.Token = "令牌"
+	t.Server.Copy = "复制令牌"
+	t.Server.ResetToken = "新令牌"
+	t.Server.Running = "正在监听 http://%s/api/v1"
+	t.Server.Stopped = "未运行"
 
 	t.Status.Saved = "已保存"
 	t.Status.UnsavedChanges = "未保存的更改"
 
 	t.Database.Location = "数据库: %s"
+	t.Database.Locked = "笔记已加密，请输入密码打开。"
+	t.Database.Password = "密码"
+	t.Database.Unlock = "解锁"
+	t.Database.WrongPassword = "密码错误"
+	t.Database.Open = "打开数据库..."
+	t.Database.New = "新建数据库..."
+	t.Database.OpenOnStartup = "启动时打开"
+	t.Database.SaveBefore = "您有未保存的更改。切换数据库前是否保存？"
+	t.Database.OpenFailed = "无法打开 %s: %v"
+
+	t.Backup.Title = "备份"
+	t.Backup.BackupNow = "立即备份"
+	t.Backup.Restore = "恢复"
+	t.Backup.Empty = "暂无备份"
+	t.Backup.Snapshots = "快照"
+	t.Backup.SnapshotsOff = "关闭"
+	t.Backup.SnapshotsEvery = "每 %s"
+	t.Backup.Keep = "保留最近"
+	t.Backup.Manual = "备份"
+	t.Backup.Snapshot = "快照"
+	t.Backup.PreMigration = "升级前"
+	t.Backup.PreRestore = "恢复前"
+	t.Backup.PreRepair = "修复前"
+	t.Backup.Encrypted = "已加密"
+	t.Backup.BackedUp = "数据库已备份到 %s"
+	t.Backup.SureRestore = "确定要恢复 %s 的备份吗？所有笔记将被替换，当前数据库会先被备份。"
+	t.Backup.SaveBefore = "您有未保存的更改。恢复备份前是否保存？"
+	t.Backup.Restored = "备份已恢复，恢复前的数据库已备份到 %s"
+	t.Backup.Password = "请输入备份加密时使用的密码"
+	t.Backup.Invalid = "备份已损坏或不是 Nota 数据库: %v"
+
+	t.Diagnostics.Title = "检查数据库"
+	t.Diagnostics.NoIssues = "未发现问题"
+	t.Diagnostics.Found = "发现 %d 个问题"
+	t.Diagnostics.Repair = "修复"
+	t.Diagnostics.RepairAll = "全部修复"
+	t.Diagnostics.CheckAgain = "重新检查"
+	t.Diagnostics.Integrity = "数据库文件: %s"
+	t.Diagnostics.InvalidMetadata = "%s: 元数据不是有效的 JSON"
+	t.Diagnostics.EmptyTitle = "笔记没有标题"
+	t.Diagnostics.InvalidTimestamp = "%s: 无效的 %s %q"
+	t.Diagnostics.FixIntegrity = "重建索引，如问题仍然存在请恢复备份"
+	t.Diagnostics.FixMetadata = "将元数据作为文本保存在 \"invalid_metadata\" 下"
+	t.Diagnostics.FixTitle = "使用内容的第一行作为标题"
+	t.Diagnostics.FixTimestamp = "使用笔记的其他时间，或当前时间"
+	t.Diagnostics.SureRepair = "确定要修复所选问题吗？数据库会先被备份。"
+	t.Diagnostics.SaveBefore = "您有未保存的更改。修复数据库前是否保存？"
+	t.Diagnostics.Repaired = "已修复 %d 个问题，修复前的数据库已备份到 %s"
+
+	t.Template.Title = "从模板新建笔记"
+	t.Template.Create = "创建"
+	t.Template.NoTemplates = "还没有模板，打开一篇笔记并选择 笔记 > 用作模板 即可将其作为模板。\n\n模板中可以使用 {{date}}、{{time}}、{{weekday}} 和 {{clipboard}}，其他 {{名称}} 会在创建笔记时询问。"
+	t.Template.Fields = "填写模板"
+	t.Template.SaveBefore = "请先保存笔记再将其用作模板"
+
+	t.Daily.Calendar = "每日笔记"
+	t.Daily.Today = "今天"
+	t.Daily.Weekdays = "一 二 三 四 五 六 日"
+	t.Daily.MonthFormat = "2006年1月"
+
+	t.Reminder.Title = "提醒"
+	t.Reminder.Set = "提醒我"
+	t.Reminder.Due = "%s 到期"
+	t.Reminder.Overdue = "已于 %s 到期"
+	t.Reminder.Date = "日期"
+	t.Reminder.Time = "时间 (HH:MM)"
+	t.Reminder.InvalidTime = "时间无效，请使用 HH:MM，例如 09:30"
+	t.Reminder.InOneHour = "1 小时后"
+	t.Reminder.Tomorrow = "明天 09:00"
+	t.Reminder.NextWeek = "下周"
+	t.Reminder.Complete = "完成"
+	t.Reminder.Open = "打开"
+	t.Reminder.Snooze = "稍后提醒"
+	t.Reminder.SnoozeMinutes = "10 分钟"
+	t.Reminder.SnoozeHour = "1 小时"
+	t.Reminder.SnoozeDay = "1 天"
+	t.Reminder.DueSoon = "即将到期"
+	t.Reminder.Notification = "提醒：%s"
+	t.Reminder.SaveBefore = "请先保存笔记再设置提醒"
+
+	t.Task.Title = "任务"
+	t.Task.NoTasks = "没有未完成的任务。任务是笔记中的复选框，例如 \"- [ ] 给 Alice 打电话 @due(2026-11-01) #work\"。"
+	t.Task.ShowDone = "显示已完成"
+	t.Task.AllTags = "所有标签"
+	t.Task.Due = "%s 到期"
+	t.Task.Open = "打开"
+	t.Task.SaveBefore = "请先保存笔记再勾选其中的任务"
+
+	t.Archive.Archived = "已归档"
+	t.Archive.SaveBefore = "请先保存笔记再归档"
 
 	return t
 }
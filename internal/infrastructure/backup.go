@@ -0,0 +1,361 @@
+package infrastructure
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/curtisnewbie/miso/flow"
+	"github.com/curtisnewbie/miso/middleware/dbquery"
+	"gorm.io/gorm"
+)
+
+// The kinds of backups kept in the backup directory next to the database
+const (
+	BackupKindManual       = "backup"        // taken by user
+	BackupKindSnapshot     = "snapshot"      // taken periodically, only the latest ones are kept
+	BackupKindPreMigration = "pre-migration" // taken before the schema is migrated
+	BackupKindPreRestore   = "pre-restore"   // taken before a backup is restored
+	BackupKindPreRepair    = "pre-repair"    // taken before the problems found by the diagnostics are repaired
+)
+
+var (
+	// ErrDatabaseNotOpened is returned when backing up or restoring before the database is opened
+	ErrDatabaseNotOpened = errors.New("database is not opened")
+
+	// ErrInvalidBackup is returned when the backup is not an intact nota database
+	ErrInvalidBackup = errors.New("invalid backup")
+
+	backupKinds = []string{BackupKindManual, BackupKindSnapshot, BackupKindPreMigration, BackupKindPreRestore, BackupKindPreRepair}
+)
+
+// Backup is a copy of the database in the backup directory
+type Backup struct {
+	Path      string
+	Kind      string
+	CreatedAt time.Time
+	Size      int64
+	Encrypted bool // encrypted like the database it was taken from, it's restored with the password of the database back then
+}
+
+// BackupDatabase copies the opened database into the backup directory and returns the path of the copy
+//
+// The copy is consistent while the database is being written, an unlocked encrypted database is encrypted with its password.
+func BackupDatabase(rail flow.Rail, kind string) (string, error) {
+	openedMu.Lock()
+	defer openedMu.Unlock()
+	return backupOpenedDatabase(rail, kind)
+}
+
+// SnapshotDatabase takes a snapshot of the opened database unless it hasn't changed since the latest snapshot,
+// only the latest keep snapshots are kept
+//
+// The path of the snapshot is returned, it's empty when no snapshot is taken.
+func SnapshotDatabase(rail flow.Rail, keep int) (string, error) {
+	openedMu.Lock()
+	defer openedMu.Unlock()
+
+	snapshots, err := listBackups(getDatabasePath(), BackupKindSnapshot)
+	if err != nil {
+		return "", err
+	}
+	path := ""
+	if len(snapshots) == 0 || lastModified(getDatabasePath()).After(snapshots[0].CreatedAt) {
+		if path, err = backupOpenedDatabase(rail, BackupKindSnapshot); err != nil {
+			return "", err
+		}
+		rail.Infof("Took snapshot of database: %s", path)
+	}
+	return path, pruneSnapshots(rail, getDatabasePath(), keep)
+}
+
+// ListBackups lists the backups of the database, newest first
+func ListBackups() ([]Backup, error) {
+	return listBackups(getDatabasePath(), "")
+}
+
+// RestoreBackup replaces the content of the opened database with the backup and returns the path of the backup
+// taken before restoring
+//
+// The backup is validated before anything is changed. An encrypted backup is decrypted with the password, which may be
+// empty when the backup was taken with the password of the unlocked database. The database keeps its own encryption,
+// whatever the backup had.
+func RestoreBackup(rail flow.Rail, path string, password string) (string, error) {
+	openedMu.Lock()
+	defer openedMu.Unlock()
+
+	if opened == nil && unlocked == nil {
+		return "", ErrDatabaseNotOpened
+	}
+
+	plain, err := readBackup(path, password)
+	if err != nil {
+		return "", err
+	}
+	src, srcConn, err := openDeserialized(plain)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidBackup, err)
+	}
+	defer src.Close()
+	defer srcConn.Close()
+	if err := validateBackup(srcConn); err != nil {
+		return "", err
+	}
+
+	preRestore, err := backupOpenedDatabase(rail, BackupKindPreRestore)
+	if err != nil {
+		return "", fmt.Errorf("failed to back up database before restoring: %w", err)
+	}
+	rail.Infof("Backed up database to %s before restoring %s", preRestore, path)
+
+	db := opened
+	if unlocked != nil {
+		db = unlocked.db
+		unlocked.mu.Lock()
+		err = copyDatabase(unlocked.conn, srcConn)
+		// data_version doesn't change with the commits of the connection itself
+		unlocked.dataVersion = 0
+		unlocked.mu.Unlock()
+	} else {
+		err = restoreInto(db, srcConn)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to restore backup, the database before restoring is backed up at %s: %w", preRestore, err)
+	}
+
+	// The backup may be taken before the latest migrations
+	err = migrateDatabase(rail, db, func(rail flow.Rail, db *gorm.DB) (string, error) { return preRestore, nil })
+	if err != nil {
+		return "", err
+	}
+	if unlocked != nil {
+		if err := unlocked.flush(); err != nil {
+			return "", err
+		}
+	}
+	return preRestore, nil
+}
+
+// backupOpenedDatabase copies the opened database into the backup directory, the caller must hold openedMu
+func backupOpenedDatabase(rail flow.Rail, kind string) (string, error) {
+	dbPath := getDatabasePath()
+	if unlocked != nil {
+		backupPath, err := newBackupPath(dbPath, kind)
+		if err != nil {
+			return "", err
+		}
+		unlocked.mu.Lock()
+		sealed, err := unlocked.seal()
+		unlocked.mu.Unlock()
+		if err != nil {
+			return "", err
+		}
+		if err := writeFileAtomically(backupPath, sealed); err != nil {
+			return "", err
+		}
+		return backupPath, nil
+	}
+	if opened == nil {
+		return "", ErrDatabaseNotOpened
+	}
+	return vacuumInto(dbPath, kind)(rail, opened)
+}
+
+// vacuumInto copies the plain database with VACUUM INTO, which is consistent while the database is open
+func vacuumInto(dbPath string, kind string) backupFunc {
+	return func(rail flow.Rail, db *gorm.DB) (string, error) {
+		backupPath, err := newBackupPath(dbPath, kind)
+		if err != nil {
+			return "", err
+		}
+		if err := dbquery.NewQuery(rail, db).ExecAny("VACUUM INTO ?", backupPath); err != nil {
+			return "", err
+		}
+		return backupPath, nil
+	}
+}
+
+// restoreInto copies the database into the plain database with the online backup API, the other connections see
+// the restored database once it's copied
+func restoreInto(db *gorm.DB, src *sql.Conn) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	conn, err := sqlDB.Conn(context.Background())
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return copyDatabase(conn, src)
+}
+
+// readBackup reads the backup file and decrypts it when it's encrypted
+func readBackup(path string, password string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(data, []byte(encryptedDatabaseMagic)) {
+		return walToRollback(data), nil
+	}
+
+	// Backups taken while the database is unlocked share its password and salt
+	if password == "" && unlocked != nil && bytes.HasPrefix(data[len(encryptedDatabaseMagic):], unlocked.salt) {
+		sealed := data[len(encryptedDatabaseMagic)+len(unlocked.salt):]
+		gcm := unlocked.gcm
+		if len(sealed) < gcm.NonceSize()+gcm.Overhead() {
+			return nil, ErrInvalidEncryptedDatabase
+		}
+		plain, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+		if err != nil {
+			return nil, ErrInvalidBackup
+		}
+		return plain, nil
+	}
+	if password == "" {
+		return nil, ErrDatabaseEncrypted
+	}
+	_, _, plain, err := decryptDatabase(data, password)
+	return plain, err
+}
+
+// walToRollback marks a database file copied in WAL mode as a rollback journal database, an in-memory copy of it
+// can't be opened in WAL mode
+func walToRollback(data []byte) []byte {
+	// The file format version numbers at offset 18 and 19 are 2 for WAL mode
+	if len(data) > 19 && data[18] == 2 && data[19] == 2 {
+		data[18], data[19] = 1, 1
+	}
+	return data
+}
+
+// validateBackup checks the integrity of the backup and that it's a nota database
+func validateBackup(conn *sql.Conn) error {
+	ctx := context.Background()
+	var result string
+	if err := conn.QueryRowContext(ctx, "PRAGMA integrity_check(1)").Scan(&result); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidBackup, err)
+	}
+	if result != "ok" {
+		return fmt.Errorf("%w: %s", ErrInvalidBackup, result)
+	}
+	var tables int
+	if err := conn.QueryRowContext(ctx, "SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'note'").Scan(&tables); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidBackup, err)
+	}
+	if tables == 0 {
+		return fmt.Errorf("%w: not a nota database", ErrInvalidBackup)
+	}
+	return nil
+}
+
+// newBackupPath returns a new path in the backup directory next to the database
+func newBackupPath(dbPath string, kind string) (string, error) {
+	dir := backupDir(dbPath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	name := fmt.Sprintf("%s-%s-%s", databaseName(dbPath), kind, time.Now().Format("20060102-150405"))
+	backupPath := filepath.Join(dir, name+".sqlite")
+	for i := 2; ; i++ {
+		if _, err := os.Stat(backupPath); os.IsNotExist(err) {
+			return backupPath, nil
+		}
+		backupPath = filepath.Join(dir, fmt.Sprintf("%s-%d.sqlite", name, i))
+	}
+}
+
+// listBackups lists the backups of the database of the kind, or of all kinds when kind is empty, newest first
+func listBackups(dbPath string, kind string) ([]Backup, error) {
+	entries, err := os.ReadDir(backupDir(dbPath))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	pattern := regexp.MustCompile(fmt.Sprintf(`^%s-(%s)-\d{8}-\d{6}(-\d+)?\.sqlite$`,
+		regexp.QuoteMeta(databaseName(dbPath)), strings.Join(backupKinds, "|")))
+	var backups []Backup
+	for _, entry := range entries {
+		m := pattern.FindStringSubmatch(entry.Name())
+		if m == nil || entry.IsDir() || (kind != "" && m[1] != kind) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		path := filepath.Join(backupDir(dbPath), entry.Name())
+		backups = append(backups, Backup{
+			Path:      path,
+			Kind:      m[1],
+			CreatedAt: info.ModTime(),
+			Size:      info.Size(),
+			Encrypted: isEncryptedFile(path),
+		})
+	}
+	slices.SortFunc(backups, func(a, b Backup) int { return b.CreatedAt.Compare(a.CreatedAt) })
+	return backups, nil
+}
+
+// pruneSnapshots removes the snapshots other than the latest keep ones, the other kinds of backups are left to user
+func pruneSnapshots(rail flow.Rail, dbPath string, keep int) error {
+	snapshots, err := listBackups(dbPath, BackupKindSnapshot)
+	if err != nil {
+		return err
+	}
+	for i := max(keep, 1); i < len(snapshots); i++ {
+		if err := os.Remove(snapshots[i].Path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		rail.Infof("Removed old snapshot: %s", snapshots[i].Path)
+	}
+	return nil
+}
+
+// lastModified returns when the database file was last written, including the writes still in the WAL
+func lastModified(dbPath string) time.Time {
+	var modified time.Time
+	for _, path := range []string{dbPath, dbPath + "-wal"} {
+		if info, err := os.Stat(path); err == nil && info.ModTime().After(modified) {
+			modified = info.ModTime()
+		}
+	}
+	return modified
+}
+
+// isEncryptedFile checks whether the file starts with the magic of an encrypted database
+func isEncryptedFile(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	magic := make([]byte, len(encryptedDatabaseMagic))
+	if _, err := io.ReadFull(f, magic); err != nil {
+		return false
+	}
+	return string(magic) == encryptedDatabaseMagic
+}
+
+// backupDir returns the backup directory next to the database
+func backupDir(dbPath string) string {
+	return filepath.Join(filepath.Dir(dbPath), "backups")
+}
+
+// databaseName returns the file name of the database without its extension, backups are named after it
+func databaseName(dbPath string) string {
+	return strings.TrimSuffix(filepath.Base(dbPath), filepath.Ext(dbPath))
+}
@@ -0,0 +1,217 @@
+package infrastructure
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/curtisnewbie/miso/flow"
+	"gorm.io/gorm"
+)
+
+// initTestDatabase opens a new database with InitializeDatabase in a temporary home directory, it's closed when the
+// test ends
+func initTestDatabase(t *testing.T) *gorm.DB {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	if err := SetDatabasePath(filepath.Join(home, "nota.sqlite")); err != nil {
+		t.Fatal(err)
+	}
+	db, err := InitializeDatabase()
+	if err != nil {
+		t.Fatalf("failed to initialize database: %v", err)
+	}
+	t.Cleanup(func() { CloseDatabase() })
+	return db
+}
+
+// writeTestNote creates or renames the note n1
+func writeTestNote(t *testing.T, db *gorm.DB, title string) {
+	err := db.Exec(`INSERT INTO note (id, title, content, created_at, updated_at) VALUES ('n1', ?, '', datetime(), datetime())
+		ON CONFLICT (id) DO UPDATE SET title = excluded.title`, title).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// readBackupNoteTitle reads the title of the note n1 from a plain database file
+func readBackupNoteTitle(t *testing.T, path string) string {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var title string
+	if err := db.QueryRow("SELECT title FROM note WHERE id = 'n1'").Scan(&title); err != nil {
+		t.Fatalf("failed to read note from %s: %v", path, err)
+	}
+	return title
+}
+
+// countBackups counts the backups of the kind
+func countBackups(t *testing.T, kind string) int {
+	backups, err := listBackups(getDatabasePath(), kind)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(backups)
+}
+
+func TestRestoreBackup(t *testing.T) {
+	rail := flow.EmptyRail()
+	db := initTestDatabase(t)
+	writeTestNote(t, db, "before")
+	backup, err := BackupDatabase(rail, BackupKindManual)
+	if err != nil {
+		t.Fatalf("BackupDatabase() failed: %v", err)
+	}
+	writeTestNote(t, db, "after")
+
+	preRestore, err := RestoreBackup(rail, backup, "")
+	if err != nil {
+		t.Fatalf("RestoreBackup() failed: %v", err)
+	}
+	var title string
+	if err := db.Raw("SELECT title FROM note WHERE id = 'n1'").Scan(&title).Error; err != nil {
+		t.Fatal(err)
+	}
+	if title != "before" {
+		t.Errorf("title after restoring = %q, want %q", title, "before")
+	}
+	if got := readBackupNoteTitle(t, preRestore); got != "after" {
+		t.Errorf("title in the pre-restore backup = %q, want %q", got, "after")
+	}
+	if n := countBackups(t, BackupKindPreRestore); n != 1 {
+		t.Errorf("%d pre-restore backups, want 1", n)
+	}
+}
+
+func TestRestoreBackupInvalid(t *testing.T) {
+	rail := flow.EmptyRail()
+	db := initTestDatabase(t)
+	writeTestNote(t, db, "live")
+	backup, err := BackupDatabase(rail, BackupKindManual)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(backup)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	otherDB := filepath.Join(dir, "other.sqlite")
+	other, err := sql.Open("sqlite3", otherDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := other.Exec("CREATE TABLE todo (id TEXT PRIMARY KEY)"); err != nil {
+		t.Fatal(err)
+	}
+	other.Close()
+
+	corrupted := append([]byte{}, data...)
+	for i := len(corrupted) / 2; i < len(corrupted); i++ {
+		corrupted[i] = 0xff
+	}
+	files := map[string][]byte{
+		"text.sqlite":      []byte("not a database at all"),
+		"truncated.sqlite": data[:100],
+		"corrupted.sqlite": corrupted,
+	}
+	tests := []string{otherDB}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, content, 0600); err != nil {
+			t.Fatal(err)
+		}
+		tests = append(tests, path)
+	}
+
+	for _, path := range tests {
+		if _, err := RestoreBackup(rail, path, ""); !errors.Is(err, ErrInvalidBackup) {
+			t.Errorf("RestoreBackup(%s) error = %v, want ErrInvalidBackup", filepath.Base(path), err)
+		}
+	}
+	var title string
+	if err := db.Raw("SELECT title FROM note WHERE id = 'n1'").Scan(&title).Error; err != nil {
+		t.Fatal(err)
+	}
+	if title != "live" {
+		t.Errorf("title after rejected restores = %q, want %q", title, "live")
+	}
+	if n := countBackups(t, BackupKindPreRestore); n != 0 {
+		t.Errorf("%d pre-restore backups, want none when the backup is rejected", n)
+	}
+}
+
+func TestSnapshotDatabase(t *testing.T) {
+	rail := flow.EmptyRail()
+	db := initTestDatabase(t)
+	manual, err := BackupDatabase(rail, BackupKindManual)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The snapshots are dated in the past, so that the writes in between are always newer
+	taken := time.Now().Add(-time.Hour)
+	for i := 0; i < 4; i++ {
+		writeTestNote(t, db, fmt.Sprintf("version %d", i+1))
+		path, err := SnapshotDatabase(rail, 2)
+		if err != nil {
+			t.Fatalf("SnapshotDatabase() failed: %v", err)
+		}
+		if path == "" {
+			t.Fatalf("SnapshotDatabase() took no snapshot after a write")
+		}
+		taken = taken.Add(time.Minute)
+		if err := os.Chtimes(path, taken, taken); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	snapshots, err := listBackups(getDatabasePath(), BackupKindSnapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshots) != 2 {
+		t.Fatalf("%d snapshots kept, want 2", len(snapshots))
+	}
+	if got := readBackupNoteTitle(t, snapshots[0].Path); got != "version 4" {
+		t.Errorf("latest snapshot has title %q, want %q", got, "version 4")
+	}
+	if got := readBackupNoteTitle(t, snapshots[1].Path); got != "version 3" {
+		t.Errorf("second latest snapshot has title %q, want %q", got, "version 3")
+	}
+	if _, err := os.Stat(manual); err != nil {
+		t.Errorf("manual backup is pruned with the snapshots: %v", err)
+	}
+}
+
+func TestSnapshotDatabaseUnchanged(t *testing.T) {
+	rail := flow.EmptyRail()
+	db := initTestDatabase(t)
+	writeTestNote(t, db, "unchanged")
+	first, err := SnapshotDatabase(rail, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first == "" {
+		t.Fatal("SnapshotDatabase() took no first snapshot")
+	}
+
+	path, err := SnapshotDatabase(rail, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path != "" {
+		t.Errorf("SnapshotDatabase() = %s, want no snapshot when nothing changed", path)
+	}
+	if n := countBackups(t, BackupKindSnapshot); n != 1 {
+		t.Errorf("%d snapshots, want 1", n)
+	}
+}
@@ -4,42 +4,102 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 
 	"github.com/curtisnewbie/miso/flow"
 	"github.com/curtisnewbie/miso/middleware/dbquery"
 	"github.com/curtisnewbie/miso/middleware/sqlite"
-	"github.com/curtisnewbie/nota/internal/domain"
 	"gorm.io/gorm"
 )
 
 const (
 	defaultDatabasePath = "$HOME/nota/data/nota.sqlite"
+
+	// DatabaseEnv is the environment variable that overrides the database path
+	DatabaseEnv = "NOTA_DB"
+)
+
+var (
+	pathMu       sync.Mutex
+	databasePath string
+
+	// openedMu guards the database opened by InitializeDatabase or UnlockDatabase, only one of them is open at a time
+	openedMu sync.Mutex
+	opened   *gorm.DB
+	unlocked *unlockedDatabase
 )
 
 // InitializeDatabase initializes the SQLite database with schema migration
+//
+// ErrDatabaseEncrypted is returned when the database is encrypted, it is opened with UnlockDatabase instead.
 func InitializeDatabase() (*gorm.DB, error) {
 	rail := flow.EmptyRail()
 
 	dbPath := getDatabasePath()
 
+	encrypted, err := IsDatabaseEncrypted()
+	if err != nil {
+		rail.Errorf("Failed to read database: %v", err)
+		return nil, err
+	}
+	if encrypted {
+		return nil, ErrDatabaseEncrypted
+	}
+
 	rail.Infof("Initializing database at: %s", dbPath)
 
+	openedMu.Lock()
+	defer openedMu.Unlock()
+
 	gormDB, err := sqlite.NewConn(dbPath, true)
 	if err != nil {
 		rail.Errorf("Failed to open database: %v", err)
 		return nil, err
 	}
 
-	err = gormDB.Exec("PRAGMA foreign_keys = ON").Error
+	if err := setupDatabase(rail, gormDB, backupBeforeMigrating(dbPath)); err != nil {
+		if sqlDB, err := gormDB.DB(); err == nil {
+			sqlDB.Close()
+		}
+		return nil, err
+	}
+	opened = gormDB
+	addRecentDatabase(rail, dbPath)
+	return gormDB, nil
+}
+
+// CloseDatabase closes the database, an unlocked encrypted database is written back to the encrypted file first
+func CloseDatabase() error {
+	openedMu.Lock()
+	defer openedMu.Unlock()
+
+	if unlocked != nil {
+		return closeUnlockedDatabase()
+	}
+	if opened != nil {
+		sqlDB, err := opened.DB()
+		opened = nil
+		if err != nil {
+			return err
+		}
+		return sqlDB.Close()
+	}
+	return nil
+}
+
+// setupDatabase migrates the schema of an opened database and makes it the primary database
+func setupDatabase(rail flow.Rail, gormDB *gorm.DB, backup backupFunc) error {
+	err := gormDB.Exec("PRAGMA foreign_keys = ON").Error
 	if err != nil {
 		rail.Errorf("Failed to enable foreign keys: %v", err)
-		return nil, err
+		return err
 	}
 
-	err = gormDB.AutoMigrate(&domain.Note{}, &domain.Config{})
+	err = migrateDatabase(rail, gormDB, backup)
 	if err != nil {
 		rail.Errorf("Failed to migrate database schema: %v", err)
-		return nil, err
+		return err
 	}
 
 	dbquery.ImplGetPrimaryDBFunc(func() *gorm.DB {
@@ -48,12 +108,51 @@ func InitializeDatabase() (*gorm.DB, error) {
 
 	rail.Infof("Database initialized successfully")
 
-	return gormDB, nil
+	return nil
 }
 
-// getDatabasePath returns the database path from config or default
+// getDatabasePath returns the database path, it's resolved the first time unless SetDatabasePath is called
+//
+// The path set with SetDatabasePath (e.g., by the --db flag) comes first, then $NOTA_DB, the default database
+// in the vault config, and the default path.
 func getDatabasePath() string {
-	return os.ExpandEnv(defaultDatabasePath)
+	pathMu.Lock()
+	defer pathMu.Unlock()
+
+	if databasePath == "" {
+		path := os.Getenv(DatabaseEnv)
+		if path == "" {
+			path = LoadVaultConfig().Default
+		}
+		if path == "" {
+			path = defaultDatabasePath
+		}
+		databasePath = normalizeDatabasePath(path)
+	}
+	return databasePath
+}
+
+// SetDatabasePath sets the path of the database opened next, the opened database has to be closed first
+func SetDatabasePath(path string) error {
+	if strings.TrimSpace(path) == "" {
+		return fmt.Errorf("database path is empty")
+	}
+	pathMu.Lock()
+	defer pathMu.Unlock()
+	databasePath = normalizeDatabasePath(path)
+	return nil
+}
+
+// normalizeDatabasePath expands environment variables and ~ in the path and makes it absolute
+func normalizeDatabasePath(path string) string {
+	path = os.ExpandEnv(strings.TrimSpace(path))
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		path = os.Getenv("HOME") + path[1:]
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	return path
 }
 
 // EnsureDatabaseDir ensures the database directory exists
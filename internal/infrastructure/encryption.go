@@ -0,0 +1,571 @@
+package infrastructure
+
+import (
+	"bytes"
+	"context"
+	"crypto/cipher"
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/curtisnewbie/miso/flow"
+	"github.com/curtisnewbie/miso/middleware/sqlite"
+	"github.com/curtisnewbie/nota/internal/domain"
+	"github.com/mattn/go-sqlite3"
+	"gorm.io/gorm"
+)
+
+const (
+	// encryptedDatabaseMagic marks an encrypted database file, followed by the salt, the nonce and the AES-256-GCM ciphertext of the SQLite database
+	encryptedDatabaseMagic = "NOTAENC1"
+
+	// flushInterval is how often an unlocked database is checked for changes that failed to be written back to the
+	// encrypted file when they were committed
+	flushInterval = 2 * time.Second
+)
+
+var (
+	// ErrDatabaseEncrypted is returned when an encrypted database is opened without the password
+	ErrDatabaseEncrypted = errors.New("database is encrypted")
+
+	// ErrDatabaseNotEncrypted is returned when the password of a plain database is changed or removed
+	ErrDatabaseNotEncrypted = errors.New("database is not encrypted")
+
+	// ErrDatabaseNotFound is returned when encrypting a database that hasn't been created yet
+	ErrDatabaseNotFound = errors.New("database not found")
+
+	// ErrEmptyPassword is returned when the password of the database is empty
+	ErrEmptyPassword = errors.New("password is empty")
+
+	// ErrWrongPassword is returned when the encrypted database can't be decrypted with the password
+	ErrWrongPassword = errors.New("wrong password")
+
+	// ErrInvalidEncryptedDatabase is returned when the encrypted database file is malformed
+	ErrInvalidEncryptedDatabase = errors.New("invalid encrypted database")
+)
+
+// unlockedDatabase is an encrypted database that has been decrypted into memory, changes are flushed back to the encrypted file
+type unlockedDatabase struct {
+	mu          sync.Mutex
+	path        string
+	db          *gorm.DB
+	conn        *sql.Conn // keeps the in-memory database alive and is used to serialize it
+	gcm         cipher.AEAD
+	salt        []byte
+	dataVersion int64
+	stop        chan struct{}
+	done        chan struct{}
+}
+
+// IsDatabaseEncrypted checks whether the database file is encrypted, a database that doesn't exist yet is not encrypted
+func IsDatabaseEncrypted() (bool, error) {
+	f, err := os.Open(getDatabasePath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	defer f.Close()
+
+	magic := make([]byte, len(encryptedDatabaseMagic))
+	if _, err := io.ReadFull(f, magic); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return false, nil
+		}
+		return false, err
+	}
+	return string(magic) == encryptedDatabaseMagic, nil
+}
+
+// UnlockDatabase decrypts the encrypted database into memory and initializes it like InitializeDatabase
+//
+// Nothing is written to disk in plaintext, changes are encrypted and written back to the database file once they are committed.
+func UnlockDatabase(password string) (*gorm.DB, error) {
+	rail := flow.EmptyRail()
+
+	openedMu.Lock()
+	defer openedMu.Unlock()
+	if unlocked != nil {
+		return unlocked.db, nil
+	}
+
+	dbPath := getDatabasePath()
+	data, err := os.ReadFile(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	gcm, salt, plain, err := decryptDatabase(data, password)
+	if err != nil {
+		return nil, err
+	}
+
+	rail.Infof("Unlocking encrypted database at: %s", dbPath)
+
+	// The memdb VFS shares the database between the connections of the pool as long as one of them is open
+	gormDB, err := sqlite.NewConn(fmt.Sprintf("file:/nota-%d?vfs=memdb", time.Now().UnixNano()), false)
+	if err != nil {
+		rail.Errorf("Failed to open database: %v", err)
+		return nil, err
+	}
+	sqlDB, err := gormDB.DB()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := sqlDB.Conn(context.Background())
+	if err != nil {
+		sqlDB.Close()
+		return nil, err
+	}
+
+	v := &unlockedDatabase{
+		path: dbPath,
+		db:   gormDB,
+		conn: conn,
+		gcm:  gcm,
+		salt: salt,
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	if err := v.load(plain); err != nil {
+		rail.Errorf("Failed to load decrypted database: %v", err)
+		v.close()
+		return nil, err
+	}
+	if err := setupDatabase(rail, gormDB, backupEncryptedBeforeMigrating(dbPath)); err != nil {
+		v.close()
+		return nil, err
+	}
+
+	// Writes are flushed once they are committed, so that they aren't lost when nota exits abruptly
+	pool := &flushingConnPool{ConnPool: gormDB.ConnPool, v: v}
+	gormDB.ConnPool = pool
+	gormDB.Statement.ConnPool = pool
+
+	go v.flushPeriodically()
+	unlocked = v
+	addRecentDatabase(rail, dbPath)
+	return gormDB, nil
+}
+
+// closeUnlockedDatabase writes the unlocked database back to the encrypted file and closes it
+func closeUnlockedDatabase() error {
+	close(unlocked.stop)
+	<-unlocked.done
+	err := unlocked.flush()
+	if err != nil {
+		flow.EmptyRail().Errorf("Failed to write encrypted database: %v", err)
+	}
+	unlocked.close()
+	unlocked = nil
+	return err
+}
+
+// EncryptDatabase encrypts the plain database file with the password, the database must not be opened
+func EncryptDatabase(password string) error {
+	if password == "" {
+		return ErrEmptyPassword
+	}
+	dbPath := getDatabasePath()
+	encrypted, err := IsDatabaseEncrypted()
+	if err != nil {
+		return err
+	}
+	if encrypted {
+		return ErrDatabaseEncrypted
+	}
+	if _, err := os.Stat(dbPath); err != nil {
+		if os.IsNotExist(err) {
+			return ErrDatabaseNotFound
+		}
+		return err
+	}
+
+	plain, err := serializeDatabaseFile(dbPath)
+	if err != nil {
+		return err
+	}
+	salt, err := domain.NewSalt()
+	if err != nil {
+		return err
+	}
+	gcm, err := domain.NewPassphraseCipher(password, salt)
+	if err != nil {
+		return err
+	}
+	sealed, err := encryptDatabase(gcm, salt, plain)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomically(dbPath, sealed); err != nil {
+		return err
+	}
+
+	// The database was checkpointed before it was serialized, the leftovers of WAL mode don't have anything worth keeping
+	for _, suffix := range []string{"-wal", "-shm"} {
+		if err := os.Remove(dbPath + suffix); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// DecryptDatabase decrypts the encrypted database file back to a plain database, the database must not be opened
+func DecryptDatabase(password string) error {
+	dbPath := getDatabasePath()
+	data, err := readEncryptedDatabase(dbPath)
+	if err != nil {
+		return err
+	}
+	_, _, plain, err := decryptDatabase(data, password)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomically(dbPath, plain)
+}
+
+// ChangeDatabasePassword re-encrypts the encrypted database file with the new password, the database must not be opened
+func ChangeDatabasePassword(oldPassword string, newPassword string) error {
+	if newPassword == "" {
+		return ErrEmptyPassword
+	}
+	dbPath := getDatabasePath()
+	data, err := readEncryptedDatabase(dbPath)
+	if err != nil {
+		return err
+	}
+	_, _, plain, err := decryptDatabase(data, oldPassword)
+	if err != nil {
+		return err
+	}
+
+	salt, err := domain.NewSalt()
+	if err != nil {
+		return err
+	}
+	gcm, err := domain.NewPassphraseCipher(newPassword, salt)
+	if err != nil {
+		return err
+	}
+	sealed, err := encryptDatabase(gcm, salt, plain)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomically(dbPath, sealed)
+}
+
+// load copies the decrypted database into the shared in-memory database
+func (v *unlockedDatabase) load(plain []byte) error {
+	src, srcConn, err := openDeserialized(plain)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	defer srcConn.Close()
+	return copyDatabase(v.conn, srcConn)
+}
+
+// flushPeriodically writes the database back to the encrypted file whenever it has changed, until the database is closed
+//
+// The writes are flushed once they are committed, this retries the flushes that failed and writes the migrated database.
+func (v *unlockedDatabase) flushPeriodically() {
+	defer close(v.done)
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-v.stop:
+			return
+		case <-ticker.C:
+			if err := v.flush(); err != nil {
+				flow.EmptyRail().Errorf("Failed to write encrypted database: %v", err)
+			}
+		}
+	}
+}
+
+// flush encrypts the database and writes it to the database file if it has changed since the last flush
+func (v *unlockedDatabase) flush() error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	ctx := context.Background()
+	var version int64
+	if err := v.conn.QueryRowContext(ctx, "PRAGMA data_version").Scan(&version); err != nil {
+		return err
+	}
+	// data_version only changes when other connections commit, the first flush always writes the migrated database
+	if v.dataVersion != 0 && version == v.dataVersion {
+		return nil
+	}
+
+	sealed, err := v.seal()
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomically(v.path, sealed); err != nil {
+		return err
+	}
+	v.dataVersion = version
+	return nil
+}
+
+// flushCommitted flushes the database after a write is committed, a failed flush is only logged since the write has
+// succeeded, it's retried by flushPeriodically
+func (v *unlockedDatabase) flushCommitted() {
+	if err := v.flush(); err != nil {
+		flow.EmptyRail().Errorf("Failed to write encrypted database: %v", err)
+	}
+}
+
+// seal serializes the in-memory database and encrypts it, the caller must hold v.mu
+func (v *unlockedDatabase) seal() ([]byte, error) {
+	ctx := context.Background()
+
+	// The read transaction keeps writers from committing while the pages are copied
+	if _, err := v.conn.ExecContext(ctx, "BEGIN"); err != nil {
+		return nil, err
+	}
+	var plain []byte
+	err := v.conn.QueryRowContext(ctx, "SELECT COUNT(*) FROM sqlite_master").Scan(new(int64))
+	if err == nil {
+		err = v.conn.Raw(func(driverConn any) error {
+			var err error
+			plain, err = driverConn.(*sqlite3.SQLiteConn).Serialize("main")
+			return err
+		})
+	}
+	if _, cerr := v.conn.ExecContext(ctx, "COMMIT"); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return encryptDatabase(v.gcm, v.salt, plain)
+}
+
+// close releases the in-memory database without flushing it
+func (v *unlockedDatabase) close() {
+	v.conn.Close()
+	if sqlDB, err := v.db.DB(); err == nil {
+		sqlDB.Close()
+	}
+}
+
+// flushingConnPool flushes the unlocked database once a write is committed, statements executed outside of
+// transactions are committed on their own
+type flushingConnPool struct {
+	gorm.ConnPool
+	v *unlockedDatabase
+}
+
+// ExecContext executes the statement and flushes the database
+func (p *flushingConnPool) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	result, err := p.ConnPool.ExecContext(ctx, query, args...)
+	if err == nil {
+		p.v.flushCommitted()
+	}
+	return result, err
+}
+
+// BeginTx begins a transaction that flushes the database when it's committed
+func (p *flushingConnPool) BeginTx(ctx context.Context, opts *sql.TxOptions) (gorm.ConnPool, error) {
+	var tx gorm.ConnPool
+	switch beginner := p.ConnPool.(type) {
+	case gorm.TxBeginner:
+		sqlTx, err := beginner.BeginTx(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		tx = sqlTx
+	case gorm.ConnPoolBeginner:
+		poolTx, err := beginner.BeginTx(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		tx = poolTx
+	default:
+		return nil, gorm.ErrInvalidTransaction
+	}
+	return &flushingTx{ConnPool: tx, v: p.v}, nil
+}
+
+// GetDBConn returns the sql.DB of the wrapped pool, for gorm.DB.DB
+func (p *flushingConnPool) GetDBConn() (*sql.DB, error) {
+	if connector, ok := p.ConnPool.(gorm.GetDBConnector); ok {
+		return connector.GetDBConn()
+	}
+	if sqlDB, ok := p.ConnPool.(*sql.DB); ok {
+		return sqlDB, nil
+	}
+	return nil, gorm.ErrInvalidDB
+}
+
+// flushingTx is a transaction of flushingConnPool, the statements in it are flushed together when it's committed
+type flushingTx struct {
+	gorm.ConnPool
+	v *unlockedDatabase
+}
+
+// Commit commits the transaction and flushes the database
+func (tx *flushingTx) Commit() error {
+	if err := tx.ConnPool.(gorm.TxCommitter).Commit(); err != nil {
+		return err
+	}
+	tx.v.flushCommitted()
+	return nil
+}
+
+// Rollback rolls back the transaction
+func (tx *flushingTx) Rollback() error {
+	return tx.ConnPool.(gorm.TxCommitter).Rollback()
+}
+
+// readEncryptedDatabase reads the database file, ErrDatabaseNotEncrypted is returned when it's a plain database
+func readEncryptedDatabase(dbPath string) ([]byte, error) {
+	data, err := os.ReadFile(dbPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrDatabaseNotFound
+		}
+		return nil, err
+	}
+	if !bytes.HasPrefix(data, []byte(encryptedDatabaseMagic)) {
+		return nil, ErrDatabaseNotEncrypted
+	}
+	return data, nil
+}
+
+// encryptDatabase seals the serialized database with a random nonce
+func encryptDatabase(gcm cipher.AEAD, salt []byte, plain []byte) ([]byte, error) {
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	sealed := make([]byte, 0, len(encryptedDatabaseMagic)+len(salt)+len(nonce)+len(plain)+gcm.Overhead())
+	sealed = append(sealed, encryptedDatabaseMagic...)
+	sealed = append(sealed, salt...)
+	sealed = append(sealed, nonce...)
+	return gcm.Seal(sealed, nonce, plain, nil), nil
+}
+
+// decryptDatabase opens the encrypted database file, the cipher and the salt are returned to encrypt the database again
+func decryptDatabase(data []byte, password string) (cipher.AEAD, []byte, []byte, error) {
+	if password == "" {
+		return nil, nil, nil, ErrEmptyPassword
+	}
+	sealed, ok := bytes.CutPrefix(data, []byte(encryptedDatabaseMagic))
+	if !ok {
+		return nil, nil, nil, ErrDatabaseNotEncrypted
+	}
+	if len(sealed) < domain.SaltSize {
+		return nil, nil, nil, ErrInvalidEncryptedDatabase
+	}
+	salt := sealed[:domain.SaltSize]
+	gcm, err := domain.NewPassphraseCipher(password, salt)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	sealed = sealed[domain.SaltSize:]
+	if len(sealed) < gcm.NonceSize()+gcm.Overhead() {
+		return nil, nil, nil, ErrInvalidEncryptedDatabase
+	}
+	plain, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, nil, nil, ErrWrongPassword
+	}
+	return gcm, bytes.Clone(salt), plain, nil
+}
+
+// openDeserialized opens a private in-memory database with the content of the serialized database
+func openDeserialized(plain []byte) (*sql.DB, *sql.Conn, error) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		return nil, nil, err
+	}
+	conn, err := db.Conn(context.Background())
+	if err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+	err = conn.Raw(func(driverConn any) error {
+		return driverConn.(*sqlite3.SQLiteConn).Deserialize(plain, "main")
+	})
+	if err != nil {
+		conn.Close()
+		db.Close()
+		return nil, nil, err
+	}
+	return db, conn, nil
+}
+
+// copyDatabase replaces the content of the dest database with the src database using the online backup API
+func copyDatabase(dest *sql.Conn, src *sql.Conn) error {
+	return src.Raw(func(srcDriverConn any) error {
+		return dest.Raw(func(destDriverConn any) error {
+			backup, err := destDriverConn.(*sqlite3.SQLiteConn).Backup("main", srcDriverConn.(*sqlite3.SQLiteConn), "main")
+			if err != nil {
+				return err
+			}
+			if _, err := backup.Step(-1); err != nil {
+				backup.Close()
+				return err
+			}
+			return backup.Finish()
+		})
+	})
+}
+
+// serializeDatabaseFile reads the plain database file into memory, WAL mode is turned off so that the file is self-contained
+func serializeDatabaseFile(dbPath string) ([]byte, error) {
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	conn, err := db.Conn(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(context.Background(), "PRAGMA journal_mode=DELETE"); err != nil {
+		return nil, fmt.Errorf("failed to turn off WAL mode, the database may be in use: %w", err)
+	}
+	var plain []byte
+	err = conn.Raw(func(driverConn any) error {
+		var err error
+		plain, err = driverConn.(*sqlite3.SQLiteConn).Serialize("main")
+		return err
+	})
+	return plain, err
+}
+
+// writeFileAtomically replaces the file with the data, the file is either fully written or left untouched
+func writeFileAtomically(path string, data []byte) error {
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
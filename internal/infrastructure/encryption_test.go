@@ -0,0 +1,271 @@
+package infrastructure
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// useTestDatabase points the database path to a new plain database with a single note in a temporary directory
+func useTestDatabase(t *testing.T) string {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	dbPath := filepath.Join(dir, "nota.sqlite")
+	if err := SetDatabasePath(dbPath); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	for _, stmt := range []string{
+		"PRAGMA journal_mode=WAL",
+		"CREATE TABLE note (id TEXT PRIMARY KEY, title TEXT)",
+		"INSERT INTO note VALUES ('n1', 'top secret title')",
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("failed to create test database: %v", err)
+		}
+	}
+	return dbPath
+}
+
+// readTestNoteTitle reads the title of the note in a plain test database
+func readTestNoteTitle(t *testing.T, dbPath string) string {
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var title string
+	if err := db.QueryRow("SELECT title FROM note WHERE id = 'n1'").Scan(&title); err != nil {
+		t.Fatalf("failed to read plain database: %v", err)
+	}
+	return title
+}
+
+// readFile reads a file the test expects to exist
+func readFile(t *testing.T, path string) []byte {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestEncryptDecryptDatabase(t *testing.T) {
+	dbPath := useTestDatabase(t)
+
+	if err := EncryptDatabase(""); !errors.Is(err, ErrEmptyPassword) {
+		t.Errorf("EncryptDatabase(\"\") error = %v, want ErrEmptyPassword", err)
+	}
+	if err := EncryptDatabase("pw"); err != nil {
+		t.Fatalf("EncryptDatabase() failed: %v", err)
+	}
+	data := readFile(t, dbPath)
+	if !bytes.HasPrefix(data, []byte(encryptedDatabaseMagic)) || bytes.Contains(data, []byte("top secret title")) {
+		t.Error("encrypted database is not marked as encrypted or contains plaintext")
+	}
+	if encrypted, err := IsDatabaseEncrypted(); err != nil || !encrypted {
+		t.Errorf("IsDatabaseEncrypted() = %v, %v, want true", encrypted, err)
+	}
+	for _, suffix := range []string{"-wal", "-shm", ".tmp"} {
+		if _, err := os.Stat(dbPath + suffix); !os.IsNotExist(err) {
+			t.Errorf("%s is left next to the encrypted database", suffix)
+		}
+	}
+	if err := EncryptDatabase("pw"); !errors.Is(err, ErrDatabaseEncrypted) {
+		t.Errorf("EncryptDatabase() of an encrypted database error = %v, want ErrDatabaseEncrypted", err)
+	}
+
+	if err := DecryptDatabase("wrong"); !errors.Is(err, ErrWrongPassword) {
+		t.Errorf("DecryptDatabase() with the wrong password error = %v, want ErrWrongPassword", err)
+	}
+	if !bytes.Equal(readFile(t, dbPath), data) {
+		t.Error("DecryptDatabase() with the wrong password changed the database")
+	}
+	if err := DecryptDatabase("pw"); err != nil {
+		t.Fatalf("DecryptDatabase() failed: %v", err)
+	}
+	if title := readTestNoteTitle(t, dbPath); title != "top secret title" {
+		t.Errorf("decrypted note title = %q, want it kept", title)
+	}
+	if err := DecryptDatabase("pw"); !errors.Is(err, ErrDatabaseNotEncrypted) {
+		t.Errorf("DecryptDatabase() of a plain database error = %v, want ErrDatabaseNotEncrypted", err)
+	}
+}
+
+func TestChangeDatabasePassword(t *testing.T) {
+	dbPath := useTestDatabase(t)
+	if err := ChangeDatabasePassword("pw", "new"); !errors.Is(err, ErrDatabaseNotEncrypted) {
+		t.Errorf("ChangeDatabasePassword() of a plain database error = %v, want ErrDatabaseNotEncrypted", err)
+	}
+	if err := EncryptDatabase("pw"); err != nil {
+		t.Fatal(err)
+	}
+	data := readFile(t, dbPath)
+
+	if err := ChangeDatabasePassword("wrong", "new"); !errors.Is(err, ErrWrongPassword) {
+		t.Errorf("ChangeDatabasePassword() with the wrong password error = %v, want ErrWrongPassword", err)
+	}
+	if err := ChangeDatabasePassword("pw", ""); !errors.Is(err, ErrEmptyPassword) {
+		t.Errorf("ChangeDatabasePassword() to an empty password error = %v, want ErrEmptyPassword", err)
+	}
+	if !bytes.Equal(readFile(t, dbPath), data) {
+		t.Error("a failed ChangeDatabasePassword() changed the database")
+	}
+
+	if err := ChangeDatabasePassword("pw", "new"); err != nil {
+		t.Fatalf("ChangeDatabasePassword() failed: %v", err)
+	}
+	if err := DecryptDatabase("pw"); !errors.Is(err, ErrWrongPassword) {
+		t.Errorf("DecryptDatabase() with the old password error = %v, want ErrWrongPassword", err)
+	}
+	if err := DecryptDatabase("new"); err != nil {
+		t.Fatalf("DecryptDatabase() with the new password failed: %v", err)
+	}
+	if title := readTestNoteTitle(t, dbPath); title != "top secret title" {
+		t.Errorf("decrypted note title = %q, want it kept", title)
+	}
+}
+
+func TestDecryptDatabaseDamaged(t *testing.T) {
+	dbPath := useTestDatabase(t)
+	if err := EncryptDatabase("pw"); err != nil {
+		t.Fatal(err)
+	}
+	data := readFile(t, dbPath)
+
+	corrupted := bytes.Clone(data)
+	corrupted[len(corrupted)/2] ^= 0x01
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"truncated to the magic", data[:len(encryptedDatabaseMagic)], ErrInvalidEncryptedDatabase},
+		{"truncated after the salt", data[:len(encryptedDatabaseMagic)+20], ErrInvalidEncryptedDatabase},
+		{"truncated ciphertext", data[:len(data)-1], ErrWrongPassword},
+		{"corrupted ciphertext", corrupted, ErrWrongPassword},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := os.WriteFile(dbPath, tt.data, 0600); err != nil {
+				t.Fatal(err)
+			}
+			if err := DecryptDatabase("pw"); !errors.Is(err, tt.want) {
+				t.Errorf("DecryptDatabase() error = %v, want %v", err, tt.want)
+			}
+			if err := ChangeDatabasePassword("pw", "new"); !errors.Is(err, tt.want) {
+				t.Errorf("ChangeDatabasePassword() error = %v, want %v", err, tt.want)
+			}
+			if !bytes.Equal(readFile(t, dbPath), tt.data) {
+				t.Error("the damaged database was changed")
+			}
+		})
+	}
+}
+
+func TestWriteFileAtomically(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nota.sqlite")
+	if err := os.WriteFile(path, []byte("old"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writeFileAtomically(path, []byte("new")); err != nil {
+		t.Fatalf("writeFileAtomically() failed: %v", err)
+	}
+	if data := readFile(t, path); string(data) != "new" {
+		t.Errorf("file = %q, want %q", data, "new")
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Error("the temporary file is left behind")
+	}
+
+	// The file is left untouched when the temporary file can't be written
+	if err := os.Mkdir(path+".tmp", 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeFileAtomically(path, []byte("newer")); err == nil {
+		t.Error("writeFileAtomically() succeeded without a temporary file")
+	}
+	if data := readFile(t, path); string(data) != "new" {
+		t.Errorf("file after a failed write = %q, want %q", data, "new")
+	}
+}
+
+// readEncryptedNoteTitle decrypts the database file and reads the title of the note n1
+func readEncryptedNoteTitle(t *testing.T, dbPath string, password string) string {
+	_, _, plain, err := decryptDatabase(readFile(t, dbPath), password)
+	if err != nil {
+		t.Fatalf("failed to decrypt database: %v", err)
+	}
+	db, conn, err := openDeserialized(plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	defer conn.Close()
+	var title string
+	if err := conn.QueryRowContext(context.Background(), "SELECT title FROM note WHERE id = 'n1'").Scan(&title); err != nil {
+		t.Fatalf("failed to read decrypted database: %v", err)
+	}
+	return title
+}
+
+func TestUnlockedDatabaseFlushesCommits(t *testing.T) {
+	db := initTestDatabase(t)
+	writeTestNote(t, db, "plain")
+	if err := CloseDatabase(); err != nil {
+		t.Fatal(err)
+	}
+	if err := EncryptDatabase("pw"); err != nil {
+		t.Fatal(err)
+	}
+	db, err := UnlockDatabase("pw")
+	if err != nil {
+		t.Fatalf("UnlockDatabase() failed: %v", err)
+	}
+	dbPath := getDatabasePath()
+
+	writeTestNote(t, db, "statement")
+	if got := readEncryptedNoteTitle(t, dbPath, "pw"); got != "statement" {
+		t.Errorf("title on disk after a statement = %q, want %q", got, "statement")
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		return tx.Exec("UPDATE note SET title = 'transaction' WHERE id = 'n1'").Error
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := readEncryptedNoteTitle(t, dbPath, "pw"); got != "transaction" {
+		t.Errorf("title on disk after a transaction = %q, want %q", got, "transaction")
+	}
+
+	rollback := errors.New("rollback")
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("UPDATE note SET title = 'rolled back' WHERE id = 'n1'").Error; err != nil {
+			return err
+		}
+		return rollback
+	})
+	if !errors.Is(err, rollback) {
+		t.Fatalf("Transaction() error = %v, want the rollback", err)
+	}
+	if got := readEncryptedNoteTitle(t, dbPath, "pw"); got != "transaction" {
+		t.Errorf("title on disk after a rollback = %q, want %q", got, "transaction")
+	}
+
+	if _, err := db.DB(); err != nil {
+		t.Errorf("DB() of the unlocked database failed: %v", err)
+	}
+}
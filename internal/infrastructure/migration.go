@@ -0,0 +1,195 @@
+package infrastructure
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/curtisnewbie/miso/flow"
+	"github.com/curtisnewbie/miso/middleware/dbquery"
+	"github.com/curtisnewbie/miso/util/atom"
+	"gorm.io/gorm"
+)
+
+// Migration is a versioned change of the database schema, migrations are applied in the order of their versions
+// and each of them is applied only once
+type Migration struct {
+	Version int
+	Name    string
+
+	// Migrate applies the migration, it runs in a transaction together with recording the migration as applied
+	Migrate func(rail flow.Rail, tx *gorm.DB) error
+
+	// Available checks whether the migration can be applied by this build (e.g., it needs a SQLite extension), nil when it always can.
+	// A migration that is not available is skipped and applied once it is.
+	Available func(rail flow.Rail, db *gorm.DB) (bool, error)
+
+	// Disable undoes the parts of an applied migration that would break this build when it's no longer available,
+	// the migration is then applied again once it is
+	Disable func(rail flow.Rail, tx *gorm.DB) error
+}
+
+// SchemaMigration records a migration applied to the database
+type SchemaMigration struct {
+	Version   int       `gorm:"primaryKey"`
+	Name      string    `gorm:"not null"`
+	AppliedAt atom.Time `gorm:"not null"`
+}
+
+// TableName specifies the table name for GORM
+func (SchemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// MigrationStatus is the state of a migration in the opened database
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	Available bool
+	AppliedAt *atom.Time // nil when the migration has not been applied
+}
+
+// backupFunc copies the database before it's migrated and returns the path of the copy
+type backupFunc func(rail flow.Rail, db *gorm.DB) (string, error)
+
+// migrateDatabase applies the pending migrations, the database is backed up first unless it's a new database
+func migrateDatabase(rail flow.Rail, db *gorm.DB, backup backupFunc) error {
+	if err := db.AutoMigrate(&SchemaMigration{}); err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+	applied, err := loadAppliedMigrations(rail, db)
+	if err != nil {
+		return err
+	}
+
+	var pending []Migration
+	for _, m := range migrations {
+		available := true
+		if m.Available != nil {
+			if available, err = m.Available(rail, db); err != nil {
+				return fmt.Errorf("failed to check migration %d %s: %w", m.Version, m.Name, err)
+			}
+		}
+		if !available {
+			// It may have been applied by another build, or before versioned migrations
+			rail.Warnf("Migration %d %s is not available in this build, skipped", m.Version, m.Name)
+			if err := disableMigration(rail, db, m); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, ok := applied[m.Version]; !ok {
+			pending = append(pending, m)
+		}
+	}
+	for version, r := range applied {
+		if version > migrations[len(migrations)-1].Version {
+			rail.Warnf("Database has migration %d %s applied by a newer version of nota", version, r.Name)
+		}
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	// A database created just now has nothing worth backing up
+	backupPath := ""
+	if len(applied) > 0 || db.Migrator().HasTable("note") {
+		if backupPath, err = backup(rail, db); err != nil {
+			return fmt.Errorf("failed to back up database before migrating: %w", err)
+		}
+		rail.Infof("Backed up database to %s before migrating", backupPath)
+	}
+
+	for _, m := range pending {
+		rail.Infof("Applying migration %d %s", m.Version, m.Name)
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := m.Migrate(rail, tx); err != nil {
+				return err
+			}
+			return dbquery.NewQuery(rail, tx).Table("schema_migrations").CreateAny(&SchemaMigration{Version: m.Version, Name: m.Name, AppliedAt: atom.Now()})
+		})
+		if err != nil {
+			if backupPath != "" {
+				return fmt.Errorf("migration %d %s failed, the database before migrating is backed up at %s: %w", m.Version, m.Name, backupPath, err)
+			}
+			return fmt.Errorf("migration %d %s failed: %w", m.Version, m.Name, err)
+		}
+	}
+	return nil
+}
+
+// disableMigration undoes a migration that is not available and forgets that it was applied
+func disableMigration(rail flow.Rail, db *gorm.DB, m Migration) error {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if m.Disable != nil {
+			if err := m.Disable(rail, tx); err != nil {
+				return err
+			}
+		}
+		return dbquery.NewQuery(rail, tx).Table("schema_migrations").Where("version = ?", m.Version).DeleteAny()
+	})
+	if err != nil {
+		return fmt.Errorf("failed to disable migration %d %s: %w", m.Version, m.Name, err)
+	}
+	return nil
+}
+
+// loadAppliedMigrations loads the applied migrations by version
+func loadAppliedMigrations(rail flow.Rail, db *gorm.DB) (map[int]SchemaMigration, error) {
+	var records []SchemaMigration
+	_, err := dbquery.NewQuery(rail, db).Table("schema_migrations").Scan(&records)
+	if err != nil {
+		return nil, err
+	}
+	applied := make(map[int]SchemaMigration, len(records))
+	for _, r := range records {
+		applied[r.Version] = r
+	}
+	return applied, nil
+}
+
+// ListMigrations lists every known migration with whether it has been applied to the database
+func ListMigrations(rail flow.Rail, db *gorm.DB) ([]MigrationStatus, error) {
+	applied, err := loadAppliedMigrations(rail, db)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	for _, m := range migrations {
+		status := MigrationStatus{Version: m.Version, Name: m.Name, Available: true}
+		if m.Available != nil {
+			if status.Available, err = m.Available(rail, db); err != nil {
+				return nil, err
+			}
+		}
+		if r, ok := applied[m.Version]; ok {
+			appliedAt := r.AppliedAt
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// backupBeforeMigrating copies the plain database file with VACUUM INTO, which is consistent while the database is open
+func backupBeforeMigrating(dbPath string) backupFunc {
+	return vacuumInto(dbPath, BackupKindPreMigration)
+}
+
+// backupEncryptedBeforeMigrating copies the encrypted database file, so that the backup is as unreadable as the database
+func backupEncryptedBeforeMigrating(dbPath string) backupFunc {
+	return func(rail flow.Rail, db *gorm.DB) (string, error) {
+		backupPath, err := newBackupPath(dbPath, BackupKindPreMigration)
+		if err != nil {
+			return "", err
+		}
+		data, err := os.ReadFile(dbPath)
+		if err != nil {
+			return "", err
+		}
+		if err := writeFileAtomically(backupPath, data); err != nil {
+			return "", err
+		}
+		return backupPath, nil
+	}
+}
@@ -0,0 +1,148 @@
+package infrastructure
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/curtisnewbie/miso/flow"
+	"github.com/curtisnewbie/miso/middleware/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestMigrationRegistry(t *testing.T) {
+	if len(migrations) == 0 {
+		t.Fatal("no migrations registered")
+	}
+	names := make(map[string]bool)
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			t.Errorf("migrations[%d] has version %d, want %d, versions must start at 1 and have no gaps", i, m.Version, i+1)
+		}
+		if m.Name == "" || names[m.Name] {
+			t.Errorf("migration %d has an empty or duplicate name %q", m.Version, m.Name)
+		}
+		names[m.Name] = true
+		if m.Migrate == nil {
+			t.Errorf("migration %d %s has no Migrate", m.Version, m.Name)
+		}
+		if m.Disable != nil && m.Available == nil {
+			t.Errorf("migration %d %s has Disable without Available, it is never disabled", m.Version, m.Name)
+		}
+	}
+}
+
+// openTestDatabase opens a new database file that is closed when the test ends
+func openTestDatabase(t *testing.T) *gorm.DB {
+	db, err := sqlite.NewConn(filepath.Join(t.TempDir(), "nota.sqlite"), false)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+// countingBackup returns a backupFunc that only counts how many times it's called
+func countingBackup(backups *int) backupFunc {
+	return func(rail flow.Rail, db *gorm.DB) (string, error) {
+		*backups++
+		return "backup.sqlite", nil
+	}
+}
+
+func TestMigrateDatabase(t *testing.T) {
+	rail := flow.EmptyRail()
+	db := openTestDatabase(t)
+	backups := 0
+	backup := countingBackup(&backups)
+
+	if err := migrateDatabase(rail, db, backup); err != nil {
+		t.Fatalf("migrating a new database failed: %v", err)
+	}
+	if backups != 0 {
+		t.Errorf("a new database was backed up %d times, want 0", backups)
+	}
+	statuses, err := ListMigrations(rail, db)
+	if err != nil {
+		t.Fatalf("failed to list migrations: %v", err)
+	}
+	if len(statuses) != len(migrations) {
+		t.Fatalf("ListMigrations() returned %d migrations, want %d", len(statuses), len(migrations))
+	}
+	for _, s := range statuses {
+		if s.Available != (s.AppliedAt != nil) {
+			t.Errorf("migration %d %s available: %v, applied: %v, want the available ones applied", s.Version, s.Name, s.Available, s.AppliedAt != nil)
+		}
+	}
+
+	// Migrating again has nothing to apply, so nothing to back up
+	if err := migrateDatabase(rail, db, backup); err != nil {
+		t.Fatalf("migrating a migrated database failed: %v", err)
+	}
+	if backups != 0 {
+		t.Errorf("a migrated database was backed up %d times, want 0", backups)
+	}
+}
+
+func TestMigrateDatabaseBeforeVersionedMigrations(t *testing.T) {
+	rail := flow.EmptyRail()
+	db := openTestDatabase(t)
+	if err := db.AutoMigrate(&v1Note{}); err != nil {
+		t.Fatalf("failed to create the note table: %v", err)
+	}
+	if err := db.Exec("INSERT INTO note (id, title, content, created_at, updated_at) VALUES ('n1', 'kept', 'body', '2024-01-01 00:00:00', '2024-01-01 00:00:00')").Error; err != nil {
+		t.Fatalf("failed to insert note: %v", err)
+	}
+
+	backups := 0
+	if err := migrateDatabase(rail, db, countingBackup(&backups)); err != nil {
+		t.Fatalf("migrating an existing database failed: %v", err)
+	}
+	if backups != 1 {
+		t.Errorf("an existing database was backed up %d times, want 1", backups)
+	}
+	var title string
+	if err := db.Raw("SELECT title FROM note WHERE id = 'n1'").Scan(&title).Error; err != nil || title != "kept" {
+		t.Errorf("note after migrating = %q, %v, want it kept", title, err)
+	}
+}
+
+func TestMigrateNoteLinkBackfill(t *testing.T) {
+	rail := flow.EmptyRail()
+	db := openTestDatabase(t)
+	if err := db.AutoMigrate(&v1Note{}, &v1NoteLink{}); err != nil {
+		t.Fatalf("failed to create the tables: %v", err)
+	}
+	notes := []struct {
+		id, title, content string
+		encrypted          bool
+	}{
+		{"a", "A", "See [[B]], [[c|the other one]], [[A]] and [[Missing]], [[B]] again", false},
+		{"b", "B", "Back to [[A]]", false},
+		{"c", "C", "", false},
+		{"d", "D", "[[B]]", true},
+	}
+	for _, n := range notes {
+		err := db.Exec("INSERT INTO note (id, title, content, encrypted, created_at, updated_at) VALUES (?, ?, ?, ?, datetime(), datetime())",
+			n.id, n.title, n.content, n.encrypted).Error
+		if err != nil {
+			t.Fatalf("failed to insert note: %v", err)
+		}
+	}
+
+	backups := 0
+	if err := migrateDatabase(rail, db, countingBackup(&backups)); err != nil {
+		t.Fatalf("migrating an existing database failed: %v", err)
+	}
+	var links []string
+	if err := db.Raw("SELECT source_id || '->' || target_id FROM note_link ORDER BY source_id, target_id").Scan(&links).Error; err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a->b", "a->c", "b->a"}; !reflect.DeepEqual(links, want) {
+		t.Errorf("links after migrating = %v, want %v", links, want)
+	}
+}
@@ -0,0 +1,279 @@
+package infrastructure
+
+import (
+	"fmt"
+
+	"github.com/curtisnewbie/miso/flow"
+	"github.com/curtisnewbie/miso/middleware/dbquery"
+	"github.com/curtisnewbie/miso/util/atom"
+	"github.com/curtisnewbie/nota/internal/domain"
+	"gorm.io/gorm"
+)
+
+// migrations is the registry of schema migrations, in the order of their versions
+//
+// A migration that has been released must never be changed or removed, the schema is changed by appending a new one.
+var migrations = []Migration{
+	{Version: 1, Name: "initial_schema", Migrate: migrateInitialSchema},
+	{Version: 2, Name: "note_fts", Migrate: migrateNoteFTS, Available: isFTS5Available, Disable: disableNoteFTS},
+	{Version: 3, Name: "note_reminder", Migrate: migrateNoteReminder},
+	{Version: 4, Name: "note_task", Migrate: migrateNoteTask},
+	{Version: 5, Name: "note_starred", Migrate: migrateNoteStarred},
+	{Version: 6, Name: "note_archived", Migrate: migrateNoteArchived},
+	{Version: 7, Name: "note_link_backfill", Migrate: migrateNoteLinkBackfill},
+}
+
+// The tables as they were before versioned migrations, databases created back then were migrated with AutoMigrate
+// of these models, they must not follow the changes of the domain models
+type (
+	v1Note struct {
+		ID         string     `gorm:"primaryKey"`
+		Title      string     `gorm:"not null"`
+		Content    string     `gorm:"type:text"`
+		Version    int        `gorm:"not null;default:1"`
+		NotebookID string     `gorm:"not null;default:'';index"`
+		Encrypted  bool       `gorm:"not null;default:false"`
+		CreatedAt  atom.Time  `gorm:"not null"`
+		UpdatedAt  atom.Time  `gorm:"not null"`
+		DeletedAt  *atom.Time `gorm:"index"`
+		Metadata   string     `gorm:"type:text"`
+	}
+	v1NoteRevision struct {
+		ID        string    `gorm:"primaryKey"`
+		NoteID    string    `gorm:"not null;index"`
+		Version   int       `gorm:"not null"`
+		Title     string    `gorm:"not null"`
+		Content   string    `gorm:"type:text"`
+		UpdatedAt atom.Time `gorm:"not null"`
+		CreatedAt atom.Time `gorm:"not null"`
+	}
+	v1Tag struct {
+		ID        string    `gorm:"primaryKey"`
+		Name      string    `gorm:"not null;uniqueIndex"`
+		CreatedAt atom.Time `gorm:"not null"`
+	}
+	v1NoteTag struct {
+		NoteID string `gorm:"primaryKey"`
+		TagID  string `gorm:"primaryKey;index"`
+	}
+	v1NoteLink struct {
+		SourceID string `gorm:"primaryKey"`
+		TargetID string `gorm:"primaryKey;index"`
+	}
+	v1Notebook struct {
+		ID        string    `gorm:"primaryKey"`
+		Name      string    `gorm:"not null"`
+		ParentID  string    `gorm:"not null;default:'';index"`
+		CreatedAt atom.Time `gorm:"not null"`
+		UpdatedAt atom.Time `gorm:"not null"`
+	}
+	v1Config struct {
+		Name  string `gorm:"primaryKey"`
+		Value string
+	}
+	v1Draft struct {
+		NoteID      string    `gorm:"primaryKey"`
+		Title       string    `gorm:"not null;default:''"`
+		Content     string    `gorm:"type:text"`
+		NotebookID  string    `gorm:"not null;default:''"`
+		BaseVersion int       `gorm:"not null;default:0"`
+		UpdatedAt   atom.Time `gorm:"not null"`
+	}
+)
+
+func (v1Note) TableName() string         { return "note" }
+func (v1NoteRevision) TableName() string { return "note_revision" }
+func (v1Tag) TableName() string          { return "tag" }
+func (v1NoteTag) TableName() string      { return "note_tag" }
+func (v1NoteLink) TableName() string     { return "note_link" }
+func (v1Notebook) TableName() string     { return "notebook" }
+func (v1Config) TableName() string       { return "config" }
+func (v1Draft) TableName() string        { return "draft" }
+
+// migrateInitialSchema creates the tables, or brings the tables of a database created before versioned migrations up to date
+func migrateInitialSchema(rail flow.Rail, tx *gorm.DB) error {
+	return tx.AutoMigrate(&v1Note{}, &v1NoteRevision{}, &v1Tag{}, &v1NoteTag{}, &v1NoteLink{}, &v1Notebook{}, &v1Config{}, &v1Draft{})
+}
+
+// isFTS5Available checks whether SQLite is compiled with FTS5, go-sqlite3 only includes it with the sqlite_fts5 build tag
+func isFTS5Available(rail flow.Rail, db *gorm.DB) (bool, error) {
+	var fts5 bool
+	err := dbquery.NewQuery(rail, db).Raw("SELECT sqlite_compileoption_used('ENABLE_FTS5')").ScanVal(&fts5)
+	return fts5, err
+}
+
+// migrateNoteFTS creates the FTS5 index for notes and the triggers that keep it in sync with the note table
+//
+// The index may be left behind by a build without FTS5 (see disableNoteFTS), it's rebuilt since notes may have
+// changed without the triggers.
+func migrateNoteFTS(rail flow.Rail, tx *gorm.DB) error {
+	statements := []string{
+		`CREATE VIRTUAL TABLE IF NOT EXISTS note_fts USING fts5(
+			title, content,
+			content='note', content_rowid='rowid',
+			tokenize='unicode61 remove_diacritics 2'
+		)`,
+		`CREATE TRIGGER IF NOT EXISTS note_fts_ai AFTER INSERT ON note BEGIN
+			INSERT INTO note_fts(rowid, title, content) VALUES (new.rowid, new.title, new.content);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS note_fts_ad AFTER DELETE ON note BEGIN
+			INSERT INTO note_fts(note_fts, rowid, title, content) VALUES ('delete', old.rowid, old.title, old.content);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS note_fts_au AFTER UPDATE OF title, content ON note BEGIN
+			INSERT INTO note_fts(note_fts, rowid, title, content) VALUES ('delete', old.rowid, old.title, old.content);
+			INSERT INTO note_fts(rowid, title, content) VALUES (new.rowid, new.title, new.content);
+		END`,
+		`INSERT INTO note_fts(note_fts) VALUES ('rebuild')`,
+	}
+	for _, stmt := range statements {
+		if err := dbquery.NewQuery(rail, tx).ExecAny(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// disableNoteFTS drops the triggers of the FTS5 index, they would make every write to note fail without FTS5
+//
+// The index itself can't be dropped without FTS5, it's left for the next build with FTS5 to rebuild.
+func disableNoteFTS(rail flow.Rail, tx *gorm.DB) error {
+	for _, trigger := range []string{"note_fts_ai", "note_fts_ad", "note_fts_au"} {
+		if err := dbquery.NewQuery(rail, tx).ExecAny(fmt.Sprintf("DROP TRIGGER IF EXISTS %s", trigger)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateNoteReminder adds the due date of the reminder of a note, and when the reminder was last sent
+func migrateNoteReminder(rail flow.Rail, tx *gorm.DB) error {
+	statements := []string{
+		`ALTER TABLE note ADD COLUMN due_at DATETIME`,
+		`ALTER TABLE note ADD COLUMN reminded_at DATETIME`,
+		`CREATE INDEX IF NOT EXISTS idx_note_due_at ON note(due_at)`,
+	}
+	for _, stmt := range statements {
+		if err := dbquery.NewQuery(rail, tx).ExecAny(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateNoteTask creates the index of the markdown checkboxes in notes and fills it with the tasks of the existing notes
+func migrateNoteTask(rail flow.Rail, tx *gorm.DB) error {
+	statements := []string{
+		`CREATE TABLE IF NOT EXISTS note_task (
+			note_id TEXT NOT NULL,
+			line INTEGER NOT NULL,
+			text TEXT NOT NULL,
+			done NUMERIC NOT NULL DEFAULT 0,
+			due_date TEXT NOT NULL DEFAULT '',
+			tags TEXT NOT NULL DEFAULT '',
+			PRIMARY KEY (note_id, line)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_note_task_due_date ON note_task(due_date)`,
+	}
+	for _, stmt := range statements {
+		if err := dbquery.NewQuery(rail, tx).ExecAny(stmt); err != nil {
+			return err
+		}
+	}
+
+	var notes []struct {
+		ID      string
+		Content string
+	}
+	if _, err := dbquery.NewQuery(rail, tx).Table("note").Select("id, content").Where("encrypted = 0").Scan(&notes); err != nil {
+		return err
+	}
+	for _, note := range notes {
+		for _, task := range domain.ParseTasks(note.Content) {
+			err := dbquery.NewQuery(rail, tx).ExecAny(
+				"INSERT OR REPLACE INTO note_task (note_id, line, text, done, due_date, tags) VALUES (?, ?, ?, ?, ?, ?)",
+				note.ID, task.Line, task.Text, task.Done, task.DueDate, task.Tags)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// migrateNoteStarred adds the flag of the notes that are listed first
+func migrateNoteStarred(rail flow.Rail, tx *gorm.DB) error {
+	statements := []string{
+		`ALTER TABLE note ADD COLUMN starred NUMERIC NOT NULL DEFAULT 0`,
+		`CREATE INDEX IF NOT EXISTS idx_note_starred ON note(starred)`,
+	}
+	for _, stmt := range statements {
+		if err := dbquery.NewQuery(rail, tx).ExecAny(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateNoteArchived adds when a note was archived, archived notes are left out of the note list and searches
+func migrateNoteArchived(rail flow.Rail, tx *gorm.DB) error {
+	statements := []string{
+		`ALTER TABLE note ADD COLUMN archived_at DATETIME`,
+		`CREATE INDEX IF NOT EXISTS idx_note_archived_at ON note(archived_at)`,
+	}
+	for _, stmt := range statements {
+		if err := dbquery.NewQuery(rail, tx).ExecAny(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateNoteLinkBackfill records the links of the existing notes, notes saved before links were indexed have none
+func migrateNoteLinkBackfill(rail flow.Rail, tx *gorm.DB) error {
+	var notes []struct {
+		ID      string
+		Content string
+	}
+	if _, err := dbquery.NewQuery(rail, tx).Table("note").Select("id, content").Where("encrypted = 0").Scan(&notes); err != nil {
+		return err
+	}
+	for _, note := range notes {
+		seen := map[string]bool{note.ID: true}
+		for _, link := range domain.ParseLinks(note.Content) {
+			targetID, err := resolveMigratedLink(rail, tx, link)
+			if err != nil {
+				return err
+			}
+			if targetID == "" || seen[targetID] {
+				continue
+			}
+			seen[targetID] = true
+			err = dbquery.NewQuery(rail, tx).ExecAny("INSERT OR IGNORE INTO note_link (source_id, target_id) VALUES (?, ?)", note.ID, targetID)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// resolveMigratedLink finds the ID of the note a link refers to the way notes are resolved when saved, labelled links
+// are looked up by ID first and the other links by title first, the ID is empty when there is no such note
+func resolveMigratedLink(rail flow.Rail, tx *gorm.DB, link domain.Link) (string, error) {
+	lookups := []string{"id = ?", "title = ?"}
+	if link.Label == "" {
+		lookups[0], lookups[1] = lookups[1], lookups[0]
+	}
+	for _, where := range lookups {
+		var id string
+		ok, err := dbquery.NewQuery(rail, tx).Table("note").Select("id").
+			Where(where+" AND deleted_at IS NULL", link.Target).Order("updated_at DESC").Limit(1).ScanAny(&id)
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			return id, nil
+		}
+	}
+	return "", nil
+}
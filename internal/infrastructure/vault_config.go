@@ -0,0 +1,95 @@
+package infrastructure
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"github.com/curtisnewbie/miso/flow"
+)
+
+const (
+	// vaultConfigPath is the file that remembers the databases, it's kept outside of any database so that it's
+	// available before one is opened
+	vaultConfigPath = "$HOME/nota/vaults.json"
+
+	// maxRecentDatabases is how many recently opened databases are remembered
+	maxRecentDatabases = 10
+)
+
+// vaultConfigMu serializes the updates of the vault config file
+var vaultConfigMu sync.Mutex
+
+// VaultConfig lists the databases (vaults) that nota has opened
+type VaultConfig struct {
+	Default string   `json:"default,omitempty"` // database opened on startup when neither --db nor $NOTA_DB is given
+	Recent  []string `json:"recent"`            // recently opened databases, most recent first
+}
+
+// LoadVaultConfig loads the vault config, an empty config is returned when it can't be read
+func LoadVaultConfig() VaultConfig {
+	var config VaultConfig
+	data, err := os.ReadFile(os.ExpandEnv(vaultConfigPath))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			flow.EmptyRail().Warnf("Failed to read vault config: %v", err)
+		}
+		return config
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		flow.EmptyRail().Warnf("Failed to parse vault config: %v", err)
+	}
+	return config
+}
+
+// RecentDatabases returns the recently opened databases, most recent first
+func RecentDatabases() []string {
+	return LoadVaultConfig().Recent
+}
+
+// SetDefaultDatabase sets the database opened on startup, an empty path restores the default path
+func SetDefaultDatabase(path string) error {
+	vaultConfigMu.Lock()
+	defer vaultConfigMu.Unlock()
+
+	config := LoadVaultConfig()
+	config.Default = ""
+	if path != "" {
+		config.Default = normalizeDatabasePath(path)
+	}
+	return saveVaultConfig(config)
+}
+
+// addRecentDatabase moves the database to the top of the recently opened databases
+func addRecentDatabase(rail flow.Rail, path string) {
+	vaultConfigMu.Lock()
+	defer vaultConfigMu.Unlock()
+
+	config := LoadVaultConfig()
+	if len(config.Recent) > 0 && config.Recent[0] == path {
+		return
+	}
+	config.Recent = slices.DeleteFunc(config.Recent, func(recent string) bool { return recent == path })
+	config.Recent = append([]string{path}, config.Recent...)
+	if len(config.Recent) > maxRecentDatabases {
+		config.Recent = config.Recent[:maxRecentDatabases]
+	}
+	if err := saveVaultConfig(config); err != nil {
+		rail.Warnf("Failed to save vault config: %v", err)
+	}
+}
+
+// saveVaultConfig writes the vault config
+func saveVaultConfig(config VaultConfig) error {
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := os.ExpandEnv(vaultConfigPath)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return writeFileAtomically(path, data)
+}
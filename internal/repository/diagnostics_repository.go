@@ -0,0 +1,177 @@
+package repository
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/curtisnewbie/miso/flow"
+	"github.com/curtisnewbie/miso/middleware/dbquery"
+	"github.com/mattn/go-sqlite3"
+	"gorm.io/gorm"
+)
+
+// RawNote is a note as it's stored, without the conversions that hide malformed values when notes are loaded
+type RawNote struct {
+	ID        string
+	Title     string
+	Content   string
+	Encrypted bool
+	CreatedAt RawTime
+	UpdatedAt RawTime
+	DeletedAt RawTime
+	Metadata  *string // nil when NULL
+}
+
+// RawTime is a timestamp as it's stored, Valid is false when it's NULL or can't be parsed
+type RawTime struct {
+	Text  string
+	Null  bool
+	Time  time.Time
+	Valid bool
+}
+
+// DiagnosticsRepository defines the interface for checking and repairing the stored data
+type DiagnosticsRepository interface {
+	CheckIntegrity(rail flow.Rail) ([]string, error)
+	Reindex(rail flow.Rail) error
+	FindRawNotes(rail flow.Rail) ([]RawNote, error)
+	FindRawNote(rail flow.Rail, id string) (*RawNote, bool, error)
+	UpdateColumn(rail flow.Rail, id string, column string, value any) error
+}
+
+// SQLiteDiagnosticsRepository implements DiagnosticsRepository for SQLite
+type SQLiteDiagnosticsRepository struct {
+	db *gorm.DB
+}
+
+// NewSQLiteDiagnosticsRepository creates a new SQLite diagnostics repository
+func NewSQLiteDiagnosticsRepository(db *gorm.DB) DiagnosticsRepository {
+	return &SQLiteDiagnosticsRepository{db: db}
+}
+
+// rawNoteRow is the row of a raw note, the timestamps are cast to text so that the driver doesn't parse them
+type rawNoteRow struct {
+	ID            string
+	Title         *string
+	Content       *string
+	Encrypted     bool
+	CreatedAt     *string
+	CreatedAtType string
+	UpdatedAt     *string
+	UpdatedAtType string
+	DeletedAt     *string
+	DeletedAtType string
+	Metadata      *string
+}
+
+const rawNoteColumns = `id, title, content, encrypted,
+	CAST(created_at AS TEXT) AS created_at, typeof(created_at) AS created_at_type,
+	CAST(updated_at AS TEXT) AS updated_at, typeof(updated_at) AS updated_at_type,
+	CAST(deleted_at AS TEXT) AS deleted_at, typeof(deleted_at) AS deleted_at_type,
+	CAST(metadata AS TEXT) AS metadata`
+
+// CheckIntegrity runs PRAGMA integrity_check, the problems found are returned and none when the database is intact
+func (r *SQLiteDiagnosticsRepository) CheckIntegrity(rail flow.Rail) ([]string, error) {
+	rail.Debugf("Checking database integrity")
+	var results []string
+	_, err := dbquery.NewQuery(rail, r.db).Raw("PRAGMA integrity_check").Scan(&results)
+	if err != nil {
+		return nil, err
+	}
+	if len(results) == 1 && results[0] == "ok" {
+		return nil, nil
+	}
+	return results, nil
+}
+
+// Reindex rebuilds every index, which repairs the indexes that don't match their tables
+func (r *SQLiteDiagnosticsRepository) Reindex(rail flow.Rail) error {
+	rail.Infof("Rebuilding indexes")
+	return dbquery.NewQuery(rail, r.db).ExecAny("REINDEX")
+}
+
+// FindRawNotes finds every note, including the ones in the trash, as stored
+func (r *SQLiteDiagnosticsRepository) FindRawNotes(rail flow.Rail) ([]RawNote, error) {
+	rail.Debugf("Finding raw notes")
+	var rows []rawNoteRow
+	_, err := dbquery.NewQuery(rail, r.db).Raw("SELECT " + rawNoteColumns + " FROM note ORDER BY rowid").Scan(&rows)
+	if err != nil {
+		return nil, err
+	}
+	notes := make([]RawNote, 0, len(rows))
+	for _, row := range rows {
+		notes = append(notes, row.toRawNote())
+	}
+	return notes, nil
+}
+
+// FindRawNote finds a note as stored
+func (r *SQLiteDiagnosticsRepository) FindRawNote(rail flow.Rail, id string) (*RawNote, bool, error) {
+	var row rawNoteRow
+	ok, err := dbquery.NewQuery(rail, r.db).Raw("SELECT "+rawNoteColumns+" FROM note WHERE id = ?", id).ScanAny(&row)
+	if err != nil || !ok {
+		return nil, ok, err
+	}
+	note := row.toRawNote()
+	return &note, true, nil
+}
+
+// UpdateColumn replaces a column of a note as is, neither the version nor updated_at of the note is changed
+func (r *SQLiteDiagnosticsRepository) UpdateColumn(rail flow.Rail, id string, column string, value any) error {
+	rail.Infof("Repairing %s of note %q", column, id)
+	return dbquery.NewQuery(rail, r.db).Table("note").Where("id = ?", id).Set(column, value).UpdateAny()
+}
+
+// toRawNote converts the row, parsing the timestamps like the driver does
+func (row rawNoteRow) toRawNote() RawNote {
+	note := RawNote{
+		ID:        row.ID,
+		Encrypted: row.Encrypted,
+		CreatedAt: parseRawTime(row.CreatedAt, row.CreatedAtType),
+		UpdatedAt: parseRawTime(row.UpdatedAt, row.UpdatedAtType),
+		DeletedAt: parseRawTime(row.DeletedAt, row.DeletedAtType),
+		Metadata:  row.Metadata,
+	}
+	if row.Title != nil {
+		note.Title = *row.Title
+	}
+	if row.Content != nil {
+		note.Content = *row.Content
+	}
+	return note
+}
+
+// parseRawTime parses a stored timestamp, the driver silently reads a timestamp it can't parse as the zero time
+func parseRawTime(text *string, sqlType string) RawTime {
+	if text == nil || sqlType == "null" {
+		return RawTime{Null: true}
+	}
+	t := RawTime{Text: *text}
+	switch sqlType {
+	case "integer":
+		// Unix time in seconds, or in milliseconds when it's too large to be seconds
+		if v, err := strconv.ParseInt(*text, 10, 64); err == nil {
+			if v > 1e12 || v < -1e12 {
+				t.Time = time.UnixMilli(v)
+			} else {
+				t.Time = time.Unix(v, 0)
+			}
+			t.Valid = true
+		}
+	case "text":
+		s := strings.TrimSuffix(*text, "Z")
+		for _, format := range sqlite3.SQLiteTimestampFormats {
+			if parsed, err := time.ParseInLocation(format, s, time.UTC); err == nil {
+				t.Time = parsed
+				t.Valid = true
+				break
+			}
+		}
+	}
+	// The zero time is what an unparsable timestamp turns into, and what a note without timestamps is saved with
+	if t.Valid && t.Time.Year() <= 1 {
+		t.Valid = false
+	}
+	return t
+}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"github.com/curtisnewbie/miso/flow"
+	"github.com/curtisnewbie/miso/middleware/dbquery"
+	"github.com/curtisnewbie/miso/util/atom"
+	"github.com/curtisnewbie/nota/internal/domain"
+	"gorm.io/gorm"
+)
+
+// DraftRepository defines the interface for draft data operations
+type DraftRepository interface {
+	Save(rail flow.Rail, draft *domain.Draft) error
+	FindAll(rail flow.Rail) ([]*domain.Draft, error)
+	Delete(rail flow.Rail, noteID string) error
+	DeleteAll(rail flow.Rail) error
+}
+
+// SQLiteDraftRepository implements DraftRepository for SQLite
+type SQLiteDraftRepository struct {
+	db *gorm.DB
+}
+
+// NewSQLiteDraftRepository creates a new SQLite draft repository
+func NewSQLiteDraftRepository(db *gorm.DB) DraftRepository {
+	return &SQLiteDraftRepository{db: db}
+}
+
+// Save creates or replaces the draft of a note
+func (r *SQLiteDraftRepository) Save(rail flow.Rail, draft *domain.Draft) error {
+	rail.Debugf("Saving draft of note: %q", draft.NoteID)
+	draft.UpdatedAt = atom.Now()
+	err := dbquery.NewQuery(rail, r.db).ExecAny(
+		`INSERT INTO draft (note_id, title, content, notebook_id, base_version, updated_at) VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(note_id) DO UPDATE SET title = excluded.title, content = excluded.content,
+		notebook_id = excluded.notebook_id, base_version = excluded.base_version, updated_at = excluded.updated_at`,
+		draft.NoteID, draft.Title, draft.Content, draft.NotebookID, draft.BaseVersion, draft.UpdatedAt,
+	)
+	if err != nil {
+		rail.Errorf("Failed to save draft of note %q: %v", draft.NoteID, err)
+	}
+	return err
+}
+
+// FindAll finds all drafts, the most recently updated first
+func (r *SQLiteDraftRepository) FindAll(rail flow.Rail) ([]*domain.Draft, error) {
+	rail.Debugf("Finding all drafts")
+	var drafts []*domain.Draft
+	_, err := dbquery.NewQuery(rail, r.db).Table("draft").Order("updated_at DESC").Scan(&drafts)
+	return drafts, err
+}
+
+// Delete deletes the draft of a note
+func (r *SQLiteDraftRepository) Delete(rail flow.Rail, noteID string) error {
+	rail.Debugf("Deleting draft of note: %q", noteID)
+	_, err := dbquery.NewQuery(rail, r.db).Table("draft").Where("note_id = ?", noteID).Delete()
+	return err
+}
+
+// DeleteAll deletes all drafts
+func (r *SQLiteDraftRepository) DeleteAll(rail flow.Rail) error {
+	rail.Debugf("Deleting all drafts")
+	return dbquery.NewQuery(rail, r.db).ExecAny("DELETE FROM draft")
+}
@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"github.com/curtisnewbie/miso/flow"
+	"github.com/curtisnewbie/miso/middleware/dbquery"
+	"github.com/curtisnewbie/nota/internal/domain"
+	"gorm.io/gorm"
+)
+
+// NoteLinkRepository defines the interface for note link data operations
+type NoteLinkRepository interface {
+	ReplaceLinks(rail flow.Rail, sourceID string, targetIDs []string) error
+	FindLinkedNotes(rail flow.Rail, sourceID string) ([]*domain.Note, error)
+	FindBacklinkedNotes(rail flow.Rail, targetID string) ([]*domain.Note, error)
+}
+
+// SQLiteNoteLinkRepository implements NoteLinkRepository for SQLite
+type SQLiteNoteLinkRepository struct {
+	db *gorm.DB
+}
+
+// NewSQLiteNoteLinkRepository creates a new SQLite note link repository
+func NewSQLiteNoteLinkRepository(db *gorm.DB) NoteLinkRepository {
+	return &SQLiteNoteLinkRepository{db: db}
+}
+
+// ReplaceLinks replaces the links of a note with links to the given notes
+func (r *SQLiteNoteLinkRepository) ReplaceLinks(rail flow.Rail, sourceID string, targetIDs []string) error {
+	rail.Debugf("Replacing links of note %s with: %v", sourceID, targetIDs)
+	err := dbquery.RunTransaction(rail, r.db, func(qry func() *dbquery.Query) error {
+		if _, err := qry().Table("note_link").Where("source_id = ?", sourceID).Delete(); err != nil {
+			return err
+		}
+		for _, targetID := range targetIDs {
+			err := qry().ExecAny("INSERT OR IGNORE INTO note_link (source_id, target_id) VALUES (?, ?)", sourceID, targetID)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		rail.Errorf("Failed to replace links of note %s: %v", sourceID, err)
+	}
+	return err
+}
+
+// FindLinkedNotes finds the notes that a note links to sorted by title
+func (r *SQLiteNoteLinkRepository) FindLinkedNotes(rail flow.Rail, sourceID string) ([]*domain.Note, error) {
+	rail.Debugf("Finding notes linked from: %s", sourceID)
+	var notes []*domain.Note
+	q := dbquery.NewQuery(rail, r.db).Table("note").
+		Select("note.*").
+		Joins("JOIN note_link ON note_link.target_id = note.id").
+		Where("note_link.source_id = ? AND note.deleted_at IS NULL", sourceID).
+		Order("note.title ASC")
+	_, err := q.Scan(&notes)
+	return notes, err
+}
+
+// FindBacklinkedNotes finds the notes that link to a note sorted by title
+func (r *SQLiteNoteLinkRepository) FindBacklinkedNotes(rail flow.Rail, targetID string) ([]*domain.Note, error) {
+	rail.Debugf("Finding notes linking to: %s", targetID)
+	var notes []*domain.Note
+	q := dbquery.NewQuery(rail, r.db).Table("note").
+		Select("note.*").
+		Joins("JOIN note_link ON note_link.source_id = note.id").
+		Where("note_link.target_id = ? AND note.deleted_at IS NULL", targetID).
+		Order("note.title ASC")
+	_, err := q.Scan(&notes)
+	return notes, err
+}
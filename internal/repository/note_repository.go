@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"encoding/json"
+	"errors"
 	"time"
 
 	"github.com/curtisnewbie/miso/flow"
@@ -11,84 +13,200 @@ import (
 	"gorm.io/gorm"
 )
 
+// ErrVersionConflict is returned when a note is updated from a version that is no longer the latest
+var ErrVersionConflict = errors.New("note was modified elsewhere since it was loaded")
+
 // NoteRepository defines the interface for note data operations
 type NoteRepository interface {
 	Save(rail flow.Rail, note *domain.Note) error
+	SaveAndMove(rail flow.Rail, note *domain.Note, notebookID string) error
 	FindByID(rail flow.Rail, id string) (*domain.Note, error)
 	FindAll(rail flow.Rail) ([]*domain.Note, error)
 	FindAllSorted(rail flow.Rail) ([]*domain.Note, error)
 	FindAllSortedPaginated(rail flow.Rail, offset, limit int) ([]*domain.Note, error)
 	Search(rail flow.Rail, query string) ([]*domain.Note, error)
-	SearchPaginated(rail flow.Rail, query string, offset, limit int) ([]*domain.Note, error)
+	SearchPaginated(rail flow.Rail, filter domain.NoteFilter, offset, limit int) ([]*domain.Note, error)
 	Delete(rail flow.Rail, id string) error
 	FindByTitle(rail flow.Rail, title string) (*domain.Note, error)
 	FindLastModified(rail flow.Rail) (*domain.Note, error)
+	FindDeleted(rail flow.Rail) ([]*domain.Note, error)
+	FindDeletedByID(rail flow.Rail, id string) (*domain.Note, error)
+	Restore(rail flow.Rail, id string) error
+	Purge(rail flow.Rail, id string) error
+	MoveToNotebook(rail flow.Rail, id string, notebookID string) error
+	PurgeDeletedBefore(rail flow.Rail, before atom.Time) (int64, error)
+	FindTemplates(rail flow.Rail) ([]*domain.Note, error)
+	UpdateMetadata(rail flow.Rail, id string, metadata map[string]interface{}) error
+	FindDaily(rail flow.Rail, day string) (*domain.Note, error)
+	FindDailyDays(rail flow.Rail, from string, to string) ([]string, error)
+	UpdateDueAt(rail flow.Rail, id string, dueAt *atom.Time) error
+	FindDueReminders(rail flow.Rail, now atom.Time) ([]*domain.Note, error)
+	MarkReminded(rail flow.Rail, ids []string, remindedAt atom.Time) error
+	UpdateStarred(rail flow.Rail, id string, starred bool) error
+	UpdateArchivedAt(rail flow.Rail, id string, archivedAt *atom.Time) error
 }
 
 // SQLiteNoteRepository implements NoteRepository for SQLite
 type SQLiteNoteRepository struct {
-	db *gorm.DB
+	db         *gorm.DB
+	ftsEnabled bool
 }
 
 // NewSQLiteNoteRepository creates a new SQLite note repository
 func NewSQLiteNoteRepository(db *gorm.DB) NoteRepository {
-	return &SQLiteNoteRepository{db: db}
+	rail := flow.EmptyRail()
+	ftsEnabled := isFullTextSearchAvailable(rail, db)
+	if !ftsEnabled {
+		rail.Warnf("FTS5 index not available, note search uses LIKE-based matching")
+	}
+	return &SQLiteNoteRepository{db: db, ftsEnabled: ftsEnabled}
 }
 
 // Save saves or updates a note
+//
+// Updating a note bumps its version and moves the previous title and content into note_revision.
+// The update only succeeds when note.Version is still the latest version, ErrVersionConflict is returned otherwise.
+//
+// When a note is locked or unlocked, its revisions are deleted instead, since they hold the content in the other form.
+// The tasks of the note are indexed in note_task along with the content.
 func (r *SQLiteNoteRepository) Save(rail flow.Rail, note *domain.Note) error {
+	return dbquery.RunTransaction(rail, r.db, func(qry func() *dbquery.Query) error {
+		return saveNote(rail, qry, note)
+	})
+}
+
+// SaveAndMove saves a note like Save and moves it into a notebook in the same transaction, an empty notebookID moves
+// it to the top level, the note is neither saved nor moved when either fails
+func (r *SQLiteNoteRepository) SaveAndMove(rail flow.Rail, note *domain.Note, notebookID string) error {
+	return dbquery.RunTransaction(rail, r.db, func(qry func() *dbquery.Query) error {
+		if err := saveNote(rail, qry, note); err != nil {
+			return err
+		}
+		if err := qry().Table("note").Where("id = ?", note.ID).Set("notebook_id", notebookID).UpdateAny(); err != nil {
+			return err
+		}
+		note.NotebookID = notebookID
+		return nil
+	})
+}
+
+// saveNote saves or updates a note within a transaction, see Save
+func saveNote(rail flow.Rail, qry func() *dbquery.Query, note *domain.Note) error {
 	if note.ID == "" {
 		note.ID = idutil.Id("note")
-		q := dbquery.NewQuery(rail, r.db).Table("note")
-		_, err := q.Create(note)
+		if _, err := qry().Table("note").Create(note); err != nil {
+			return err
+		}
+		return replaceTasks(qry, note)
+	}
+
+	var existing domain.Note
+	ok, err := qry().Table("note").Where("id = ?", note.ID).ScanAny(&existing)
+	if err != nil {
 		return err
 	}
-	// For updates, use Set to specify columns
-	q := dbquery.NewQuery(rail, r.db).Table("note").Where("id = ?", note.ID).Set("title", note.Title).Set("content", note.Content).Set("updated_at", atom.Now())
-	_, err := q.Update()
-	return err
+	if !ok {
+		// Note with a preassigned ID (e.g., imported), create it as is
+		if _, err := qry().Table("note").Create(note); err != nil {
+			return err
+		}
+		return replaceTasks(qry, note)
+	}
+
+	now := atom.Now()
+	if existing.Title == note.Title && existing.Content == note.Content && existing.Encrypted == note.Encrypted {
+		note.Version = existing.Version
+		note.UpdatedAt = now
+		return qry().Table("note").Where("id = ?", note.ID).Set("updated_at", now).UpdateAny()
+	}
+
+	if existing.Version != note.Version {
+		rail.Warnf("Version conflict on note %s, expected version %d, latest version %d", note.ID, note.Version, existing.Version)
+		return ErrVersionConflict
+	}
+
+	if existing.Encrypted != note.Encrypted {
+		if _, err := qry().Table("note_revision").Where("note_id = ?", note.ID).Delete(); err != nil {
+			return err
+		}
+	} else {
+		revision := &domain.NoteRevision{
+			ID:        idutil.Id("rev"),
+			NoteID:    existing.ID,
+			Version:   existing.Version,
+			Title:     existing.Title,
+			Content:   existing.Content,
+			UpdatedAt: existing.UpdatedAt,
+			CreatedAt: now,
+		}
+		if _, err := qry().Table("note_revision").Create(revision); err != nil {
+			return err
+		}
+	}
+
+	// For updates, use Set to specify columns, the version condition guards against concurrent writers
+	updated, err := qry().Table("note").Where("id = ? AND version = ?", note.ID, existing.Version).
+		Set("title", note.Title).
+		Set("content", note.Content).
+		Set("encrypted", note.Encrypted).
+		Set("version", existing.Version+1).
+		Set("updated_at", now).
+		Update()
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return ErrVersionConflict
+	}
+	note.Version = existing.Version + 1
+	note.UpdatedAt = now
+	return replaceTasks(qry, note)
 }
 
-// FindByID finds a note by ID
+// FindByID finds a note by ID (excluding soft-deleted, archived notes are found)
 func (r *SQLiteNoteRepository) FindByID(rail flow.Rail, id string) (*domain.Note, error) {
 	rail.Debugf("Finding note by ID: %s", id)
 	var note domain.Note
-	q := dbquery.NewQuery(rail, r.db).Table("note").Where("id = ?", id)
-	_, err := q.Scan(&note)
+	q := dbquery.NewQuery(rail, r.db).Table("note").Where("id = ? AND deleted_at IS NULL", id)
+	ok, err := q.ScanAny(&note)
 	if err != nil {
-		rail.Warnf("Note not found: %s", id)
+		rail.Errorf("Failed to find note %s: %v", id, err)
 		return nil, err
 	}
+	if !ok {
+		rail.Warnf("Note not found: %s", id)
+		return nil, dbquery.ErrRecordNotFound
+	}
 	return &note, nil
 }
 
-// FindAll finds all notes (excluding soft-deleted)
+// FindAll finds all notes including the archived ones sorted by updated_at DESC (excluding soft-deleted)
 func (r *SQLiteNoteRepository) FindAll(rail flow.Rail) ([]*domain.Note, error) {
 	rail.Debugf("Finding all notes")
 	var notes []*domain.Note
-	q := dbquery.NewQuery(rail, r.db).Table("note").Where("deleted_at IS NULL")
+	q := dbquery.NewQuery(rail, r.db).Table("note").Where("deleted_at IS NULL").Order("updated_at DESC")
 	_, err := q.Scan(&notes)
 	rail.Debugf("Found %d notes", len(notes))
 	return notes, err
 }
 
-// FindAllSorted finds all notes sorted by updated_at DESC (excluding soft-deleted)
+// FindAllSorted finds all notes, the starred ones first, sorted by updated_at DESC (excluding soft-deleted and archived)
 func (r *SQLiteNoteRepository) FindAllSorted(rail flow.Rail) ([]*domain.Note, error) {
 	rail.Debugf("Finding all notes sorted by updated_at")
 	var notes []*domain.Note
-	q := dbquery.NewQuery(rail, r.db).Table("note").Where("deleted_at IS NULL").Order("updated_at DESC")
+	q := dbquery.NewQuery(rail, r.db).Table("note").Where("deleted_at IS NULL AND archived_at IS NULL").Order("starred DESC, updated_at DESC")
 	_, err := q.Scan(&notes)
 	rail.Debugf("Found %d notes", len(notes))
 	return notes, err
 }
 
-// FindAllSortedPaginated finds notes sorted by updated_at DESC with pagination (excluding soft-deleted)
+// FindAllSortedPaginated finds notes, the starred ones first, sorted by updated_at DESC with pagination (excluding soft-deleted and archived)
 func (r *SQLiteNoteRepository) FindAllSortedPaginated(rail flow.Rail, offset, limit int) ([]*domain.Note, error) {
 	rail.Debugf("Finding notes sorted by updated_at (offset=%d, limit=%d)", offset, limit)
 	var notes []*domain.Note
 	q := dbquery.NewQuery(rail, r.db).Table("note").
-		Where("deleted_at IS NULL").
-		Order("updated_at DESC").
+		Where("deleted_at IS NULL AND archived_at IS NULL").
+		Order("starred DESC, updated_at DESC").
 		Limit(limit).
 		Offset(offset)
 	_, err := q.Scan(&notes)
@@ -96,42 +214,106 @@ func (r *SQLiteNoteRepository) FindAllSortedPaginated(rail flow.Rail, offset, li
 	return notes, err
 }
 
-// Search searches notes by title and content using LIKE-based search
+// Search searches notes by title and content, ranked by relevance when the FTS5 index is available
+//
+// Archived notes are only searched with the archived: term in the query.
 func (r *SQLiteNoteRepository) Search(rail flow.Rail, query string) ([]*domain.Note, error) {
-	if query == "" {
+	filter := domain.NoteFilter{Query: query}.WithQueryTerms()
+	if filter.IsEmpty() {
 		return r.FindAllSorted(rail)
 	}
 
 	rail.Debugf("Searching notes with query: %s", query)
 	var notes []*domain.Note
-	searchPattern := "%" + query + "%"
-	q := dbquery.NewQuery(rail, r.db).Table("note").
-		Where("deleted_at IS NULL AND (title LIKE ? OR content LIKE ?)", searchPattern, searchPattern).
-		Order("updated_at DESC")
-	_, err := q.Scan(&notes)
+	_, err := r.filterQuery(rail, filter).Scan(&notes)
 	rail.Debugf("Found %d notes matching query", len(notes))
 	return notes, err
 }
 
-// SearchPaginated searches notes by title and content using LIKE-based search with pagination
-func (r *SQLiteNoteRepository) SearchPaginated(rail flow.Rail, query string, offset, limit int) ([]*domain.Note, error) {
-	if query == "" {
+// SearchPaginated searches notes by title and content with pagination, ranked by relevance when the FTS5 index is available
+//
+// If tags is not empty, only notes that have every one of the given tags (by name) are returned. Archived notes are
+// only searched when filter.Archived is set or the query has the archived: term, the others are then left out.
+func (r *SQLiteNoteRepository) SearchPaginated(rail flow.Rail, filter domain.NoteFilter, offset, limit int) ([]*domain.Note, error) {
+	filter = filter.WithQueryTerms()
+	if filter.IsEmpty() {
 		return r.FindAllSortedPaginated(rail, offset, limit)
 	}
 
-	rail.Debugf("Searching notes with query: %s (offset=%d, limit=%d)", query, offset, limit)
+	rail.Debugf("Searching notes with filter: %+v (offset=%d, limit=%d)", filter, offset, limit)
 	var notes []*domain.Note
-	searchPattern := "%" + query + "%"
-	q := dbquery.NewQuery(rail, r.db).Table("note").
-		Where("deleted_at IS NULL AND (title LIKE ? OR content LIKE ?)", searchPattern, searchPattern).
-		Order("updated_at DESC").
-		Limit(limit).
-		Offset(offset)
-	_, err := q.Scan(&notes)
+	_, err := r.filterQuery(rail, filter).Limit(limit).Offset(offset).Scan(&notes)
 	rail.Debugf("Found %d notes matching query", len(notes))
 	return notes, err
 }
 
+// filterQuery builds the query of the notes matching the filter
+func (r *SQLiteNoteRepository) filterQuery(rail flow.Rail, filter domain.NoteFilter) *dbquery.Query {
+	var q *dbquery.Query
+	if filter.Query == "" {
+		q = dbquery.NewQuery(rail, r.db).Table("note").
+			Where("note.deleted_at IS NULL")
+		if filter.DueSoon {
+			q = q.Order("note.due_at ASC")
+		}
+		q = q.Order("note.starred DESC, note.updated_at DESC")
+	} else {
+		q = r.searchQuery(rail, filter.Query)
+	}
+	if filter.DueSoon {
+		q = q.Where("note.due_at IS NOT NULL AND note.due_at <= ?", atom.WrapTime(time.Now().Add(domain.DueSoonWindow)))
+	}
+	if len(filter.Tags) > 0 {
+		q = q.Where(`note.id IN (
+			SELECT note_tag.note_id FROM note_tag JOIN tag ON tag.id = note_tag.tag_id
+			WHERE tag.name IN ? GROUP BY note_tag.note_id HAVING COUNT(DISTINCT tag.id) = ?
+		)`, filter.Tags, len(filter.Tags))
+	}
+	if filter.NotebookID != "" {
+		q = q.Where("note.notebook_id IN ("+subtreeQuery+")", filter.NotebookID)
+	}
+	if filter.Archived {
+		q = q.Where("note.archived_at IS NOT NULL")
+	} else {
+		q = q.Where("note.archived_at IS NULL")
+	}
+	return q
+}
+
+// searchQuery builds the search query, using the FTS5 index if possible and LIKE-based matching otherwise
+//
+// Only the title of locked notes is matched, their content is encrypted.
+func (r *SQLiteNoteRepository) searchQuery(rail flow.Rail, query string) *dbquery.Query {
+	if r.ftsEnabled {
+		if match, exclude := buildFTSQuery(query); match != "" || exclude != "" {
+			var q *dbquery.Query
+			if match != "" {
+				q = dbquery.NewQuery(rail, r.db).Table("note").
+					Select("note.*").
+					Joins("JOIN note_fts ON note_fts.rowid = note.rowid").
+					Where("note_fts MATCH ? AND note.deleted_at IS NULL", match).
+					Where("(note.encrypted = 0 OR note.rowid IN (SELECT rowid FROM note_fts WHERE note_fts MATCH ?))", titleOnlyFTSQuery(match)).
+					Order(ftsRankOrder + ", note.updated_at DESC")
+			} else {
+				q = dbquery.NewQuery(rail, r.db).Table("note").
+					Where("note.deleted_at IS NULL").
+					Order("note.updated_at DESC")
+			}
+			if exclude != "" {
+				q = q.Where(`((note.encrypted = 0 AND note.rowid NOT IN (SELECT rowid FROM note_fts WHERE note_fts MATCH ?))
+					OR (note.encrypted = 1 AND note.rowid NOT IN (SELECT rowid FROM note_fts WHERE note_fts MATCH ?)))`,
+					exclude, titleOnlyFTSQuery(exclude))
+			}
+			return q
+		}
+	}
+
+	searchPattern := "%" + query + "%"
+	return dbquery.NewQuery(rail, r.db).Table("note").
+		Where("note.deleted_at IS NULL AND (note.title LIKE ? OR (note.encrypted = 0 AND note.content LIKE ?))", searchPattern, searchPattern).
+		Order("note.updated_at DESC")
+}
+
 // Delete soft-deletes a note by setting deleted_at timestamp
 func (r *SQLiteNoteRepository) Delete(rail flow.Rail, id string) error {
 	rail.Infof("Deleting note: %s", id)
@@ -146,25 +328,29 @@ func (r *SQLiteNoteRepository) Delete(rail flow.Rail, id string) error {
 	return err
 }
 
-// FindByTitle finds a note by title
+// FindByTitle finds a note by title, archived notes are found so that links to them keep working
 func (r *SQLiteNoteRepository) FindByTitle(rail flow.Rail, title string) (*domain.Note, error) {
 	rail.Debugf("Finding note by title: %s", title)
 	var note domain.Note
-	q := dbquery.NewQuery(rail, r.db).Table("note").Where("title = ? AND deleted_at IS NULL", title)
-	_, err := q.Scan(&note)
+	q := dbquery.NewQuery(rail, r.db).Table("note").Where("title = ? AND deleted_at IS NULL", title).Order("updated_at DESC")
+	ok, err := q.ScanAny(&note)
 	if err != nil {
-		rail.Warnf("Note not found with title: %s", title)
+		rail.Errorf("Failed to find note by title %s: %v", title, err)
 		return nil, err
 	}
+	if !ok {
+		rail.Debugf("Note not found with title: %s", title)
+		return nil, dbquery.ErrRecordNotFound
+	}
 	return &note, nil
 }
 
-// FindLastModified finds the most recently modified note (excluding soft-deleted)
+// FindLastModified finds the most recently modified note (excluding soft-deleted and archived)
 func (r *SQLiteNoteRepository) FindLastModified(rail flow.Rail) (*domain.Note, error) {
 	rail.Debugf("Finding last modified note")
 	var note domain.Note
 	q := dbquery.NewQuery(rail, r.db).Table("note").
-		Where("deleted_at IS NULL").
+		Where("deleted_at IS NULL AND archived_at IS NULL").
 		Order("updated_at DESC").
 		Limit(1)
 	_, err := q.Scan(&note)
@@ -175,3 +361,230 @@ func (r *SQLiteNoteRepository) FindLastModified(rail flow.Rail) (*domain.Note, e
 	rail.Debugf("Last modified note: %s", note.ID)
 	return &note, nil
 }
+
+// FindDeleted finds all soft-deleted notes sorted by deleted_at DESC
+func (r *SQLiteNoteRepository) FindDeleted(rail flow.Rail) ([]*domain.Note, error) {
+	rail.Debugf("Finding deleted notes")
+	var notes []*domain.Note
+	q := dbquery.NewQuery(rail, r.db).Table("note").
+		Where("deleted_at IS NOT NULL").
+		Order("deleted_at DESC")
+	_, err := q.Scan(&notes)
+	rail.Debugf("Found %d deleted notes", len(notes))
+	return notes, err
+}
+
+// FindDeletedByID finds a soft-deleted note by ID
+func (r *SQLiteNoteRepository) FindDeletedByID(rail flow.Rail, id string) (*domain.Note, error) {
+	rail.Debugf("Finding deleted note by ID: %s", id)
+	var note domain.Note
+	q := dbquery.NewQuery(rail, r.db).Table("note").Where("id = ? AND deleted_at IS NOT NULL", id)
+	ok, err := q.ScanAny(&note)
+	if err != nil {
+		rail.Errorf("Failed to find deleted note %s: %v", id, err)
+		return nil, err
+	}
+	if !ok {
+		rail.Warnf("Deleted note not found: %s", id)
+		return nil, dbquery.ErrRecordNotFound
+	}
+	return &note, nil
+}
+
+// Restore restores a soft-deleted note by clearing deleted_at
+func (r *SQLiteNoteRepository) Restore(rail flow.Rail, id string) error {
+	rail.Infof("Restoring note: %s", id)
+	q := dbquery.NewQuery(rail, r.db).Table("note").Where("id = ?", id).Set("deleted_at", nil)
+	_, err := q.Update()
+	if err != nil {
+		rail.Errorf("Failed to restore note %s: %v", id, err)
+	} else {
+		rail.Infof("Successfully restored note: %s", id)
+	}
+	return err
+}
+
+// MoveToNotebook moves a note into a notebook, an empty notebookID moves it to the top level
+func (r *SQLiteNoteRepository) MoveToNotebook(rail flow.Rail, id string, notebookID string) error {
+	rail.Infof("Moving note %s to notebook: %s", id, notebookID)
+	err := dbquery.NewQuery(rail, r.db).Table("note").Where("id = ?", id).Set("notebook_id", notebookID).UpdateAny()
+	if err != nil {
+		rail.Errorf("Failed to move note %s to notebook %s: %v", id, notebookID, err)
+	}
+	return err
+}
+
+// FindTemplates finds the notes flagged as templates sorted by title (excluding soft-deleted, archived and locked notes)
+func (r *SQLiteNoteRepository) FindTemplates(rail flow.Rail) ([]*domain.Note, error) {
+	rail.Debugf("Finding templates")
+	var notes []*domain.Note
+	q := dbquery.NewQuery(rail, r.db).Table("note").
+		Where("deleted_at IS NULL AND archived_at IS NULL AND encrypted = 0").
+		Where("json_valid(metadata) AND json_extract(metadata, ?) = 1", "$."+domain.TemplateMetadataKey).
+		Order("title ASC")
+	_, err := q.Scan(&notes)
+	rail.Debugf("Found %d templates", len(notes))
+	return notes, err
+}
+
+// FindDaily finds the daily note of a day, day is formatted with domain.DailyDateLayout (excluding soft-deleted)
+//
+// Archived daily notes are found, so that archiving the note of a day doesn't start another one for the day.
+func (r *SQLiteNoteRepository) FindDaily(rail flow.Rail, day string) (*domain.Note, error) {
+	rail.Debugf("Finding daily note of: %s", day)
+	var note domain.Note
+	q := dbquery.NewQuery(rail, r.db).Table("note").
+		Where("deleted_at IS NULL").
+		Where("json_valid(metadata) AND json_extract(metadata, ?) = ?", "$."+domain.DailyMetadataKey, day).
+		Order("updated_at DESC")
+	ok, err := q.ScanAny(&note)
+	if err != nil {
+		rail.Errorf("Failed to find daily note of %s: %v", day, err)
+		return nil, err
+	}
+	if !ok {
+		rail.Debugf("Daily note not found: %s", day)
+		return nil, dbquery.ErrRecordNotFound
+	}
+	return &note, nil
+}
+
+// FindDailyDays finds the days between from and to (inclusive) that have a daily note (excluding soft-deleted)
+func (r *SQLiteNoteRepository) FindDailyDays(rail flow.Rail, from string, to string) ([]string, error) {
+	rail.Debugf("Finding days with daily notes between %s and %s", from, to)
+	var days []string
+	_, err := dbquery.NewQuery(rail, r.db).Raw(`SELECT DISTINCT day FROM (
+			SELECT json_extract(metadata, ?) AS day FROM note WHERE deleted_at IS NULL AND json_valid(metadata)
+		) WHERE day BETWEEN ? AND ? ORDER BY day`, "$."+domain.DailyMetadataKey, from, to).Scan(&days)
+	return days, err
+}
+
+// UpdateDueAt sets when the reminder of a note is due, nil removes the reminder, the reminder is sent again once due
+func (r *SQLiteNoteRepository) UpdateDueAt(rail flow.Rail, id string, dueAt *atom.Time) error {
+	rail.Infof("Updating reminder of note %s: %v", id, dueAt)
+	err := dbquery.NewQuery(rail, r.db).Table("note").Where("id = ?", id).
+		Set("due_at", dueAt).
+		Set("reminded_at", nil).
+		UpdateAny()
+	if err != nil {
+		rail.Errorf("Failed to update reminder of note %s: %v", id, err)
+	}
+	return err
+}
+
+// FindDueReminders finds the notes whose reminder is due at now and hasn't been sent since, the earliest first (excluding soft-deleted and archived)
+func (r *SQLiteNoteRepository) FindDueReminders(rail flow.Rail, now atom.Time) ([]*domain.Note, error) {
+	rail.Debugf("Finding reminders due at: %s", now.Format(time.RFC3339))
+	var notes []*domain.Note
+	q := dbquery.NewQuery(rail, r.db).Table("note").
+		Where("deleted_at IS NULL AND archived_at IS NULL AND due_at IS NOT NULL AND due_at <= ?", now).
+		Where("(reminded_at IS NULL OR reminded_at < due_at)").
+		Order("due_at ASC")
+	_, err := q.Scan(&notes)
+	return notes, err
+}
+
+// MarkReminded records that the reminders of the notes have been sent, in a single update so that either every one of
+// them is marked or none is
+func (r *SQLiteNoteRepository) MarkReminded(rail flow.Rail, ids []string, remindedAt atom.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return dbquery.NewQuery(rail, r.db).Table("note").Where("id IN ?", ids).Set("reminded_at", remindedAt).UpdateAny()
+}
+
+// UpdateStarred stars or unstars a note, neither the version nor updated_at of the note is changed
+func (r *SQLiteNoteRepository) UpdateStarred(rail flow.Rail, id string, starred bool) error {
+	rail.Infof("Setting note %s as starred: %v", id, starred)
+	err := dbquery.NewQuery(rail, r.db).Table("note").Where("id = ?", id).Set("starred", starred).UpdateAny()
+	if err != nil {
+		rail.Errorf("Failed to star note %s: %v", id, err)
+	}
+	return err
+}
+
+// UpdateArchivedAt archives a note, nil unarchives it, neither the version nor updated_at of the note is changed
+func (r *SQLiteNoteRepository) UpdateArchivedAt(rail flow.Rail, id string, archivedAt *atom.Time) error {
+	rail.Infof("Updating archived_at of note %s: %v", id, archivedAt)
+	err := dbquery.NewQuery(rail, r.db).Table("note").Where("id = ?", id).Set("archived_at", archivedAt).UpdateAny()
+	if err != nil {
+		rail.Errorf("Failed to update archived_at of note %s: %v", id, err)
+	}
+	return err
+}
+
+// UpdateMetadata replaces the metadata of a note, neither the version nor updated_at of the note is changed
+func (r *SQLiteNoteRepository) UpdateMetadata(rail flow.Rail, id string, metadata map[string]interface{}) error {
+	rail.Infof("Updating metadata of note: %s", id)
+	encoded, err := json.Marshal(metadata)
+	if err != nil {
+		return err
+	}
+	err = dbquery.NewQuery(rail, r.db).Table("note").Where("id = ?", id).Set("metadata", string(encoded)).UpdateAny()
+	if err != nil {
+		rail.Errorf("Failed to update metadata of note %s: %v", id, err)
+	}
+	return err
+}
+
+// Purge permanently deletes a note together with its revisions, tags, links, tasks and draft
+func (r *SQLiteNoteRepository) Purge(rail flow.Rail, id string) error {
+	rail.Infof("Purging note: %s", id)
+	err := dbquery.RunTransaction(rail, r.db, func(qry func() *dbquery.Query) error {
+		if _, err := qry().Table("note_revision").Where("note_id = ?", id).Delete(); err != nil {
+			return err
+		}
+		if _, err := qry().Table("note_tag").Where("note_id = ?", id).Delete(); err != nil {
+			return err
+		}
+		if _, err := qry().Table("note_task").Where("note_id = ?", id).Delete(); err != nil {
+			return err
+		}
+		if _, err := qry().Table("draft").Where("note_id = ?", id).Delete(); err != nil {
+			return err
+		}
+		if _, err := qry().Table("note_link").Where("source_id = ? OR target_id = ?", id, id).Delete(); err != nil {
+			return err
+		}
+		_, err := qry().Table("note").Where("id = ?", id).Delete()
+		return err
+	})
+	if err != nil {
+		rail.Errorf("Failed to purge note %s: %v", id, err)
+	} else {
+		rail.Infof("Successfully purged note: %s", id)
+	}
+	return err
+}
+
+// PurgeDeletedBefore permanently deletes notes that were soft-deleted before the given time
+func (r *SQLiteNoteRepository) PurgeDeletedBefore(rail flow.Rail, before atom.Time) (int64, error) {
+	rail.Infof("Purging notes deleted before: %s", before.Format(time.RFC3339))
+	var purged int64
+	err := dbquery.RunTransaction(rail, r.db, func(qry func() *dbquery.Query) error {
+		for _, table := range []string{"note_revision", "note_tag", "note_task", "draft"} {
+			_, err := qry().Table(table).
+				Where("note_id IN (SELECT id FROM note WHERE deleted_at IS NOT NULL AND deleted_at < ?)", before).
+				Delete()
+			if err != nil {
+				return err
+			}
+		}
+		_, err := qry().Table("note_link").
+			Where(`source_id IN (SELECT id FROM note WHERE deleted_at IS NOT NULL AND deleted_at < ?)
+				OR target_id IN (SELECT id FROM note WHERE deleted_at IS NOT NULL AND deleted_at < ?)`, before, before).
+			Delete()
+		if err != nil {
+			return err
+		}
+		n, err := qry().Table("note").Where("deleted_at IS NOT NULL AND deleted_at < ?", before).Delete()
+		purged = n
+		return err
+	})
+	if err != nil {
+		rail.Errorf("Failed to purge deleted notes: %v", err)
+	} else {
+		rail.Infof("Successfully purged %d deleted notes", purged)
+	}
+	return purged, err
+}
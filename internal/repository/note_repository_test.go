@@ -0,0 +1,399 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/curtisnewbie/miso/flow"
+	"github.com/curtisnewbie/miso/util/atom"
+	"github.com/curtisnewbie/nota/internal/domain"
+	"github.com/curtisnewbie/nota/internal/infrastructure"
+	"gorm.io/gorm"
+)
+
+// newTestDB opens a new database with the schema of the application, in a temporary home directory
+func newTestDB(t *testing.T) *gorm.DB {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	if err := infrastructure.SetDatabasePath(filepath.Join(home, "nota.sqlite")); err != nil {
+		t.Fatal(err)
+	}
+	db, err := infrastructure.InitializeDatabase()
+	if err != nil {
+		t.Fatalf("failed to initialize database: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+// saveTestNote saves a new note
+func saveTestNote(t *testing.T, repo NoteRepository, title string, content string) *domain.Note {
+	now := atom.Now()
+	note := &domain.Note{Title: title, Content: content, Version: 1, Metadata: map[string]interface{}{}, CreatedAt: now, UpdatedAt: now}
+	if err := repo.Save(flow.EmptyRail(), note); err != nil {
+		t.Fatalf("failed to save note %q: %v", title, err)
+	}
+	return note
+}
+
+// noteIDs returns the sorted IDs of the notes
+func noteIDs(notes []*domain.Note) []string {
+	ids := make([]string, 0, len(notes))
+	for _, note := range notes {
+		ids = append(ids, note.ID)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// sortedIDs returns the IDs sorted, for comparing with noteIDs
+func sortedIDs(ids ...string) []string {
+	ids = append([]string{}, ids...)
+	sort.Strings(ids)
+	return ids
+}
+
+// searchModes are the two ways notes are searched, the FTS5 index is only available when built with the
+// sqlite_fts5 tag
+var searchModes = []struct {
+	name string
+	fts  bool
+}{
+	{"fts", true},
+	{"like", false},
+}
+
+// searchRepo returns the repository searching the database in the mode, the test is skipped when the mode is not available
+func searchRepo(t *testing.T, db *gorm.DB, fts bool) *SQLiteNoteRepository {
+	if fts && !isFullTextSearchAvailable(flow.EmptyRail(), db) {
+		t.Skip("FTS5 is not available, run the tests with -tags sqlite_fts5")
+	}
+	return &SQLiteNoteRepository{db: db, ftsEnabled: fts}
+}
+
+func TestSearch(t *testing.T) {
+	rail := flow.EmptyRail()
+	db := newTestDB(t)
+	repo := NewSQLiteNoteRepository(db)
+	meeting := saveTestNote(t, repo, "Meeting notes", "Discuss the budget with the team")
+	groceries := saveTestNote(t, repo, "Groceries", "Apples and snacks for the meeting room")
+	budget := saveTestNote(t, repo, "Budget", "Budget for 2024, the budget is tight")
+	deleted := saveTestNote(t, repo, "Old budget", "budget")
+	if err := repo.Delete(rail, deleted.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		fts   []string
+		like  []string
+	}{
+		{"word", "budget", sortedIDs(budget.ID, meeting.ID), sortedIDs(budget.ID, meeting.ID)},
+		{"prefix while typing", "budg", sortedIDs(budget.ID, meeting.ID), sortedIDs(budget.ID, meeting.ID)},
+		{"case insensitive", "MEETING", sortedIDs(meeting.ID, groceries.ID), sortedIDs(meeting.ID, groceries.ID)},
+		{"every word", "budget team", sortedIDs(meeting.ID), sortedIDs()},
+		{"phrase", `"the budget"`, sortedIDs(meeting.ID, budget.ID), sortedIDs()},
+		{"substring", "ocerie", sortedIDs(), sortedIDs(groceries.ID)},
+		{"no match", "holiday", sortedIDs(), sortedIDs()},
+	}
+	for _, mode := range searchModes {
+		t.Run(mode.name, func(t *testing.T) {
+			r := searchRepo(t, db, mode.fts)
+			for _, tt := range tests {
+				want := tt.like
+				if mode.fts {
+					want = tt.fts
+				}
+				notes, err := r.Search(rail, tt.query)
+				if err != nil {
+					t.Fatalf("Search(%q) failed: %v", tt.query, err)
+				}
+				if got := noteIDs(notes); !reflect.DeepEqual(got, want) {
+					t.Errorf("%s: Search(%q) = %v, want %v", tt.name, tt.query, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestSearchRanksByRelevance(t *testing.T) {
+	db := newTestDB(t)
+	repo := searchRepo(t, db, true)
+	mentioned := saveTestNote(t, repo, "Meeting notes", "Discuss the budget with the team")
+	about := saveTestNote(t, repo, "Budget", "Budget for 2024, the budget is tight")
+	saveTestNote(t, repo, "Groceries", "Apples")
+
+	notes, err := repo.Search(flow.EmptyRail(), "budget")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(notes) != 2 || notes[0].ID != about.ID || notes[1].ID != mentioned.ID {
+		t.Errorf("Search() = %v, want the note about the budget first", noteIDs(notes))
+	}
+}
+
+// countRevisions counts the revisions of a note
+func countRevisions(t *testing.T, db *gorm.DB, noteID string) int64 {
+	var count int64
+	if err := db.Raw("SELECT COUNT(*) FROM note_revision WHERE note_id = ?", noteID).Scan(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	return count
+}
+
+func TestSaveVersionConflict(t *testing.T) {
+	rail := flow.EmptyRail()
+	db := newTestDB(t)
+	repo := NewSQLiteNoteRepository(db)
+	note := saveTestNote(t, repo, "Plan", "first draft")
+
+	first := *note
+	first.Content = "edited in the first window"
+	if err := repo.Save(rail, &first); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+	if first.Version != 2 {
+		t.Errorf("Save() version = %d, want 2", first.Version)
+	}
+
+	stale := *note
+	stale.Title = "Plan B"
+	stale.Content = "edited in the second window"
+	if err := repo.Save(rail, &stale); !errors.Is(err, ErrVersionConflict) {
+		t.Fatalf("Save() of a stale version error = %v, want ErrVersionConflict", err)
+	}
+	if stale.Version != 1 {
+		t.Errorf("Save() of a stale version changed its version to %d", stale.Version)
+	}
+
+	stored, err := repo.FindByID(rail, note.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Title != "Plan" || stored.Content != "edited in the first window" || stored.Version != 2 {
+		t.Errorf("stored note = %q, %q, version %d, want the first edit kept", stored.Title, stored.Content, stored.Version)
+	}
+	if n := countRevisions(t, db, note.ID); n != 1 {
+		t.Errorf("note has %d revisions, want 1", n)
+	}
+}
+
+func TestSaveUnchanged(t *testing.T) {
+	rail := flow.EmptyRail()
+	db := newTestDB(t)
+	repo := NewSQLiteNoteRepository(db)
+	note := saveTestNote(t, repo, "Plan", "content")
+	edited := *note
+	edited.Content = "edited"
+	if err := repo.Save(rail, &edited); err != nil {
+		t.Fatal(err)
+	}
+
+	// Saving what is already stored is not a conflict, even from a stale version
+	for _, version := range []int{2, 1} {
+		again := edited
+		again.Version = version
+		if err := repo.Save(rail, &again); err != nil {
+			t.Fatalf("Save() of an unchanged note at version %d failed: %v", version, err)
+		}
+		if again.Version != 2 {
+			t.Errorf("Save() of an unchanged note at version %d = version %d, want 2", version, again.Version)
+		}
+	}
+	stored, err := repo.FindByID(rail, note.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Version != 2 {
+		t.Errorf("stored version = %d, want 2", stored.Version)
+	}
+	if n := countRevisions(t, db, note.ID); n != 1 {
+		t.Errorf("note has %d revisions, want 1", n)
+	}
+}
+
+func TestSaveWritesRevisions(t *testing.T) {
+	rail := flow.EmptyRail()
+	db := newTestDB(t)
+	repo := NewSQLiteNoteRepository(db)
+	note := saveTestNote(t, repo, "v1", "content 1")
+	for i := 2; i <= 4; i++ {
+		note.Title = fmt.Sprintf("v%d", i)
+		note.Content = fmt.Sprintf("content %d", i)
+		if err := repo.Save(rail, note); err != nil {
+			t.Fatal(err)
+		}
+		if note.Version != i {
+			t.Fatalf("Save() version = %d, want %d", note.Version, i)
+		}
+	}
+
+	var revisions []domain.NoteRevision
+	if err := db.Raw("SELECT * FROM note_revision WHERE note_id = ? ORDER BY version", note.ID).Scan(&revisions).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) != 3 {
+		t.Fatalf("note has %d revisions, want 3", len(revisions))
+	}
+	for i, revision := range revisions {
+		version := i + 1
+		if revision.Version != version || revision.Title != fmt.Sprintf("v%d", version) || revision.Content != fmt.Sprintf("content %d", version) {
+			t.Errorf("revision %d = version %d, %q, %q, want version %d as it was saved", i, revision.Version, revision.Title, revision.Content, version)
+		}
+	}
+}
+
+func TestSaveDropsRevisionsWhenLocking(t *testing.T) {
+	rail := flow.EmptyRail()
+	db := newTestDB(t)
+	repo := NewSQLiteNoteRepository(db)
+	note := saveTestNote(t, repo, "Diary", "plaintext")
+	note.Content = "more plaintext"
+	if err := repo.Save(rail, note); err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []struct {
+		name      string
+		content   string
+		encrypted bool
+		revisions int64
+	}{
+		{"lock", "nota-encrypted:v1:first", true, 0},
+		{"edit locked", "nota-encrypted:v1:second", true, 1},
+		{"remove lock", "plaintext again", false, 0},
+	}
+	for _, step := range steps {
+		version := note.Version
+		note.Content = step.content
+		note.Encrypted = step.encrypted
+		if err := repo.Save(rail, note); err != nil {
+			t.Fatalf("%s: Save() failed: %v", step.name, err)
+		}
+		if note.Version != version+1 {
+			t.Errorf("%s: version = %d, want %d", step.name, note.Version, version+1)
+		}
+		if n := countRevisions(t, db, note.ID); n != step.revisions {
+			t.Errorf("%s: note has %d revisions, want %d", step.name, n, step.revisions)
+		}
+	}
+}
+
+func TestSearchLockedNotes(t *testing.T) {
+	rail := flow.EmptyRail()
+	db := newTestDB(t)
+	repo := NewSQLiteNoteRepository(db)
+	plain := saveTestNote(t, repo, "Shopping", "buy a diary")
+	locked := saveTestNote(t, repo, "Diary", "dear diary")
+	locked.Content = "nota-encrypted:v1:c2hvcHBpbmc="
+	locked.Encrypted = true
+	if err := repo.Save(rail, locked); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"diary", sortedIDs(plain.ID, locked.ID)},
+		{"shopping", sortedIDs(plain.ID)},
+		{"encrypted", sortedIDs()},
+		{"c2hvcHBpbmc", sortedIDs()},
+	}
+	for _, mode := range searchModes {
+		t.Run(mode.name, func(t *testing.T) {
+			r := searchRepo(t, db, mode.fts)
+			for _, tt := range tests {
+				notes, err := r.Search(rail, tt.query)
+				if err != nil {
+					t.Fatalf("Search(%q) failed: %v", tt.query, err)
+				}
+				if got := noteIDs(notes); !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("Search(%q) = %v, want %v, only the title of locked notes is searched", tt.query, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestSearchExcludes(t *testing.T) {
+	rail := flow.EmptyRail()
+	db := newTestDB(t)
+	repo := searchRepo(t, db, true)
+	meeting := saveTestNote(t, repo, "Meeting notes", "Discuss the budget with the team")
+	groceries := saveTestNote(t, repo, "Groceries", "Apples and snacks for the meeting room")
+	budget := saveTestNote(t, repo, "Budget", "Budget for 2024")
+	locked := saveTestNote(t, repo, "Locked", "budget")
+	locked.Content = "nota-encrypted:v1:YnVkZ2V0"
+	locked.Encrypted = true
+	if err := repo.Save(rail, locked); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"meeting NOT budget ", sortedIDs(groceries.ID)},
+		{"NOT budget ", sortedIDs(groceries.ID, locked.ID)},
+		{"NOT budget meeting", sortedIDs(groceries.ID)},
+		{"NOT budget NOT apples ", sortedIDs(locked.ID)},
+		{"NOT locked ", sortedIDs(meeting.ID, groceries.ID, budget.ID)},
+	}
+	for _, tt := range tests {
+		notes, err := repo.Search(rail, tt.query)
+		if err != nil {
+			t.Fatalf("Search(%q) failed: %v", tt.query, err)
+		}
+		if got := noteIDs(notes); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestPurgeDeletesDrafts(t *testing.T) {
+	rail := flow.EmptyRail()
+	db := newTestDB(t)
+	repo := NewSQLiteNoteRepository(db)
+	drafts := NewSQLiteDraftRepository(db)
+	purged := saveTestNote(t, repo, "Purged", "gone")
+	trashed := saveTestNote(t, repo, "Trashed", "gone later")
+	kept := saveTestNote(t, repo, "Kept", "still here")
+	for _, note := range []*domain.Note{purged, trashed, kept} {
+		if err := drafts.Save(rail, &domain.Draft{NoteID: note.ID, Title: note.Title, Content: "edited", BaseVersion: 1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := repo.Purge(rail, purged.ID); err != nil {
+		t.Fatalf("Purge() failed: %v", err)
+	}
+	if err := repo.Delete(rail, trashed.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.PurgeDeletedBefore(rail, atom.WrapTime(time.Now().Add(time.Minute))); err != nil {
+		t.Fatalf("PurgeDeletedBefore() failed: %v", err)
+	}
+
+	left, err := drafts.FindAll(rail)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, draft := range left {
+		got = append(got, draft.NoteID)
+	}
+	if want := []string{kept.ID}; !reflect.DeepEqual(got, want) {
+		t.Errorf("drafts left after purging = %v, want %v", got, want)
+	}
+}
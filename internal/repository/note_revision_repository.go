@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"github.com/curtisnewbie/miso/flow"
+	"github.com/curtisnewbie/miso/middleware/dbquery"
+	"github.com/curtisnewbie/nota/internal/domain"
+	"gorm.io/gorm"
+)
+
+// NoteRevisionRepository defines the interface for note revision data operations
+//
+// Revisions are written by NoteRepository.Save, in the same transaction that updates the note.
+type NoteRevisionRepository interface {
+	FindByNoteID(rail flow.Rail, noteID string) ([]*domain.NoteRevision, error)
+	FindByNoteIDAndVersion(rail flow.Rail, noteID string, version int) (*domain.NoteRevision, error)
+}
+
+// SQLiteNoteRevisionRepository implements NoteRevisionRepository for SQLite
+type SQLiteNoteRevisionRepository struct {
+	db *gorm.DB
+}
+
+// NewSQLiteNoteRevisionRepository creates a new SQLite note revision repository
+func NewSQLiteNoteRevisionRepository(db *gorm.DB) NoteRevisionRepository {
+	return &SQLiteNoteRevisionRepository{db: db}
+}
+
+// FindByNoteID finds all revisions of a note sorted by version DESC
+func (r *SQLiteNoteRevisionRepository) FindByNoteID(rail flow.Rail, noteID string) ([]*domain.NoteRevision, error) {
+	rail.Debugf("Finding revisions of note: %s", noteID)
+	var revisions []*domain.NoteRevision
+	q := dbquery.NewQuery(rail, r.db).Table("note_revision").
+		Where("note_id = ?", noteID).
+		Order("version DESC")
+	_, err := q.Scan(&revisions)
+	rail.Debugf("Found %d revisions", len(revisions))
+	return revisions, err
+}
+
+// FindByNoteIDAndVersion finds a specific revision of a note
+func (r *SQLiteNoteRevisionRepository) FindByNoteIDAndVersion(rail flow.Rail, noteID string, version int) (*domain.NoteRevision, error) {
+	rail.Debugf("Finding revision %d of note: %s", version, noteID)
+	var revision domain.NoteRevision
+	q := dbquery.NewQuery(rail, r.db).Table("note_revision").
+		Where("note_id = ? AND version = ?", noteID, version)
+	ok, err := q.ScanAny(&revision)
+	if err != nil {
+		rail.Errorf("Failed to find revision %d of note %s: %v", version, noteID, err)
+		return nil, err
+	}
+	if !ok {
+		rail.Warnf("Revision %d of note %s not found", version, noteID)
+		return nil, dbquery.ErrRecordNotFound
+	}
+	return &revision, nil
+}
@@ -0,0 +1,182 @@
+package repository
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/curtisnewbie/miso/flow"
+	"github.com/curtisnewbie/miso/middleware/dbquery"
+	"gorm.io/gorm"
+)
+
+const (
+	// ftsRankOrder ranks FTS matches by bm25, a match in title weighs 10 times as much as a match in content
+	ftsRankOrder = "bm25(note_fts, 10.0, 1.0)"
+)
+
+// ftsToken is a single term, phrase or operator parsed from a search query
+type ftsToken struct {
+	text     string
+	operator bool
+	prefix   bool
+	phrase   bool
+}
+
+// isFullTextSearchAvailable checks whether the FTS5 index for notes has been created and can be queried
+func isFullTextSearchAvailable(rail flow.Rail, db *gorm.DB) bool {
+	var fts5 bool
+	err := dbquery.NewQuery(rail, db).Raw("SELECT sqlite_compileoption_used('ENABLE_FTS5')").ScanVal(&fts5)
+	if err != nil || !fts5 {
+		return false
+	}
+	var tables int64
+	err = dbquery.NewQuery(rail, db).
+		Raw("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'note_fts'").
+		ScanVal(&tables)
+	return err == nil && tables > 0
+}
+
+// buildFTSQuery converts a user search query into FTS5 MATCH expressions
+//
+// Supported syntax:
+//   - "quoted phrase" matches the words in order
+//   - term* matches every word starting with term
+//   - NOT, AND, OR (uppercase) are passed through as operators
+//   - NOT at the start of the query excludes the notes matching the next term, e.g., "NOT draft"
+//
+// Every term is quoted so that punctuation in the query never produces an FTS5 syntax error, and the last
+// term is matched as a prefix unless the query ends with whitespace, since search runs while the user types.
+//
+// The notes must match match and must not match exclude, FTS5 can't express a NOT without a term before it.
+// Both are empty if the query doesn't contain any searchable term, either may be empty on its own.
+func buildFTSQuery(query string) (match string, exclude string) {
+	tokens := tokenizeFTSQuery(query)
+
+	for i := len(tokens) - 1; i >= 0; i-- {
+		if !tokens[i].operator {
+			if !tokens[i].phrase && !strings.HasSuffix(query, " ") {
+				tokens[i].prefix = true
+			}
+			break
+		}
+	}
+
+	// Take out the terms negated at the start of the query, NOT is only kept between two operands
+	var excluded []ftsToken
+	for {
+		i := 0
+		negated := false
+		for i < len(tokens) && tokens[i].operator {
+			negated = tokens[i].text == "NOT"
+			i++
+		}
+		tokens = tokens[i:]
+		if !negated || len(tokens) == 0 {
+			break
+		}
+		excluded = append(excluded, tokens[0])
+		tokens = tokens[1:]
+	}
+
+	// Drop operators that don't sit between two operands
+	var cleaned []ftsToken
+	for _, tok := range tokens {
+		if tok.operator && (len(cleaned) == 0 || cleaned[len(cleaned)-1].operator) {
+			continue
+		}
+		cleaned = append(cleaned, tok)
+	}
+	for len(cleaned) > 0 && cleaned[len(cleaned)-1].operator {
+		cleaned = cleaned[:len(cleaned)-1]
+	}
+
+	parts := make([]string, 0, len(cleaned))
+	for _, tok := range cleaned {
+		parts = append(parts, formatFTSToken(tok))
+	}
+	excludedParts := make([]string, 0, len(excluded))
+	for _, tok := range excluded {
+		excludedParts = append(excludedParts, formatFTSToken(tok))
+	}
+	return strings.Join(parts, " "), strings.Join(excludedParts, " OR ")
+}
+
+// formatFTSToken formats a token of a search query for a MATCH expression, terms and phrases are quoted
+func formatFTSToken(tok ftsToken) string {
+	if tok.operator {
+		return tok.text
+	}
+	part := `"` + strings.ReplaceAll(tok.text, `"`, `""`) + `"`
+	if tok.prefix {
+		part += "*"
+	}
+	return part
+}
+
+// titleOnlyFTSQuery restricts an FTS5 MATCH expression built by buildFTSQuery to the title column
+func titleOnlyFTSQuery(match string) string {
+	return "{title} : (" + match + ")"
+}
+
+// tokenizeFTSQuery splits a search query into terms, phrases and operators
+func tokenizeFTSQuery(query string) []ftsToken {
+	var tokens []ftsToken
+	runes := []rune(query)
+
+	for i := 0; i < len(runes); {
+		if unicode.IsSpace(runes[i]) {
+			i++
+			continue
+		}
+
+		if runes[i] == '"' {
+			// Phrase, an unterminated quote runs to the end of the query
+			j := i + 1
+			for j < len(runes) && runes[j] != '"' {
+				j++
+			}
+			text := strings.TrimSpace(string(runes[i+1 : j]))
+			i = j + 1
+			prefix := false
+			if i < len(runes) && runes[i] == '*' {
+				prefix = true
+				i++
+			}
+			if hasSearchableRune(text) {
+				tokens = append(tokens, ftsToken{text: text, phrase: true, prefix: prefix})
+			}
+			continue
+		}
+
+		j := i
+		for j < len(runes) && !unicode.IsSpace(runes[j]) && runes[j] != '"' {
+			j++
+		}
+		word := string(runes[i:j])
+		i = j
+
+		switch word {
+		case "NOT", "AND", "OR":
+			tokens = append(tokens, ftsToken{text: word, operator: true})
+			continue
+		}
+
+		prefix := strings.HasSuffix(word, "*")
+		word = strings.TrimRight(word, "*")
+		if hasSearchableRune(word) {
+			tokens = append(tokens, ftsToken{text: word, prefix: prefix})
+		}
+	}
+
+	return tokens
+}
+
+// hasSearchableRune checks whether s contains any rune that the unicode61 tokenizer indexes
+func hasSearchableRune(s string) bool {
+	for _, r := range s {
+		if unicode.IsLetter(r) || unicode.IsNumber(r) {
+			return true
+		}
+	}
+	return false
+}
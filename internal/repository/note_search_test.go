@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBuildFTSQuery(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		match   string
+		exclude string
+	}{
+		{"empty", "", "", ""},
+		{"prefix while typing", "foo", `"foo"*`, ""},
+		{"no prefix after trailing space", "foo ", `"foo"`, ""},
+		{"explicit prefix", "foo* ", `"foo"*`, ""},
+		{"implicit and", "foo bar", `"foo" "bar"*`, ""},
+		{"not between operands", "meeting NOT draft ", `"meeting" NOT "draft"`, ""},
+		{"leading not", "NOT draft", "", `"draft"*`},
+		{"leading not with trailing space", "NOT draft ", "", `"draft"`},
+		{"leading not before other terms", "NOT draft meeting", `"meeting"*`, `"draft"`},
+		{"several leading nots", "NOT a NOT b c ", `"c"`, `"a" OR "b"`},
+		{"leading and", "AND foo ", `"foo"`, ""},
+		{"trailing not", "foo NOT", `"foo"*`, ""},
+		{"trailing operators", "foo AND OR ", `"foo"`, ""},
+		{"stacked operators", "foo AND OR bar ", `"foo" AND "bar"`, ""},
+		{"only operators", "NOT AND OR", "", ""},
+		{"phrase", `"exact words"`, `"exact words"`, ""},
+		{"phrase prefix", `"exact wor"*`, `"exact wor"*`, ""},
+		{"unterminated quote", `say "hello wor`, `"say" "hello wor"`, ""},
+		{"quote inside term", `a"b c"`, `"a" "b c"`, ""},
+		{"punctuation is quoted", "c++ ", `"c++"`, ""},
+		{"unsearchable terms are dropped", "*** -- foo ", `"foo"`, ""},
+		{"lowercase operators are terms", "not draft ", `"not" "draft"`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			match, exclude := buildFTSQuery(tt.query)
+			if match != tt.match || exclude != tt.exclude {
+				t.Errorf("buildFTSQuery(%q) = (%q, %q), want (%q, %q)", tt.query, match, exclude, tt.match, tt.exclude)
+			}
+		})
+	}
+}
+
+func TestTokenizeFTSQuery(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  []ftsToken
+	}{
+		{"empty", "", nil},
+		{"whitespace", "  \t ", nil},
+		{"terms", "foo bar*", []ftsToken{{text: "foo"}, {text: "bar", prefix: true}}},
+		{"operators", "a NOT b", []ftsToken{{text: "a"}, {text: "NOT", operator: true}, {text: "b"}}},
+		{"phrase", `"bar baz"* qux`, []ftsToken{{text: "bar baz", phrase: true, prefix: true}, {text: "qux"}}},
+		{"unterminated quote", `"bar baz`, []ftsToken{{text: "bar baz", phrase: true}}},
+		{"empty phrase", `"  " foo`, []ftsToken{{text: "foo"}}},
+		{"unsearchable", "*** --", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tokenizeFTSQuery(tt.query); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tokenizeFTSQuery(%q) = %+v, want %+v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"github.com/curtisnewbie/miso/flow"
+	"github.com/curtisnewbie/miso/middleware/dbquery"
+	"github.com/curtisnewbie/nota/internal/domain"
+	"gorm.io/gorm"
+)
+
+// NoteTaskRepository defines the interface for the task index, the index is kept up to date by NoteRepository.Save
+type NoteTaskRepository interface {
+	FindTasks(rail flow.Rail, filter domain.TaskFilter) ([]*domain.NoteTask, error)
+	FindTask(rail flow.Rail, noteID string, line int) (*domain.NoteTask, error)
+}
+
+// SQLiteNoteTaskRepository implements NoteTaskRepository for SQLite
+type SQLiteNoteTaskRepository struct {
+	db *gorm.DB
+}
+
+// NewSQLiteNoteTaskRepository creates a new SQLite note task repository
+func NewSQLiteNoteTaskRepository(db *gorm.DB) NoteTaskRepository {
+	return &SQLiteNoteTaskRepository{db: db}
+}
+
+// FindTasks finds the tasks of the notes (excluding soft-deleted, archived and templates), the ones due the earliest
+// first and the ones without a due date last, tasks of a note are kept in the order they are written
+//
+// The checkboxes of templates are placeholders, they are indexed but not listed, so that turning a note into a template
+// or back only changes its metadata.
+func (r *SQLiteNoteTaskRepository) FindTasks(rail flow.Rail, filter domain.TaskFilter) ([]*domain.NoteTask, error) {
+	rail.Debugf("Finding tasks: %+v", filter)
+	var tasks []*domain.NoteTask
+	q := dbquery.NewQuery(rail, r.db).Table("note_task").
+		Select("note_task.*, note.title AS note_title").
+		Joins("JOIN note ON note.id = note_task.note_id").
+		Where("note.deleted_at IS NULL AND note.archived_at IS NULL").
+		Where("CASE WHEN json_valid(note.metadata) THEN json_extract(note.metadata, ?) IS NOT 1 ELSE 1 END", "$."+domain.TemplateMetadataKey)
+	if !filter.IncludeDone {
+		q = q.Where("note_task.done = 0")
+	}
+	if filter.Tag != "" {
+		q = q.Where("(' ' || note_task.tags || ' ') LIKE ?", "% "+filter.Tag+" %")
+	}
+	q = q.Order("note_task.due_date = '' ASC, note_task.due_date ASC, note.updated_at DESC, note_task.line ASC")
+	_, err := q.Scan(&tasks)
+	return tasks, err
+}
+
+// FindTask finds the task at a line of a note
+func (r *SQLiteNoteTaskRepository) FindTask(rail flow.Rail, noteID string, line int) (*domain.NoteTask, error) {
+	var task domain.NoteTask
+	ok, err := dbquery.NewQuery(rail, r.db).Table("note_task").
+		Where("note_id = ? AND line = ?", noteID, line).
+		ScanAny(&task)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, dbquery.ErrRecordNotFound
+	}
+	return &task, nil
+}
+
+// replaceTasks replaces the indexed tasks of a note with the tasks in its content, locked notes have no tasks indexed
+func replaceTasks(qry func() *dbquery.Query, note *domain.Note) error {
+	if _, err := qry().Table("note_task").Where("note_id = ?", note.ID).Delete(); err != nil {
+		return err
+	}
+	if note.Encrypted {
+		return nil
+	}
+	for _, task := range domain.ParseTasks(note.Content) {
+		err := qry().ExecAny("INSERT OR REPLACE INTO note_task (note_id, line, text, done, due_date, tags) VALUES (?, ?, ?, ?, ?, ?)",
+			note.ID, task.Line, task.Text, task.Done, task.DueDate, task.Tags)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"github.com/curtisnewbie/miso/flow"
+	"github.com/curtisnewbie/miso/middleware/dbquery"
+	"github.com/curtisnewbie/miso/util/atom"
+	"github.com/curtisnewbie/miso/util/idutil"
+	"github.com/curtisnewbie/nota/internal/domain"
+	"gorm.io/gorm"
+)
+
+// subtreeQuery selects the ID of a notebook and the IDs of all its descendants
+const subtreeQuery = `WITH RECURSIVE subtree(id) AS (
+	SELECT ? UNION ALL SELECT notebook.id FROM notebook JOIN subtree ON notebook.parent_id = subtree.id
+) SELECT id FROM subtree`
+
+// NotebookRepository defines the interface for notebook data operations
+type NotebookRepository interface {
+	Create(rail flow.Rail, notebook *domain.Notebook) error
+	FindAll(rail flow.Rail) ([]*domain.Notebook, error)
+	FindByID(rail flow.Rail, id string) (*domain.Notebook, error)
+	FindSubtreeIDs(rail flow.Rail, id string) ([]string, error)
+	Rename(rail flow.Rail, id string, name string) error
+	Move(rail flow.Rail, id string, parentID string) error
+	Delete(rail flow.Rail, id string) error
+}
+
+// SQLiteNotebookRepository implements NotebookRepository for SQLite
+type SQLiteNotebookRepository struct {
+	db *gorm.DB
+}
+
+// NewSQLiteNotebookRepository creates a new SQLite notebook repository
+func NewSQLiteNotebookRepository(db *gorm.DB) NotebookRepository {
+	return &SQLiteNotebookRepository{db: db}
+}
+
+// Create creates a new notebook
+func (r *SQLiteNotebookRepository) Create(rail flow.Rail, notebook *domain.Notebook) error {
+	rail.Infof("Creating notebook: %s", notebook.Name)
+	if notebook.ID == "" {
+		notebook.ID = idutil.Id("nb")
+	}
+	notebook.CreatedAt = atom.Now()
+	notebook.UpdatedAt = notebook.CreatedAt
+	_, err := dbquery.NewQuery(rail, r.db).Table("notebook").Create(notebook)
+	if err != nil {
+		rail.Errorf("Failed to create notebook %s: %v", notebook.Name, err)
+	}
+	return err
+}
+
+// FindAll finds all notebooks sorted by name
+func (r *SQLiteNotebookRepository) FindAll(rail flow.Rail) ([]*domain.Notebook, error) {
+	rail.Debugf("Finding all notebooks")
+	var notebooks []*domain.Notebook
+	q := dbquery.NewQuery(rail, r.db).Table("notebook").Order("name ASC")
+	_, err := q.Scan(&notebooks)
+	rail.Debugf("Found %d notebooks", len(notebooks))
+	return notebooks, err
+}
+
+// FindByID finds a notebook by ID
+func (r *SQLiteNotebookRepository) FindByID(rail flow.Rail, id string) (*domain.Notebook, error) {
+	rail.Debugf("Finding notebook by ID: %s", id)
+	var notebook domain.Notebook
+	ok, err := dbquery.NewQuery(rail, r.db).Table("notebook").Where("id = ?", id).ScanAny(&notebook)
+	if err != nil {
+		rail.Errorf("Failed to find notebook %s: %v", id, err)
+		return nil, err
+	}
+	if !ok {
+		rail.Warnf("Notebook not found: %s", id)
+		return nil, dbquery.ErrRecordNotFound
+	}
+	return &notebook, nil
+}
+
+// FindSubtreeIDs finds the ID of a notebook together with the IDs of all its descendants
+func (r *SQLiteNotebookRepository) FindSubtreeIDs(rail flow.Rail, id string) ([]string, error) {
+	rail.Debugf("Finding subtree of notebook: %s", id)
+	var ids []string
+	_, err := dbquery.NewQuery(rail, r.db).Raw(subtreeQuery, id).Scan(&ids)
+	return ids, err
+}
+
+// Rename renames a notebook
+func (r *SQLiteNotebookRepository) Rename(rail flow.Rail, id string, name string) error {
+	rail.Infof("Renaming notebook %s to: %s", id, name)
+	err := dbquery.NewQuery(rail, r.db).Table("notebook").Where("id = ?", id).
+		Set("name", name).
+		Set("updated_at", atom.Now()).
+		UpdateAny()
+	if err != nil {
+		rail.Errorf("Failed to rename notebook %s: %v", id, err)
+	}
+	return err
+}
+
+// Move moves a notebook under another notebook, an empty parentID moves it to the top level
+func (r *SQLiteNotebookRepository) Move(rail flow.Rail, id string, parentID string) error {
+	rail.Infof("Moving notebook %s to: %s", id, parentID)
+	err := dbquery.NewQuery(rail, r.db).Table("notebook").Where("id = ?", id).
+		Set("parent_id", parentID).
+		Set("updated_at", atom.Now()).
+		UpdateAny()
+	if err != nil {
+		rail.Errorf("Failed to move notebook %s to %s: %v", id, parentID, err)
+	}
+	return err
+}
+
+// Delete deletes a notebook with all its descendants, notes inside them are moved to the trash
+func (r *SQLiteNotebookRepository) Delete(rail flow.Rail, id string) error {
+	rail.Infof("Deleting notebook: %s", id)
+	err := dbquery.RunTransaction(rail, r.db, func(qry func() *dbquery.Query) error {
+		var ids []string
+		if _, err := qry().Raw(subtreeQuery, id).Scan(&ids); err != nil {
+			return err
+		}
+		err := qry().Table("note").
+			Where("notebook_id IN ? AND deleted_at IS NULL", ids).
+			Set("deleted_at", atom.Now()).
+			UpdateAny()
+		if err != nil {
+			return err
+		}
+		_, err = qry().Table("notebook").Where("id IN ?", ids).Delete()
+		return err
+	})
+	if err != nil {
+		rail.Errorf("Failed to delete notebook %s: %v", id, err)
+	}
+	return err
+}
@@ -0,0 +1,173 @@
+package repository
+
+import (
+	"github.com/curtisnewbie/miso/flow"
+	"github.com/curtisnewbie/miso/middleware/dbquery"
+	"github.com/curtisnewbie/miso/util/atom"
+	"github.com/curtisnewbie/miso/util/idutil"
+	"github.com/curtisnewbie/nota/internal/domain"
+	"gorm.io/gorm"
+)
+
+// TagRepository defines the interface for tag data operations
+type TagRepository interface {
+	Create(rail flow.Rail, tag *domain.Tag) error
+	FindAll(rail flow.Rail) ([]*domain.Tag, error)
+	FindByID(rail flow.Rail, id string) (*domain.Tag, error)
+	FindByName(rail flow.Rail, name string) (*domain.Tag, error)
+	FindByNoteID(rail flow.Rail, noteID string) ([]*domain.Tag, error)
+	Rename(rail flow.Rail, id string, name string) error
+	Delete(rail flow.Rail, id string) error
+	Merge(rail flow.Rail, sourceID string, targetID string) error
+	AddToNote(rail flow.Rail, noteID string, tagID string) error
+	RemoveFromNote(rail flow.Rail, noteID string, tagID string) error
+}
+
+// SQLiteTagRepository implements TagRepository for SQLite
+type SQLiteTagRepository struct {
+	db *gorm.DB
+}
+
+// NewSQLiteTagRepository creates a new SQLite tag repository
+func NewSQLiteTagRepository(db *gorm.DB) TagRepository {
+	return &SQLiteTagRepository{db: db}
+}
+
+// Create creates a new tag
+func (r *SQLiteTagRepository) Create(rail flow.Rail, tag *domain.Tag) error {
+	rail.Infof("Creating tag: %s", tag.Name)
+	if tag.ID == "" {
+		tag.ID = idutil.Id("tag")
+	}
+	tag.CreatedAt = atom.Now()
+	_, err := dbquery.NewQuery(rail, r.db).Table("tag").Create(tag)
+	if err != nil {
+		rail.Errorf("Failed to create tag %s: %v", tag.Name, err)
+	}
+	return err
+}
+
+// FindAll finds all tags sorted by name
+func (r *SQLiteTagRepository) FindAll(rail flow.Rail) ([]*domain.Tag, error) {
+	rail.Debugf("Finding all tags")
+	var tags []*domain.Tag
+	q := dbquery.NewQuery(rail, r.db).Table("tag").Order("name ASC")
+	_, err := q.Scan(&tags)
+	rail.Debugf("Found %d tags", len(tags))
+	return tags, err
+}
+
+// FindByID finds a tag by ID
+func (r *SQLiteTagRepository) FindByID(rail flow.Rail, id string) (*domain.Tag, error) {
+	rail.Debugf("Finding tag by ID: %s", id)
+	var tag domain.Tag
+	ok, err := dbquery.NewQuery(rail, r.db).Table("tag").Where("id = ?", id).ScanAny(&tag)
+	if err != nil {
+		rail.Errorf("Failed to find tag %s: %v", id, err)
+		return nil, err
+	}
+	if !ok {
+		rail.Warnf("Tag not found: %s", id)
+		return nil, dbquery.ErrRecordNotFound
+	}
+	return &tag, nil
+}
+
+// FindByName finds a tag by name
+func (r *SQLiteTagRepository) FindByName(rail flow.Rail, name string) (*domain.Tag, error) {
+	rail.Debugf("Finding tag by name: %s", name)
+	var tag domain.Tag
+	ok, err := dbquery.NewQuery(rail, r.db).Table("tag").Where("name = ?", name).ScanAny(&tag)
+	if err != nil {
+		rail.Errorf("Failed to find tag %s: %v", name, err)
+		return nil, err
+	}
+	if !ok {
+		rail.Debugf("Tag not found with name: %s", name)
+		return nil, dbquery.ErrRecordNotFound
+	}
+	return &tag, nil
+}
+
+// FindByNoteID finds the tags of a note sorted by name
+func (r *SQLiteTagRepository) FindByNoteID(rail flow.Rail, noteID string) ([]*domain.Tag, error) {
+	rail.Debugf("Finding tags of note: %s", noteID)
+	var tags []*domain.Tag
+	q := dbquery.NewQuery(rail, r.db).Table("tag").
+		Select("tag.*").
+		Joins("JOIN note_tag ON note_tag.tag_id = tag.id").
+		Where("note_tag.note_id = ?", noteID).
+		Order("tag.name ASC")
+	_, err := q.Scan(&tags)
+	return tags, err
+}
+
+// Rename renames a tag
+func (r *SQLiteTagRepository) Rename(rail flow.Rail, id string, name string) error {
+	rail.Infof("Renaming tag %s to: %s", id, name)
+	err := dbquery.NewQuery(rail, r.db).Table("tag").Where("id = ?", id).Set("name", name).UpdateAny()
+	if err != nil {
+		rail.Errorf("Failed to rename tag %s: %v", id, err)
+	}
+	return err
+}
+
+// Delete deletes a tag and detaches it from all notes
+func (r *SQLiteTagRepository) Delete(rail flow.Rail, id string) error {
+	rail.Infof("Deleting tag: %s", id)
+	err := dbquery.RunTransaction(rail, r.db, func(qry func() *dbquery.Query) error {
+		if _, err := qry().Table("note_tag").Where("tag_id = ?", id).Delete(); err != nil {
+			return err
+		}
+		_, err := qry().Table("tag").Where("id = ?", id).Delete()
+		return err
+	})
+	if err != nil {
+		rail.Errorf("Failed to delete tag %s: %v", id, err)
+	}
+	return err
+}
+
+// Merge moves every note tagged with source to target, then deletes source
+func (r *SQLiteTagRepository) Merge(rail flow.Rail, sourceID string, targetID string) error {
+	rail.Infof("Merging tag %s into: %s", sourceID, targetID)
+	err := dbquery.RunTransaction(rail, r.db, func(qry func() *dbquery.Query) error {
+		err := qry().ExecAny(`INSERT OR IGNORE INTO note_tag (note_id, tag_id)
+			SELECT note_id, ? FROM note_tag WHERE tag_id = ?`, targetID, sourceID)
+		if err != nil {
+			return err
+		}
+		if _, err := qry().Table("note_tag").Where("tag_id = ?", sourceID).Delete(); err != nil {
+			return err
+		}
+		_, err = qry().Table("tag").Where("id = ?", sourceID).Delete()
+		return err
+	})
+	if err != nil {
+		rail.Errorf("Failed to merge tag %s into %s: %v", sourceID, targetID, err)
+	}
+	return err
+}
+
+// AddToNote attaches a tag to a note, attaching an already attached tag is a no-op
+func (r *SQLiteTagRepository) AddToNote(rail flow.Rail, noteID string, tagID string) error {
+	rail.Infof("Adding tag %s to note: %s", tagID, noteID)
+	err := dbquery.NewQuery(rail, r.db).
+		ExecAny("INSERT OR IGNORE INTO note_tag (note_id, tag_id) VALUES (?, ?)", noteID, tagID)
+	if err != nil {
+		rail.Errorf("Failed to add tag %s to note %s: %v", tagID, noteID, err)
+	}
+	return err
+}
+
+// RemoveFromNote detaches a tag from a note
+func (r *SQLiteTagRepository) RemoveFromNote(rail flow.Rail, noteID string, tagID string) error {
+	rail.Infof("Removing tag %s from note: %s", tagID, noteID)
+	_, err := dbquery.NewQuery(rail, r.db).Table("note_tag").
+		Where("note_id = ? AND tag_id = ?", noteID, tagID).
+		Delete()
+	if err != nil {
+		rail.Errorf("Failed to remove tag %s from note %s: %v", tagID, noteID, err)
+	}
+	return err
+}
@@ -0,0 +1,417 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/curtisnewbie/miso/flow"
+	"github.com/curtisnewbie/miso/util/atom"
+	"github.com/curtisnewbie/nota/internal/domain"
+	"github.com/curtisnewbie/nota/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// defaultPageSize is the number of notes listed when the request has no limit
+	defaultPageSize = 50
+
+	// maxPageSize is the maximum number of notes listed in a single request
+	maxPageSize = 500
+
+	// maxBodySize is the maximum size of a request body
+	maxBodySize = 10 << 20
+)
+
+// ErrInvalidOrigin is returned when an allowed origin is not a scheme and a host, e.g., https://example.com
+var ErrInvalidOrigin = errors.New("origin must be an http or https scheme and a host, e.g., https://example.com")
+
+// Server is the HTTP server exposing notes as a JSON REST API
+//
+//	GET    /api/v1/notes?q=&tag=&notebook_id=&offset=&limit=
+//	POST   /api/v1/notes
+//	GET    /api/v1/notes/:id
+//	PUT    /api/v1/notes/:id
+//	DELETE /api/v1/notes/:id
+//
+// Every request must carry the token in the Authorization header, i.e., "Authorization: Bearer <token>". Scripts
+// running in a browser may only call the API from the allowed origin, no other origin is sent CORS headers.
+type Server struct {
+	noteService service.NoteService
+	token       string
+	onChange    func()
+
+	mu            sync.Mutex
+	allowedOrigin string
+	httpServer    *http.Server
+	addr          string
+}
+
+// noteRequest is the body of requests creating or updating a note, fields left out are kept as is on update,
+// metadata can only be set on create, updates carrying metadata are rejected with 400 Bad Request.
+//
+// Updates carrying a version are rejected with 409 Conflict when the note was updated since that version, and updates
+// of the title or the content of a locked note are rejected with 423 Locked.
+type noteRequest struct {
+	Title      *string                `json:"title"`
+	Content    *string                `json:"content"`
+	NotebookID *string                `json:"notebook_id"`
+	Metadata   map[string]interface{} `json:"metadata"`
+	Version    *int                   `json:"version"`
+}
+
+// noteListResponse is the body of responses listing notes
+type noteListResponse struct {
+	Notes  []domain.NoteJSON `json:"notes"`
+	Offset int               `json:"offset"`
+	Limit  int               `json:"limit"`
+}
+
+// errorResponse is the body of responses to failed requests
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// NewServer creates a new API server, onChange is called after a note is created, updated or deleted
+func NewServer(noteService service.NoteService, token string, onChange func()) *Server {
+	return &Server{
+		noteService: noteService,
+		token:       token,
+		onChange:    onChange,
+	}
+}
+
+// Start starts listening on addr and serves requests in the background
+func (s *Server) Start(addr string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.httpServer != nil {
+		return errors.New("server is already running")
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	httpServer := &http.Server{
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	s.httpServer = httpServer
+	s.addr = listener.Addr().String()
+
+	rail := flow.EmptyRail()
+	rail.Infof("API server listening on: %s", s.addr)
+	go func() {
+		if err := httpServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			rail.Errorf("API server stopped: %v", err)
+		}
+	}()
+	return nil
+}
+
+// Stop stops the server, requests in progress are given a few seconds to complete
+func (s *Server) Stop() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.httpServer == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := s.httpServer.Shutdown(ctx)
+	s.httpServer = nil
+	flow.EmptyRail().Infof("API server stopped")
+	return err
+}
+
+// Addr returns the address the server listens on, empty when the server is not running
+func (s *Server) Addr() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.httpServer == nil {
+		return ""
+	}
+	return s.addr
+}
+
+// SetToken replaces the token that requests must carry
+func (s *Server) SetToken(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.token = token
+}
+
+// SetAllowedOrigin replaces the origin that scripts in a browser may call the API from, empty allows none. The
+// origin must be normalized with NormalizeOrigin.
+func (s *Server) SetAllowedOrigin(origin string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.allowedOrigin = origin
+}
+
+// NormalizeOrigin checks that origin is a scheme and a host, e.g., https://example.com, and returns it the way
+// browsers send it in the Origin header, an empty origin is returned as is
+func NormalizeOrigin(origin string) (string, error) {
+	origin = strings.TrimSuffix(strings.TrimSpace(origin), "/")
+	if origin == "" {
+		return "", nil
+	}
+	u, err := url.Parse(origin)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.User != nil ||
+		u.Path != "" || u.RawQuery != "" || u.Fragment != "" {
+		return "", ErrInvalidOrigin
+	}
+	return u.Scheme + "://" + strings.ToLower(u.Host), nil
+}
+
+// Handler returns the HTTP handler of the API
+func (s *Server) Handler() http.Handler {
+	gin.SetMode(gin.ReleaseMode)
+	engine := gin.New()
+	engine.Use(gin.Recovery(), s.cors)
+
+	api := engine.Group("/api/v1", s.authenticate)
+	api.GET("/notes", s.listNotes)
+	api.POST("/notes", s.createNote)
+	api.GET("/notes/:id", s.getNote)
+	api.PUT("/notes/:id", s.updateNote)
+	api.DELETE("/notes/:id", s.deleteNote)
+	return engine
+}
+
+// cors allows scripts running on the allowed origin, e.g., bookmarklets, to call the API, requests from other origins
+// get no CORS headers and so are blocked by the browser
+func (s *Server) cors(c *gin.Context) {
+	s.mu.Lock()
+	allowed := s.allowedOrigin
+	s.mu.Unlock()
+
+	c.Header("Vary", "Origin")
+	origin := c.GetHeader("Origin")
+	if allowed == "" || origin != allowed {
+		c.Next()
+		return
+	}
+
+	c.Header("Access-Control-Allow-Origin", origin)
+	c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+	c.Header("Access-Control-Allow-Headers", "Authorization, Content-Type")
+	if c.Request.Method == http.MethodOptions {
+		c.AbortWithStatus(http.StatusNoContent)
+		return
+	}
+	c.Next()
+}
+
+// authenticate rejects requests without the token
+func (s *Server) authenticate(c *gin.Context) {
+	s.mu.Lock()
+	expected := s.token
+	s.mu.Unlock()
+
+	token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !ok || expected == "" || subtle.ConstantTimeCompare([]byte(strings.TrimSpace(token)), []byte(expected)) != 1 {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse{Error: "unauthorized"})
+		return
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBodySize)
+	c.Next()
+}
+
+// listNotes lists notes most recently updated first, filtered by the q, tag, notebook_id and archived parameters
+func (s *Server) listNotes(c *gin.Context) {
+	offset, err := queryInt(c, "offset", 0)
+	if err != nil || offset < 0 {
+		s.badRequest(c, "invalid offset")
+		return
+	}
+	limit, err := queryInt(c, "limit", defaultPageSize)
+	if err != nil || limit <= 0 {
+		s.badRequest(c, "invalid limit")
+		return
+	}
+	limit = min(limit, maxPageSize)
+
+	filter := domain.NoteFilter{
+		Query:      c.Query("q"),
+		Tags:       c.QueryArray("tag"),
+		NotebookID: c.Query("notebook_id"),
+		Archived:   c.Query("archived") == "true",
+	}
+
+	rail := flow.EmptyRail()
+	var notes []*domain.Note
+	if filter.IsEmpty() {
+		notes, err = s.noteService.ListNotesPaginated(rail, offset, limit)
+	} else {
+		notes, err = s.noteService.SearchNotesPaginated(rail, filter, offset, limit)
+	}
+	if err != nil {
+		s.writeError(c, err)
+		return
+	}
+
+	result := noteListResponse{Notes: make([]domain.NoteJSON, len(notes)), Offset: offset, Limit: limit}
+	for i, note := range notes {
+		result.Notes[i] = note.ToJSON()
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// getNote returns a single note
+func (s *Server) getNote(c *gin.Context) {
+	note, err := s.noteService.GetNote(flow.EmptyRail(), c.Param("id"))
+	if err != nil {
+		s.writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, note.ToJSON())
+}
+
+// createNote creates a note, the title is required
+func (s *Server) createNote(c *gin.Context) {
+	var req noteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		s.badRequest(c, "invalid request body")
+		return
+	}
+
+	now := atom.Now()
+	note := &domain.Note{
+		Version:   1,
+		Metadata:  req.Metadata,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if note.Metadata == nil {
+		note.Metadata = make(map[string]interface{})
+	}
+	req.apply(note)
+
+	if err := s.noteService.CreateNote(flow.EmptyRail(), note); err != nil {
+		s.writeError(c, err)
+		return
+	}
+	s.changed()
+	c.JSON(http.StatusCreated, note.ToJSON())
+}
+
+// updateNote updates the fields of a note that are present in the request
+func (s *Server) updateNote(c *gin.Context) {
+	var req noteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		s.badRequest(c, "invalid request body")
+		return
+	}
+	if req.Metadata != nil {
+		s.badRequest(c, "metadata can only be set on create")
+		return
+	}
+
+	rail := flow.EmptyRail()
+	note, err := s.noteService.GetNote(rail, c.Param("id"))
+	if err != nil {
+		s.writeError(c, err)
+		return
+	}
+
+	if req.Version != nil {
+		if *req.Version != note.Version {
+			s.writeError(c, service.ErrVersionConflict)
+			return
+		}
+	}
+
+	if note.Encrypted && (req.Title != nil || req.Content != nil) {
+		s.writeError(c, service.ErrNoteLocked)
+		return
+	}
+
+	req.apply(note)
+	switch {
+	case req.Title != nil || req.Content != nil:
+		if req.NotebookID != nil {
+			err = s.noteService.UpdateAndMoveNote(rail, note, note.NotebookID)
+		} else {
+			err = s.noteService.UpdateNote(rail, note)
+		}
+	case req.NotebookID != nil:
+		err = s.noteService.MoveNote(rail, note.ID, note.NotebookID)
+	}
+	if err != nil {
+		s.writeError(c, err)
+		return
+	}
+	s.changed()
+	c.JSON(http.StatusOK, note.ToJSON())
+}
+
+// deleteNote moves a note to the trash
+func (s *Server) deleteNote(c *gin.Context) {
+	if err := s.noteService.DeleteNote(flow.EmptyRail(), c.Param("id")); err != nil {
+		s.writeError(c, err)
+		return
+	}
+	s.changed()
+	c.Status(http.StatusNoContent)
+}
+
+// changed notifies that a note was changed through the API
+func (s *Server) changed() {
+	if s.onChange != nil {
+		s.onChange()
+	}
+}
+
+// badRequest responds with 400 Bad Request
+func (s *Server) badRequest(c *gin.Context, message string) {
+	c.JSON(http.StatusBadRequest, errorResponse{Error: message})
+}
+
+// writeError responds with the status code matching the service error
+func (s *Server) writeError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, service.ErrNoteNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, service.ErrEmptyTitle), errors.Is(err, service.ErrNotebookNotFound):
+		status = http.StatusBadRequest
+	case errors.Is(err, service.ErrVersionConflict):
+		status = http.StatusConflict
+	case errors.Is(err, service.ErrNoteLocked):
+		status = http.StatusLocked
+	}
+	c.JSON(status, errorResponse{Error: err.Error()})
+}
+
+// apply copies the fields present in the request to the note
+func (r *noteRequest) apply(note *domain.Note) {
+	if r.Title != nil {
+		note.Title = strings.TrimSpace(*r.Title)
+	}
+	if r.Content != nil {
+		note.Content = *r.Content
+	}
+	if r.NotebookID != nil {
+		note.NotebookID = *r.NotebookID
+	}
+}
+
+// queryInt parses an integer query parameter
+func queryInt(c *gin.Context, name string, defaultValue int) (int, error) {
+	value := c.Query(name)
+	if value == "" {
+		return defaultValue, nil
+	}
+	return strconv.Atoi(value)
+}
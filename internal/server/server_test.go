@@ -0,0 +1,79 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeOrigin(t *testing.T) {
+	tests := []struct {
+		origin string
+		want   string
+	}{
+		{"", ""},
+		{"  ", ""},
+		{"https://example.com", "https://example.com"},
+		{"https://Example.com/", "https://example.com"},
+		{"http://localhost:8080", "http://localhost:8080"},
+	}
+	for _, tt := range tests {
+		got, err := NormalizeOrigin(tt.origin)
+		if err != nil {
+			t.Errorf("NormalizeOrigin(%q) failed: %v", tt.origin, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("NormalizeOrigin(%q) = %q, want %q", tt.origin, got, tt.want)
+		}
+	}
+
+	for _, origin := range []string{"*", "example.com", "ftp://example.com", "https://example.com/path", "https://example.com?q=1", "https://user@example.com"} {
+		if _, err := NormalizeOrigin(origin); !errors.Is(err, ErrInvalidOrigin) {
+			t.Errorf("NormalizeOrigin(%q) error = %v, want ErrInvalidOrigin", origin, err)
+		}
+	}
+}
+
+func TestCORS(t *testing.T) {
+	s := NewServer(nil, "token", nil)
+	handler := s.Handler()
+	preflight := func(origin string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodOptions, "/api/v1/notes", nil)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := preflight("https://example.com"); rec.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("Access-Control-Allow-Origin = %q without an allowed origin, want none", rec.Header().Get("Access-Control-Allow-Origin"))
+	}
+
+	s.SetAllowedOrigin("https://example.com")
+	rec := preflight("https://example.com")
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("preflight from the allowed origin: status %d, want %d", rec.Code, http.StatusNoContent)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "https://example.com" {
+		t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, "https://example.com")
+	}
+	if rec := preflight("https://evil.example"); rec.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("Access-Control-Allow-Origin = %q for another origin, want none", rec.Header().Get("Access-Control-Allow-Origin"))
+	}
+}
+
+func TestUpdateNoteRejectsMetadata(t *testing.T) {
+	s := NewServer(nil, "token", nil)
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/notes/n1", strings.NewReader(`{"metadata": {"source": "web"}}`))
+	req.Header.Set("Authorization", "Bearer token")
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("PUT with metadata: status %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
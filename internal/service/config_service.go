@@ -1,6 +1,12 @@
 package service
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"time"
+
 	"github.com/curtisnewbie/miso/flow"
 	"github.com/curtisnewbie/nota/internal/domain"
 	"github.com/curtisnewbie/nota/internal/i18n"
@@ -8,13 +14,45 @@ import (
 )
 
 const (
-	configKeyLanguage = "language"
+	configKeyLanguage         = "language"
+	configKeyAPIToken         = "api_token"
+	configKeyAPIServerAddr    = "api_server_addr"
+	configKeyAPIServerEnabled = "api_server_enabled"
+	configKeyAPIAllowedOrigin = "api_allowed_origin"
+	configKeyAutosaveDelay    = "autosave_delay"
+	configKeySnapshotInterval = "snapshot_interval"
+	configKeySnapshotKeep     = "snapshot_keep"
+	configKeyDailyTitleFormat = "daily_title_format"
+
+	// DefaultAPIServerAddr is the address the API server listens on unless configured otherwise
+	DefaultAPIServerAddr = "127.0.0.1:7777"
+
+	// DefaultSnapshotInterval is how often a snapshot of the database is taken unless configured otherwise
+	DefaultSnapshotInterval = time.Hour
+
+	// DefaultSnapshotKeep is how many snapshots are kept unless configured otherwise
+	DefaultSnapshotKeep = 24
+
+	// DefaultAutosaveDelay is how long after the last edit a note is saved automatically unless configured otherwise
+	DefaultAutosaveDelay = 5 * time.Second
 )
 
 // ConfigService defines the interface for config operations
 type ConfigService interface {
 	SaveLanguage(rail flow.Rail, lang i18n.Language) error
 	GetLanguage(rail flow.Rail) (i18n.Language, error)
+	GetAPIToken(rail flow.Rail) (string, error)
+	ResetAPIToken(rail flow.Rail) (string, error)
+	SaveAPIServer(rail flow.Rail, enabled bool, addr string) error
+	GetAPIServer(rail flow.Rail) (enabled bool, addr string)
+	SaveAPIAllowedOrigin(rail flow.Rail, origin string) error
+	GetAPIAllowedOrigin(rail flow.Rail) string
+	SaveAutosaveDelay(rail flow.Rail, delay time.Duration) error
+	GetAutosaveDelay(rail flow.Rail) time.Duration
+	SaveSnapshotSchedule(rail flow.Rail, interval time.Duration, keep int) error
+	GetSnapshotSchedule(rail flow.Rail) (interval time.Duration, keep int)
+	SaveDailyTitleFormat(rail flow.Rail, format string) error
+	GetDailyTitleFormat(rail flow.Rail) string
 }
 
 // ConfigServiceImpl implements ConfigService
@@ -64,3 +102,157 @@ func (s *ConfigServiceImpl) GetLanguage(rail flow.Rail) (i18n.Language, error) {
 	rail.Infof("Language preference: %s", lang)
 	return lang, nil
 }
+
+// GetAPIToken retrieves the token of the API server, a token is generated when there is none yet
+func (s *ConfigServiceImpl) GetAPIToken(rail flow.Rail) (string, error) {
+	config, err := s.configRepo.FindByName(rail, configKeyAPIToken)
+	if err == nil && config.Value != "" {
+		return config.Value, nil
+	}
+	return s.ResetAPIToken(rail)
+}
+
+// ResetAPIToken replaces the token of the API server with a new random token
+func (s *ConfigServiceImpl) ResetAPIToken(rail flow.Rail) (string, error) {
+	rail.Infof("Generating API token")
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	token := hex.EncodeToString(b)
+
+	err := s.configRepo.Save(rail, &domain.Config{Name: configKeyAPIToken, Value: token})
+	if err != nil {
+		rail.Errorf("Failed to save API token: %v", err)
+		return "", err
+	}
+	return token, nil
+}
+
+// SaveAPIServer saves whether the API server is started with the window and the address it listens on
+func (s *ConfigServiceImpl) SaveAPIServer(rail flow.Rail, enabled bool, addr string) error {
+	rail.Infof("Saving API server preference: enabled=%v, addr=%s", enabled, addr)
+
+	err := s.configRepo.Save(rail, &domain.Config{Name: configKeyAPIServerEnabled, Value: fmt.Sprint(enabled)})
+	if err == nil {
+		err = s.configRepo.Save(rail, &domain.Config{Name: configKeyAPIServerAddr, Value: addr})
+	}
+	if err != nil {
+		rail.Errorf("Failed to save API server preference: %v", err)
+	}
+	return err
+}
+
+// GetAPIServer retrieves whether the API server is started with the window and the address it listens on
+func (s *ConfigServiceImpl) GetAPIServer(rail flow.Rail) (enabled bool, addr string) {
+	addr = DefaultAPIServerAddr
+	if config, err := s.configRepo.FindByName(rail, configKeyAPIServerAddr); err == nil && config.Value != "" {
+		addr = config.Value
+	}
+	if config, err := s.configRepo.FindByName(rail, configKeyAPIServerEnabled); err == nil {
+		enabled = config.Value == "true"
+	}
+	return enabled, addr
+}
+
+// SaveAPIAllowedOrigin saves the origin that scripts in a browser may call the API server from, empty allows none
+func (s *ConfigServiceImpl) SaveAPIAllowedOrigin(rail flow.Rail, origin string) error {
+	rail.Infof("Saving API allowed origin: %s", origin)
+
+	err := s.configRepo.Save(rail, &domain.Config{Name: configKeyAPIAllowedOrigin, Value: origin})
+	if err != nil {
+		rail.Errorf("Failed to save API allowed origin: %v", err)
+	}
+	return err
+}
+
+// GetAPIAllowedOrigin retrieves the origin that scripts in a browser may call the API server from, empty allows none
+func (s *ConfigServiceImpl) GetAPIAllowedOrigin(rail flow.Rail) string {
+	config, err := s.configRepo.FindByName(rail, configKeyAPIAllowedOrigin)
+	if err != nil {
+		return ""
+	}
+	return config.Value
+}
+
+// SaveAutosaveDelay saves how long after the last edit a note is saved automatically, 0 turns autosave off
+func (s *ConfigServiceImpl) SaveAutosaveDelay(rail flow.Rail, delay time.Duration) error {
+	rail.Infof("Saving autosave delay: %v", delay)
+
+	err := s.configRepo.Save(rail, &domain.Config{Name: configKeyAutosaveDelay, Value: strconv.Itoa(int(delay.Seconds()))})
+	if err != nil {
+		rail.Errorf("Failed to save autosave delay: %v", err)
+	}
+	return err
+}
+
+// GetAutosaveDelay retrieves how long after the last edit a note is saved automatically, 0 when autosave is turned off
+//
+// DefaultAutosaveDelay is used when the delay hasn't been configured.
+func (s *ConfigServiceImpl) GetAutosaveDelay(rail flow.Rail) time.Duration {
+	config, err := s.configRepo.FindByName(rail, configKeyAutosaveDelay)
+	if err != nil || config.Value == "" {
+		return DefaultAutosaveDelay
+	}
+	seconds, err := strconv.Atoi(config.Value)
+	if err != nil || seconds < 0 {
+		rail.Warnf("Invalid autosave delay: %s, using the default", config.Value)
+		return DefaultAutosaveDelay
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// SaveSnapshotSchedule saves how often a snapshot of the database is taken and how many are kept, 0 turns snapshots off
+func (s *ConfigServiceImpl) SaveSnapshotSchedule(rail flow.Rail, interval time.Duration, keep int) error {
+	rail.Infof("Saving snapshot schedule: interval=%v, keep=%d", interval, keep)
+
+	err := s.configRepo.Save(rail, &domain.Config{Name: configKeySnapshotInterval, Value: strconv.Itoa(int(interval.Seconds()))})
+	if err == nil {
+		err = s.configRepo.Save(rail, &domain.Config{Name: configKeySnapshotKeep, Value: strconv.Itoa(keep)})
+	}
+	if err != nil {
+		rail.Errorf("Failed to save snapshot schedule: %v", err)
+	}
+	return err
+}
+
+// GetSnapshotSchedule retrieves how often a snapshot of the database is taken and how many are kept, the interval is 0 when snapshots are off
+func (s *ConfigServiceImpl) GetSnapshotSchedule(rail flow.Rail) (interval time.Duration, keep int) {
+	interval, keep = DefaultSnapshotInterval, DefaultSnapshotKeep
+	if config, err := s.configRepo.FindByName(rail, configKeySnapshotInterval); err == nil && config.Value != "" {
+		if seconds, err := strconv.Atoi(config.Value); err == nil && seconds >= 0 {
+			interval = time.Duration(seconds) * time.Second
+		} else {
+			rail.Warnf("Invalid snapshot interval: %s, using default", config.Value)
+		}
+	}
+	if config, err := s.configRepo.FindByName(rail, configKeySnapshotKeep); err == nil && config.Value != "" {
+		if n, err := strconv.Atoi(config.Value); err == nil && n > 0 {
+			keep = n
+		} else {
+			rail.Warnf("Invalid number of snapshots to keep: %s, using default", config.Value)
+		}
+	}
+	return interval, keep
+}
+
+// SaveDailyTitleFormat saves the layout that daily notes are titled with, e.g., 2006-01-02
+func (s *ConfigServiceImpl) SaveDailyTitleFormat(rail flow.Rail, format string) error {
+	rail.Infof("Saving daily note title format: %s", format)
+
+	err := s.configRepo.Save(rail, &domain.Config{Name: configKeyDailyTitleFormat, Value: format})
+	if err != nil {
+		rail.Errorf("Failed to save daily note title format: %v", err)
+	}
+	return err
+}
+
+// GetDailyTitleFormat retrieves the layout that daily notes are titled with, domain.DefaultDailyTitleFormat by default
+func (s *ConfigServiceImpl) GetDailyTitleFormat(rail flow.Rail) string {
+	config, err := s.configRepo.FindByName(rail, configKeyDailyTitleFormat)
+	if err != nil || config.Value == "" {
+		return domain.DefaultDailyTitleFormat
+	}
+	return config.Value
+}
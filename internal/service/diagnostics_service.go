@@ -0,0 +1,206 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/curtisnewbie/miso/flow"
+	"github.com/curtisnewbie/miso/util/atom"
+	"github.com/curtisnewbie/nota/internal/domain"
+	"github.com/curtisnewbie/nota/internal/i18n"
+	"github.com/curtisnewbie/nota/internal/repository"
+)
+
+// maxRepairedTitleLength is how many characters of the first line of the content are kept in the title of a note
+// repaired for its empty title
+const maxRepairedTitleLength = 80
+
+// DiagnosticsService defines the interface for checking the database for problems and repairing them
+type DiagnosticsService interface {
+	Diagnose(rail flow.Rail) ([]domain.Issue, error)
+	Repair(rail flow.Rail, issues []domain.Issue) (repaired int, err error)
+}
+
+// DiagnosticsServiceImpl implements DiagnosticsService
+type DiagnosticsServiceImpl struct {
+	diagnosticsRepo repository.DiagnosticsRepository
+}
+
+// NewDiagnosticsService creates a new diagnostics service
+func NewDiagnosticsService(diagnosticsRepo repository.DiagnosticsRepository) DiagnosticsService {
+	return &DiagnosticsServiceImpl{diagnosticsRepo: diagnosticsRepo}
+}
+
+// Diagnose checks the integrity of the database and every note, including the ones in the trash
+//
+// Notes are checked for metadata that is not a JSON object, blank titles and timestamps that are missing or can't be
+// parsed, which would otherwise fail loading the note or be silently replaced.
+func (s *DiagnosticsServiceImpl) Diagnose(rail flow.Rail) ([]domain.Issue, error) {
+	rail.Infof("Diagnosing database")
+
+	var issues []domain.Issue
+	problems, err := s.diagnosticsRepo.CheckIntegrity(rail)
+	if err != nil {
+		rail.Errorf("Failed to check database integrity: %v", err)
+		return nil, err
+	}
+	for _, problem := range problems {
+		issues = append(issues, domain.Issue{Kind: domain.IssueIntegrity, Value: problem})
+	}
+
+	notes, err := s.diagnosticsRepo.FindRawNotes(rail)
+	if err != nil {
+		rail.Errorf("Failed to load notes: %v", err)
+		return nil, err
+	}
+	for _, note := range notes {
+		issues = append(issues, diagnoseNote(note)...)
+	}
+
+	rail.Infof("Found %d issues in database", len(issues))
+	return issues, nil
+}
+
+// Repair repairs the issues, the notes are checked again first and the issues no longer found are skipped
+//
+// An integrity issue is repaired by rebuilding the indexes, it remains when the damage is elsewhere.
+func (s *DiagnosticsServiceImpl) Repair(rail flow.Rail, issues []domain.Issue) (int, error) {
+	repaired := 0
+	reindexed := false
+	for _, issue := range issues {
+		if issue.Kind == domain.IssueIntegrity {
+			if reindexed {
+				continue
+			}
+			if err := s.diagnosticsRepo.Reindex(rail); err != nil {
+				return repaired, fmt.Errorf("failed to rebuild indexes: %w", err)
+			}
+			reindexed = true
+			repaired++
+			continue
+		}
+
+		note, ok, err := s.diagnosticsRepo.FindRawNote(rail, issue.NoteID)
+		if err != nil {
+			return repaired, err
+		}
+		if !ok {
+			continue
+		}
+		column, value, ok := repairNote(*note, issue)
+		if !ok {
+			continue
+		}
+		if err := s.diagnosticsRepo.UpdateColumn(rail, note.ID, column, value); err != nil {
+			rail.Errorf("Failed to repair %s of note %q: %v", column, note.ID, err)
+			return repaired, err
+		}
+		repaired++
+	}
+	return repaired, nil
+}
+
+// diagnoseNote finds the issues of a note
+func diagnoseNote(note repository.RawNote) []domain.Issue {
+	var issues []domain.Issue
+	newIssue := func(kind domain.IssueKind, column string, value string) domain.Issue {
+		return domain.Issue{Kind: kind, NoteID: note.ID, Title: note.Title, Column: column, Value: value}
+	}
+
+	if note.Metadata != nil && !isValidMetadata(*note.Metadata) {
+		issues = append(issues, newIssue(domain.IssueInvalidMetadata, "metadata", *note.Metadata))
+	}
+	if strings.TrimSpace(note.Title) == "" {
+		issues = append(issues, newIssue(domain.IssueEmptyTitle, "title", note.Title))
+	}
+	if !note.CreatedAt.Valid {
+		issues = append(issues, newIssue(domain.IssueInvalidTimestamp, "created_at", note.CreatedAt.Text))
+	}
+	if !note.UpdatedAt.Valid {
+		issues = append(issues, newIssue(domain.IssueInvalidTimestamp, "updated_at", note.UpdatedAt.Text))
+	}
+	if !note.DeletedAt.Null && !note.DeletedAt.Valid {
+		issues = append(issues, newIssue(domain.IssueInvalidTimestamp, "deleted_at", note.DeletedAt.Text))
+	}
+	return issues
+}
+
+// repairNote returns the column of the note to change and its new value, false when the issue is not found anymore
+//
+// Malformed metadata is kept as a string under InvalidMetadataKey, a blank title is taken from the first line of the
+// content, and a missing timestamp is taken from the other timestamps of the note or the current time. A note in the
+// trash with a malformed deleted_at stays in the trash.
+func repairNote(note repository.RawNote, issue domain.Issue) (string, any, bool) {
+	switch issue.Kind {
+	case domain.IssueInvalidMetadata:
+		if note.Metadata == nil || isValidMetadata(*note.Metadata) {
+			return "", nil, false
+		}
+		repaired, err := json.Marshal(map[string]any{domain.InvalidMetadataKey: *note.Metadata})
+		if err != nil {
+			return "", nil, false
+		}
+		return "metadata", string(repaired), true
+
+	case domain.IssueEmptyTitle:
+		if strings.TrimSpace(note.Title) != "" {
+			return "", nil, false
+		}
+		return "title", titleFromContent(note), true
+
+	case domain.IssueInvalidTimestamp:
+		now := atom.Now()
+		switch issue.Column {
+		case "created_at":
+			if note.CreatedAt.Valid {
+				return "", nil, false
+			}
+			if note.UpdatedAt.Valid {
+				return "created_at", atom.WrapTime(note.UpdatedAt.Time), true
+			}
+			return "created_at", now, true
+		case "updated_at":
+			if note.UpdatedAt.Valid {
+				return "", nil, false
+			}
+			if note.CreatedAt.Valid {
+				return "updated_at", atom.WrapTime(note.CreatedAt.Time), true
+			}
+			return "updated_at", now, true
+		case "deleted_at":
+			if note.DeletedAt.Null || note.DeletedAt.Valid {
+				return "", nil, false
+			}
+			return "deleted_at", now, true
+		}
+	}
+	return "", nil, false
+}
+
+// isValidMetadata checks whether the stored metadata can be loaded, it has to be empty or a JSON object
+func isValidMetadata(raw string) bool {
+	if raw == "" {
+		return true
+	}
+	var metadata map[string]any
+	return json.Unmarshal([]byte(raw), &metadata) == nil
+}
+
+// titleFromContent takes the first non-empty line of the content as the title, without markdown heading markers
+func titleFromContent(note repository.RawNote) string {
+	if !note.Encrypted {
+		for _, line := range strings.Split(note.Content, "\n") {
+			line = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), "#"))
+			if line == "" {
+				continue
+			}
+			if utf8.RuneCountInString(line) > maxRepairedTitleLength {
+				line = string([]rune(line)[:maxRepairedTitleLength])
+			}
+			return line
+		}
+	}
+	return i18n.T().Dialog.Untitled
+}
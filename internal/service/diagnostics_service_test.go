@@ -0,0 +1,102 @@
+package service
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/curtisnewbie/miso/util/atom"
+	"github.com/curtisnewbie/nota/internal/domain"
+	"github.com/curtisnewbie/nota/internal/i18n"
+	"github.com/curtisnewbie/nota/internal/repository"
+)
+
+func TestRepairNote(t *testing.T) {
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	updated := time.Date(2024, 2, 3, 4, 5, 6, 0, time.UTC)
+	valid := func(t time.Time) repository.RawTime {
+		return repository.RawTime{Text: t.Format(time.RFC3339), Time: t, Valid: true}
+	}
+	invalid := repository.RawTime{Text: "yesterday"}
+	null := repository.RawTime{Null: true}
+	str := func(s string) *string { return &s }
+	issue := func(kind domain.IssueKind, column string) domain.Issue {
+		return domain.Issue{Kind: kind, NoteID: "n1", Column: column}
+	}
+	longLine := strings.Repeat("é", maxRepairedTitleLength+5)
+
+	tests := []struct {
+		name   string
+		note   repository.RawNote
+		issue  domain.Issue
+		column string
+		value  any // nil when the value is the current time
+		ok     bool
+	}{
+		{"malformed metadata kept", repository.RawNote{Metadata: str("{oops")}, issue(domain.IssueInvalidMetadata, "metadata"),
+			"metadata", `{"invalid_metadata":"{oops"}`, true},
+		{"metadata not an object", repository.RawNote{Metadata: str("[1]")}, issue(domain.IssueInvalidMetadata, "metadata"),
+			"metadata", `{"invalid_metadata":"[1]"}`, true},
+		{"metadata fixed since", repository.RawNote{Metadata: str(`{"a":1}`)}, issue(domain.IssueInvalidMetadata, "metadata"),
+			"", nil, false},
+		{"metadata null since", repository.RawNote{}, issue(domain.IssueInvalidMetadata, "metadata"),
+			"", nil, false},
+		{"title from heading", repository.RawNote{Title: " ", Content: "\n\n## Plan\nbody"}, issue(domain.IssueEmptyTitle, "title"),
+			"title", "Plan", true},
+		{"title truncated", repository.RawNote{Content: longLine}, issue(domain.IssueEmptyTitle, "title"),
+			"title", longLine[:maxRepairedTitleLength*len("é")], true},
+		{"title of empty note", repository.RawNote{Content: " \n#\n"}, issue(domain.IssueEmptyTitle, "title"),
+			"title", i18n.T().Dialog.Untitled, true},
+		{"title of locked note", repository.RawNote{Content: "ciphertext", Encrypted: true}, issue(domain.IssueEmptyTitle, "title"),
+			"title", i18n.T().Dialog.Untitled, true},
+		{"title fixed since", repository.RawNote{Title: "set"}, issue(domain.IssueEmptyTitle, "title"),
+			"", nil, false},
+		{"created_at from updated_at", repository.RawNote{CreatedAt: invalid, UpdatedAt: valid(updated)}, issue(domain.IssueInvalidTimestamp, "created_at"),
+			"created_at", atom.WrapTime(updated), true},
+		{"created_at from now", repository.RawNote{CreatedAt: null, UpdatedAt: invalid}, issue(domain.IssueInvalidTimestamp, "created_at"),
+			"created_at", nil, true},
+		{"updated_at from created_at", repository.RawNote{CreatedAt: valid(created), UpdatedAt: invalid}, issue(domain.IssueInvalidTimestamp, "updated_at"),
+			"updated_at", atom.WrapTime(created), true},
+		{"updated_at from now", repository.RawNote{CreatedAt: invalid, UpdatedAt: null}, issue(domain.IssueInvalidTimestamp, "updated_at"),
+			"updated_at", nil, true},
+		{"timestamp fixed since", repository.RawNote{CreatedAt: valid(created), UpdatedAt: valid(updated)}, issue(domain.IssueInvalidTimestamp, "created_at"),
+			"", nil, false},
+		{"deleted_at stays in trash", repository.RawNote{DeletedAt: invalid}, issue(domain.IssueInvalidTimestamp, "deleted_at"),
+			"deleted_at", nil, true},
+		{"deleted_at restored since", repository.RawNote{DeletedAt: null}, issue(domain.IssueInvalidTimestamp, "deleted_at"),
+			"", nil, false},
+		{"unknown column", repository.RawNote{CreatedAt: invalid}, issue(domain.IssueInvalidTimestamp, "title"),
+			"", nil, false},
+		{"integrity is not a note issue", repository.RawNote{}, domain.Issue{Kind: domain.IssueIntegrity},
+			"", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := time.Now()
+			column, value, ok := repairNote(tt.note, tt.issue)
+			if column != tt.column || ok != tt.ok {
+				t.Fatalf("repairNote() = %q, %v, %v, want %q, %v", column, value, ok, tt.column, tt.ok)
+			}
+			if !ok {
+				return
+			}
+			if tt.value == nil {
+				now, isTime := value.(atom.Time)
+				if !isTime || now.Unwrap().Before(before.Add(-time.Second)) {
+					t.Errorf("repairNote() value = %v, want the current time", value)
+				}
+				return
+			}
+			if tt.column == "metadata" {
+				var metadata map[string]any
+				if err := json.Unmarshal([]byte(value.(string)), &metadata); err != nil {
+					t.Errorf("repaired metadata %v is not a JSON object: %v", value, err)
+				}
+			}
+			if value != tt.value {
+				t.Errorf("repairNote() value = %v, want %v", value, tt.value)
+			}
+		})
+	}
+}
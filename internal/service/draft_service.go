@@ -0,0 +1,53 @@
+package service
+
+import (
+	"github.com/curtisnewbie/miso/flow"
+	"github.com/curtisnewbie/nota/internal/domain"
+	"github.com/curtisnewbie/nota/internal/repository"
+)
+
+// DraftService defines the interface for draft operations
+type DraftService interface {
+	SaveDraft(rail flow.Rail, draft *domain.Draft) error
+	ListDrafts(rail flow.Rail) ([]*domain.Draft, error)
+	DeleteDraft(rail flow.Rail, noteID string) error
+	DeleteAllDrafts(rail flow.Rail) error
+}
+
+// DraftServiceImpl implements DraftService
+type DraftServiceImpl struct {
+	draftRepo repository.DraftRepository
+}
+
+// NewDraftService creates a new draft service
+func NewDraftService(draftRepo repository.DraftRepository) DraftService {
+	return &DraftServiceImpl{draftRepo: draftRepo}
+}
+
+// SaveDraft saves the unsaved edits of a note, replacing its previous draft
+func (s *DraftServiceImpl) SaveDraft(rail flow.Rail, draft *domain.Draft) error {
+	return s.draftRepo.Save(rail, draft)
+}
+
+// ListDrafts lists the drafts left behind, the most recently updated first
+func (s *DraftServiceImpl) ListDrafts(rail flow.Rail) ([]*domain.Draft, error) {
+	return s.draftRepo.FindAll(rail)
+}
+
+// DeleteDraft deletes the draft of a note, an empty noteID deletes the draft of a note that was never saved
+func (s *DraftServiceImpl) DeleteDraft(rail flow.Rail, noteID string) error {
+	err := s.draftRepo.Delete(rail, noteID)
+	if err != nil {
+		rail.Errorf("Failed to delete draft of note %q: %v", noteID, err)
+	}
+	return err
+}
+
+// DeleteAllDrafts deletes every draft
+func (s *DraftServiceImpl) DeleteAllDrafts(rail flow.Rail) error {
+	err := s.draftRepo.DeleteAll(rail)
+	if err != nil {
+		rail.Errorf("Failed to delete drafts: %v", err)
+	}
+	return err
+}
@@ -2,9 +2,14 @@ package service
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/curtisnewbie/miso/flow"
@@ -16,19 +21,52 @@ import (
 type ImportExportService interface {
 	ExportNote(rail flow.Rail, note *domain.Note, path string) error
 	ExportNotes(rail flow.Rail, notes []*domain.Note, dir string) error
+	ExportNotesTo(rail flow.Rail, notes []*domain.Note, w io.Writer) error
+	ExportNotesAsMarkdown(rail flow.Rail, notes []*domain.Note, dir string) error
 	ImportNote(rail flow.Rail, path string, onDuplicate func(note *domain.Note) bool) (*domain.Note, error)
 	ImportNotes(rail flow.Rail, dir string, onDuplicate func(note *domain.Note) bool) ([]*domain.Note, error)
 	ImportNotesFromFile(rail flow.Rail, path string, onDuplicate func(note *domain.Note) bool) ([]*domain.Note, error)
+	ImportMarkdown(rail flow.Rail, dir string, onDuplicate func(note *domain.Note) bool) ([]ImportResult, error)
+}
+
+// ErrNoteInTrash is returned when an imported note has the ID of a note in the trash
+var ErrNoteInTrash = errors.New("a note with the same ID is in the trash")
+
+// ImportStatus is the outcome of importing a single file
+type ImportStatus string
+
+const (
+	ImportStatusImported ImportStatus = "imported"
+	ImportStatusSkipped  ImportStatus = "skipped"
+	ImportStatusFailed   ImportStatus = "failed"
+)
+
+// ImportResult reports how a single file was imported
+type ImportResult struct {
+	Path   string       `json:"path"`
+	Status ImportStatus `json:"status"`
+	NoteID string       `json:"note_id,omitempty"`
+	Title  string       `json:"title,omitempty"`
+	Reason string       `json:"reason,omitempty"`
+}
+
+// ExportData is the batch export format holding every exported note
+type ExportData struct {
+	Version int               `json:"version"`
+	Notes   []domain.NoteJSON `json:"notes"`
+	Count   int               `json:"count"`
 }
 
 // ImportExportServiceImpl implements ImportExportService
 type ImportExportServiceImpl struct {
-	noteRepo repository.NoteRepository
+	noteRepo    repository.NoteRepository
+	tagRepo     repository.TagRepository
+	noteService NoteService
 }
 
-// NewImportExportService creates a new import/export service
-func NewImportExportService(noteRepo repository.NoteRepository) ImportExportService {
-	return &ImportExportServiceImpl{noteRepo: noteRepo}
+// NewImportExportService creates a new import/export service, noteService indexes the links of the imported notes
+func NewImportExportService(noteRepo repository.NoteRepository, tagRepo repository.TagRepository, noteService NoteService) ImportExportService {
+	return &ImportExportServiceImpl{noteRepo: noteRepo, tagRepo: tagRepo, noteService: noteService}
 }
 
 // ExportNote exports a single note to a JSON file
@@ -107,8 +145,104 @@ func (s *ImportExportServiceImpl) ExportNotes(rail flow.Rail, notes []*domain.No
 	return nil
 }
 
+// ExportNotesTo writes notes in the batch export format
+func (s *ImportExportServiceImpl) ExportNotesTo(rail flow.Rail, notes []*domain.Note, w io.Writer) error {
+	rail.Infof("Exporting %d notes", len(notes))
+
+	exportData := ExportData{
+		Version: 1,
+		Notes:   make([]domain.NoteJSON, len(notes)),
+		Count:   len(notes),
+	}
+	for i, note := range notes {
+		exportData.Notes[i] = note.ToJSON()
+	}
+
+	data, err := json.MarshalIndent(exportData, "", "  ")
+	if err != nil {
+		rail.Errorf("Failed to marshal export data: %v", err)
+		return fmt.Errorf("failed to marshal notes: %w", err)
+	}
+
+	if _, err := w.Write(data); err != nil {
+		rail.Errorf("Failed to write export data: %v", err)
+		return fmt.Errorf("failed to write notes: %w", err)
+	}
+	return nil
+}
+
+// ExportNotesAsMarkdown exports notes to a directory as <slug>.md files with YAML front matter
+func (s *ImportExportServiceImpl) ExportNotesAsMarkdown(rail flow.Rail, notes []*domain.Note, dir string) error {
+	if len(notes) == 0 {
+		return fmt.Errorf("no notes to export")
+	}
+
+	if dir == "" {
+		return fmt.Errorf("directory path cannot be empty")
+	}
+
+	rail.Infof("Exporting %d notes as markdown to: %s", len(notes), dir)
+
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		rail.Errorf("Failed to create export directory: %v", err)
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	// Oldest notes are named first, so that a note keeps its file name across exports
+	sorted := make([]*domain.Note, len(notes))
+	copy(sorted, notes)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].CreatedAt.Before(sorted[j].CreatedAt)
+	})
+
+	used := map[string]bool{}
+	successCount := 0
+	for _, note := range sorted {
+		tags, err := s.tagRepo.FindByNoteID(rail, note.ID)
+		if err != nil {
+			rail.Warnf("Failed to find tags of note %s: %v", note.ID, err)
+		}
+		tagNames := make([]string, 0, len(tags))
+		for _, tag := range tags {
+			tagNames = append(tagNames, tag.Name)
+		}
+
+		data, err := toMarkdown(note, tagNames)
+		if err != nil {
+			rail.Warnf("Failed to export note %s: %v", note.ID, err)
+			continue
+		}
+
+		path := filepath.Join(dir, uniqueSlug(note.Title, used)+".md")
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			rail.Warnf("Failed to write note %s to %s: %v", note.ID, path, err)
+			continue
+		}
+		successCount++
+	}
+
+	rail.Infof("Successfully exported %d/%d notes as markdown to: %s", successCount, len(notes), dir)
+
+	if successCount == 0 {
+		return fmt.Errorf("failed to export any notes")
+	}
+
+	return nil
+}
+
 // ImportNote imports a single note from a JSON file
 func (s *ImportExportServiceImpl) ImportNote(rail flow.Rail, path string, onDuplicate func(note *domain.Note) bool) (*domain.Note, error) {
+	note, err := s.importNote(rail, path, onDuplicate)
+	if err != nil {
+		return nil, err
+	}
+	s.noteService.IndexLinks(rail, []string{note.ID})
+	return note, nil
+}
+
+// importNote imports a single note from a JSON file without indexing its links
+func (s *ImportExportServiceImpl) importNote(rail flow.Rail, path string, onDuplicate func(note *domain.Note) bool) (*domain.Note, error) {
 	if path == "" {
 		return nil, fmt.Errorf("file path cannot be empty")
 	}
@@ -138,10 +272,16 @@ func (s *ImportExportServiceImpl) ImportNote(rail flow.Rail, path string, onDupl
 		rail.Errorf("Failed to convert JSON to note: %v", err)
 		return nil, fmt.Errorf("failed to convert note: %w", err)
 	}
+	if s.isInTrash(rail, note.ID) {
+		rail.Warnf("Skipped note in the trash: %s", note.ID)
+		return nil, ErrNoteInTrash
+	}
 
 	existing, err := s.noteRepo.FindByID(rail, note.ID)
 	if err == nil {
 		if onDuplicate != nil && onDuplicate(existing) {
+			// Overwriting replaces whatever version is stored
+			note.Version = existing.Version
 			err = s.noteRepo.Save(rail, note)
 			if err != nil {
 				rail.Errorf("Failed to overwrite note: %v", err)
@@ -187,7 +327,7 @@ func (s *ImportExportServiceImpl) ImportNotes(rail flow.Rail, dir string, onDupl
 		}
 
 		path := filepath.Join(dir, file.Name())
-		note, err := s.ImportNote(rail, path, onDuplicate)
+		note, err := s.importNote(rail, path, onDuplicate)
 		if err != nil {
 			rail.Warnf("Failed to import note from %s: %v", file.Name(), err)
 			continue
@@ -202,6 +342,7 @@ func (s *ImportExportServiceImpl) ImportNotes(rail flow.Rail, dir string, onDupl
 		return nil, fmt.Errorf("no valid notes found to import")
 	}
 
+	s.indexLinks(rail, importedNotes)
 	return importedNotes, nil
 }
 
@@ -220,12 +361,6 @@ func (s *ImportExportServiceImpl) ImportNotesFromFile(rail flow.Rail, path strin
 	}
 
 	// Parse batch export structure
-	type ExportData struct {
-		Version int               `json:"version"`
-		Notes   []domain.NoteJSON `json:"notes"`
-		Count   int               `json:"count"`
-	}
-
 	var exportData ExportData
 	err = json.Unmarshal(data, &exportData)
 	if err != nil {
@@ -253,11 +388,17 @@ func (s *ImportExportServiceImpl) ImportNotesFromFile(rail flow.Rail, path strin
 			rail.Warnf("Failed to convert note JSON: %v", err)
 			continue
 		}
+		if s.isInTrash(rail, note.ID) {
+			rail.Infof("Skipped note in the trash: %s", note.ID)
+			skippedCount++
+			continue
+		}
 
 		existing, err := s.noteRepo.FindByID(rail, note.ID)
 		if err == nil {
 			// Note exists, check if we should overwrite
 			if onDuplicate != nil && onDuplicate(existing) {
+				note.Version = existing.Version
 				err = s.noteRepo.Save(rail, note)
 				if err != nil {
 					rail.Warnf("Failed to overwrite note %s: %v", note.ID, err)
@@ -287,5 +428,179 @@ func (s *ImportExportServiceImpl) ImportNotesFromFile(rail flow.Rail, path strin
 		return nil, fmt.Errorf("no notes were imported")
 	}
 
+	s.indexLinks(rail, importedNotes)
 	return importedNotes, nil
 }
+
+// ImportMarkdown imports every markdown file found in a directory tree, e.g., an Obsidian vault.
+//
+// Front matter fields are mapped to the note and its tags, unknown fields are kept in metadata,
+// and the subfolder of each file is recorded in metadata under FolderMetadataKey.
+// Hidden directories such as .obsidian and .trash are not imported.
+func (s *ImportExportServiceImpl) ImportMarkdown(rail flow.Rail, dir string, onDuplicate func(note *domain.Note) bool) ([]ImportResult, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("directory path cannot be empty")
+	}
+
+	rail.Infof("Importing markdown files from: %s", dir)
+
+	var results []ImportResult
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if path == dir {
+				return err
+			}
+			rail.Warnf("Failed to read %s: %v", path, err)
+			results = append(results, ImportResult{Path: path, Status: ImportStatusFailed, Reason: err.Error()})
+			return nil
+		}
+
+		if entry.IsDir() {
+			if path != dir && strings.HasPrefix(entry.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if ext != ".md" && ext != ".markdown" {
+			return nil
+		}
+
+		results = append(results, s.importMarkdownFile(rail, dir, path, onDuplicate))
+		return nil
+	})
+	if err != nil {
+		rail.Errorf("Failed to read directory: %v", err)
+		return nil, fmt.Errorf("failed to read directory: %w", err)
+	}
+
+	imported, skipped, failed := 0, 0, 0
+	for _, result := range results {
+		switch result.Status {
+		case ImportStatusImported:
+			imported++
+		case ImportStatusSkipped:
+			skipped++
+		case ImportStatusFailed:
+			failed++
+		}
+	}
+	rail.Infof("Imported %d, skipped %d, failed %d markdown files from: %s", imported, skipped, failed, dir)
+
+	// Links are indexed once every file is imported, they may point to a file imported after the one linking
+	var noteIDs []string
+	for _, result := range results {
+		if result.Status == ImportStatusImported {
+			noteIDs = append(noteIDs, result.NoteID)
+		}
+	}
+	s.noteService.IndexLinks(rail, noteIDs)
+
+	return results, nil
+}
+
+// importMarkdownFile imports a single markdown file found under root
+func (s *ImportExportServiceImpl) importMarkdownFile(rail flow.Rail, root string, path string, onDuplicate func(note *domain.Note) bool) ImportResult {
+	result := ImportResult{Path: path, Status: ImportStatusFailed}
+	if rel, err := filepath.Rel(root, path); err == nil {
+		result.Path = rel
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		result.Reason = err.Error()
+		return result
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		rail.Warnf("Failed to read markdown file %s: %v", path, err)
+		result.Reason = err.Error()
+		return result
+	}
+
+	if len(strings.TrimSpace(string(data))) == 0 {
+		result.Status = ImportStatusSkipped
+		result.Reason = "empty file"
+		return result
+	}
+
+	note, tags, err := fromMarkdown(data, filepath.Base(path), info.ModTime())
+	if err != nil {
+		rail.Warnf("Failed to parse markdown file %s: %v", path, err)
+		result.Reason = err.Error()
+		return result
+	}
+	result.Title = note.Title
+
+	if folder := filepath.ToSlash(filepath.Dir(result.Path)); folder != "." {
+		if note.Metadata == nil {
+			note.Metadata = map[string]interface{}{}
+		}
+		note.Metadata[FolderMetadataKey] = folder
+	}
+
+	if s.isInTrash(rail, note.ID) {
+		rail.Infof("Skipped note in the trash: %s", note.ID)
+		result.Status = ImportStatusSkipped
+		result.NoteID = note.ID
+		result.Reason = "note in the trash"
+		return result
+	}
+	if note.ID != "" {
+		if existing, err := s.noteRepo.FindByID(rail, note.ID); err == nil {
+			if onDuplicate == nil || !onDuplicate(existing) {
+				rail.Infof("Skipped duplicate note: %s", note.ID)
+				result.Status = ImportStatusSkipped
+				result.NoteID = note.ID
+				result.Reason = "duplicate note"
+				return result
+			}
+			note.Version = existing.Version
+		}
+	}
+
+	if err := s.noteRepo.Save(rail, note); err != nil {
+		rail.Warnf("Failed to save note from %s: %v", path, err)
+		result.Reason = err.Error()
+		return result
+	}
+	result.Status = ImportStatusImported
+	result.NoteID = note.ID
+
+	for _, name := range tags {
+		tag, err := s.tagRepo.FindByName(rail, name)
+		if err != nil {
+			tag = &domain.Tag{Name: name}
+			if err := s.tagRepo.Create(rail, tag); err != nil {
+				rail.Warnf("Failed to create tag %s: %v", name, err)
+				continue
+			}
+		}
+		if err := s.tagRepo.AddToNote(rail, note.ID, tag.ID); err != nil {
+			rail.Warnf("Failed to tag note %s with %s: %v", note.ID, name, err)
+		}
+	}
+
+	return result
+}
+
+// isInTrash checks whether the note with the ID is in the trash, saving an imported note with the ID would update the
+// trashed note, which stays hidden in the trash
+func (s *ImportExportServiceImpl) isInTrash(rail flow.Rail, id string) bool {
+	if id == "" {
+		return false
+	}
+	_, err := s.noteRepo.FindDeletedByID(rail, id)
+	return err == nil
+}
+
+// indexLinks indexes the links of the imported notes
+func (s *ImportExportServiceImpl) indexLinks(rail flow.Rail, notes []*domain.Note) {
+	noteIDs := make([]string, 0, len(notes))
+	for _, note := range notes {
+		noteIDs = append(noteIDs, note.ID)
+	}
+	s.noteService.IndexLinks(rail, noteIDs)
+}
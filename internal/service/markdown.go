@@ -0,0 +1,291 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/curtisnewbie/miso/util/atom"
+	"github.com/curtisnewbie/nota/internal/domain"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// maxSlugLength is the maximum number of runes in a file name slug
+	maxSlugLength = 80
+
+	// FolderMetadataKey is the metadata key holding the folder an imported markdown file was found in
+	FolderMetadataKey = "folder"
+)
+
+// frontMatterTimeLayouts are the timestamp layouts accepted in front matter
+var frontMatterTimeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// reservedFileNames are the file names that Windows does not allow
+var reservedFileNames = map[string]bool{
+	"con": true, "prn": true, "aux": true, "nul": true,
+	"com1": true, "com2": true, "com3": true, "com4": true, "com5": true, "com6": true, "com7": true, "com8": true, "com9": true,
+	"lpt1": true, "lpt2": true, "lpt3": true, "lpt4": true, "lpt5": true, "lpt6": true, "lpt7": true, "lpt8": true, "lpt9": true,
+}
+
+// frontMatter is the YAML front matter of an exported markdown note
+type frontMatter struct {
+	ID        string                 `yaml:"id"`
+	Title     string                 `yaml:"title"`
+	CreatedAt string                 `yaml:"created_at"`
+	UpdatedAt string                 `yaml:"updated_at"`
+	Version   int                    `yaml:"version"`
+	Encrypted bool                   `yaml:"encrypted,omitempty"`
+	Metadata  map[string]interface{} `yaml:"metadata,omitempty"`
+	Tags      []string               `yaml:"tags,omitempty"`
+}
+
+// toMarkdown renders a note as markdown with YAML front matter
+func toMarkdown(note *domain.Note, tags []string) ([]byte, error) {
+	fm := frontMatter{
+		ID:        note.ID,
+		Title:     note.Title,
+		CreatedAt: note.CreatedAt.Format(time.RFC3339),
+		UpdatedAt: note.UpdatedAt.Format(time.RFC3339),
+		Version:   note.Version,
+		Encrypted: note.Encrypted,
+		Metadata:  note.Metadata,
+		Tags:      tags,
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("---\n")
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(fm); err != nil {
+		return nil, fmt.Errorf("failed to encode front matter: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encode front matter: %w", err)
+	}
+	buf.WriteString("---\n\n")
+	buf.WriteString(note.Content)
+	if !strings.HasSuffix(note.Content, "\n") {
+		buf.WriteString("\n")
+	}
+	return buf.Bytes(), nil
+}
+
+// slugify turns a title into a file name that is safe on every filesystem
+func slugify(title string) string {
+	var b strings.Builder
+	dash := false
+	length := 0
+	for _, r := range strings.ToLower(title) {
+		if length >= maxSlugLength {
+			break
+		}
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			dash = false
+			length++
+			continue
+		}
+		if !dash && b.Len() > 0 {
+			b.WriteRune('-')
+			dash = true
+			length++
+		}
+	}
+
+	slug := strings.Trim(b.String(), "-")
+	if slug == "" {
+		return "untitled"
+	}
+	if reservedFileNames[slug] {
+		return slug + "-note"
+	}
+	return slug
+}
+
+// uniqueSlug returns the slug of the title, suffixed with a number when it is already used
+func uniqueSlug(title string, used map[string]bool) string {
+	slug := slugify(title)
+	candidate := slug
+	for i := 2; used[candidate]; i++ {
+		candidate = fmt.Sprintf("%s-%d", slug, i)
+	}
+	used[candidate] = true
+	return candidate
+}
+
+// fromMarkdown parses a markdown file with optional YAML front matter into a note and its tag names,
+// name is the file name used as title when there is neither a title in front matter nor a heading
+func fromMarkdown(data []byte, name string, modTime time.Time) (*domain.Note, []string, error) {
+	content := strings.ReplaceAll(string(data), "\r\n", "\n")
+	content = strings.TrimPrefix(content, "\ufeff")
+
+	fields := map[string]interface{}{}
+	if raw, body, ok := splitFrontMatter(content); ok {
+		if err := yaml.Unmarshal([]byte(raw), &fields); err != nil {
+			return nil, nil, fmt.Errorf("failed to parse front matter: %w", err)
+		}
+		if fields == nil {
+			fields = map[string]interface{}{}
+		}
+		content = body
+	}
+
+	note := &domain.Note{
+		Content:   strings.TrimPrefix(content, "\n"),
+		Version:   1,
+		CreatedAt: atom.WrapTime(modTime),
+		UpdatedAt: atom.WrapTime(modTime),
+		Metadata:  map[string]interface{}{},
+	}
+	var tags []string
+	encrypted := false
+
+	for key, value := range fields {
+		switch key {
+		case "id":
+			note.ID = strings.TrimSpace(fmt.Sprint(value))
+		case "title":
+			note.Title = strings.TrimSpace(fmt.Sprint(value))
+		case "created_at", "created", "date":
+			if t, ok := parseFrontMatterTime(value); ok {
+				note.CreatedAt = atom.WrapTime(t)
+			}
+		case "updated_at", "updated", "modified":
+			if t, ok := parseFrontMatterTime(value); ok {
+				note.UpdatedAt = atom.WrapTime(t)
+			}
+		case "version":
+			if v, ok := value.(int); ok && v > 0 {
+				note.Version = v
+			}
+		case "encrypted":
+			encrypted, _ = value.(bool)
+		case "tags", "tag":
+			tags = append(tags, parseFrontMatterTags(value)...)
+		case "metadata":
+			if m, ok := value.(map[string]interface{}); ok {
+				for k, v := range m {
+					note.Metadata[k] = v
+				}
+			}
+		default:
+			note.Metadata[key] = value
+		}
+	}
+
+	if encrypted {
+		// Locked notes are only imported as locked when the body is still the encrypted content
+		if body := strings.TrimSpace(note.Content); domain.IsEncryptedContent(body) {
+			note.Content = body
+			note.Encrypted = true
+		}
+	}
+	if note.Title == "" && !note.Encrypted {
+		note.Title = firstHeading(note.Content)
+	}
+	if note.Title == "" {
+		note.Title = strings.TrimSuffix(name, filepath.Ext(name))
+	}
+	if note.UpdatedAt.Before(note.CreatedAt) {
+		note.UpdatedAt = note.CreatedAt
+	}
+	if len(note.Metadata) == 0 {
+		note.Metadata = nil
+	}
+	return note, tags, nil
+}
+
+// splitFrontMatter splits content into the raw front matter and the body, ok is false when there is no front matter
+func splitFrontMatter(content string) (raw string, body string, ok bool) {
+	if !strings.HasPrefix(content, "---\n") {
+		return "", content, false
+	}
+
+	rest := content[len("---\n"):]
+	offset := 0
+	for offset <= len(rest) {
+		end := strings.IndexByte(rest[offset:], '\n')
+		line := rest[offset:]
+		if end >= 0 {
+			line = rest[offset : offset+end]
+		}
+		if trimmed := strings.TrimRight(line, " \t"); trimmed == "---" || trimmed == "..." {
+			if end < 0 {
+				return rest[:offset], "", true
+			}
+			return rest[:offset], rest[offset+end+1:], true
+		}
+		if end < 0 {
+			break
+		}
+		offset += end + 1
+	}
+	return "", content, false
+}
+
+// firstHeading returns the text of the first level one heading outside of code blocks
+func firstHeading(content string) string {
+	inFence := false
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+			continue
+		}
+		if !inFence && strings.HasPrefix(trimmed, "# ") {
+			return strings.TrimSpace(strings.TrimRight(trimmed[2:], "#"))
+		}
+	}
+	return ""
+}
+
+// parseFrontMatterTime parses a timestamp written in front matter
+func parseFrontMatterTime(value interface{}) (time.Time, bool) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, true
+	case string:
+		for _, layout := range frontMatterTimeLayouts {
+			if t, err := time.ParseInLocation(layout, strings.TrimSpace(v), time.Local); err == nil {
+				return t, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// parseFrontMatterTags parses tags written either as a list or as a comma or space separated string
+func parseFrontMatterTags(value interface{}) []string {
+	var raw []string
+	switch v := value.(type) {
+	case []interface{}:
+		for _, item := range v {
+			if item != nil {
+				raw = append(raw, fmt.Sprint(item))
+			}
+		}
+	case string:
+		raw = strings.FieldsFunc(v, func(r rune) bool {
+			return r == ',' || unicode.IsSpace(r)
+		})
+	}
+
+	tags := make([]string, 0, len(raw))
+	for _, tag := range raw {
+		tag = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+		if tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
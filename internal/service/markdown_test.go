@@ -0,0 +1,76 @@
+package service
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSlugify(t *testing.T) {
+	tests := []struct {
+		name  string
+		title string
+		want  string
+	}{
+		{"lowercased", "Meeting Notes", "meeting-notes"},
+		{"punctuation collapsed", "  Q1 -- plan: v2!  ", "q1-plan-v2"},
+		{"path separators", "a/b\\c..d", "a-b-c-d"},
+		{"unicode letters kept", "Café 笔记", "café-笔记"},
+		{"empty", "", "untitled"},
+		{"only punctuation", "?!/", "untitled"},
+		{"reserved windows name", "CON", "con-note"},
+		{"reserved name as part", "con job", "con-job"},
+		{"truncated", strings.Repeat("a", maxSlugLength+10), strings.Repeat("a", maxSlugLength)},
+		{"no trailing dash after truncation", strings.Repeat("a", maxSlugLength-1) + " b", strings.Repeat("a", maxSlugLength-1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := slugify(tt.title); got != tt.want {
+				t.Errorf("slugify(%q) = %q, want %q", tt.title, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUniqueSlug(t *testing.T) {
+	used := map[string]bool{}
+	titles := []string{"Notes", "notes", "NOTES!", "Notes 2", "", "?"}
+	want := []string{"notes", "notes-2", "notes-3", "notes-2-2", "untitled", "untitled-2"}
+	for i, title := range titles {
+		if got := uniqueSlug(title, used); got != want[i] {
+			t.Errorf("uniqueSlug(%q) = %q, want %q", title, got, want[i])
+		}
+	}
+	if len(used) != len(want) {
+		t.Errorf("used has %d slugs, want %d", len(used), len(want))
+	}
+}
+
+func TestSplitFrontMatter(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		raw     string
+		body    string
+		ok      bool
+	}{
+		{"front matter", "---\ntitle: a\n---\nbody\n", "title: a\n", "body\n", true},
+		{"dots close", "---\ntitle: a\n...\nbody", "title: a\n", "body", true},
+		{"empty front matter", "---\n---\nbody", "", "body", true},
+		{"closing with trailing spaces", "---\ntitle: a\n--- \t\nbody", "title: a\n", "body", true},
+		{"closing at end of file", "---\ntitle: a\n---", "title: a\n", "", true},
+		{"no front matter", "# title\n---\nbody", "", "# title\n---\nbody", false},
+		{"unterminated", "---\ntitle: a\nbody", "", "---\ntitle: a\nbody", false},
+		{"opening not alone", "--- a\n---\nbody", "", "--- a\n---\nbody", false},
+		{"longer rule does not close", "---\ntitle: a\n----\nb: c\n---\nbody", "title: a\n----\nb: c\n", "body", true},
+		{"empty", "", "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw, body, ok := splitFrontMatter(tt.content)
+			if raw != tt.raw || body != tt.body || ok != tt.ok {
+				t.Errorf("splitFrontMatter(%q) = %q, %q, %v, want %q, %q, %v",
+					tt.content, raw, body, ok, tt.raw, tt.body, tt.ok)
+			}
+		})
+	}
+}
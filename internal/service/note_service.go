@@ -3,8 +3,11 @@ package service
 import (
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/curtisnewbie/miso/flow"
+	"github.com/curtisnewbie/miso/util/atom"
 	"github.com/curtisnewbie/nota/internal/domain"
 	"github.com/curtisnewbie/nota/internal/repository"
 )
@@ -12,29 +15,110 @@ import (
 var (
 	ErrNoteNotFound = errors.New("note not found")
 	ErrEmptyTitle   = errors.New("title cannot be empty")
+
+	// ErrVersionConflict is returned by UpdateNote when the note was updated elsewhere since it was loaded
+	ErrVersionConflict = repository.ErrVersionConflict
+
+	ErrRevisionNotFound = errors.New("revision not found")
+
+	// ErrNoteLocked is returned by UpdateNote for locked notes, they are updated with UpdateLockedNote instead
+	ErrNoteLocked        = errors.New("note is locked")
+	ErrEmptyPassphrase   = domain.ErrEmptyPassphrase
+	ErrWrongPassphrase   = domain.ErrWrongPassphrase
+	ErrNoteAlreadyLocked = errors.New("note is already locked")
+
+	ErrTagNotFound  = errors.New("tag not found")
+	ErrEmptyTagName = errors.New("tag name cannot be empty")
+	ErrTagExists    = errors.New("tag already exists")
+
+	ErrNotebookNotFound  = errors.New("notebook not found")
+	ErrEmptyNotebookName = errors.New("notebook name cannot be empty")
+	ErrNotebookCycle     = errors.New("notebook cannot be moved into itself")
+
+	ErrTaskNotFound = errors.New("task not found, the note may have changed")
 )
 
 // NoteService defines the interface for note business operations
 type NoteService interface {
 	CreateNote(rail flow.Rail, note *domain.Note) error
 	UpdateNote(rail flow.Rail, note *domain.Note) error
+	UpdateAndMoveNote(rail flow.Rail, note *domain.Note, notebookID string) error
 	DeleteNote(rail flow.Rail, id string) error
 	GetNote(rail flow.Rail, id string) (*domain.Note, error)
 	ListNotes(rail flow.Rail) ([]*domain.Note, error)
+	ListAllNotes(rail flow.Rail) ([]*domain.Note, error)
 	ListNotesPaginated(rail flow.Rail, offset, limit int) ([]*domain.Note, error)
 	SearchNotes(rail flow.Rail, query string) ([]*domain.Note, error)
-	SearchNotesPaginated(rail flow.Rail, query string, offset, limit int) ([]*domain.Note, error)
+	SearchNotesPaginated(rail flow.Rail, filter domain.NoteFilter, offset, limit int) ([]*domain.Note, error)
 	GetLastModifiedNote(rail flow.Rail) (*domain.Note, error)
+	LockNote(rail flow.Rail, note *domain.Note, key *domain.NoteKey) error
+	UnlockNote(rail flow.Rail, id string, passphrase string) (*domain.Note, *domain.NoteKey, error)
+	UpdateLockedNote(rail flow.Rail, note *domain.Note, key *domain.NoteKey) error
+	RemoveLock(rail flow.Rail, note *domain.Note, key *domain.NoteKey) error
+	ListRevisions(rail flow.Rail, noteID string) ([]*domain.NoteRevision, error)
+	DiffRevisions(rail flow.Rail, noteID string, fromVersion, toVersion int) ([]domain.DiffLine, error)
+	RestoreRevision(rail flow.Rail, noteID string, version int) (*domain.Note, error)
+	ListDeleted(rail flow.Rail) ([]*domain.Note, error)
+	RestoreNote(rail flow.Rail, id string) error
+	PurgeNote(rail flow.Rail, id string) error
+	EmptyTrash(rail flow.Rail, olderThan time.Duration) (int64, error)
+	ListTags(rail flow.Rail) ([]*domain.Tag, error)
+	GetNoteTags(rail flow.Rail, noteID string) ([]*domain.Tag, error)
+	AddTag(rail flow.Rail, noteID string, name string) (*domain.Tag, error)
+	RemoveTag(rail flow.Rail, noteID string, tagID string) error
+	RenameTag(rail flow.Rail, tagID string, name string) error
+	MergeTags(rail flow.Rail, sourceID string, targetID string) error
+	DeleteTag(rail flow.Rail, tagID string) error
+	ListNotebooks(rail flow.Rail) ([]*domain.Notebook, error)
+	CreateNotebook(rail flow.Rail, name string, parentID string) (*domain.Notebook, error)
+	RenameNotebook(rail flow.Rail, notebookID string, name string) error
+	MoveNote(rail flow.Rail, noteID string, notebookID string) error
+	MoveNotebook(rail flow.Rail, notebookID string, parentID string) error
+	DeleteNotebook(rail flow.Rail, notebookID string) error
+	GetLinkedNotes(rail flow.Rail, noteID string) ([]*domain.Note, error)
+	GetBacklinks(rail flow.Rail, noteID string) ([]*domain.Note, error)
+	ResolveLink(rail flow.Rail, target string) (*domain.Note, error)
+	ListTemplates(rail flow.Rail) ([]*domain.Note, error)
+	SetTemplate(rail flow.Rail, noteID string, template bool) error
+	GetDailyNote(rail flow.Rail, date time.Time, titleFormat string) (*domain.Note, error)
+	ListDailyDates(rail flow.Rail, from time.Time, to time.Time) ([]time.Time, error)
+	SetReminder(rail flow.Rail, noteID string, dueAt *time.Time) error
+	SnoozeReminder(rail flow.Rail, noteID string, d time.Duration) (time.Time, error)
+	TakeDueReminders(rail flow.Rail, now time.Time) ([]*domain.Note, error)
+	ListTasks(rail flow.Rail, filter domain.TaskFilter) ([]*domain.NoteTask, error)
+	SetTaskDone(rail flow.Rail, noteID string, line int, done bool) (*domain.Note, error)
+	SetStarred(rail flow.Rail, noteID string, starred bool) error
+	SetArchived(rail flow.Rail, noteID string, archived bool) error
+	IndexLinks(rail flow.Rail, noteIDs []string)
 }
 
 // NoteServiceImpl implements NoteService
 type NoteServiceImpl struct {
-	noteRepo repository.NoteRepository
+	noteRepo     repository.NoteRepository
+	revisionRepo repository.NoteRevisionRepository
+	tagRepo      repository.TagRepository
+	notebookRepo repository.NotebookRepository
+	linkRepo     repository.NoteLinkRepository
+	taskRepo     repository.NoteTaskRepository
 }
 
 // NewNoteService creates a new note service
-func NewNoteService(noteRepo repository.NoteRepository) NoteService {
-	return &NoteServiceImpl{noteRepo: noteRepo}
+func NewNoteService(
+	noteRepo repository.NoteRepository,
+	revisionRepo repository.NoteRevisionRepository,
+	tagRepo repository.TagRepository,
+	notebookRepo repository.NotebookRepository,
+	linkRepo repository.NoteLinkRepository,
+	taskRepo repository.NoteTaskRepository,
+) NoteService {
+	return &NoteServiceImpl{
+		noteRepo:     noteRepo,
+		revisionRepo: revisionRepo,
+		tagRepo:      tagRepo,
+		notebookRepo: notebookRepo,
+		linkRepo:     linkRepo,
+		taskRepo:     taskRepo,
+	}
 }
 
 // CreateNote creates a new note
@@ -44,17 +128,36 @@ func (s *NoteServiceImpl) CreateNote(rail flow.Rail, note *domain.Note) error {
 		rail.Warnf("Attempted to create note with empty title")
 		return ErrEmptyTitle
 	}
+	if note.NotebookID != "" {
+		if _, err := s.notebookRepo.FindByID(rail, note.NotebookID); err != nil {
+			rail.Warnf("Notebook %s not found, creating note at the top level", note.NotebookID)
+			note.NotebookID = ""
+		}
+	}
+	s.resolveLinks(rail, note)
 	err := s.noteRepo.Save(rail, note)
 	if err != nil {
 		rail.Errorf("Failed to create note: %v", err)
 	} else {
 		rail.Infof("Successfully created note: %s", note.ID)
+		s.updateLinks(rail, note)
 	}
 	return err
 }
 
-// UpdateNote updates an existing note
+// UpdateNote updates an existing note, note.Version must be the version the note was loaded at
 func (s *NoteServiceImpl) UpdateNote(rail flow.Rail, note *domain.Note) error {
+	return s.updateNote(rail, note, nil)
+}
+
+// UpdateAndMoveNote updates a note like UpdateNote and moves it into a notebook, an empty notebookID moves it to the
+// top level, either both are applied or neither is
+func (s *NoteServiceImpl) UpdateAndMoveNote(rail flow.Rail, note *domain.Note, notebookID string) error {
+	return s.updateNote(rail, note, &notebookID)
+}
+
+// updateNote updates a note, and moves it into the notebook in the same transaction when notebookID is not nil
+func (s *NoteServiceImpl) updateNote(rail flow.Rail, note *domain.Note, notebookID *string) error {
 	rail.Infof("Updating note: %s", note.ID)
 	if note.ID == "" {
 		rail.Warnf("Attempted to update note with empty ID")
@@ -65,17 +168,34 @@ func (s *NoteServiceImpl) UpdateNote(rail flow.Rail, note *domain.Note) error {
 		return ErrEmptyTitle
 	}
 
-	_, err := s.noteRepo.FindByID(rail, note.ID)
+	existing, err := s.noteRepo.FindByID(rail, note.ID)
 	if err != nil {
 		rail.Warnf("Note not found for update: %s", note.ID)
 		return ErrNoteNotFound
 	}
+	if existing.Encrypted || note.Encrypted {
+		rail.Warnf("Attempted to update locked note without passphrase: %s", note.ID)
+		return ErrNoteLocked
+	}
+	if notebookID != nil && *notebookID != "" {
+		if _, err := s.notebookRepo.FindByID(rail, *notebookID); err != nil {
+			return ErrNotebookNotFound
+		}
+	}
 
-	err = s.noteRepo.Save(rail, note)
-	if err != nil {
+	s.resolveLinks(rail, note)
+	if notebookID != nil {
+		err = s.noteRepo.SaveAndMove(rail, note, *notebookID)
+	} else {
+		err = s.noteRepo.Save(rail, note)
+	}
+	if errors.Is(err, ErrVersionConflict) {
+		rail.Warnf("Note %s was updated elsewhere since version %d", note.ID, note.Version)
+	} else if err != nil {
 		rail.Errorf("Failed to update note: %v", err)
 	} else {
 		rail.Infof("Successfully updated note: %s", note.ID)
+		s.updateLinks(rail, note)
 	}
 	return err
 }
@@ -114,13 +234,19 @@ func (s *NoteServiceImpl) GetNote(rail flow.Rail, id string) (*domain.Note, erro
 	return note, nil
 }
 
-// ListNotes retrieves all notes (excludes soft-deleted), sorted by updated_at DESC
+// ListNotes retrieves all notes (excludes soft-deleted and archived), the starred ones first, sorted by updated_at DESC
 func (s *NoteServiceImpl) ListNotes(rail flow.Rail) ([]*domain.Note, error) {
 	rail.Debugf("Listing all notes")
 	return s.noteRepo.FindAllSorted(rail)
 }
 
-// ListNotesPaginated retrieves notes with pagination (excludes soft-deleted), sorted by updated_at DESC
+// ListAllNotes retrieves all notes including the archived ones (excludes soft-deleted), e.g., for exports
+func (s *NoteServiceImpl) ListAllNotes(rail flow.Rail) ([]*domain.Note, error) {
+	rail.Debugf("Listing all notes including archived")
+	return s.noteRepo.FindAll(rail)
+}
+
+// ListNotesPaginated retrieves notes with pagination (excludes soft-deleted and archived), the starred ones first, sorted by updated_at DESC
 func (s *NoteServiceImpl) ListNotesPaginated(rail flow.Rail, offset, limit int) ([]*domain.Note, error) {
 	rail.Debugf("Listing notes with pagination (offset=%d, limit=%d)", offset, limit)
 	return s.noteRepo.FindAllSortedPaginated(rail, offset, limit)
@@ -132,10 +258,10 @@ func (s *NoteServiceImpl) SearchNotes(rail flow.Rail, query string) ([]*domain.N
 	return s.noteRepo.Search(rail, query)
 }
 
-// SearchNotesPaginated searches notes by title and content using FTS with pagination
-func (s *NoteServiceImpl) SearchNotesPaginated(rail flow.Rail, query string, offset, limit int) ([]*domain.Note, error) {
-	rail.Debugf("Searching notes with query: %s (offset=%d, limit=%d)", query, offset, limit)
-	return s.noteRepo.SearchPaginated(rail, query, offset, limit)
+// SearchNotesPaginated searches notes by title and content using FTS with pagination, limited to the notes matching the filter
+func (s *NoteServiceImpl) SearchNotesPaginated(rail flow.Rail, filter domain.NoteFilter, offset, limit int) ([]*domain.Note, error) {
+	rail.Debugf("Searching notes with filter: %+v (offset=%d, limit=%d)", filter, offset, limit)
+	return s.noteRepo.SearchPaginated(rail, filter, offset, limit)
 }
 
 // GetLastModifiedNote retrieves the most recently modified note
@@ -149,3 +275,765 @@ func (s *NoteServiceImpl) GetLastModifiedNote(rail flow.Rail) (*domain.Note, err
 	rail.Infof("Last modified note: %s", note.ID)
 	return note, nil
 }
+
+// LockNote encrypts the content of a note with the key derived from its passphrase, note.Content is the plaintext to encrypt
+//
+// Like UpdateNote, note.Version must be the version the note was loaded at. The revisions of the note are deleted
+// since they hold the plaintext, and note.Content is left as plaintext for the note to be edited further.
+func (s *NoteServiceImpl) LockNote(rail flow.Rail, note *domain.Note, key *domain.NoteKey) error {
+	rail.Infof("Locking note: %s", note.ID)
+	if key == nil {
+		return ErrEmptyPassphrase
+	}
+	if note.Title == "" {
+		return ErrEmptyTitle
+	}
+	existing, err := s.noteRepo.FindByID(rail, note.ID)
+	if err != nil {
+		rail.Warnf("Note not found for locking: %s", note.ID)
+		return ErrNoteNotFound
+	}
+	if existing.Encrypted {
+		return ErrNoteAlreadyLocked
+	}
+
+	s.resolveLinks(rail, note)
+	if err := s.saveEncrypted(rail, note, key); err != nil {
+		rail.Errorf("Failed to lock note %s: %v", note.ID, err)
+		return err
+	}
+	rail.Infof("Successfully locked note: %s", note.ID)
+	return nil
+}
+
+// UnlockNote retrieves a locked note with its content decrypted, the note is not changed in database
+//
+// The key derived from the passphrase is returned for saving the edits of the note. ErrWrongPassphrase is returned
+// when the passphrase doesn't match. Notes that aren't locked are returned as is, without a key.
+func (s *NoteServiceImpl) UnlockNote(rail flow.Rail, id string, passphrase string) (*domain.Note, *domain.NoteKey, error) {
+	rail.Infof("Unlocking note: %s", id)
+	note, err := s.GetNote(rail, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !note.Encrypted {
+		return note, nil, nil
+	}
+
+	content, key, err := domain.UnlockContent(note.Content, passphrase)
+	if err != nil {
+		rail.Warnf("Failed to unlock note %s: %v", id, err)
+		return nil, nil, err
+	}
+	note.Content = content
+	return note, key, nil
+}
+
+// UpdateLockedNote updates a locked note, note.Content is the plaintext that is encrypted again with the key
+//
+// The key must be the one returned when the note was unlocked, it is checked against the content saved in database.
+func (s *NoteServiceImpl) UpdateLockedNote(rail flow.Rail, note *domain.Note, key *domain.NoteKey) error {
+	rail.Infof("Updating locked note: %s", note.ID)
+	if note.Title == "" {
+		return ErrEmptyTitle
+	}
+	existing, err := s.noteRepo.FindByID(rail, note.ID)
+	if err != nil {
+		rail.Warnf("Note not found for update: %s", note.ID)
+		return ErrNoteNotFound
+	}
+	if !existing.Encrypted {
+		// The lock was removed elsewhere
+		note.Encrypted = false
+		return s.UpdateNote(rail, note)
+	}
+	if key == nil {
+		return ErrNoteLocked
+	}
+	content, err := key.Decrypt(existing.Content)
+	if err != nil {
+		rail.Warnf("Failed to check key of note %s: %v", note.ID, err)
+		return err
+	}
+
+	// The content is encrypted with a new nonce every time, so unchanged notes have to be detected before encrypting
+	if existing.Title == note.Title && content == note.Content {
+		if existing.Version != note.Version {
+			return ErrVersionConflict
+		}
+		note.UpdatedAt = existing.UpdatedAt
+		note.Encrypted = true
+		return nil
+	}
+
+	s.resolveLinks(rail, note)
+	err = s.saveEncrypted(rail, note, key)
+	if errors.Is(err, ErrVersionConflict) {
+		rail.Warnf("Note %s was updated elsewhere since version %d", note.ID, note.Version)
+	} else if err != nil {
+		rail.Errorf("Failed to update locked note: %v", err)
+	} else {
+		rail.Infof("Successfully updated locked note: %s", note.ID)
+	}
+	return err
+}
+
+// RemoveLock saves a locked note as plaintext again, note.Content is the plaintext to save
+//
+// The key must be the one returned when the note was unlocked. The revisions of the note are deleted since they are encrypted.
+func (s *NoteServiceImpl) RemoveLock(rail flow.Rail, note *domain.Note, key *domain.NoteKey) error {
+	rail.Infof("Removing lock of note: %s", note.ID)
+	if note.Title == "" {
+		return ErrEmptyTitle
+	}
+	existing, err := s.noteRepo.FindByID(rail, note.ID)
+	if err != nil {
+		rail.Warnf("Note not found for removing lock: %s", note.ID)
+		return ErrNoteNotFound
+	}
+	if existing.Encrypted {
+		if key == nil {
+			return ErrNoteLocked
+		}
+		if _, err := key.Decrypt(existing.Content); err != nil {
+			return err
+		}
+	}
+
+	note.Encrypted = false
+	s.resolveLinks(rail, note)
+	err = s.noteRepo.Save(rail, note)
+	if err != nil {
+		note.Encrypted = true
+		rail.Errorf("Failed to remove lock of note %s: %v", note.ID, err)
+		return err
+	}
+	s.updateLinks(rail, note)
+	rail.Infof("Successfully removed lock of note: %s", note.ID)
+	return nil
+}
+
+// saveEncrypted saves a copy of the note with its content encrypted, the links of the note are cleared since
+// they would reveal what the note refers to
+func (s *NoteServiceImpl) saveEncrypted(rail flow.Rail, note *domain.Note, key *domain.NoteKey) error {
+	content, err := key.Encrypt(note.Content)
+	if err != nil {
+		return err
+	}
+
+	encrypted := *note
+	encrypted.Content = content
+	encrypted.Encrypted = true
+	if err := s.noteRepo.Save(rail, &encrypted); err != nil {
+		return err
+	}
+	note.Version = encrypted.Version
+	note.UpdatedAt = encrypted.UpdatedAt
+	note.Encrypted = true
+
+	if err := s.linkRepo.ReplaceLinks(rail, note.ID, []string{}); err != nil {
+		rail.Errorf("Failed to clear links of note %s: %v", note.ID, err)
+	}
+	return nil
+}
+
+// ListRevisions retrieves the previous versions of a note, sorted by version DESC
+func (s *NoteServiceImpl) ListRevisions(rail flow.Rail, noteID string) ([]*domain.NoteRevision, error) {
+	rail.Debugf("Listing revisions of note: %s", noteID)
+	if noteID == "" {
+		rail.Warnf("Attempted to list revisions of note with empty ID")
+		return nil, fmt.Errorf("note ID cannot be empty")
+	}
+	return s.revisionRepo.FindByNoteID(rail, noteID)
+}
+
+// DiffRevisions computes a line-based diff between two versions of a note (title included as the first line)
+//
+// Either version may be the note's current version. ErrNoteLocked is returned for locked notes.
+func (s *NoteServiceImpl) DiffRevisions(rail flow.Rail, noteID string, fromVersion, toVersion int) ([]domain.DiffLine, error) {
+	rail.Debugf("Diffing note %s from version %d to version %d", noteID, fromVersion, toVersion)
+
+	fromTitle, fromContent, err := s.findVersion(rail, noteID, fromVersion)
+	if err != nil {
+		return nil, err
+	}
+	toTitle, toContent, err := s.findVersion(rail, noteID, toVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	return domain.DiffLines(fromTitle+"\n"+fromContent, toTitle+"\n"+toContent), nil
+}
+
+// RestoreRevision restores a note to a previous version
+//
+// Restoring saves the note like any other update, so the version being replaced is kept in history as well.
+// ErrNoteLocked is returned for locked notes, an older version would replace the encrypted content.
+func (s *NoteServiceImpl) RestoreRevision(rail flow.Rail, noteID string, version int) (*domain.Note, error) {
+	rail.Infof("Restoring note %s to version %d", noteID, version)
+
+	note, err := s.GetNote(rail, noteID)
+	if err != nil {
+		return nil, err
+	}
+	if note.Encrypted {
+		rail.Warnf("Attempted to restore a revision of locked note: %s", noteID)
+		return nil, ErrNoteLocked
+	}
+
+	revision, err := s.revisionRepo.FindByNoteIDAndVersion(rail, noteID, version)
+	if err != nil {
+		rail.Warnf("Revision %d not found for note: %s", version, noteID)
+		return nil, ErrRevisionNotFound
+	}
+
+	note.Title = revision.Title
+	note.Content = revision.Content
+	err = s.noteRepo.Save(rail, note)
+	if err != nil {
+		rail.Errorf("Failed to restore note: %v", err)
+		return nil, err
+	}
+	s.updateLinks(rail, note)
+
+	rail.Infof("Successfully restored note %s to version %d, now at version %d", noteID, version, note.Version)
+	return note, nil
+}
+
+// findVersion finds the title and content of a note at the given version, ErrNoteLocked is returned for locked notes
+func (s *NoteServiceImpl) findVersion(rail flow.Rail, noteID string, version int) (title string, content string, err error) {
+	note, err := s.GetNote(rail, noteID)
+	if err != nil {
+		return "", "", err
+	}
+	if note.Encrypted {
+		rail.Warnf("Attempted to read a revision of locked note: %s", noteID)
+		return "", "", ErrNoteLocked
+	}
+	if note.Version == version {
+		return note.Title, note.Content, nil
+	}
+
+	revision, err := s.revisionRepo.FindByNoteIDAndVersion(rail, noteID, version)
+	if err != nil {
+		rail.Warnf("Revision %d not found for note: %s", version, noteID)
+		return "", "", ErrRevisionNotFound
+	}
+	return revision.Title, revision.Content, nil
+}
+
+// ListDeleted retrieves all soft-deleted notes, sorted by deleted_at DESC
+func (s *NoteServiceImpl) ListDeleted(rail flow.Rail) ([]*domain.Note, error) {
+	rail.Debugf("Listing deleted notes")
+	return s.noteRepo.FindDeleted(rail)
+}
+
+// RestoreNote restores a soft-deleted note from the trash
+func (s *NoteServiceImpl) RestoreNote(rail flow.Rail, id string) error {
+	rail.Infof("Restoring note: %s", id)
+	if id == "" {
+		rail.Warnf("Attempted to restore note with empty ID")
+		return fmt.Errorf("note ID cannot be empty")
+	}
+
+	note, err := s.noteRepo.FindDeletedByID(rail, id)
+	if err != nil {
+		rail.Warnf("Deleted note not found for restore: %s", id)
+		return ErrNoteNotFound
+	}
+
+	if err := s.noteRepo.Restore(rail, id); err != nil {
+		return err
+	}
+
+	// the notebook may have been deleted together with the note
+	if note.NotebookID != "" {
+		if _, err := s.notebookRepo.FindByID(rail, note.NotebookID); err != nil {
+			rail.Infof("Notebook %s no longer exists, moving restored note %s to the top level", note.NotebookID, id)
+			return s.noteRepo.MoveToNotebook(rail, id, "")
+		}
+	}
+	return nil
+}
+
+// PurgeNote permanently deletes a note that is in the trash
+func (s *NoteServiceImpl) PurgeNote(rail flow.Rail, id string) error {
+	rail.Infof("Purging note: %s", id)
+	if id == "" {
+		rail.Warnf("Attempted to purge note with empty ID")
+		return fmt.Errorf("note ID cannot be empty")
+	}
+
+	_, err := s.noteRepo.FindDeletedByID(rail, id)
+	if err != nil {
+		rail.Warnf("Deleted note not found for purge: %s", id)
+		return ErrNoteNotFound
+	}
+
+	return s.noteRepo.Purge(rail, id)
+}
+
+// EmptyTrash permanently deletes notes that have been in the trash for longer than olderThan, zero empties the whole trash
+func (s *NoteServiceImpl) EmptyTrash(rail flow.Rail, olderThan time.Duration) (int64, error) {
+	rail.Infof("Emptying trash (older than %v)", olderThan)
+	return s.noteRepo.PurgeDeletedBefore(rail, atom.WrapTime(time.Now().Add(-olderThan)))
+}
+
+// ListTags retrieves all tags sorted by name
+func (s *NoteServiceImpl) ListTags(rail flow.Rail) ([]*domain.Tag, error) {
+	rail.Debugf("Listing tags")
+	return s.tagRepo.FindAll(rail)
+}
+
+// GetNoteTags retrieves the tags of a note sorted by name
+func (s *NoteServiceImpl) GetNoteTags(rail flow.Rail, noteID string) ([]*domain.Tag, error) {
+	rail.Debugf("Getting tags of note: %s", noteID)
+	if noteID == "" {
+		return []*domain.Tag{}, nil
+	}
+	return s.tagRepo.FindByNoteID(rail, noteID)
+}
+
+// AddTag attaches a tag to a note by name, the tag is created if it doesn't exist yet
+func (s *NoteServiceImpl) AddTag(rail flow.Rail, noteID string, name string) (*domain.Tag, error) {
+	name = strings.TrimSpace(name)
+	rail.Infof("Adding tag %s to note: %s", name, noteID)
+	if name == "" {
+		rail.Warnf("Attempted to add tag with empty name")
+		return nil, ErrEmptyTagName
+	}
+
+	_, err := s.GetNote(rail, noteID)
+	if err != nil {
+		return nil, err
+	}
+
+	tag, err := s.tagRepo.FindByName(rail, name)
+	if err != nil {
+		tag = &domain.Tag{Name: name}
+		if err := s.tagRepo.Create(rail, tag); err != nil {
+			return nil, err
+		}
+	}
+
+	err = s.tagRepo.AddToNote(rail, noteID, tag.ID)
+	if err != nil {
+		return nil, err
+	}
+	return tag, nil
+}
+
+// RemoveTag detaches a tag from a note, the tag itself is kept
+func (s *NoteServiceImpl) RemoveTag(rail flow.Rail, noteID string, tagID string) error {
+	rail.Infof("Removing tag %s from note: %s", tagID, noteID)
+	return s.tagRepo.RemoveFromNote(rail, noteID, tagID)
+}
+
+// RenameTag renames a tag, renaming to the name of another tag fails with ErrTagExists, use MergeTags instead
+func (s *NoteServiceImpl) RenameTag(rail flow.Rail, tagID string, name string) error {
+	name = strings.TrimSpace(name)
+	rail.Infof("Renaming tag %s to: %s", tagID, name)
+	if name == "" {
+		rail.Warnf("Attempted to rename tag to empty name")
+		return ErrEmptyTagName
+	}
+
+	_, err := s.tagRepo.FindByID(rail, tagID)
+	if err != nil {
+		return ErrTagNotFound
+	}
+
+	existing, err := s.tagRepo.FindByName(rail, name)
+	if err == nil && existing.ID != tagID {
+		rail.Warnf("Tag with name %s already exists: %s", name, existing.ID)
+		return ErrTagExists
+	}
+
+	return s.tagRepo.Rename(rail, tagID, name)
+}
+
+// MergeTags moves every note tagged with source to target, and deletes source
+func (s *NoteServiceImpl) MergeTags(rail flow.Rail, sourceID string, targetID string) error {
+	rail.Infof("Merging tag %s into: %s", sourceID, targetID)
+	if sourceID == targetID {
+		return nil
+	}
+
+	if _, err := s.tagRepo.FindByID(rail, sourceID); err != nil {
+		return ErrTagNotFound
+	}
+	if _, err := s.tagRepo.FindByID(rail, targetID); err != nil {
+		return ErrTagNotFound
+	}
+
+	return s.tagRepo.Merge(rail, sourceID, targetID)
+}
+
+// DeleteTag deletes a tag and detaches it from every note
+func (s *NoteServiceImpl) DeleteTag(rail flow.Rail, tagID string) error {
+	rail.Infof("Deleting tag: %s", tagID)
+	if _, err := s.tagRepo.FindByID(rail, tagID); err != nil {
+		return ErrTagNotFound
+	}
+	return s.tagRepo.Delete(rail, tagID)
+}
+
+// ListNotebooks retrieves all notebooks sorted by name
+func (s *NoteServiceImpl) ListNotebooks(rail flow.Rail) ([]*domain.Notebook, error) {
+	rail.Debugf("Listing notebooks")
+	return s.notebookRepo.FindAll(rail)
+}
+
+// CreateNotebook creates a notebook under the parent notebook, an empty parentID creates a top-level notebook
+func (s *NoteServiceImpl) CreateNotebook(rail flow.Rail, name string, parentID string) (*domain.Notebook, error) {
+	name = strings.TrimSpace(name)
+	rail.Infof("Creating notebook %s under: %s", name, parentID)
+	if name == "" {
+		rail.Warnf("Attempted to create notebook with empty name")
+		return nil, ErrEmptyNotebookName
+	}
+	if parentID != "" {
+		if _, err := s.notebookRepo.FindByID(rail, parentID); err != nil {
+			return nil, ErrNotebookNotFound
+		}
+	}
+
+	notebook := &domain.Notebook{Name: name, ParentID: parentID}
+	if err := s.notebookRepo.Create(rail, notebook); err != nil {
+		return nil, err
+	}
+	return notebook, nil
+}
+
+// RenameNotebook renames a notebook
+func (s *NoteServiceImpl) RenameNotebook(rail flow.Rail, notebookID string, name string) error {
+	name = strings.TrimSpace(name)
+	rail.Infof("Renaming notebook %s to: %s", notebookID, name)
+	if name == "" {
+		rail.Warnf("Attempted to rename notebook to empty name")
+		return ErrEmptyNotebookName
+	}
+	if _, err := s.notebookRepo.FindByID(rail, notebookID); err != nil {
+		return ErrNotebookNotFound
+	}
+	return s.notebookRepo.Rename(rail, notebookID, name)
+}
+
+// MoveNote moves a note into a notebook, an empty notebookID moves it to the top level
+func (s *NoteServiceImpl) MoveNote(rail flow.Rail, noteID string, notebookID string) error {
+	rail.Infof("Moving note %s to notebook: %s", noteID, notebookID)
+	if _, err := s.noteRepo.FindByID(rail, noteID); err != nil {
+		return ErrNoteNotFound
+	}
+	if notebookID != "" {
+		if _, err := s.notebookRepo.FindByID(rail, notebookID); err != nil {
+			return ErrNotebookNotFound
+		}
+	}
+	return s.noteRepo.MoveToNotebook(rail, noteID, notebookID)
+}
+
+// MoveNotebook moves a notebook under another notebook, an empty parentID moves it to the top level
+func (s *NoteServiceImpl) MoveNotebook(rail flow.Rail, notebookID string, parentID string) error {
+	rail.Infof("Moving notebook %s to: %s", notebookID, parentID)
+	if _, err := s.notebookRepo.FindByID(rail, notebookID); err != nil {
+		return ErrNotebookNotFound
+	}
+	if parentID == "" {
+		return s.notebookRepo.Move(rail, notebookID, "")
+	}
+
+	if _, err := s.notebookRepo.FindByID(rail, parentID); err != nil {
+		return ErrNotebookNotFound
+	}
+	subtree, err := s.notebookRepo.FindSubtreeIDs(rail, notebookID)
+	if err != nil {
+		return err
+	}
+	for _, id := range subtree {
+		if id == parentID {
+			rail.Warnf("Attempted to move notebook %s into its own subtree: %s", notebookID, parentID)
+			return ErrNotebookCycle
+		}
+	}
+	return s.notebookRepo.Move(rail, notebookID, parentID)
+}
+
+// DeleteNotebook deletes a notebook with all its sub-notebooks, the notes inside them are moved to the trash
+func (s *NoteServiceImpl) DeleteNotebook(rail flow.Rail, notebookID string) error {
+	rail.Infof("Deleting notebook: %s", notebookID)
+	if _, err := s.notebookRepo.FindByID(rail, notebookID); err != nil {
+		return ErrNotebookNotFound
+	}
+	return s.notebookRepo.Delete(rail, notebookID)
+}
+
+// GetLinkedNotes retrieves the notes that a note links to
+func (s *NoteServiceImpl) GetLinkedNotes(rail flow.Rail, noteID string) ([]*domain.Note, error) {
+	rail.Debugf("Getting notes linked from: %s", noteID)
+	return s.linkRepo.FindLinkedNotes(rail, noteID)
+}
+
+// GetBacklinks retrieves the notes that link to a note
+func (s *NoteServiceImpl) GetBacklinks(rail flow.Rail, noteID string) ([]*domain.Note, error) {
+	rail.Debugf("Getting backlinks of: %s", noteID)
+	return s.linkRepo.FindBacklinkedNotes(rail, noteID)
+}
+
+// ResolveLink finds the note that a link target refers to, the target is either a note ID or a note title
+func (s *NoteServiceImpl) ResolveLink(rail flow.Rail, target string) (*domain.Note, error) {
+	rail.Debugf("Resolving link: %s", target)
+	note, err := s.resolveLink(rail, domain.Link{Target: target})
+	if err != nil {
+		return nil, ErrNoteNotFound
+	}
+	return note, nil
+}
+
+// ListTemplates retrieves the notes used as templates sorted by title, locked notes are never used as templates
+func (s *NoteServiceImpl) ListTemplates(rail flow.Rail) ([]*domain.Note, error) {
+	rail.Debugf("Listing templates")
+	return s.noteRepo.FindTemplates(rail)
+}
+
+// SetTemplate flags a note as a template, or clears the flag, in the metadata of the note
+func (s *NoteServiceImpl) SetTemplate(rail flow.Rail, noteID string, template bool) error {
+	rail.Infof("Setting note %s as template: %v", noteID, template)
+	note, err := s.noteRepo.FindByID(rail, noteID)
+	if err != nil {
+		return ErrNoteNotFound
+	}
+	if template && note.Encrypted {
+		return ErrNoteLocked
+	}
+	if note.IsTemplate() == template {
+		return nil
+	}
+
+	metadata := note.Metadata
+	if metadata == nil {
+		metadata = make(map[string]interface{})
+	}
+	if template {
+		metadata[domain.TemplateMetadataKey] = true
+	} else {
+		delete(metadata, domain.TemplateMetadataKey)
+	}
+	return s.noteRepo.UpdateMetadata(rail, noteID, metadata)
+}
+
+// GetDailyNote retrieves the daily note of a day, ErrNoteNotFound is returned when the day has no daily note yet
+//
+// A note titled by the date in titleFormat that is not marked as a daily note, e.g., one written by hand before, is
+// taken as the daily note of the day and marked as such.
+func (s *NoteServiceImpl) GetDailyNote(rail flow.Rail, date time.Time, titleFormat string) (*domain.Note, error) {
+	day := date.Format(domain.DailyDateLayout)
+	rail.Debugf("Getting daily note of: %s", day)
+	if note, err := s.noteRepo.FindDaily(rail, day); err == nil {
+		return note, nil
+	}
+
+	if titleFormat == "" {
+		titleFormat = domain.DefaultDailyTitleFormat
+	}
+	note, err := s.noteRepo.FindByTitle(rail, date.Format(titleFormat))
+	if err != nil {
+		return nil, ErrNoteNotFound
+	}
+	if _, ok := note.DailyDate(); ok {
+		// The daily note of another day that happens to be titled like this one
+		return nil, ErrNoteNotFound
+	}
+
+	rail.Infof("Marking note %s as the daily note of %s", note.ID, day)
+	if note.Metadata == nil {
+		note.Metadata = make(map[string]interface{})
+	}
+	note.Metadata[domain.DailyMetadataKey] = day
+	if err := s.noteRepo.UpdateMetadata(rail, note.ID, note.Metadata); err != nil {
+		return nil, err
+	}
+	return note, nil
+}
+
+// ListDailyDates retrieves the days between from and to (inclusive) that have a daily note
+func (s *NoteServiceImpl) ListDailyDates(rail flow.Rail, from time.Time, to time.Time) ([]time.Time, error) {
+	rail.Debugf("Listing daily notes between %v and %v", from, to)
+	days, err := s.noteRepo.FindDailyDays(rail, from.Format(domain.DailyDateLayout), to.Format(domain.DailyDateLayout))
+	if err != nil {
+		return nil, err
+	}
+	dates := make([]time.Time, 0, len(days))
+	for _, day := range days {
+		if date, err := time.ParseInLocation(domain.DailyDateLayout, day, time.Local); err == nil {
+			dates = append(dates, date)
+		}
+	}
+	return dates, nil
+}
+
+// SetReminder sets when the reminder of a note is due, nil removes the reminder, e.g., when it's completed
+func (s *NoteServiceImpl) SetReminder(rail flow.Rail, noteID string, dueAt *time.Time) error {
+	if _, err := s.noteRepo.FindByID(rail, noteID); err != nil {
+		return ErrNoteNotFound
+	}
+	var due *atom.Time
+	if dueAt != nil {
+		t := atom.WrapTime(dueAt.Truncate(time.Minute))
+		due = &t
+	}
+	return s.noteRepo.UpdateDueAt(rail, noteID, due)
+}
+
+// SnoozeReminder puts the reminder of a note off for d from now, returning when it's due again
+func (s *NoteServiceImpl) SnoozeReminder(rail flow.Rail, noteID string, d time.Duration) (time.Time, error) {
+	dueAt := time.Now().Add(d)
+	if err := s.SetReminder(rail, noteID, &dueAt); err != nil {
+		return time.Time{}, err
+	}
+	return dueAt.Truncate(time.Minute), nil
+}
+
+// TakeDueReminders retrieves the reminders that are due at now and marks them as sent, so that each of them is only sent once
+//
+// Reminders that came due while the app was closed are retrieved the next time it's checked.
+func (s *NoteServiceImpl) TakeDueReminders(rail flow.Rail, now time.Time) ([]*domain.Note, error) {
+	notes, err := s.noteRepo.FindDueReminders(rail, atom.WrapTime(now))
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(notes))
+	for _, note := range notes {
+		ids = append(ids, note.ID)
+	}
+	// The notes are marked at once, a failure leaves all of them to be retrieved again on the next check
+	remindedAt := atom.WrapTime(now)
+	if err := s.noteRepo.MarkReminded(rail, ids, remindedAt); err != nil {
+		return nil, err
+	}
+	for _, note := range notes {
+		rail.Infof("Reminder of note %s is due: %s", note.ID, note.DueAt.Format(time.RFC3339))
+		note.RemindedAt = &remindedAt
+	}
+	return notes, nil
+}
+
+// ListTasks retrieves the tasks found in the notes, the ones due the earliest first
+func (s *NoteServiceImpl) ListTasks(rail flow.Rail, filter domain.TaskFilter) ([]*domain.NoteTask, error) {
+	return s.taskRepo.FindTasks(rail, filter)
+}
+
+// SetTaskDone ticks or unticks a task by rewriting its line in the content of the note, the note is saved as a new
+// version and returned
+//
+// ErrTaskNotFound is returned when the line no longer holds the task, e.g., when the note has been edited since the
+// tasks were listed.
+func (s *NoteServiceImpl) SetTaskDone(rail flow.Rail, noteID string, line int, done bool) (*domain.Note, error) {
+	rail.Infof("Setting task at line %d of note %s as done: %v", line, noteID, done)
+	task, err := s.taskRepo.FindTask(rail, noteID, line)
+	if err != nil {
+		return nil, ErrTaskNotFound
+	}
+	note, err := s.noteRepo.FindByID(rail, noteID)
+	if err != nil {
+		return nil, ErrNoteNotFound
+	}
+	if note.Encrypted {
+		return nil, ErrNoteLocked
+	}
+
+	content, ok := domain.SetTaskDone(note.Content, line, task.Text, done)
+	if !ok {
+		return nil, ErrTaskNotFound
+	}
+	if content == note.Content {
+		return note, nil
+	}
+	note.Content = content
+	if err := s.UpdateNote(rail, note); err != nil {
+		return nil, err
+	}
+	return note, nil
+}
+
+// SetStarred stars a note, or unstars it, starred notes are listed first
+func (s *NoteServiceImpl) SetStarred(rail flow.Rail, noteID string, starred bool) error {
+	if _, err := s.noteRepo.FindByID(rail, noteID); err != nil {
+		return ErrNoteNotFound
+	}
+	return s.noteRepo.UpdateStarred(rail, noteID, starred)
+}
+
+// SetArchived archives a note, or unarchives it, archived notes are left out of the note list and searches
+//
+// Archiving an archived note keeps when it was archived.
+func (s *NoteServiceImpl) SetArchived(rail flow.Rail, noteID string, archived bool) error {
+	note, err := s.noteRepo.FindByID(rail, noteID)
+	if err != nil {
+		return ErrNoteNotFound
+	}
+	if archived == (note.ArchivedAt != nil) {
+		return nil
+	}
+	var archivedAt *atom.Time
+	if archived {
+		now := atom.Now()
+		archivedAt = &now
+	}
+	return s.noteRepo.UpdateArchivedAt(rail, noteID, archivedAt)
+}
+
+// resolveLink finds the note of a link, labelled links are looked up by ID first and the other links by title first
+func (s *NoteServiceImpl) resolveLink(rail flow.Rail, link domain.Link) (*domain.Note, error) {
+	byID := func() (*domain.Note, error) { return s.noteRepo.FindByID(rail, link.Target) }
+	byTitle := func() (*domain.Note, error) { return s.noteRepo.FindByTitle(rail, link.Target) }
+	if link.Label == "" {
+		byID, byTitle = byTitle, byID
+	}
+	if note, err := byID(); err == nil {
+		return note, nil
+	}
+	return byTitle()
+}
+
+// resolveLinks rewrites the links of a note to refer to notes by ID, so that they keep working when notes are renamed
+func (s *NoteServiceImpl) resolveLinks(rail flow.Rail, note *domain.Note) {
+	note.Content = domain.RewriteLinks(note.Content, func(link domain.Link) (string, bool) {
+		target, err := s.resolveLink(rail, link)
+		if err != nil || target.ID == link.Target {
+			return "", false
+		}
+		return "[[" + target.ID + "|" + link.Text() + "]]", true
+	})
+}
+
+// IndexLinks records the notes that each of the notes links to, e.g., after the notes are imported, failures are only
+// logged
+func (s *NoteServiceImpl) IndexLinks(rail flow.Rail, noteIDs []string) {
+	for _, id := range noteIDs {
+		note, err := s.noteRepo.FindByID(rail, id)
+		if err != nil {
+			rail.Warnf("Failed to index links of note %s: %v", id, err)
+			continue
+		}
+		s.updateLinks(rail, note)
+	}
+}
+
+// updateLinks records the notes that a note links to, failures are only logged since the note itself is saved
+func (s *NoteServiceImpl) updateLinks(rail flow.Rail, note *domain.Note) {
+	targetIDs := []string{}
+	seen := map[string]bool{note.ID: true}
+	for _, link := range domain.ParseLinks(note.Content) {
+		target, err := s.resolveLink(rail, link)
+		if err != nil || seen[target.ID] {
+			continue
+		}
+		seen[target.ID] = true
+		targetIDs = append(targetIDs, target.ID)
+	}
+
+	if err := s.linkRepo.ReplaceLinks(rail, note.ID, targetIDs); err != nil {
+		rail.Errorf("Failed to update links of note %s: %v", note.ID, err)
+	}
+}
@@ -0,0 +1,260 @@
+package ui
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/curtisnewbie/nota/internal/i18n"
+)
+
+var (
+	// snapshotIntervals are the snapshot intervals offered in the backup panel, 0 turns snapshots off
+	snapshotIntervals = []time.Duration{0, 15 * time.Minute, time.Hour, 6 * time.Hour, 24 * time.Hour}
+
+	// snapshotKeeps are the numbers of snapshots to keep offered in the backup panel
+	snapshotKeeps = []int{6, 12, 24, 48, 96}
+)
+
+// BackupEntry is a backup of the database shown in the backup panel
+type BackupEntry struct {
+	Path      string
+	Kind      string // backup, snapshot, pre-migration, pre-restore or pre-repair
+	CreatedAt time.Time
+	Size      int64
+	Encrypted bool
+}
+
+// BackupPanel lists the backups of the database with the actions to take and restore backups
+type BackupPanel struct {
+	backupHandler BackupHandler
+	window        fyne.Window
+	updating      bool
+	backups       []BackupEntry
+	selected      int
+	backupList    *widget.List
+	emptyLabel    *widget.Label
+	restoreBtn    *widget.Button
+	intervalSel   *widget.Select
+	keepSel       *widget.Select
+	dialog        dialog.Dialog
+}
+
+// NewBackupPanel creates a new backup panel
+func NewBackupPanel(backupHandler BackupHandler, window fyne.Window) *BackupPanel {
+	return &BackupPanel{
+		backupHandler: backupHandler,
+		window:        window,
+		selected:      -1,
+	}
+}
+
+// Show shows the backups and the snapshot schedule in a dialog
+func (p *BackupPanel) Show(backups []BackupEntry, interval time.Duration, keep int) {
+	t := i18n.T()
+
+	p.backupList = widget.NewList(
+		func() int { return len(p.backups) },
+		func() fyne.CanvasObject {
+			nameLabel := widget.NewLabel("")
+			infoLabel := widget.NewLabel("")
+			infoLabel.TextStyle = fyne.TextStyle{Italic: true}
+			return container.NewVBox(nameLabel, infoLabel)
+		},
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			if id >= 0 && id < len(p.backups) {
+				backup := p.backups[id]
+				container := obj.(*fyne.Container)
+				container.Objects[0].(*widget.Label).SetText(fmt.Sprintf("%s  %s", backup.CreatedAt.Format("2006/01/02 15:04:05"), backupKindLabel(backup.Kind)))
+				info := fmt.Sprintf("%s, %s", filepath.Base(backup.Path), formatSize(backup.Size))
+				if backup.Encrypted {
+					info += ", " + t.Backup.Encrypted
+				}
+				container.Objects[1].(*widget.Label).SetText(info)
+			}
+		},
+	)
+	p.backupList.OnSelected = func(id widget.ListItemID) {
+		p.selectBackup(id)
+	}
+	p.backupList.OnUnselected = func(id widget.ListItemID) {
+		p.selectBackup(-1)
+	}
+
+	p.emptyLabel = widget.NewLabel(t.Backup.Empty)
+	p.emptyLabel.Alignment = fyne.TextAlignCenter
+
+	backupBtn := widget.NewButtonWithIcon(t.Backup.BackupNow, theme.DocumentSaveIcon(), func() {
+		p.backupHandler.OnBackupNow()
+	})
+	backupBtn.Importance = widget.HighImportance
+
+	p.restoreBtn = widget.NewButtonWithIcon(t.Backup.Restore, theme.HistoryIcon(), func() {
+		p.onRestoreRequested()
+	})
+
+	intervalLabels := make([]string, len(snapshotIntervals))
+	for i, interval := range snapshotIntervals {
+		intervalLabels[i] = snapshotIntervalLabel(interval)
+	}
+	keepLabels := make([]string, len(snapshotKeeps))
+	for i, keep := range snapshotKeeps {
+		keepLabels[i] = strconv.Itoa(keep)
+	}
+	p.intervalSel = widget.NewSelect(intervalLabels, func(string) { p.onScheduleChanged() })
+	p.keepSel = widget.NewSelect(keepLabels, func(string) { p.onScheduleChanged() })
+
+	schedule := container.NewHBox(
+		widget.NewLabel(t.Backup.Snapshots), p.intervalSel,
+		widget.NewLabel(t.Backup.Keep), p.keepSel,
+	)
+	content := container.NewBorder(
+		schedule,
+		container.NewHBox(backupBtn, p.restoreBtn),
+		nil,
+		nil,
+		container.NewStack(p.backupList, p.emptyLabel),
+	)
+
+	p.dialog = dialog.NewCustom(t.Backup.Title, t.Editor.Exit, content, p.window)
+	p.dialog.Resize(fyne.NewSize(640, 500))
+	p.SetSchedule(interval, keep)
+	p.SetBackups(backups)
+	p.dialog.Show()
+}
+
+// SetBackups updates the backups shown in the panel
+func (p *BackupPanel) SetBackups(backups []BackupEntry) {
+	if p.backupList == nil {
+		return
+	}
+
+	p.backups = backups
+	p.backupList.UnselectAll()
+	p.backupList.Refresh()
+	p.selectBackup(-1)
+
+	if len(backups) == 0 {
+		p.emptyLabel.Show()
+	} else {
+		p.emptyLabel.Hide()
+	}
+}
+
+// SetSchedule updates the snapshot schedule shown in the panel
+func (p *BackupPanel) SetSchedule(interval time.Duration, keep int) {
+	if p.intervalSel == nil {
+		return
+	}
+
+	p.updating = true
+	defer func() { p.updating = false }()
+
+	p.intervalSel.SetSelectedIndex(slices.Index(snapshotIntervals, interval))
+	p.keepSel.SetSelectedIndex(slices.Index(snapshotKeeps, keep))
+}
+
+// selectBackup enables restoring the selected backup
+func (p *BackupPanel) selectBackup(index int) {
+	p.selected = index
+	if index < 0 || index >= len(p.backups) {
+		p.restoreBtn.Disable()
+		return
+	}
+	p.restoreBtn.Enable()
+}
+
+// onRestoreRequested asks for confirmation and restores the selected backup
+func (p *BackupPanel) onRestoreRequested() {
+	if p.selected < 0 || p.selected >= len(p.backups) {
+		return
+	}
+
+	t := i18n.T()
+	backup := p.backups[p.selected]
+	dialog.ShowConfirm(t.Backup.Restore, fmt.Sprintf(t.Backup.SureRestore, backup.CreatedAt.Format("2006/01/02 15:04:05")), func(confirmed bool) {
+		if confirmed {
+			p.backupHandler.OnRestoreBackup(backup.Path, "")
+		}
+	}, p.window)
+}
+
+// AskPassword asks for the password of an encrypted backup and restores it
+func (p *BackupPanel) AskPassword(path string) {
+	t := i18n.T()
+	passwordEntry := widget.NewPasswordEntry()
+	passwordEntry.SetPlaceHolder(t.Database.Password)
+	content := container.NewVBox(widget.NewLabel(t.Backup.Password), passwordEntry)
+	d := dialog.NewCustomConfirm(t.Backup.Restore, t.Backup.Restore, t.Editor.Exit, content, func(confirmed bool) {
+		if confirmed {
+			p.backupHandler.OnRestoreBackup(path, passwordEntry.Text)
+		}
+	}, p.window)
+	d.Resize(fyne.NewSize(400, 0))
+	d.Show()
+	p.window.Canvas().Focus(passwordEntry)
+}
+
+// Hide closes the panel
+func (p *BackupPanel) Hide() {
+	if p.dialog != nil {
+		p.dialog.Hide()
+	}
+}
+
+// onScheduleChanged saves the snapshot schedule selected by user
+func (p *BackupPanel) onScheduleChanged() {
+	if p.updating || p.intervalSel.SelectedIndex() < 0 || p.keepSel.SelectedIndex() < 0 {
+		return
+	}
+	p.backupHandler.OnSnapshotScheduleChanged(snapshotIntervals[p.intervalSel.SelectedIndex()], snapshotKeeps[p.keepSel.SelectedIndex()])
+}
+
+// snapshotIntervalLabel describes how often snapshots are taken
+func snapshotIntervalLabel(interval time.Duration) string {
+	t := i18n.T()
+	switch {
+	case interval <= 0:
+		return t.Backup.SnapshotsOff
+	case interval%time.Hour == 0:
+		return fmt.Sprintf(t.Backup.SnapshotsEvery, fmt.Sprintf("%dh", int(interval.Hours())))
+	default:
+		return fmt.Sprintf(t.Backup.SnapshotsEvery, fmt.Sprintf("%dm", int(interval.Minutes())))
+	}
+}
+
+// backupKindLabel describes how the backup was taken
+func backupKindLabel(kind string) string {
+	t := i18n.T()
+	switch kind {
+	case "snapshot":
+		return t.Backup.Snapshot
+	case "pre-migration":
+		return t.Backup.PreMigration
+	case "pre-restore":
+		return t.Backup.PreRestore
+	case "pre-repair":
+		return t.Backup.PreRepair
+	default:
+		return t.Backup.Manual
+	}
+}
+
+// formatSize formats the size of a file for display
+func formatSize(size int64) string {
+	switch {
+	case size >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(size)/(1<<20))
+	case size >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(size)/(1<<10))
+	default:
+		return fmt.Sprintf("%d B", size)
+	}
+}
@@ -0,0 +1,131 @@
+package ui
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/curtisnewbie/nota/internal/domain"
+	"github.com/curtisnewbie/nota/internal/i18n"
+)
+
+// CalendarPanel shows a month of days to open the daily note of, the days with a daily note are highlighted
+type CalendarPanel struct {
+	dailyHandler DailyHandler
+	window       fyne.Window
+	month        time.Time // first day of the month shown
+	monthLabel   *widget.Label
+	daysGrid     *fyne.Container
+	dialog       dialog.Dialog
+}
+
+// NewCalendarPanel creates a new calendar panel
+func NewCalendarPanel(dailyHandler DailyHandler, window fyne.Window) *CalendarPanel {
+	return &CalendarPanel{
+		dailyHandler: dailyHandler,
+		window:       window,
+	}
+}
+
+// Show shows the month of date in a dialog
+func (p *CalendarPanel) Show(date time.Time) {
+	t := i18n.T()
+
+	p.monthLabel = widget.NewLabel("")
+	p.monthLabel.Alignment = fyne.TextAlignCenter
+	p.monthLabel.TextStyle = fyne.TextStyle{Bold: true}
+
+	prevBtn := widget.NewButtonWithIcon("", theme.NavigateBackIcon(), func() {
+		p.showMonth(p.month.AddDate(0, -1, 0))
+	})
+	nextBtn := widget.NewButtonWithIcon("", theme.NavigateNextIcon(), func() {
+		p.showMonth(p.month.AddDate(0, 1, 0))
+	})
+	todayBtn := widget.NewButton(t.Daily.Today, func() {
+		p.showMonth(time.Now())
+	})
+
+	header := container.NewGridWithColumns(7)
+	for _, name := range weekdayNames() {
+		label := widget.NewLabel(name)
+		label.Alignment = fyne.TextAlignCenter
+		header.Add(label)
+	}
+	p.daysGrid = container.NewGridWithColumns(7)
+
+	content := container.NewBorder(
+		container.NewVBox(
+			container.NewBorder(nil, nil, prevBtn, container.NewHBox(todayBtn, nextBtn), p.monthLabel),
+			header,
+		),
+		nil,
+		nil,
+		nil,
+		p.daysGrid,
+	)
+
+	p.dialog = dialog.NewCustom(t.Daily.Calendar, t.Editor.Exit, content, p.window)
+	p.dialog.Resize(fyne.NewSize(460, 0))
+	p.showMonth(date)
+	p.dialog.Show()
+}
+
+// showMonth shows the days of the month of date, highlighting the ones with a daily note
+func (p *CalendarPanel) showMonth(date time.Time) {
+	p.month = time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, time.Local)
+	last := p.month.AddDate(0, 1, -1)
+	p.monthLabel.SetText(p.month.Format(i18n.T().Daily.MonthFormat))
+
+	hasNote := make(map[string]bool)
+	dates, err := p.dailyHandler.ListDailyDates(p.month, last)
+	if err != nil {
+		dialog.ShowError(err, p.window)
+	}
+	for _, d := range dates {
+		hasNote[d.Format(domain.DailyDateLayout)] = true
+	}
+	today := time.Now().Format(domain.DailyDateLayout)
+
+	p.daysGrid.RemoveAll()
+	// Weeks start on Monday
+	for i := 0; i < (int(p.month.Weekday())+6)%7; i++ {
+		p.daysGrid.Add(widget.NewLabel(""))
+	}
+	for day := p.month; !day.After(last); day = day.AddDate(0, 0, 1) {
+		btn := widget.NewButton(strconv.Itoa(day.Day()), func() {
+			p.dialog.Hide()
+			p.dailyHandler.OnOpenDay(day)
+		})
+		key := day.Format(domain.DailyDateLayout)
+		switch {
+		case hasNote[key]:
+			btn.Importance = widget.HighImportance
+		case key == today:
+			btn.Importance = widget.MediumImportance
+		default:
+			btn.Importance = widget.LowImportance
+		}
+		p.daysGrid.Add(btn)
+	}
+	p.daysGrid.Refresh()
+}
+
+// weekdayNames returns the short names of the days of the week, starting on Monday
+func weekdayNames() []string {
+	return strings.Fields(i18n.T().Daily.Weekdays)
+}
+
+// weekdayName returns the short name of the day of the week of date
+func weekdayName(date time.Time) string {
+	names := weekdayNames()
+	index := (int(date.Weekday()) + 6) % 7
+	if index < len(names) {
+		return names[index]
+	}
+	return date.Weekday().String()
+}
@@ -0,0 +1,93 @@
+package ui
+
+import (
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/curtisnewbie/nota/internal/domain"
+	"github.com/curtisnewbie/nota/internal/i18n"
+)
+
+// ConflictResolution is how the user resolves a note that was updated elsewhere while being edited
+type ConflictResolution int
+
+const (
+	ConflictOverwrite ConflictResolution = iota // save the edited version over the latest version
+	ConflictReload                              // discard the edits and load the latest version
+	ConflictSaveAsNew                           // keep the latest version and save the edits as a new note
+)
+
+// ConflictDialog shows the differences between the edited note and the latest version saved elsewhere
+type ConflictDialog struct {
+	conflictHandler ConflictHandler
+	window          fyne.Window
+	dialog          dialog.Dialog
+}
+
+// NewConflictDialog creates a new conflict dialog
+func NewConflictDialog(conflictHandler ConflictHandler, window fyne.Window) *ConflictDialog {
+	return &ConflictDialog{
+		conflictHandler: conflictHandler,
+		window:          window,
+	}
+}
+
+// Show shows the diff turning the latest version into the edited note with the ways to resolve the conflict
+func (d *ConflictDialog) Show(edited *domain.Note, latest *domain.Note) {
+	t := i18n.T()
+
+	message := widget.NewLabel(fmt.Sprintf(t.Conflict.Message, latest.Version, latest.UpdatedAt.Format("2006/01/02 15:04:05")))
+	message.Wrapping = fyne.TextWrapWord
+
+	lines := []domain.DiffLine{}
+	if edited.Title != latest.Title {
+		lines = append(lines,
+			domain.DiffLine{Op: domain.DiffDelete, Text: "# " + latest.Title},
+			domain.DiffLine{Op: domain.DiffInsert, Text: "# " + edited.Title},
+			domain.DiffLine{Op: domain.DiffEqual},
+		)
+	}
+	lines = append(lines, domain.DiffLines(latest.Content, edited.Content)...)
+
+	diffView := widget.NewRichText(diffSegments(lines)...)
+	diffView.Wrapping = fyne.TextWrapWord
+
+	legend := widget.NewLabel(t.Conflict.Legend)
+	legend.Importance = widget.LowImportance
+
+	resolve := func(resolution ConflictResolution) {
+		d.dialog.Hide()
+		d.conflictHandler.OnResolveConflict(resolution)
+	}
+	overwriteBtn := widget.NewButtonWithIcon(t.Conflict.Overwrite, theme.DocumentSaveIcon(), func() {
+		resolve(ConflictOverwrite)
+	})
+	overwriteBtn.Importance = widget.DangerImportance
+	reloadBtn := widget.NewButtonWithIcon(t.Conflict.Reload, theme.ViewRefreshIcon(), func() {
+		resolve(ConflictReload)
+	})
+	saveAsNewBtn := widget.NewButtonWithIcon(t.Conflict.SaveAsNew, theme.ContentAddIcon(), func() {
+		resolve(ConflictSaveAsNew)
+	})
+	saveAsNewBtn.Importance = widget.HighImportance
+	cancelBtn := widget.NewButton(t.Conflict.KeepEditing, func() {
+		d.dialog.Hide()
+	})
+
+	content := container.NewBorder(
+		container.NewVBox(message, legend),
+		container.NewHBox(cancelBtn, layout.NewSpacer(), reloadBtn, overwriteBtn, saveAsNewBtn),
+		nil,
+		nil,
+		container.NewScroll(diffView),
+	)
+
+	d.dialog = dialog.NewCustomWithoutButtons(t.Conflict.Title, content, d.window)
+	d.dialog.Resize(fyne.NewSize(800, 560))
+	d.dialog.Show()
+}
@@ -0,0 +1,166 @@
+package ui
+
+import (
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/curtisnewbie/nota/internal/domain"
+	"github.com/curtisnewbie/nota/internal/i18n"
+)
+
+// DiagnosticsPanel shows the problems found in the database with the actions to repair them
+type DiagnosticsPanel struct {
+	diagnosticsHandler DiagnosticsHandler
+	window             fyne.Window
+	issues             []domain.Issue
+	selected           int
+	issueList          *widget.List
+	summaryLabel       *widget.Label
+	repairBtn          *widget.Button
+	repairAllBtn       *widget.Button
+	dialog             dialog.Dialog
+	shown              bool
+}
+
+// NewDiagnosticsPanel creates a new diagnostics panel
+func NewDiagnosticsPanel(diagnosticsHandler DiagnosticsHandler, window fyne.Window) *DiagnosticsPanel {
+	return &DiagnosticsPanel{
+		diagnosticsHandler: diagnosticsHandler,
+		window:             window,
+		selected:           -1,
+	}
+}
+
+// Show shows the problems found in the database in a dialog, the open dialog is updated when it's checked again
+func (p *DiagnosticsPanel) Show(issues []domain.Issue) {
+	if p.shown {
+		p.SetIssues(issues)
+		return
+	}
+
+	t := i18n.T()
+
+	p.issueList = widget.NewList(
+		func() int { return len(p.issues) },
+		func() fyne.CanvasObject {
+			problemLabel := widget.NewLabel("")
+			problemLabel.Truncation = fyne.TextTruncateEllipsis
+			fixLabel := widget.NewLabel("")
+			fixLabel.TextStyle = fyne.TextStyle{Italic: true}
+			return container.NewVBox(problemLabel, fixLabel)
+		},
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			if id >= 0 && id < len(p.issues) {
+				container := obj.(*fyne.Container)
+				problem, fix := describeIssue(p.issues[id])
+				container.Objects[0].(*widget.Label).SetText(problem)
+				container.Objects[1].(*widget.Label).SetText(fix)
+			}
+		},
+	)
+	p.issueList.OnSelected = func(id widget.ListItemID) {
+		p.selectIssue(id)
+	}
+	p.issueList.OnUnselected = func(id widget.ListItemID) {
+		p.selectIssue(-1)
+	}
+
+	p.summaryLabel = widget.NewLabel("")
+
+	p.repairBtn = widget.NewButtonWithIcon(t.Diagnostics.Repair, theme.ConfirmIcon(), func() {
+		if p.selected >= 0 && p.selected < len(p.issues) {
+			p.onRepairRequested([]domain.Issue{p.issues[p.selected]})
+		}
+	})
+	p.repairAllBtn = widget.NewButtonWithIcon(t.Diagnostics.RepairAll, theme.ConfirmIcon(), func() {
+		p.onRepairRequested(p.issues)
+	})
+	p.repairAllBtn.Importance = widget.HighImportance
+	checkBtn := widget.NewButtonWithIcon(t.Diagnostics.CheckAgain, theme.ViewRefreshIcon(), func() {
+		p.diagnosticsHandler.OnShowDiagnostics()
+	})
+
+	content := container.NewBorder(
+		p.summaryLabel,
+		container.NewHBox(p.repairAllBtn, p.repairBtn, checkBtn),
+		nil,
+		nil,
+		p.issueList,
+	)
+
+	p.dialog = dialog.NewCustom(t.Diagnostics.Title, t.Editor.Exit, content, p.window)
+	p.dialog.Resize(fyne.NewSize(700, 500))
+	p.dialog.SetOnClosed(func() { p.shown = false })
+	p.SetIssues(issues)
+	p.dialog.Show()
+	p.shown = true
+}
+
+// SetIssues updates the problems shown in the panel
+func (p *DiagnosticsPanel) SetIssues(issues []domain.Issue) {
+	if p.issueList == nil {
+		return
+	}
+
+	t := i18n.T()
+	p.issues = issues
+	p.issueList.UnselectAll()
+	p.issueList.Refresh()
+	p.selectIssue(-1)
+
+	if len(issues) == 0 {
+		p.summaryLabel.SetText(t.Diagnostics.NoIssues)
+		p.repairAllBtn.Disable()
+	} else {
+		p.summaryLabel.SetText(fmt.Sprintf(t.Diagnostics.Found, len(issues)))
+		p.repairAllBtn.Enable()
+	}
+}
+
+// selectIssue enables repairing the selected problem
+func (p *DiagnosticsPanel) selectIssue(index int) {
+	p.selected = index
+	if index < 0 || index >= len(p.issues) {
+		p.repairBtn.Disable()
+		return
+	}
+	p.repairBtn.Enable()
+}
+
+// onRepairRequested asks for confirmation and repairs the problems
+func (p *DiagnosticsPanel) onRepairRequested(issues []domain.Issue) {
+	if len(issues) == 0 {
+		return
+	}
+
+	t := i18n.T()
+	dialog.ShowConfirm(t.Diagnostics.Repair, t.Diagnostics.SureRepair, func(confirmed bool) {
+		if confirmed {
+			p.diagnosticsHandler.OnRepairIssues(issues)
+		}
+	}, p.window)
+}
+
+// describeIssue describes the problem and how it's repaired
+func describeIssue(issue domain.Issue) (problem string, fix string) {
+	t := i18n.T()
+	title := issue.Title
+	if title == "" {
+		title = issue.NoteID
+	}
+	switch issue.Kind {
+	case domain.IssueIntegrity:
+		return fmt.Sprintf(t.Diagnostics.Integrity, issue.Value), t.Diagnostics.FixIntegrity
+	case domain.IssueInvalidMetadata:
+		return fmt.Sprintf(t.Diagnostics.InvalidMetadata, title), t.Diagnostics.FixMetadata
+	case domain.IssueEmptyTitle:
+		return fmt.Sprintf("%s (%s)", t.Diagnostics.EmptyTitle, issue.NoteID), t.Diagnostics.FixTitle
+	case domain.IssueInvalidTimestamp:
+		return fmt.Sprintf(t.Diagnostics.InvalidTimestamp, title, issue.Column, issue.Value), t.Diagnostics.FixTimestamp
+	}
+	return string(issue.Kind), ""
+}
@@ -0,0 +1,161 @@
+package ui
+
+import (
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/curtisnewbie/nota/internal/domain"
+	"github.com/curtisnewbie/nota/internal/i18n"
+)
+
+// HistoryPanel shows the previous versions of a note with a diff against the current version
+type HistoryPanel struct {
+	historyHandler HistoryHandler
+	window         fyne.Window
+	note           *domain.Note
+	revisions      []*domain.NoteRevision
+	selected       int
+	revisionList   *widget.List
+	diffView       *widget.RichText
+	restoreBtn     *widget.Button
+	dialog         dialog.Dialog
+}
+
+// NewHistoryPanel creates a new history panel
+func NewHistoryPanel(historyHandler HistoryHandler, window fyne.Window) *HistoryPanel {
+	return &HistoryPanel{
+		historyHandler: historyHandler,
+		window:         window,
+		selected:       -1,
+	}
+}
+
+// Show shows the history of the note in a dialog
+func (h *HistoryPanel) Show(note *domain.Note, revisions []*domain.NoteRevision) {
+	t := i18n.T()
+
+	if len(revisions) == 0 {
+		dialog.ShowInformation(t.History.Title, t.History.NoRevisions, h.window)
+		return
+	}
+
+	h.note = note
+	h.revisions = revisions
+	h.selected = -1
+
+	h.revisionList = widget.NewList(
+		func() int { return len(h.revisions) + 1 },
+		func() fyne.CanvasObject {
+			versionLabel := widget.NewLabel("")
+			versionLabel.TextStyle = fyne.TextStyle{Bold: true}
+			dateLabel := widget.NewLabel("")
+			dateLabel.TextStyle = fyne.TextStyle{Italic: true}
+			return container.NewVBox(versionLabel, dateLabel)
+		},
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			container := obj.(*fyne.Container)
+			versionLabel := container.Objects[0].(*widget.Label)
+			dateLabel := container.Objects[1].(*widget.Label)
+			if id == 0 {
+				versionLabel.SetText(fmt.Sprintf(t.History.Version, h.note.Version) + " - " + t.History.Current)
+				dateLabel.SetText(h.note.UpdatedAt.Format("2006/01/02 15:04:05"))
+				return
+			}
+			if id-1 < len(h.revisions) {
+				revision := h.revisions[id-1]
+				versionLabel.SetText(fmt.Sprintf(t.History.Version, revision.Version) + " - " + revision.Title)
+				dateLabel.SetText(revision.UpdatedAt.Format("2006/01/02 15:04:05"))
+			}
+		},
+	)
+	h.revisionList.OnSelected = func(id widget.ListItemID) {
+		h.selectRevision(id - 1)
+	}
+
+	h.diffView = widget.NewRichText()
+	h.diffView.Wrapping = fyne.TextWrapWord
+
+	h.restoreBtn = widget.NewButtonWithIcon(t.History.Restore, theme.HistoryIcon(), func() {
+		h.onRestoreRequested()
+	})
+	h.restoreBtn.Importance = widget.HighImportance
+	h.restoreBtn.Disable()
+
+	rightPanel := container.NewBorder(
+		nil,
+		container.NewHBox(h.restoreBtn),
+		nil,
+		nil,
+		container.NewScroll(h.diffView),
+	)
+
+	split := container.NewHSplit(h.revisionList, rightPanel)
+	split.SetOffset(0.3)
+
+	h.dialog = dialog.NewCustom(t.History.Title, t.Editor.Exit, split, h.window)
+	h.dialog.Resize(fyne.NewSize(900, 600))
+	h.dialog.Show()
+
+	h.revisionList.Select(1)
+}
+
+// selectRevision shows the diff between the selected revision and the current version
+func (h *HistoryPanel) selectRevision(index int) {
+	h.selected = index
+	if index < 0 || index >= len(h.revisions) {
+		h.diffView.Segments = nil
+		h.diffView.Refresh()
+		h.restoreBtn.Disable()
+		return
+	}
+
+	lines, err := h.historyHandler.DiffRevision(h.revisions[index].Version)
+	if err != nil {
+		dialog.ShowError(err, h.window)
+		return
+	}
+
+	h.diffView.Segments = diffSegments(lines)
+	h.diffView.Refresh()
+	h.restoreBtn.Enable()
+}
+
+// onRestoreRequested asks for confirmation and restores the selected revision
+func (h *HistoryPanel) onRestoreRequested() {
+	if h.selected < 0 || h.selected >= len(h.revisions) {
+		return
+	}
+
+	t := i18n.T()
+	version := h.revisions[h.selected].Version
+	dialog.ShowConfirm(t.Dialog.RestoreRevision, t.Dialog.SureRestore, func(confirmed bool) {
+		if !confirmed {
+			return
+		}
+		h.dialog.Hide()
+		h.historyHandler.OnRestoreRevision(version)
+	}, h.window)
+}
+
+// diffSegments renders diff lines as rich text, insertions and deletions are colored
+func diffSegments(lines []domain.DiffLine) []widget.RichTextSegment {
+	segments := make([]widget.RichTextSegment, 0, len(lines))
+	for _, line := range lines {
+		style := widget.RichTextStyle{TextStyle: fyne.TextStyle{Monospace: true}}
+		prefix := "  "
+		switch line.Op {
+		case domain.DiffInsert:
+			prefix = "+ "
+			style.ColorName = theme.ColorNameSuccess
+		case domain.DiffDelete:
+			prefix = "- "
+			style.ColorName = theme.ColorNameError
+		}
+		segments = append(segments, &widget.TextSegment{Text: prefix + line.Text, Style: style})
+	}
+	return segments
+}
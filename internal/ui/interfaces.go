@@ -1,6 +1,8 @@
 package ui
 
 import (
+	"time"
+
 	"github.com/curtisnewbie/nota/internal/domain"
 	"github.com/curtisnewbie/nota/internal/i18n"
 )
@@ -21,7 +23,9 @@ type AppActionsHandler interface {
 	OnCreateNote()
 	OnDeleteNote()
 	OnImportNote()
+	OnImportMarkdown()
 	OnExportNote()
+	OnExportMarkdown()
 	OnNoteSelected(note *domain.Note)
 	OnContentChanged()
 	OnSave()
@@ -45,3 +49,149 @@ type PinHandler interface {
 type LanguageHandler interface {
 	OnLanguageChanged(lang i18n.Language)
 }
+
+// HistoryHandler handles note revision history events
+type HistoryHandler interface {
+	OnShowHistory()
+	DiffRevision(version int) ([]domain.DiffLine, error)
+	OnRestoreRevision(version int)
+}
+
+// TrashHandler handles trash events
+type TrashHandler interface {
+	OnShowTrash()
+	OnRestoreNote(id string)
+	OnPurgeNote(id string)
+	OnEmptyTrash()
+}
+
+// TagHandler handles tag events
+type TagHandler interface {
+	OnAddTag(name string)
+	OnRemoveTag(tagID string)
+	OnTagFilterChanged()
+	OnManageTags()
+	OnRenameTag(tagID string, name string)
+	OnMergeTags(sourceID string, targetID string)
+	OnDeleteTag(tagID string)
+}
+
+// NotebookHandler handles notebook events
+type NotebookHandler interface {
+	OnNotebookSelected(notebookID string)
+	OnCreateNotebook(name string, parentID string)
+	OnRenameNotebook(notebookID string, name string)
+	OnMoveNotebook(notebookID string, parentID string)
+	OnDeleteNotebook(notebookID string)
+	OnShowMoveNote()
+	OnMoveNote(notebookID string)
+}
+
+// LinkHandler handles wiki link events
+type LinkHandler interface {
+	OnLinkSelected(target string)
+}
+
+// ServerHandler handles API server events
+type ServerHandler interface {
+	OnShowServer()
+	OnToggleServer(enabled bool, addr string, origin string)
+	OnResetServerToken()
+}
+
+// ConflictHandler handles the resolution of notes updated elsewhere while being edited
+type ConflictHandler interface {
+	OnResolveConflict(resolution ConflictResolution)
+}
+
+// AutosaveHandler handles autosave setting events
+type AutosaveHandler interface {
+	GetAutosaveDelay() time.Duration
+	OnAutosaveDelayChanged(delay time.Duration)
+}
+
+// LockHandler handles locking notes with a passphrase
+type LockHandler interface {
+	OnShowLockNote()
+	OnLockNote(passphrase string)
+	OnUnlockNote(passphrase string)
+	OnRemoveLock()
+}
+
+// UnlockHandler handles unlocking the encrypted database on startup
+type UnlockHandler interface {
+	OnUnlockDatabase(password string)
+}
+
+// VaultHandler handles switching between databases (vaults)
+type VaultHandler interface {
+	RecentDatabases() []string
+	GetDefaultDatabase() string
+	OnSwitchDatabase(path string)
+	OnOpenDatabase()
+	OnNewDatabase()
+	OnSetDefaultDatabase(path string)
+}
+
+// BackupHandler handles taking and restoring backups of the database
+type BackupHandler interface {
+	OnShowBackups()
+	OnBackupNow()
+	OnRestoreBackup(path string, password string)
+	OnSnapshotScheduleChanged(interval time.Duration, keep int)
+}
+
+// DiagnosticsHandler handles checking the database for problems and repairing them
+type DiagnosticsHandler interface {
+	OnShowDiagnostics()
+	OnRepairIssues(issues []domain.Issue)
+}
+
+// TemplateHandler handles creating notes from templates and using notes as templates
+type TemplateHandler interface {
+	OnShowTemplates()
+	OnCreateFromTemplate(templateID string, fields map[string]string)
+	IsTemplate() bool
+	OnSetTemplate(template bool)
+}
+
+// DailyHandler handles opening and navigating daily notes
+type DailyHandler interface {
+	OnOpenToday()
+	OnOpenDay(date time.Time)
+	OnOpenAdjacentDay(offset int)
+	OnShowCalendar()
+	ListDailyDates(from time.Time, to time.Time) ([]time.Time, error)
+	GetDailyTitleFormat() string
+	OnDailyTitleFormatChanged(format string)
+}
+
+// ReminderHandler handles the reminders of notes and the due soon filter
+type ReminderHandler interface {
+	OnShowReminder()
+	OnSetReminder(dueAt *time.Time)
+	OnOpenReminder(noteID string)
+	OnSnoozeReminder(noteID string, d time.Duration)
+	OnCompleteReminder(noteID string)
+	OnDueSoonFilterChanged()
+}
+
+// TaskHandler handles listing the tasks found across notes and ticking them
+type TaskHandler interface {
+	OnShowTasks()
+	ListTasks(filter domain.TaskFilter) ([]*domain.NoteTask, error)
+	OnSetTaskDone(task *domain.NoteTask, done bool)
+	OnOpenTask(task *domain.NoteTask)
+}
+
+// StarHandler handles starring notes so that they are listed first
+type StarHandler interface {
+	OnSetStarred(noteID string, starred bool)
+}
+
+// ArchiveHandler handles archiving notes and listing the archived notes
+type ArchiveHandler interface {
+	IsArchived() bool
+	OnSetArchived(archived bool)
+	OnArchivedFilterChanged()
+}
@@ -2,6 +2,9 @@ package ui
 
 import (
 	"context"
+	"errors"
+	"strings"
+	"time"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
@@ -17,6 +20,12 @@ import (
 type NoteService interface {
 	ListNotes(rail flow.Rail) ([]*domain.Note, error)
 	ListNotesPaginated(rail flow.Rail, offset, limit int) ([]*domain.Note, error)
+	SearchNotesPaginated(rail flow.Rail, filter domain.NoteFilter, offset, limit int) ([]*domain.Note, error)
+	ListTags(rail flow.Rail) ([]*domain.Tag, error)
+	GetNoteTags(rail flow.Rail, noteID string) ([]*domain.Tag, error)
+	ListNotebooks(rail flow.Rail) ([]*domain.Notebook, error)
+	GetLinkedNotes(rail flow.Rail, noteID string) ([]*domain.Note, error)
+	GetBacklinks(rail flow.Rail, noteID string) ([]*domain.Note, error)
 }
 
 // ImportExportService defines the interface for import/export operations
@@ -29,6 +38,18 @@ type MainUI struct {
 	menuBar          *MenuBar
 	noteEditor       *NoteEditor
 	noteList         *NoteList
+	notebookTree     *NotebookTree
+	historyPanel     *HistoryPanel
+	trashPanel       *TrashPanel
+	tagManager       *TagManager
+	serverPanel      *ServerPanel
+	backupPanel      *BackupPanel
+	diagnosticsPanel *DiagnosticsPanel
+	templatePicker   *TemplatePicker
+	calendarPanel    *CalendarPanel
+	reminderPanel    *ReminderPanel
+	tasksPanel       *TasksPanel
+	conflictDialog   *ConflictDialog
 	container        *fyne.Container
 	noteService      NoteService
 	minimized        bool
@@ -52,11 +73,49 @@ func NewMainUI(
 
 	mainUI.menuBar = NewMenuBar(app, app, app.(LanguageHandler), app.GetDatabaseLocation())
 	mainUI.menuBar.SetWindow(window)
+	mainUI.menuBar.SetTrashHandler(app.(TrashHandler))
+	mainUI.menuBar.SetTagHandler(app.(TagHandler))
+	mainUI.menuBar.SetNotebookHandler(app.(NotebookHandler))
+	mainUI.menuBar.SetServerHandler(app.(ServerHandler))
+	mainUI.menuBar.SetAutosaveHandler(app.(AutosaveHandler))
+	mainUI.menuBar.SetLockHandler(app.(LockHandler))
+	mainUI.menuBar.SetVaultHandler(app.(VaultHandler))
+	mainUI.menuBar.SetBackupHandler(app.(BackupHandler))
+	mainUI.menuBar.SetDiagnosticsHandler(app.(DiagnosticsHandler))
+	mainUI.menuBar.SetTemplateHandler(app.(TemplateHandler))
+	mainUI.menuBar.SetDailyHandler(app.(DailyHandler))
+	mainUI.menuBar.SetReminderHandler(app.(ReminderHandler))
+	mainUI.menuBar.SetArchiveHandler(app.(ArchiveHandler))
+	mainUI.menuBar.SetTaskHandler(app.(TaskHandler))
 	mainUI.noteEditor = NewNoteEditor(app)
+	mainUI.noteEditor.SetWindow(window)
 	mainUI.noteEditor.SetDeleteHandler(app)
+	mainUI.noteEditor.SetHistoryHandler(app.(HistoryHandler))
+	mainUI.noteEditor.SetTagHandler(app.(TagHandler))
+	mainUI.noteEditor.SetLinkHandler(app.(LinkHandler))
+	mainUI.noteEditor.SetLockHandler(app.(LockHandler))
+	mainUI.noteEditor.SetDailyHandler(app.(DailyHandler))
+	mainUI.noteEditor.SetReminderHandler(app.(ReminderHandler))
+	mainUI.noteEditor.SetStarHandler(app.(StarHandler))
+	mainUI.historyPanel = NewHistoryPanel(app.(HistoryHandler), window)
+	mainUI.trashPanel = NewTrashPanel(app.(TrashHandler), window)
+	mainUI.tagManager = NewTagManager(app.(TagHandler), window)
+	mainUI.serverPanel = NewServerPanel(app.(ServerHandler), window)
+	mainUI.backupPanel = NewBackupPanel(app.(BackupHandler), window)
+	mainUI.diagnosticsPanel = NewDiagnosticsPanel(app.(DiagnosticsHandler), window)
+	mainUI.templatePicker = NewTemplatePicker(app.(TemplateHandler), window)
+	mainUI.calendarPanel = NewCalendarPanel(app.(DailyHandler), window)
+	mainUI.reminderPanel = NewReminderPanel(app.(ReminderHandler), window)
+	mainUI.tasksPanel = NewTasksPanel(app.(TaskHandler), window)
+	mainUI.conflictDialog = NewConflictDialog(app.(ConflictHandler), window)
 	mainUI.noteList = NewNoteList(app, app)
 	mainUI.noteList.SetWindow(window)
 	mainUI.noteList.SetDeleteHandler(app)
+	mainUI.noteList.SetTagHandler(app.(TagHandler))
+	mainUI.noteList.SetReminderHandler(app.(ReminderHandler))
+	mainUI.noteList.SetStarHandler(app.(StarHandler))
+	mainUI.noteList.SetArchiveHandler(app.(ArchiveHandler))
+	mainUI.notebookTree = NewNotebookTree(app.(NotebookHandler), window)
 
 	return mainUI
 }
@@ -65,7 +124,8 @@ func NewMainUI(
 func (m *MainUI) Build() fyne.CanvasObject {
 	m.menuBarContainer = m.menuBar.Build()
 
-	leftPanel := m.noteList.Build()
+	leftPanel := container.NewVSplit(m.notebookTree.Build(), m.noteList.Build())
+	leftPanel.SetOffset(0.30)
 	m.rightPanel = m.noteEditor.Build()
 
 	splitContainer := container.NewHSplit(leftPanel, m.rightPanel)
@@ -100,6 +160,233 @@ func (m *MainUI) setupRightClickHandler() {
 // DisplayNote displays a note
 func (m *MainUI) DisplayNote(note *domain.Note) {
 	m.noteEditor.DisplayNote(note)
+	m.displayNoteTags(note)
+	m.displayNoteLinks(note)
+}
+
+// RefreshNoteInfo updates the timestamps, tags and links shown for the note without touching the title and content
+func (m *MainUI) RefreshNoteInfo(note *domain.Note) {
+	m.noteEditor.SetNoteInfo(note)
+	m.displayNoteTags(note)
+	m.displayNoteLinks(note)
+}
+
+// displayNoteLinks displays the links and backlinks of the note in the editor
+func (m *MainUI) displayNoteLinks(note *domain.Note) {
+	if note == nil || note.ID == "" {
+		m.noteEditor.DisplayLinks(nil, nil)
+		return
+	}
+
+	rail := flow.NewRail(context.Background())
+	links, err := m.noteService.GetLinkedNotes(rail, note.ID)
+	if err != nil {
+		dialog.ShowError(err, m.window)
+		return
+	}
+	backlinks, err := m.noteService.GetBacklinks(rail, note.ID)
+	if err != nil {
+		dialog.ShowError(err, m.window)
+		return
+	}
+	m.noteEditor.DisplayLinks(links, backlinks)
+}
+
+// displayNoteTags displays the tags of the note in the editor
+func (m *MainUI) displayNoteTags(note *domain.Note) {
+	if note == nil || note.ID == "" {
+		m.noteEditor.DisplayTags(nil)
+		return
+	}
+
+	rail := flow.NewRail(context.Background())
+	tags, err := m.noteService.GetNoteTags(rail, note.ID)
+	if err != nil {
+		dialog.ShowError(err, m.window)
+		return
+	}
+	m.noteEditor.DisplayTags(tags)
+}
+
+// RefreshTags reloads the tags shown in the tag filter, the editor and the tag manager
+func (m *MainUI) RefreshTags() {
+	rail := flow.NewRail(context.Background())
+	tags, err := m.noteService.ListTags(rail)
+	if err != nil {
+		dialog.ShowError(err, m.window)
+		return
+	}
+	m.noteList.SetTags(tags)
+	m.noteEditor.SetAllTags(tags)
+	m.tagManager.SetTags(tags)
+	m.displayNoteTags(m.noteEditor.note)
+}
+
+// RefreshNotebooks reloads the notebooks shown in the notebook tree
+func (m *MainUI) RefreshNotebooks() {
+	rail := flow.NewRail(context.Background())
+	notebooks, err := m.noteService.ListNotebooks(rail)
+	if err != nil {
+		dialog.ShowError(err, m.window)
+		return
+	}
+	m.notebookTree.SetNotebooks(notebooks)
+}
+
+// ShowMoveNoteDialog asks for the notebook that the current note is moved into
+func (m *MainUI) ShowMoveNoteDialog() {
+	m.notebookTree.ShowMoveNoteDialog()
+}
+
+// GetSelectedNotebookID returns the ID of the notebook selected in the notebook tree
+func (m *MainUI) GetSelectedNotebookID() string {
+	return m.notebookTree.GetSelectedNotebookID()
+}
+
+// ShowTagManager shows the tag manager
+func (m *MainUI) ShowTagManager() {
+	rail := flow.NewRail(context.Background())
+	tags, err := m.noteService.ListTags(rail)
+	if err != nil {
+		dialog.ShowError(err, m.window)
+		return
+	}
+	m.tagManager.Show(tags)
+}
+
+// ShowHistory shows the revision history of a note
+func (m *MainUI) ShowHistory(note *domain.Note, revisions []*domain.NoteRevision) {
+	m.historyPanel.Show(note, revisions)
+}
+
+// ShowTrash shows the deleted notes
+func (m *MainUI) ShowTrash(notes []*domain.Note) {
+	m.trashPanel.Show(notes)
+}
+
+// UpdateTrash updates the deleted notes shown in the trash panel
+func (m *MainUI) UpdateTrash(notes []*domain.Note) {
+	m.trashPanel.SetNotes(notes)
+}
+
+// ShowConflict shows the differences between the edited note and the latest version saved elsewhere
+func (m *MainUI) ShowConflict(edited *domain.Note, latest *domain.Note) {
+	m.conflictDialog.Show(edited, latest)
+}
+
+// ShowLockNoteDialog asks twice for the passphrase that the current note is locked with
+func (m *MainUI) ShowLockNoteDialog() {
+	t := i18n.T()
+	passphraseEntry := widget.NewPasswordEntry()
+	confirmEntry := widget.NewPasswordEntry()
+	confirmEntry.Validator = func(text string) error {
+		if text != passphraseEntry.Text {
+			return errors.New(t.Lock.PassphraseMismatch)
+		}
+		return nil
+	}
+	warning := widget.NewLabel(t.Lock.SureLock)
+	warning.Wrapping = fyne.TextWrapWord
+
+	form := dialog.NewForm(t.Menu.LockNote, t.Lock.Lock, t.Editor.Exit,
+		[]*widget.FormItem{
+			widget.NewFormItem("", warning),
+			widget.NewFormItem(t.Lock.Passphrase, passphraseEntry),
+			widget.NewFormItem(t.Lock.ConfirmPassphrase, confirmEntry),
+		},
+		func(confirmed bool) {
+			if confirmed {
+				m.app.(LockHandler).OnLockNote(passphraseEntry.Text)
+			}
+		},
+		m.window,
+	)
+	form.Resize(fyne.NewSize(460, 0))
+	form.Show()
+}
+
+// IsLocked checks whether the displayed note is locked and its content is not shown
+func (m *MainUI) IsLocked() bool {
+	return m.noteEditor.IsLocked()
+}
+
+// ShowServer shows the API server panel, runningAddr is empty when the server is not running
+func (m *MainUI) ShowServer(addr string, origin string, runningAddr string, token string) {
+	m.serverPanel.Show(addr, origin, runningAddr, token)
+}
+
+// UpdateServer updates the state shown in the API server panel
+func (m *MainUI) UpdateServer(addr string, origin string, runningAddr string, token string) {
+	m.serverPanel.SetState(addr, origin, runningAddr, token)
+}
+
+// ShowBackups shows the backup panel with the backups of the database and the snapshot schedule
+func (m *MainUI) ShowBackups(backups []BackupEntry, interval time.Duration, keep int) {
+	m.backupPanel.Show(backups, interval, keep)
+}
+
+// UpdateBackups updates the backups shown in the backup panel
+func (m *MainUI) UpdateBackups(backups []BackupEntry) {
+	m.backupPanel.SetBackups(backups)
+}
+
+// AskBackupPassword asks for the password of an encrypted backup to restore it
+func (m *MainUI) AskBackupPassword(path string) {
+	m.backupPanel.AskPassword(path)
+}
+
+// HideBackups closes the backup panel
+func (m *MainUI) HideBackups() {
+	m.backupPanel.Hide()
+}
+
+// ShowDiagnostics shows the problems found in the database
+func (m *MainUI) ShowDiagnostics(issues []domain.Issue) {
+	m.diagnosticsPanel.Show(issues)
+}
+
+// ShowTemplates asks for the template that a new note is created from
+func (m *MainUI) ShowTemplates(templates []*domain.Note) {
+	m.templatePicker.Show(templates)
+}
+
+// ShowCalendar shows the month of date to open the daily note of a day
+func (m *MainUI) ShowCalendar(date time.Time) {
+	m.calendarPanel.Show(date)
+}
+
+// ShowReminderEditor asks for when the reminder of the current note is due
+func (m *MainUI) ShowReminderEditor(dueAt *time.Time) {
+	m.reminderPanel.ShowEditor(dueAt)
+}
+
+// ShowDueReminder shows the reminder of a note that came due
+func (m *MainUI) ShowDueReminder(note *domain.Note) {
+	m.reminderPanel.ShowDue(note)
+}
+
+// ShowTasks shows the tasks found across notes
+func (m *MainUI) ShowTasks() {
+	m.tasksPanel.Show()
+}
+
+// RefreshTasks lists the tasks again if they are shown
+func (m *MainUI) RefreshTasks() {
+	m.tasksPanel.Refresh()
+}
+
+// ShowImportReport shows the summary of an import with a line for each file that was not imported
+func (m *MainUI) ShowImportReport(summary string, lines []string) {
+	t := i18n.T()
+	content := container.NewVBox(widget.NewLabel(summary))
+	if len(lines) > 0 {
+		details := widget.NewLabel(strings.Join(lines, "\n"))
+		details.Wrapping = fyne.TextWrapWord
+		scroll := container.NewVScroll(details)
+		scroll.SetMinSize(fyne.NewSize(500, 250))
+		content.Add(scroll)
+	}
+	dialog.ShowCustom(t.Dialog.ImportReport, t.Editor.Exit, content, m.window)
 }
 
 // ShowEmptyState shows the empty state
@@ -108,18 +395,30 @@ func (m *MainUI) ShowEmptyState() {
 	m.noteList.DisplayNotes([]*domain.Note{})
 }
 
-// RefreshNoteList refreshes the note list
+// NoteFilter builds the filter for the search query combined with the selected notebook and tags
+func (m *MainUI) NoteFilter(query string) domain.NoteFilter {
+	return domain.NoteFilter{
+		Query:      query,
+		Tags:       m.noteList.GetSelectedTags(),
+		NotebookID: m.GetSelectedNotebookID(),
+		DueSoon:    m.noteList.IsDueSoon(),
+		Archived:   m.noteList.IsArchived(),
+	}
+}
+
+// RefreshNoteList refreshes the note list, keeping the current search query, notebook and tag filter
 func (m *MainUI) RefreshNoteList() {
 	rail := flow.NewRail(context.Background())
 	noteList := m.noteList
+	query := noteList.GetSearchQuery()
 
 	// Load first page of notes
-	notes, err := m.noteService.ListNotesPaginated(rail, 0, noteList.GetPageSize())
+	notes, err := m.noteService.SearchNotesPaginated(rail, m.NoteFilter(query), 0, noteList.GetPageSize())
 	if err != nil {
 		dialog.ShowError(err, m.window)
 		return
 	}
-	noteList.SetCurrentQuery("")
+	noteList.SetCurrentQuery(query)
 	noteList.LoadNotes(notes)
 }
 
@@ -244,6 +543,12 @@ func (m *MainUI) ToggleMinimizedMode(minimized bool) {
 			}
 		}
 
+		// Locked notes can't be edited until they are unlocked
+		if m.noteEditor.IsLocked() {
+			titleEntry.Disable()
+			contentEntry.Disable()
+		}
+
 		// Track the widgets so we can sync changes back when exiting
 		m.noteEditor.SetMinimizedWidgets(titleEntry, contentEntry)
 
@@ -0,0 +1,182 @@
+package ui
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/widget"
+	"github.com/curtisnewbie/nota/internal/domain"
+)
+
+// noteLinkScheme is the URL scheme of wiki links rendered as hyperlinks
+const noteLinkScheme = "nota"
+
+var (
+	checkboxPattern       = regexp.MustCompile(`^(\s*(?:[-*+]|\d+[.)])\s+)\[([ xX])\](\s)`)
+	tableSeparatorPattern = regexp.MustCompile(`^\s*\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)*\|?\s*$`)
+)
+
+// renderMarkdown renders note content as rich text, onLink is called with the target of a tapped wiki link
+func renderMarkdown(content string, onLink func(target string)) []widget.RichTextSegment {
+	segments := []widget.RichTextSegment{}
+	var chunk []string
+	flush := func() {
+		if len(chunk) == 0 {
+			return
+		}
+		segments = append(segments, widget.NewRichTextFromMarkdown(strings.Join(chunk, "\n")).Segments...)
+		chunk = nil
+	}
+
+	lines := strings.Split(content, "\n")
+	inFence := false
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+			chunk = append(chunk, line)
+			continue
+		}
+		if inFence {
+			chunk = append(chunk, line)
+			continue
+		}
+
+		// Tables are not supported by the markdown parser, they are rendered as aligned monospace rows
+		if i+1 < len(lines) && isTableHeader(line, lines[i+1]) {
+			end := i + 2
+			for end < len(lines) && isTableRow(lines[end]) {
+				end++
+			}
+			flush()
+			segments = append(segments, tableSegments(lines[i], lines[i+2:end])...)
+			i = end - 1
+			continue
+		}
+
+		chunk = append(chunk, renderMarkdownLine(line))
+	}
+	flush()
+
+	bindNoteLinks(segments, onLink)
+	return segments
+}
+
+// renderMarkdownLine replaces checkboxes with symbols and wiki links with hyperlinks
+func renderMarkdownLine(line string) string {
+	line = checkboxPattern.ReplaceAllStringFunc(line, func(s string) string {
+		m := checkboxPattern.FindStringSubmatch(s)
+		box := "☐"
+		if m[2] != " " {
+			box = "☑"
+		}
+		return m[1] + box + m[3]
+	})
+
+	return domain.RewriteLinks(line, func(link domain.Link) (string, bool) {
+		label := strings.NewReplacer("[", "\\[", "]", "\\]").Replace(link.Text())
+		return "[" + label + "](" + noteLinkScheme + ":" + url.PathEscape(link.Target) + ")", true
+	})
+}
+
+// bindNoteLinks makes the hyperlinks of wiki links call onLink instead of opening a URL
+func bindNoteLinks(segments []widget.RichTextSegment, onLink func(target string)) {
+	for _, segment := range segments {
+		switch s := segment.(type) {
+		case *widget.HyperlinkSegment:
+			if s.URL == nil || s.URL.Scheme != noteLinkScheme {
+				continue
+			}
+			target, err := url.PathUnescape(s.URL.Opaque)
+			if err != nil {
+				continue
+			}
+			s.OnTapped = func() {
+				if onLink != nil {
+					onLink(target)
+				}
+			}
+		case *widget.ParagraphSegment:
+			bindNoteLinks(s.Texts, onLink)
+		case *widget.ListSegment:
+			bindNoteLinks(s.Items, onLink)
+		}
+	}
+}
+
+// isTableRow checks whether the line looks like a row of a markdown table
+func isTableRow(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	return strings.Count(trimmed, "|") > 0 && trimmed != "|" && !strings.HasPrefix(trimmed, ">")
+}
+
+// isTableHeader checks whether the line is a table header followed by a delimiter row with the same number of columns
+func isTableHeader(line string, next string) bool {
+	if !isTableRow(line) || !strings.Contains(next, "|") || !tableSeparatorPattern.MatchString(next) {
+		return false
+	}
+	return len(splitTableRow(line)) == len(splitTableRow(next))
+}
+
+// splitTableRow splits a markdown table row into trimmed cells
+func splitTableRow(line string) []string {
+	trimmed := strings.TrimSpace(line)
+	trimmed = strings.TrimPrefix(trimmed, "|")
+	trimmed = strings.TrimSuffix(trimmed, "|")
+	cells := strings.Split(trimmed, "|")
+	for i := range cells {
+		cells[i] = strings.TrimSpace(cells[i])
+	}
+	return cells
+}
+
+// tableSegments renders a markdown table with padded columns, the header is bold
+func tableSegments(header string, rows []string) []widget.RichTextSegment {
+	table := [][]string{splitTableRow(header)}
+	for _, row := range rows {
+		table = append(table, splitTableRow(row))
+	}
+
+	widths := []int{}
+	for _, row := range table {
+		for i, cell := range row {
+			if i >= len(widths) {
+				widths = append(widths, 0)
+			}
+			widths[i] = max(widths[i], utf8.RuneCountInString(cell))
+		}
+	}
+
+	format := func(row []string) string {
+		cells := make([]string, len(widths))
+		for i := range widths {
+			cell := ""
+			if i < len(row) {
+				cell = row[i]
+			}
+			cells[i] = cell + strings.Repeat(" ", widths[i]-utf8.RuneCountInString(cell))
+		}
+		return strings.TrimRight(strings.Join(cells, " │ "), " ")
+	}
+
+	separator := make([]string, len(widths))
+	for i, w := range widths {
+		separator[i] = strings.Repeat("─", w)
+	}
+
+	headerStyle := widget.RichTextStyleCodeBlock
+	headerStyle.TextStyle = fyne.TextStyle{Monospace: true, Bold: true}
+	lines := []string{strings.Join(separator, "─┼─")}
+	for _, row := range table[1:] {
+		lines = append(lines, format(row))
+	}
+
+	return []widget.RichTextSegment{
+		&widget.TextSegment{Text: format(table[0]), Style: headerStyle},
+		&widget.TextSegment{Text: strings.Join(lines, "\n"), Style: widget.RichTextStyleCodeBlock},
+	}
+}
@@ -2,22 +2,45 @@ package ui
 
 import (
 	"fmt"
+	"time"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
 	"github.com/curtisnewbie/nota/internal/i18n"
 )
 
+// autosaveDelays are the autosave delays offered in the View menu, 0 turns autosave off
+var autosaveDelays = []time.Duration{0, 5 * time.Second, 30 * time.Second, time.Minute}
+
+// dailyTitleFormats are the layouts of the title of daily notes offered in the Note menu
+var dailyTitleFormats = []string{"2006-01-02", "2006/01/02", "02.01.2006", "01/02/2006", "2006-01-02 Monday", "Monday, January 2, 2006"}
+
 // MenuBar represents the top menu bar
 type MenuBar struct {
-	appActionsHandler AppActionsHandler
-	pinHandler        PinHandler
-	languageHandler   LanguageHandler
-	pinned            bool
-	databaseLocation  string
-	container         *fyne.Container
-	window            fyne.Window
+	appActionsHandler  AppActionsHandler
+	pinHandler         PinHandler
+	languageHandler    LanguageHandler
+	trashHandler       TrashHandler
+	tagHandler         TagHandler
+	notebookHandler    NotebookHandler
+	serverHandler      ServerHandler
+	autosaveHandler    AutosaveHandler
+	lockHandler        LockHandler
+	vaultHandler       VaultHandler
+	backupHandler      BackupHandler
+	diagnosticsHandler DiagnosticsHandler
+	templateHandler    TemplateHandler
+	dailyHandler       DailyHandler
+	reminderHandler    ReminderHandler
+	taskHandler        TaskHandler
+	archiveHandler     ArchiveHandler
+	pinned             bool
+	databaseLocation   string
+	databaseBtn        *widget.Button
+	container          *fyne.Container
+	window             fyne.Window
 }
 
 // NewMenuBar creates a new menu bar
@@ -35,6 +58,76 @@ func (m *MenuBar) SetWindow(window fyne.Window) {
 	m.window = window
 }
 
+// SetTrashHandler sets the trash handler for the View menu
+func (m *MenuBar) SetTrashHandler(handler TrashHandler) {
+	m.trashHandler = handler
+}
+
+// SetTagHandler sets the tag handler for the Note menu
+func (m *MenuBar) SetTagHandler(handler TagHandler) {
+	m.tagHandler = handler
+}
+
+// SetNotebookHandler sets the notebook handler for the Note menu
+func (m *MenuBar) SetNotebookHandler(handler NotebookHandler) {
+	m.notebookHandler = handler
+}
+
+// SetServerHandler sets the API server handler for the File menu
+func (m *MenuBar) SetServerHandler(handler ServerHandler) {
+	m.serverHandler = handler
+}
+
+// SetAutosaveHandler sets the autosave handler for the View menu
+func (m *MenuBar) SetAutosaveHandler(handler AutosaveHandler) {
+	m.autosaveHandler = handler
+}
+
+// SetLockHandler sets the lock handler for the Note menu
+func (m *MenuBar) SetLockHandler(handler LockHandler) {
+	m.lockHandler = handler
+}
+
+// SetVaultHandler sets the vault handler for the database switcher
+func (m *MenuBar) SetVaultHandler(handler VaultHandler) {
+	m.vaultHandler = handler
+}
+
+// SetBackupHandler sets the backup handler for the File menu
+func (m *MenuBar) SetBackupHandler(handler BackupHandler) {
+	m.backupHandler = handler
+}
+
+// SetDiagnosticsHandler sets the diagnostics handler for the File menu
+func (m *MenuBar) SetDiagnosticsHandler(handler DiagnosticsHandler) {
+	m.diagnosticsHandler = handler
+}
+
+// SetTemplateHandler sets the template handler for the Note menu
+func (m *MenuBar) SetTemplateHandler(handler TemplateHandler) {
+	m.templateHandler = handler
+}
+
+// SetDailyHandler sets the daily note handler for the Note menu
+func (m *MenuBar) SetDailyHandler(handler DailyHandler) {
+	m.dailyHandler = handler
+}
+
+// SetArchiveHandler sets the archive handler for the Note menu
+func (m *MenuBar) SetArchiveHandler(handler ArchiveHandler) {
+	m.archiveHandler = handler
+}
+
+// SetReminderHandler sets the reminder handler for the Note menu
+func (m *MenuBar) SetReminderHandler(handler ReminderHandler) {
+	m.reminderHandler = handler
+}
+
+// SetTaskHandler sets the task handler for the Note menu
+func (m *MenuBar) SetTaskHandler(handler TaskHandler) {
+	m.taskHandler = handler
+}
+
 // Build builds the menu bar UI
 func (m *MenuBar) Build() *fyne.Container {
 	t := i18n.T()
@@ -55,8 +148,10 @@ func (m *MenuBar) Build() *fyne.Container {
 		m.showLanguageMenu()
 	})
 
-	dbLabel := widget.NewLabel(fmt.Sprintf(t.Database.Location, m.databaseLocation))
-	dbLabel.TextStyle = fyne.TextStyle{Italic: true}
+	m.databaseBtn = widget.NewButtonWithIcon(fmt.Sprintf(t.Database.Location, m.databaseLocation), theme.StorageIcon(), func() {
+		m.showVaultMenu()
+	})
+	m.databaseBtn.Importance = widget.LowImportance
 
 	m.container = container.NewHBox(
 		noteBtn,
@@ -64,7 +159,7 @@ func (m *MenuBar) Build() *fyne.Container {
 		viewBtn,
 		languageBtn,
 		widget.NewSeparator(),
-		dbLabel,
+		m.databaseBtn,
 	)
 
 	return m.container
@@ -78,10 +173,91 @@ func (m *MenuBar) showNoteMenu() {
 
 	t := i18n.T()
 
+	isTemplate := m.templateHandler != nil && m.templateHandler.IsTemplate()
+	templateItem := fyne.NewMenuItem(t.Menu.UseAsTemplate, func() {
+		if m.templateHandler != nil {
+			m.templateHandler.OnSetTemplate(!isTemplate)
+		}
+	})
+	templateItem.Checked = isTemplate
+
+	isArchived := m.archiveHandler != nil && m.archiveHandler.IsArchived()
+	archiveItem := fyne.NewMenuItem(t.Menu.Archive, func() {
+		if m.archiveHandler != nil {
+			m.archiveHandler.OnSetArchived(!isArchived)
+		}
+	})
+	archiveItem.Checked = isArchived
+
+	dailyTitleItem := fyne.NewMenuItem(t.Menu.DailyTitle, nil)
+	if m.dailyHandler != nil {
+		dailyTitleItem.ChildMenu = m.dailyTitleMenu()
+	}
+
 	menu := fyne.NewMenu("",
 		fyne.NewMenuItem(t.Menu.NewNote, func() {
 			m.appActionsHandler.OnCreateNote()
 		}),
+		fyne.NewMenuItem(t.Menu.FromTemplate, func() {
+			if m.templateHandler != nil {
+				m.templateHandler.OnShowTemplates()
+			}
+		}),
+		fyne.NewMenuItem(t.Menu.MoveNote, func() {
+			if m.notebookHandler != nil {
+				m.notebookHandler.OnShowMoveNote()
+			}
+		}),
+		fyne.NewMenuItem(t.Menu.ManageTags, func() {
+			if m.tagHandler != nil {
+				m.tagHandler.OnManageTags()
+			}
+		}),
+		templateItem,
+		archiveItem,
+		fyne.NewMenuItem(t.Menu.Reminder, func() {
+			if m.reminderHandler != nil {
+				m.reminderHandler.OnShowReminder()
+			}
+		}),
+		fyne.NewMenuItem(t.Menu.Tasks, func() {
+			if m.taskHandler != nil {
+				m.taskHandler.OnShowTasks()
+			}
+		}),
+		fyne.NewMenuItemSeparator(),
+		fyne.NewMenuItem(t.Menu.Today, func() {
+			if m.dailyHandler != nil {
+				m.dailyHandler.OnOpenToday()
+			}
+		}),
+		fyne.NewMenuItem(t.Menu.PreviousDay, func() {
+			if m.dailyHandler != nil {
+				m.dailyHandler.OnOpenAdjacentDay(-1)
+			}
+		}),
+		fyne.NewMenuItem(t.Menu.NextDay, func() {
+			if m.dailyHandler != nil {
+				m.dailyHandler.OnOpenAdjacentDay(1)
+			}
+		}),
+		fyne.NewMenuItem(t.Menu.Calendar, func() {
+			if m.dailyHandler != nil {
+				m.dailyHandler.OnShowCalendar()
+			}
+		}),
+		dailyTitleItem,
+		fyne.NewMenuItemSeparator(),
+		fyne.NewMenuItem(t.Menu.LockNote, func() {
+			if m.lockHandler != nil {
+				m.lockHandler.OnShowLockNote()
+			}
+		}),
+		fyne.NewMenuItem(t.Menu.RemoveLock, func() {
+			if m.lockHandler != nil {
+				m.lockHandler.OnRemoveLock()
+			}
+		}),
 	)
 
 	popUp := widget.NewPopUpMenu(menu, m.window.Canvas())
@@ -102,9 +278,31 @@ func (m *MenuBar) showFileMenu() {
 		fyne.NewMenuItem(t.Menu.Import, func() {
 			m.appActionsHandler.OnImportNote()
 		}),
+		fyne.NewMenuItem(t.Menu.ImportMarkdown, func() {
+			m.appActionsHandler.OnImportMarkdown()
+		}),
 		fyne.NewMenuItem(t.Menu.Export, func() {
 			m.appActionsHandler.OnExportNote()
 		}),
+		fyne.NewMenuItem(t.Menu.ExportMarkdown, func() {
+			m.appActionsHandler.OnExportMarkdown()
+		}),
+		fyne.NewMenuItemSeparator(),
+		fyne.NewMenuItem(t.Menu.APIServer, func() {
+			if m.serverHandler != nil {
+				m.serverHandler.OnShowServer()
+			}
+		}),
+		fyne.NewMenuItem(t.Menu.Backups, func() {
+			if m.backupHandler != nil {
+				m.backupHandler.OnShowBackups()
+			}
+		}),
+		fyne.NewMenuItem(t.Menu.CheckDatabase, func() {
+			if m.diagnosticsHandler != nil {
+				m.diagnosticsHandler.OnShowDiagnostics()
+			}
+		}),
 	)
 
 	popUp := widget.NewPopUpMenu(menu, m.window.Canvas())
@@ -125,7 +323,17 @@ func (m *MenuBar) showViewMenu() {
 		fyne.NewMenuItem(t.Menu.MinimizedMode, func() {
 			m.togglePinMode()
 		}),
+		fyne.NewMenuItem(t.Menu.Trash, func() {
+			if m.trashHandler != nil {
+				m.trashHandler.OnShowTrash()
+			}
+		}),
 	)
+	if m.autosaveHandler != nil {
+		autosaveItem := fyne.NewMenuItem(t.Menu.Autosave, nil)
+		autosaveItem.ChildMenu = m.autosaveMenu()
+		menu.Items = append(menu.Items, autosaveItem)
+	}
 
 	popUp := widget.NewPopUpMenu(menu, m.window.Canvas())
 	pos := m.menuButtonPosition(2)
@@ -133,6 +341,41 @@ func (m *MenuBar) showViewMenu() {
 	popUp.Show()
 }
 
+// autosaveMenu builds the menu of autosave delays with the current delay checked
+func (m *MenuBar) autosaveMenu() *fyne.Menu {
+	t := i18n.T()
+	current := m.autosaveHandler.GetAutosaveDelay()
+	items := make([]*fyne.MenuItem, 0, len(autosaveDelays))
+	for _, delay := range autosaveDelays {
+		label := t.Menu.AutosaveOff
+		if delay > 0 {
+			label = fmt.Sprintf(t.Menu.AutosaveAfter, int(delay.Seconds()))
+		}
+		item := fyne.NewMenuItem(label, func() {
+			m.autosaveHandler.OnAutosaveDelayChanged(delay)
+		})
+		item.Checked = delay == current
+		items = append(items, item)
+	}
+	return fyne.NewMenu(t.Menu.Autosave, items...)
+}
+
+// dailyTitleMenu builds the menu of daily note title formats, showing today's title in each, with the current one checked
+func (m *MenuBar) dailyTitleMenu() *fyne.Menu {
+	t := i18n.T()
+	current := m.dailyHandler.GetDailyTitleFormat()
+	now := time.Now()
+	items := make([]*fyne.MenuItem, 0, len(dailyTitleFormats))
+	for _, format := range dailyTitleFormats {
+		item := fyne.NewMenuItem(now.Format(format), func() {
+			m.dailyHandler.OnDailyTitleFormatChanged(format)
+		})
+		item.Checked = format == current
+		items = append(items, item)
+	}
+	return fyne.NewMenu(t.Menu.DailyTitle, items...)
+}
+
 // showLanguageMenu shows the Language dropdown menu
 func (m *MenuBar) showLanguageMenu() {
 	if m.window == nil {
@@ -160,6 +403,52 @@ func (m *MenuBar) showLanguageMenu() {
 	popUp.Show()
 }
 
+// showVaultMenu shows the recently opened databases to switch to, and the actions to open another one
+func (m *MenuBar) showVaultMenu() {
+	if m.window == nil || m.vaultHandler == nil {
+		return
+	}
+
+	t := i18n.T()
+
+	menu := fyne.NewMenu("")
+	for _, path := range m.vaultHandler.RecentDatabases() {
+		item := fyne.NewMenuItem(path, func() {
+			m.vaultHandler.OnSwitchDatabase(path)
+		})
+		item.Checked = path == m.databaseLocation
+		menu.Items = append(menu.Items, item)
+	}
+	if len(menu.Items) > 0 {
+		menu.Items = append(menu.Items, fyne.NewMenuItemSeparator())
+	}
+
+	openOnStartup := fyne.NewMenuItem(t.Database.OpenOnStartup, nil)
+	openOnStartup.Checked = m.vaultHandler.GetDefaultDatabase() == m.databaseLocation
+	openOnStartup.Action = func() {
+		if openOnStartup.Checked {
+			m.vaultHandler.OnSetDefaultDatabase("")
+		} else {
+			m.vaultHandler.OnSetDefaultDatabase(m.databaseLocation)
+		}
+	}
+	menu.Items = append(menu.Items,
+		fyne.NewMenuItem(t.Database.Open, func() {
+			m.vaultHandler.OnOpenDatabase()
+		}),
+		fyne.NewMenuItem(t.Database.New, func() {
+			m.vaultHandler.OnNewDatabase()
+		}),
+		fyne.NewMenuItemSeparator(),
+		openOnStartup,
+	)
+
+	popUp := widget.NewPopUpMenu(menu, m.window.Canvas())
+	pos := fyne.CurrentApp().Driver().AbsolutePositionForObject(m.databaseBtn)
+	popUp.Move(pos.AddXY(0, m.databaseBtn.Size().Height))
+	popUp.Show()
+}
+
 // menuButtonPosition calculates the position for a menu popup based on button index
 func (m *MenuBar) menuButtonPosition(index int) fyne.Position {
 	if m.window == nil {
@@ -206,9 +495,7 @@ func (m *MenuBar) Refresh() {
 	}
 
 	// Update database label
-	if len(buttons) >= 5 {
-		if dbLabel, ok := buttons[5].(*widget.Label); ok {
-			dbLabel.SetText(fmt.Sprintf(t.Database.Location, m.databaseLocation))
-		}
+	if m.databaseBtn != nil {
+		m.databaseBtn.SetText(fmt.Sprintf(t.Database.Location, m.databaseLocation))
 	}
 }
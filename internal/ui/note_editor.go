@@ -2,9 +2,11 @@ package ui
 
 import (
 	"fmt"
+	"time"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
 	"github.com/curtisnewbie/nota/internal/domain"
@@ -15,17 +17,44 @@ import (
 type NoteEditor struct {
 	editHandler           NoteEditHandler
 	deleteHandler         DeleteHandler
+	historyHandler        HistoryHandler
+	tagHandler            TagHandler
+	linkHandler           LinkHandler
+	lockHandler           LockHandler
+	dailyHandler          DailyHandler
+	reminderHandler       ReminderHandler
+	starHandler           StarHandler
+	window                fyne.Window
 	note                  *domain.Note
 	isSaving              bool
 	minimalMode           bool
+	locked                bool
 	titleEntry            *widget.Entry
 	contentEntry          *widget.Entry
+	preview               *widget.RichText
+	previewScroll         *container.Scroll
+	contentArea           *fyne.Container
+	lockedView            fyne.CanvasObject
+	passphraseEntry       *widget.Entry
+	modeRadio             *widget.RadioGroup
+	mode                  EditorMode
 	createdLabel          *widget.Label
 	updatedLabel          *widget.Label
+	reminderBtn           *widget.Button
 	statusLabel           *widget.Label
 	saveBtn               *widget.Button
+	starBtn               *widget.Button
 	deleteBtn             *widget.Button
+	historyBtn            *widget.Button
+	tagsBox               *fyne.Container
+	addTagBtn             *widget.Button
+	allTags               []*domain.Tag
+	linksAccordion        *widget.Accordion
+	linksBox              *fyne.Container
+	backlinksBox          *fyne.Container
 	topBar                *fyne.Container
+	dailyBar              *fyne.Container
+	dailyLabel            *widget.Label
 	bottomBar             *fyne.Container
 	leftPanel             *fyne.Container
 	container             *fyne.Container
@@ -34,6 +63,15 @@ type NoteEditor struct {
 	minimizedStatusLabel  *widget.Label
 }
 
+// EditorMode is how the note content is shown in the editor
+type EditorMode int
+
+const (
+	EditorModeEdit EditorMode = iota
+	EditorModePreview
+	EditorModeSplit
+)
+
 // NewNoteEditor creates a new note editor
 func NewNoteEditor(editHandler NoteEditHandler) *NoteEditor {
 	return &NoteEditor{
@@ -61,11 +99,37 @@ func (e *NoteEditor) Build() *fyne.Container {
 	e.contentEntry.SetPlaceHolder(t.Editor.ContentPlaceholder)
 	e.contentEntry.SetMinRowsVisible(30) // Increase default visible rows
 	e.contentEntry.OnChanged = func(string) {
+		e.refreshPreview()
 		if e.editHandler != nil && !e.isSaving {
 			e.editHandler.OnContentChanged()
 		}
 	}
 
+	// Markdown preview, updated live while editing
+	e.preview = widget.NewRichText()
+	e.preview.Wrapping = fyne.TextWrapWord
+	e.previewScroll = container.NewScroll(e.preview)
+	e.contentArea = container.NewStack()
+	e.lockedView = e.buildLockedView()
+
+	modes := []string{t.Editor.ModeEdit, t.Editor.ModePreview, t.Editor.ModeSplit}
+	e.modeRadio = widget.NewRadioGroup(modes, func(selected string) {
+		for i, mode := range modes {
+			if mode == selected {
+				e.SetMode(EditorMode(i))
+				return
+			}
+		}
+	})
+	e.modeRadio.Horizontal = true
+	e.modeRadio.Required = true
+
+	e.tagsBox = container.NewHBox()
+	e.addTagBtn = widget.NewButtonWithIcon("", theme.ContentAddIcon(), func() {
+		e.showAddTagDialog()
+	})
+	e.addTagBtn.Importance = widget.LowImportance
+
 	e.createdLabel = widget.NewLabel("")
 	e.createdLabel.TextStyle = fyne.TextStyle{Italic: true}
 
@@ -88,37 +152,143 @@ func (e *NoteEditor) Build() *fyne.Container {
 	})
 	e.deleteBtn.Importance = widget.DangerImportance
 
-	// Create button row with save and delete buttons
-	buttonRow := container.NewHBox(e.saveBtn, e.deleteBtn)
+	e.historyBtn = widget.NewButtonWithIcon("", theme.HistoryIcon(), func() {
+		if e.historyHandler != nil {
+			e.historyHandler.OnShowHistory()
+		}
+	})
+
+	e.starBtn = widget.NewButtonWithIcon("", starIcon, func() {
+		if e.starHandler != nil && e.note != nil {
+			e.starHandler.OnSetStarred(e.note.ID, !e.note.Starred)
+		}
+	})
+
+	// Create button row with star, save, history and delete buttons
+	buttonRow := container.NewHBox(e.starBtn, e.saveBtn, e.historyBtn, e.deleteBtn)
 
-	e.topBar = container.NewBorder(nil, nil, nil, buttonRow)
+	e.topBar = container.NewBorder(nil, nil, e.modeRadio, buttonRow)
+
+	// Navigation between daily notes, only shown for daily notes
+	e.dailyLabel = widget.NewLabel("")
+	e.dailyLabel.TextStyle = fyne.TextStyle{Bold: true}
+	prevDayBtn := widget.NewButtonWithIcon("", theme.NavigateBackIcon(), func() {
+		if e.dailyHandler != nil {
+			e.dailyHandler.OnOpenAdjacentDay(-1)
+		}
+	})
+	nextDayBtn := widget.NewButtonWithIcon("", theme.NavigateNextIcon(), func() {
+		if e.dailyHandler != nil {
+			e.dailyHandler.OnOpenAdjacentDay(1)
+		}
+	})
+	calendarBtn := widget.NewButtonWithIcon("", theme.CalendarIcon(), func() {
+		if e.dailyHandler != nil {
+			e.dailyHandler.OnShowCalendar()
+		}
+	})
+	e.dailyBar = container.NewHBox(prevDayBtn, e.dailyLabel, nextDayBtn, calendarBtn)
+	e.dailyBar.Hide()
+
+	// Links and backlinks of the note, collapsed by default
+	e.linksBox = container.NewHBox()
+	e.backlinksBox = container.NewHBox()
+	e.linksAccordion = widget.NewAccordion(
+		widget.NewAccordionItem(fmt.Sprintf(t.Links.Links, 0), container.NewHScroll(e.linksBox)),
+		widget.NewAccordionItem(fmt.Sprintf(t.Links.Backlinks, 0), container.NewHScroll(e.backlinksBox)),
+	)
+	e.linksAccordion.MultiOpen = true
+
+	// Reminder of the note, showing when it's due
+	e.reminderBtn = widget.NewButtonWithIcon(t.Reminder.Set, theme.HistoryIcon(), func() {
+		if e.reminderHandler != nil {
+			e.reminderHandler.OnShowReminder()
+		}
+	})
+	e.reminderBtn.Importance = widget.LowImportance
 
 	e.bottomBar = container.NewVBox(
+		e.linksAccordion,
 		widget.NewSeparator(),
-		container.NewHBox(e.createdLabel, e.updatedLabel),
+		container.NewHBox(e.createdLabel, e.updatedLabel, e.reminderBtn),
 		e.statusLabel,
 	)
 
 	e.leftPanel = container.NewBorder(
-		e.topBar,
-		e.bottomBar,
-		nil,
-		nil,
 		container.NewVBox(
+			e.topBar,
+			e.dailyBar,
 			e.titleEntry,
+			container.NewHScroll(container.NewHBox(e.tagsBox, e.addTagBtn)),
 			widget.NewSeparator(),
-			e.contentEntry,
 		),
+		e.bottomBar,
+		nil,
+		nil,
+		e.contentArea,
 	)
+	e.modeRadio.SetSelected(modes[e.mode])
 
 	e.container = container.NewBorder(nil, nil, nil, nil, e.leftPanel)
 
 	return e.container
 }
 
-// DisplayNote displays a note in the editor
+// buildLockedView builds the placeholder shown instead of the content of a locked note
+func (e *NoteEditor) buildLockedView() fyne.CanvasObject {
+	t := i18n.T()
+
+	message := widget.NewLabel(t.Lock.Locked)
+	message.Alignment = fyne.TextAlignCenter
+
+	unlock := func() {
+		passphrase := e.passphraseEntry.Text
+		e.passphraseEntry.SetText("")
+		if e.lockHandler != nil {
+			e.lockHandler.OnUnlockNote(passphrase)
+		}
+	}
+	e.passphraseEntry = widget.NewPasswordEntry()
+	e.passphraseEntry.SetPlaceHolder(t.Lock.Passphrase)
+	e.passphraseEntry.OnSubmitted = func(string) { unlock() }
+	unlockBtn := widget.NewButtonWithIcon(t.Lock.Unlock, theme.LoginIcon(), unlock)
+	unlockBtn.Importance = widget.HighImportance
+
+	form := container.NewBorder(nil, nil, nil, unlockBtn, e.passphraseEntry)
+	return container.NewCenter(container.NewVBox(
+		widget.NewIcon(theme.VisibilityOffIcon()),
+		message,
+		container.NewGridWrap(fyne.NewSize(360, form.MinSize().Height), form),
+	))
+}
+
+// setLocked shows the locked placeholder instead of the content, or the content in the selected mode
+func (e *NoteEditor) setLocked(locked bool) {
+	if e.locked == locked {
+		return
+	}
+	e.locked = locked
+	if locked {
+		e.passphraseEntry.SetText("")
+		e.contentArea.Objects = []fyne.CanvasObject{e.lockedView}
+		e.contentArea.Refresh()
+		return
+	}
+	e.SetMode(e.mode)
+}
+
+// IsLocked checks whether the displayed note is locked and its content is not shown
+func (e *NoteEditor) IsLocked() bool {
+	return e.locked
+}
+
+// DisplayNote displays a note in the editor, locked notes whose content is still encrypted are shown as locked
 func (e *NoteEditor) DisplayNote(note *domain.Note) {
 	e.note = note
+	e.setLocked(note != nil && note.Encrypted && domain.IsEncryptedContent(note.Content))
+	e.displayDailyBar(note)
+	e.displayReminder(note)
+	e.displayStar(note)
 
 	if note == nil {
 		e.titleEntry.SetText("")
@@ -133,10 +303,16 @@ func (e *NoteEditor) DisplayNote(note *domain.Note) {
 	}
 
 	e.titleEntry.SetText(note.Title)
-	e.contentEntry.SetText(note.Content)
 	e.createdLabel.SetText(fmt.Sprintf("Created: %s", note.CreatedAt.Format("2006/01/02 15:04")))
 	e.updatedLabel.SetText(fmt.Sprintf("Updated: %s", note.UpdatedAt.Format("2006/01/02 15:04")))
 	e.statusLabel.SetText("Saved")
+	if e.locked {
+		e.contentEntry.SetText("")
+		e.titleEntry.Disable()
+		e.contentEntry.Disable()
+		return
+	}
+	e.contentEntry.SetText(note.Content)
 	// Enable fields when a note is displayed
 	e.titleEntry.Enable()
 	e.contentEntry.Enable()
@@ -145,6 +321,66 @@ func (e *NoteEditor) DisplayNote(note *domain.Note) {
 	// e.setEditMode(false)
 }
 
+// displayDailyBar shows the navigation between daily notes with the day of the note, hidden for other notes
+func (e *NoteEditor) displayDailyBar(note *domain.Note) {
+	if note == nil {
+		e.dailyBar.Hide()
+		return
+	}
+	date, ok := note.DailyDate()
+	if !ok {
+		e.dailyBar.Hide()
+		return
+	}
+	e.dailyLabel.SetText(fmt.Sprintf("%s %s", date.Format(domain.DailyDateLayout), weekdayName(date)))
+	e.dailyBar.Show()
+}
+
+// SetNoteInfo updates the note and its timestamps without resetting the title and content being edited
+func (e *NoteEditor) SetNoteInfo(note *domain.Note) {
+	e.note = note
+	if note == nil {
+		return
+	}
+	e.createdLabel.SetText(fmt.Sprintf("Created: %s", note.CreatedAt.Format("2006/01/02 15:04")))
+	e.updatedLabel.SetText(fmt.Sprintf("Updated: %s", note.UpdatedAt.Format("2006/01/02 15:04")))
+	e.displayReminder(note)
+	e.displayStar(note)
+}
+
+// displayStar shows whether the note is starred, notes that are not saved yet can't be starred
+func (e *NoteEditor) displayStar(note *domain.Note) {
+	if note == nil || note.ID == "" {
+		e.starBtn.SetIcon(starIcon)
+		e.starBtn.Disable()
+		return
+	}
+	e.starBtn.SetIcon(starIconOf(note.Starred))
+	e.starBtn.Enable()
+}
+
+// displayReminder shows when the reminder of the note is due, overdue reminders are highlighted
+func (e *NoteEditor) displayReminder(note *domain.Note) {
+	if note == nil {
+		e.reminderBtn.Hide()
+		return
+	}
+	e.reminderBtn.Show()
+	if note.DueAt == nil {
+		e.reminderBtn.SetText(i18n.T().Reminder.Set)
+		e.reminderBtn.Importance = widget.LowImportance
+		e.reminderBtn.Refresh()
+		return
+	}
+	e.reminderBtn.SetText(describeDue(note, time.Now()))
+	if note.IsOverdue(time.Now()) {
+		e.reminderBtn.Importance = widget.WarningImportance
+	} else {
+		e.reminderBtn.Importance = widget.MediumImportance
+	}
+	e.reminderBtn.Refresh()
+}
+
 // GetTitle returns the current title
 func (e *NoteEditor) GetTitle() string {
 	return e.titleEntry.Text
@@ -207,13 +443,166 @@ func (e *NoteEditor) SetDeleteHandler(handler DeleteHandler) {
 	e.deleteHandler = handler
 }
 
+// SetHistoryHandler sets the history handler for the note editor
+func (e *NoteEditor) SetHistoryHandler(handler HistoryHandler) {
+	e.historyHandler = handler
+}
+
+// SetTagHandler sets the tag handler for the note editor
+func (e *NoteEditor) SetTagHandler(handler TagHandler) {
+	e.tagHandler = handler
+}
+
+// SetWindow sets the window for the note editor (needed for dialogs)
+func (e *NoteEditor) SetWindow(window fyne.Window) {
+	e.window = window
+}
+
+// SetAllTags sets the existing tags suggested when adding a tag
+func (e *NoteEditor) SetAllTags(tags []*domain.Tag) {
+	e.allTags = tags
+}
+
+// DisplayTags displays the tags of the current note
+func (e *NoteEditor) DisplayTags(tags []*domain.Tag) {
+	e.tagsBox.RemoveAll()
+	for _, tag := range tags {
+		tagID := tag.ID
+		btn := widget.NewButtonWithIcon(tag.Name, theme.CancelIcon(), func() {
+			if e.tagHandler != nil {
+				e.tagHandler.OnRemoveTag(tagID)
+			}
+		})
+		btn.Importance = widget.MediumImportance
+		btn.IconPlacement = widget.ButtonIconTrailingText
+		e.tagsBox.Add(btn)
+	}
+	e.tagsBox.Refresh()
+}
+
+// SetMode switches between editing, previewing the rendered markdown, or both side by side
+func (e *NoteEditor) SetMode(mode EditorMode) {
+	e.mode = mode
+	if e.contentArea == nil || e.locked {
+		return
+	}
+
+	switch mode {
+	case EditorModePreview:
+		e.contentArea.Objects = []fyne.CanvasObject{e.previewScroll}
+	case EditorModeSplit:
+		split := container.NewHSplit(e.contentEntry, e.previewScroll)
+		split.SetOffset(0.5)
+		e.contentArea.Objects = []fyne.CanvasObject{split}
+	default:
+		e.contentArea.Objects = []fyne.CanvasObject{e.contentEntry}
+	}
+	e.contentArea.Refresh()
+	e.refreshPreview()
+}
+
+// refreshPreview renders the content as markdown when the preview is shown
+func (e *NoteEditor) refreshPreview() {
+	if e.preview == nil || e.mode == EditorModeEdit {
+		return
+	}
+
+	e.preview.Segments = renderMarkdown(e.contentEntry.Text, func(target string) {
+		if e.linkHandler != nil {
+			e.linkHandler.OnLinkSelected(target)
+		}
+	})
+	e.preview.Refresh()
+}
+
+// SetLockHandler sets the lock handler
+func (e *NoteEditor) SetLockHandler(handler LockHandler) {
+	e.lockHandler = handler
+}
+
+// SetDailyHandler sets the daily note handler
+func (e *NoteEditor) SetDailyHandler(handler DailyHandler) {
+	e.dailyHandler = handler
+}
+
+// SetReminderHandler sets the reminder handler
+func (e *NoteEditor) SetReminderHandler(handler ReminderHandler) {
+	e.reminderHandler = handler
+}
+
+// SetStarHandler sets the star handler
+func (e *NoteEditor) SetStarHandler(handler StarHandler) {
+	e.starHandler = handler
+}
+
+// SetLinkHandler sets the link handler
+func (e *NoteEditor) SetLinkHandler(handler LinkHandler) {
+	e.linkHandler = handler
+}
+
+// DisplayLinks displays the notes that the note links to and the notes linking to it
+func (e *NoteEditor) DisplayLinks(links []*domain.Note, backlinks []*domain.Note) {
+	t := i18n.T()
+	e.displayLinkButtons(e.linksBox, links)
+	e.displayLinkButtons(e.backlinksBox, backlinks)
+	e.linksAccordion.Items[0].Title = fmt.Sprintf(t.Links.Links, len(links))
+	e.linksAccordion.Items[1].Title = fmt.Sprintf(t.Links.Backlinks, len(backlinks))
+	e.linksAccordion.Refresh()
+}
+
+// displayLinkButtons fills the box with a button for each linked note
+func (e *NoteEditor) displayLinkButtons(box *fyne.Container, notes []*domain.Note) {
+	box.RemoveAll()
+	for _, note := range notes {
+		noteID := note.ID
+		btn := widget.NewButtonWithIcon(note.Title, theme.NavigateNextIcon(), func() {
+			if e.linkHandler != nil {
+				e.linkHandler.OnLinkSelected(noteID)
+			}
+		})
+		btn.Importance = widget.LowImportance
+		box.Add(btn)
+	}
+	box.Refresh()
+}
+
+// showAddTagDialog asks for the name of the tag to add to the current note
+func (e *NoteEditor) showAddTagDialog() {
+	if e.window == nil || e.tagHandler == nil {
+		return
+	}
+
+	t := i18n.T()
+	options := make([]string, 0, len(e.allTags))
+	for _, tag := range e.allTags {
+		options = append(options, tag.Name)
+	}
+	nameEntry := widget.NewSelectEntry(options)
+	nameEntry.SetPlaceHolder(t.Tags.NamePlaceholder)
+
+	dialog.ShowForm(t.Tags.Add, t.Editor.Save, t.Editor.Exit,
+		[]*widget.FormItem{widget.NewFormItem(t.Tags.Title, nameEntry)},
+		func(confirmed bool) {
+			if confirmed {
+				e.tagHandler.OnAddTag(nameEntry.Text)
+			}
+		},
+		e.window,
+	)
+}
+
 // ShowEmptyState shows the empty state
 func (e *NoteEditor) ShowEmptyState() {
+	e.setLocked(false)
 	e.titleEntry.SetText("")
 	e.contentEntry.SetText("")
 	e.createdLabel.SetText("")
 	e.updatedLabel.SetText("")
 	e.statusLabel.SetText(i18n.T().Dialog.NoNotesAvailable)
+	e.DisplayTags(nil)
+	e.DisplayLinks(nil, nil)
+	e.displayReminder(nil)
+	e.displayStar(nil)
 	// Disable fields when no notes are available
 	e.titleEntry.Disable()
 	e.contentEntry.Disable()
@@ -1,10 +1,12 @@
 package ui
 
 import (
+	"sort"
 	"time"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
 	"github.com/curtisnewbie/nota/internal/domain"
 	"github.com/curtisnewbie/nota/internal/i18n"
@@ -20,6 +22,10 @@ type NoteList struct {
 	selectionHandler NoteSelectionHandler
 	searchHandler    SearchHandler
 	deleteHandler    DeleteHandler
+	tagHandler       TagHandler
+	reminderHandler  ReminderHandler
+	starHandler      StarHandler
+	archiveHandler   ArchiveHandler
 	window           fyne.Window
 	notes            []*domain.Note
 	searchEntry      *widget.Entry
@@ -29,6 +35,17 @@ type NoteList struct {
 	menu             *fyne.Menu
 	popUpMenu        *widget.PopUpMenu
 	notesContainer   *fyne.Container
+	// Tag filter fields
+	tags         []*domain.Tag
+	selectedTags map[string]bool
+	tagBox       *fyne.Container
+	tagSection   *fyne.Container
+	// Due soon filter fields
+	dueSoon    bool
+	dueSoonBtn *widget.Button
+	// Archived filter fields
+	archived    bool
+	archivedBtn *widget.Button
 	// Pagination fields
 	currentOffset int
 	pageSize      int
@@ -52,6 +69,7 @@ func NewNoteList(selectionHandler NoteSelectionHandler, searchHandler SearchHand
 		currentQuery:     "",
 		hasMore:          true,
 		loading:          false,
+		selectedTags:     map[string]bool{},
 	}
 }
 
@@ -65,6 +83,26 @@ func (n *NoteList) SetDeleteHandler(handler DeleteHandler) {
 	n.deleteHandler = handler
 }
 
+// SetTagHandler sets the tag handler for the tag filter
+func (n *NoteList) SetTagHandler(handler TagHandler) {
+	n.tagHandler = handler
+}
+
+// SetReminderHandler sets the reminder handler for the due soon filter
+func (n *NoteList) SetReminderHandler(handler ReminderHandler) {
+	n.reminderHandler = handler
+}
+
+// SetStarHandler sets the star handler for the star toggle of each note
+func (n *NoteList) SetStarHandler(handler StarHandler) {
+	n.starHandler = handler
+}
+
+// SetArchiveHandler sets the archive handler for the archived filter
+func (n *NoteList) SetArchiveHandler(handler ArchiveHandler) {
+	n.archiveHandler = handler
+}
+
 // Build builds the note list UI
 func (n *NoteList) Build() *fyne.Container {
 	t := i18n.T()
@@ -77,12 +115,32 @@ func (n *NoteList) Build() *fyne.Container {
 		}
 	}
 
-	// Create toolbar with search only
+	// Tag filter section, hidden until there are tags
+	n.tagBox = container.NewHBox()
+	n.tagSection = container.NewVBox(
+		container.NewHScroll(n.tagBox),
+		widget.NewSeparator(),
+	)
+	n.tagSection.Hide()
+
+	// Due soon filter, lists the upcoming reminders when selected
+	n.dueSoonBtn = widget.NewButtonWithIcon(t.Reminder.DueSoon, theme.HistoryIcon(), func() {
+		n.toggleDueSoon()
+	})
+	n.dueSoonBtn.Importance = widget.LowImportance
+
+	// Archived filter, lists the archived notes instead of the others when selected
+	n.archivedBtn = widget.NewButtonWithIcon(t.Archive.Archived, theme.FolderIcon(), func() {
+		n.toggleArchived()
+	})
+	n.archivedBtn.Importance = widget.LowImportance
+
+	// Create toolbar with tag filter, search, due soon and archived filters
 	toolbar := container.NewBorder(
+		n.tagSection,
 		nil,
 		nil,
-		nil,
-		nil,
+		container.NewHBox(n.dueSoonBtn, n.archivedBtn),
 		n.searchEntry,
 	)
 
@@ -94,16 +152,30 @@ func (n *NoteList) Build() *fyne.Container {
 			titleLabel := widget.NewLabel("")
 			dateLabel := widget.NewLabel("")
 			dateLabel.TextStyle = fyne.TextStyle{Italic: true}
-			return container.NewVBox(titleLabel, dateLabel)
+			starBtn := widget.NewButtonWithIcon("", starIcon, nil)
+			starBtn.Importance = widget.LowImportance
+			return container.NewBorder(nil, nil, nil, starBtn, container.NewVBox(titleLabel, dateLabel))
 		},
 		func(id widget.ListItemID, obj fyne.CanvasObject) {
 			if id >= 0 && id < len(n.notes) {
 				note := n.notes[id]
-				container := obj.(*fyne.Container)
+				row := obj.(*fyne.Container)
+				container := row.Objects[0].(*fyne.Container)
 				titleLabel := container.Objects[0].(*widget.Label)
 				dateLabel := container.Objects[1].(*widget.Label)
+				starBtn := row.Objects[1].(*widget.Button)
+				starBtn.SetIcon(starIconOf(note.Starred))
+				starBtn.OnTapped = func() {
+					if n.starHandler != nil {
+						n.starHandler.OnSetStarred(note.ID, !note.Starred)
+					}
+				}
 				titleLabel.SetText(note.Title)
-				dateLabel.SetText(note.UpdatedAt.Format("2006/01/02"))
+				if note.DueAt != nil {
+					dateLabel.SetText(describeDue(note, time.Now()))
+				} else {
+					dateLabel.SetText(note.UpdatedAt.Format("2006/01/02"))
+				}
 			}
 		},
 	)
@@ -333,3 +405,112 @@ func (n *NoteList) ClearSearch() {
 func (n *NoteList) GetSearchQuery() string {
 	return n.searchEntry.Text
 }
+
+// SetTags updates the tags available in the tag filter, selected tags that no longer exist are unselected
+func (n *NoteList) SetTags(tags []*domain.Tag) {
+	n.tags = tags
+
+	names := map[string]bool{}
+	for _, tag := range tags {
+		names[tag.Name] = true
+	}
+	for name := range n.selectedTags {
+		if !names[name] {
+			delete(n.selectedTags, name)
+		}
+	}
+
+	n.refreshTagBox()
+}
+
+// GetSelectedTags returns the names of the tags selected in the tag filter
+func (n *NoteList) GetSelectedTags() []string {
+	selected := make([]string, 0, len(n.selectedTags))
+	for name := range n.selectedTags {
+		selected = append(selected, name)
+	}
+	sort.Strings(selected)
+	return selected
+}
+
+// refreshTagBox rebuilds the tag filter buttons
+func (n *NoteList) refreshTagBox() {
+	if n.tagBox == nil {
+		return
+	}
+
+	n.tagBox.RemoveAll()
+	for _, tag := range n.tags {
+		name := tag.Name
+		btn := widget.NewButton(name, func() {
+			n.toggleTag(name)
+		})
+		if n.selectedTags[name] {
+			btn.Importance = widget.HighImportance
+		} else {
+			btn.Importance = widget.LowImportance
+		}
+		n.tagBox.Add(btn)
+	}
+
+	if len(n.tags) == 0 {
+		n.tagSection.Hide()
+	} else {
+		n.tagSection.Show()
+	}
+	n.tagBox.Refresh()
+}
+
+// toggleTag selects or unselects a tag in the tag filter
+func (n *NoteList) toggleTag(name string) {
+	if n.selectedTags[name] {
+		delete(n.selectedTags, name)
+	} else {
+		n.selectedTags[name] = true
+	}
+	n.refreshTagBox()
+
+	if n.tagHandler != nil {
+		n.tagHandler.OnTagFilterChanged()
+	}
+}
+
+// IsDueSoon checks whether the due soon filter is selected
+func (n *NoteList) IsDueSoon() bool {
+	return n.dueSoon
+}
+
+// toggleDueSoon selects or unselects the due soon filter
+func (n *NoteList) toggleDueSoon() {
+	n.dueSoon = !n.dueSoon
+	if n.dueSoon {
+		n.dueSoonBtn.Importance = widget.HighImportance
+	} else {
+		n.dueSoonBtn.Importance = widget.LowImportance
+	}
+	n.dueSoonBtn.Refresh()
+
+	if n.reminderHandler != nil {
+		n.reminderHandler.OnDueSoonFilterChanged()
+	}
+}
+
+// IsArchived checks whether the archived filter is selected
+func (n *NoteList) IsArchived() bool {
+	return n.archived
+}
+
+// toggleArchived selects or unselects the archived filter
+func (n *NoteList) toggleArchived() {
+	n.archived = !n.archived
+	if n.archived {
+		n.archivedBtn.Importance = widget.HighImportance
+	} else {
+		n.archivedBtn.Importance = widget.LowImportance
+	}
+	n.archivedBtn.Refresh()
+
+	if n.archiveHandler != nil {
+		n.archiveHandler.OnArchivedFilterChanged()
+	}
+}
@@ -0,0 +1,281 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/curtisnewbie/nota/internal/domain"
+	"github.com/curtisnewbie/nota/internal/i18n"
+)
+
+// allNotesNodeID is the tree node that shows the notes of every notebook
+const allNotesNodeID = "all"
+
+// NotebookTree shows the notebooks as a collapsible tree, selecting a notebook filters the note list
+type NotebookTree struct {
+	notebookHandler NotebookHandler
+	window          fyne.Window
+	notebooks       map[string]*domain.Notebook
+	children        map[string][]string
+	selectedID      string
+	tree            *widget.Tree
+	newBtn          *widget.Button
+	renameBtn       *widget.Button
+	moveBtn         *widget.Button
+	deleteBtn       *widget.Button
+	container       *fyne.Container
+}
+
+// NewNotebookTree creates a new notebook tree
+func NewNotebookTree(notebookHandler NotebookHandler, window fyne.Window) *NotebookTree {
+	return &NotebookTree{
+		notebookHandler: notebookHandler,
+		window:          window,
+		notebooks:       map[string]*domain.Notebook{},
+		children:        map[string][]string{},
+	}
+}
+
+// Build builds the notebook tree UI
+func (n *NotebookTree) Build() *fyne.Container {
+	t := i18n.T()
+
+	n.tree = widget.NewTree(
+		func(id widget.TreeNodeID) []widget.TreeNodeID {
+			if id == "" {
+				return append([]string{allNotesNodeID}, n.children[""]...)
+			}
+			return n.children[id]
+		},
+		func(id widget.TreeNodeID) bool {
+			return id == "" || len(n.children[id]) > 0
+		},
+		func(branch bool) fyne.CanvasObject {
+			return widget.NewLabel("")
+		},
+		func(id widget.TreeNodeID, branch bool, obj fyne.CanvasObject) {
+			label := obj.(*widget.Label)
+			if id == allNotesNodeID {
+				label.TextStyle = fyne.TextStyle{Bold: true}
+				label.SetText(t.Notebooks.AllNotes)
+				return
+			}
+			label.TextStyle = fyne.TextStyle{}
+			if notebook, ok := n.notebooks[id]; ok {
+				label.SetText(notebook.Name)
+			}
+		},
+	)
+
+	n.newBtn = widget.NewButtonWithIcon("", theme.FolderNewIcon(), func() {
+		n.showCreateDialog()
+	})
+	n.renameBtn = widget.NewButtonWithIcon("", theme.DocumentCreateIcon(), func() {
+		n.showRenameDialog()
+	})
+	n.moveBtn = widget.NewButtonWithIcon("", theme.MailForwardIcon(), func() {
+		n.showMoveDialog()
+	})
+	n.deleteBtn = widget.NewButtonWithIcon("", theme.DeleteIcon(), func() {
+		n.onDeleteRequested()
+	})
+
+	header := container.NewBorder(
+		nil,
+		nil,
+		widget.NewLabelWithStyle(t.Notebooks.Title, fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		container.NewHBox(n.newBtn, n.renameBtn, n.moveBtn, n.deleteBtn),
+	)
+
+	// Select all notes before listening to selections, the note list is loaded by the caller
+	n.renameBtn.Disable()
+	n.moveBtn.Disable()
+	n.deleteBtn.Disable()
+	n.tree.Select(allNotesNodeID)
+	n.tree.OnSelected = func(id widget.TreeNodeID) {
+		n.selectNotebook(id)
+	}
+
+	n.container = container.NewBorder(header, nil, nil, nil, n.tree)
+
+	return n.container
+}
+
+// SetNotebooks updates the notebooks shown in the tree, the selection is kept if the notebook still exists
+func (n *NotebookTree) SetNotebooks(notebooks []*domain.Notebook) {
+	n.notebooks = map[string]*domain.Notebook{}
+	for _, notebook := range notebooks {
+		n.notebooks[notebook.ID] = notebook
+	}
+
+	n.children = map[string][]string{}
+	for _, notebook := range notebooks {
+		parentID := notebook.ParentID
+		if _, ok := n.notebooks[parentID]; !ok {
+			parentID = ""
+		}
+		n.children[parentID] = append(n.children[parentID], notebook.ID)
+	}
+
+	if n.tree == nil {
+		return
+	}
+	n.tree.Refresh()
+	if _, ok := n.notebooks[n.selectedID]; !ok && n.selectedID != "" {
+		n.tree.Select(allNotesNodeID)
+	}
+}
+
+// GetSelectedNotebookID returns the ID of the selected notebook, empty when all notes are shown
+func (n *NotebookTree) GetSelectedNotebookID() string {
+	return n.selectedID
+}
+
+// selectNotebook filters the note list by the selected notebook
+func (n *NotebookTree) selectNotebook(id string) {
+	if id == allNotesNodeID {
+		id = ""
+	}
+	n.selectedID = id
+
+	if id == "" {
+		n.renameBtn.Disable()
+		n.moveBtn.Disable()
+		n.deleteBtn.Disable()
+	} else {
+		n.renameBtn.Enable()
+		n.moveBtn.Enable()
+		n.deleteBtn.Enable()
+		n.tree.OpenBranch(id)
+	}
+
+	if n.notebookHandler != nil {
+		n.notebookHandler.OnNotebookSelected(id)
+	}
+}
+
+// showCreateDialog asks for the name of a notebook created under the selected notebook
+func (n *NotebookTree) showCreateDialog() {
+	t := i18n.T()
+	parentID := n.selectedID
+	title := t.Notebooks.New
+	if parentID != "" {
+		title = t.Notebooks.NewSub
+	}
+
+	nameEntry := widget.NewEntry()
+	nameEntry.SetPlaceHolder(t.Notebooks.NamePlaceholder)
+
+	dialog.ShowForm(title, t.Editor.Save, t.Editor.Exit,
+		[]*widget.FormItem{widget.NewFormItem(t.Notebooks.Title, nameEntry)},
+		func(confirmed bool) {
+			if confirmed {
+				n.notebookHandler.OnCreateNotebook(nameEntry.Text, parentID)
+			}
+		},
+		n.window,
+	)
+}
+
+// showRenameDialog asks for the new name of the selected notebook
+func (n *NotebookTree) showRenameDialog() {
+	notebook, ok := n.notebooks[n.selectedID]
+	if !ok {
+		return
+	}
+
+	t := i18n.T()
+	nameEntry := widget.NewEntry()
+	nameEntry.SetText(notebook.Name)
+
+	dialog.ShowForm(t.Notebooks.Rename, t.Editor.Save, t.Editor.Exit,
+		[]*widget.FormItem{widget.NewFormItem(t.Notebooks.Title, nameEntry)},
+		func(confirmed bool) {
+			if confirmed {
+				n.notebookHandler.OnRenameNotebook(notebook.ID, nameEntry.Text)
+			}
+		},
+		n.window,
+	)
+}
+
+// showMoveDialog asks for the notebook that the selected notebook is moved into
+func (n *NotebookTree) showMoveDialog() {
+	notebook, ok := n.notebooks[n.selectedID]
+	if !ok {
+		return
+	}
+
+	t := i18n.T()
+	n.showNotebookSelect(fmt.Sprintf(t.Notebooks.MoveInto, notebook.Name), t.Notebooks.Move, func(parentID string) {
+		n.notebookHandler.OnMoveNotebook(notebook.ID, parentID)
+	})
+}
+
+// ShowMoveNoteDialog asks for the notebook that the current note is moved into
+func (n *NotebookTree) ShowMoveNoteDialog() {
+	t := i18n.T()
+	n.showNotebookSelect(t.Menu.MoveNote, t.Notebooks.Move, func(notebookID string) {
+		n.notebookHandler.OnMoveNote(notebookID)
+	})
+}
+
+// showNotebookSelect shows a form to pick a notebook by its path, the top level is picked as an empty ID
+func (n *NotebookTree) showNotebookSelect(title string, confirm string, onSelected func(notebookID string)) {
+	t := i18n.T()
+	targets := map[string]string{t.Notebooks.TopLevel: ""}
+	options := []string{}
+	for id := range n.notebooks {
+		path := n.notebookPath(id)
+		targets[path] = id
+		options = append(options, path)
+	}
+	sort.Strings(options)
+	options = append([]string{t.Notebooks.TopLevel}, options...)
+	targetSelect := widget.NewSelect(options, nil)
+
+	dialog.ShowForm(title, confirm, t.Editor.Exit,
+		[]*widget.FormItem{widget.NewFormItem(t.Notebooks.Title, targetSelect)},
+		func(confirmed bool) {
+			if confirmed && targetSelect.Selected != "" {
+				onSelected(targets[targetSelect.Selected])
+			}
+		},
+		n.window,
+	)
+}
+
+// notebookPath returns the names of the notebook and its ancestors joined by slashes
+func (n *NotebookTree) notebookPath(id string) string {
+	path := ""
+	visited := map[string]bool{}
+	for notebook, ok := n.notebooks[id]; ok && !visited[notebook.ID]; notebook, ok = n.notebooks[notebook.ParentID] {
+		visited[notebook.ID] = true
+		if path == "" {
+			path = notebook.Name
+		} else {
+			path = notebook.Name + " / " + path
+		}
+	}
+	return path
+}
+
+// onDeleteRequested asks for confirmation and deletes the selected notebook
+func (n *NotebookTree) onDeleteRequested() {
+	id := n.selectedID
+	if _, ok := n.notebooks[id]; !ok {
+		return
+	}
+
+	t := i18n.T()
+	dialog.ShowConfirm(t.Dialog.DeleteNotebook, t.Dialog.SureDeleteNotebook, func(confirmed bool) {
+		if confirmed {
+			n.notebookHandler.OnDeleteNotebook(id)
+		}
+	}, n.window)
+}
@@ -0,0 +1,154 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/curtisnewbie/nota/internal/domain"
+	"github.com/curtisnewbie/nota/internal/i18n"
+)
+
+// ReminderPanel asks for when the reminder of a note is due, and shows the reminders that came due
+type ReminderPanel struct {
+	reminderHandler ReminderHandler
+	window          fyne.Window
+}
+
+// NewReminderPanel creates a new reminder panel
+func NewReminderPanel(reminderHandler ReminderHandler, window fyne.Window) *ReminderPanel {
+	return &ReminderPanel{
+		reminderHandler: reminderHandler,
+		window:          window,
+	}
+}
+
+// ShowEditor asks for when the reminder of the current note is due, dueAt is the reminder already set, if any
+func (p *ReminderPanel) ShowEditor(dueAt *time.Time) {
+	t := i18n.T()
+
+	initial := time.Now().Add(time.Hour).Truncate(time.Hour)
+	if dueAt != nil {
+		initial = *dueAt
+	}
+	dateEntry := widget.NewDateEntry()
+	dateEntry.SetDate(&initial)
+	timeEntry := widget.NewEntry()
+	timeEntry.SetText(initial.Format("15:04"))
+	timeEntry.Validator = func(text string) error {
+		if _, err := time.Parse("15:04", text); err != nil {
+			return errors.New(t.Reminder.InvalidTime)
+		}
+		return nil
+	}
+
+	var d dialog.Dialog
+	set := func(due time.Time) {
+		d.Hide()
+		p.reminderHandler.OnSetReminder(&due)
+	}
+	presets := container.NewHBox(
+		widget.NewButton(t.Reminder.InOneHour, func() {
+			set(time.Now().Add(time.Hour))
+		}),
+		widget.NewButton(t.Reminder.Tomorrow, func() {
+			now := time.Now()
+			set(time.Date(now.Year(), now.Month(), now.Day()+1, 9, 0, 0, 0, time.Local))
+		}),
+		widget.NewButton(t.Reminder.NextWeek, func() {
+			set(time.Now().AddDate(0, 0, 7))
+		}),
+	)
+	content := container.NewVBox(
+		widget.NewForm(
+			widget.NewFormItem(t.Reminder.Date, dateEntry),
+			widget.NewFormItem(t.Reminder.Time, timeEntry),
+		),
+		presets,
+	)
+	if dueAt != nil {
+		completeBtn := widget.NewButtonWithIcon(t.Reminder.Complete, theme.ConfirmIcon(), func() {
+			d.Hide()
+			p.reminderHandler.OnSetReminder(nil)
+		})
+		content.Add(completeBtn)
+	}
+
+	d = dialog.NewCustomConfirm(t.Reminder.Title, t.Reminder.Set, t.Editor.Exit, content, func(confirmed bool) {
+		if !confirmed {
+			return
+		}
+		clock, err := time.Parse("15:04", timeEntry.Text)
+		if err != nil || dateEntry.Date == nil {
+			dialog.ShowError(errors.New(t.Reminder.InvalidTime), p.window)
+			return
+		}
+		date := *dateEntry.Date
+		due := time.Date(date.Year(), date.Month(), date.Day(), clock.Hour(), clock.Minute(), 0, 0, time.Local)
+		p.reminderHandler.OnSetReminder(&due)
+	}, p.window)
+	d.Resize(fyne.NewSize(460, 0))
+	d.Show()
+}
+
+// ShowDue shows the reminder of a note that came due, with the actions to open the note, snooze or complete the reminder
+func (p *ReminderPanel) ShowDue(note *domain.Note) {
+	t := i18n.T()
+
+	titleLabel := widget.NewLabel(note.Title)
+	titleLabel.TextStyle = fyne.TextStyle{Bold: true}
+	titleLabel.Wrapping = fyne.TextWrapWord
+	dueLabel := widget.NewLabel(describeDue(note, time.Now()))
+
+	var d dialog.Dialog
+	snooze := func(duration time.Duration) func() {
+		return func() {
+			d.Hide()
+			p.reminderHandler.OnSnoozeReminder(note.ID, duration)
+		}
+	}
+	openBtn := widget.NewButtonWithIcon(t.Reminder.Open, theme.DocumentIcon(), func() {
+		d.Hide()
+		p.reminderHandler.OnOpenReminder(note.ID)
+	})
+	completeBtn := widget.NewButtonWithIcon(t.Reminder.Complete, theme.ConfirmIcon(), func() {
+		d.Hide()
+		p.reminderHandler.OnCompleteReminder(note.ID)
+	})
+	completeBtn.Importance = widget.HighImportance
+
+	content := container.NewVBox(
+		titleLabel,
+		dueLabel,
+		widget.NewSeparator(),
+		container.NewHBox(
+			widget.NewLabel(t.Reminder.Snooze),
+			widget.NewButton(t.Reminder.SnoozeMinutes, snooze(10*time.Minute)),
+			widget.NewButton(t.Reminder.SnoozeHour, snooze(time.Hour)),
+			widget.NewButton(t.Reminder.SnoozeDay, snooze(24*time.Hour)),
+		),
+		container.NewHBox(openBtn, completeBtn),
+	)
+
+	d = dialog.NewCustom(t.Reminder.Title, t.Editor.Exit, content, p.window)
+	d.Resize(fyne.NewSize(460, 0))
+	d.Show()
+}
+
+// describeDue describes when the reminder of a note is due, empty when the note has no reminder
+func describeDue(note *domain.Note, now time.Time) string {
+	if note == nil || note.DueAt == nil {
+		return ""
+	}
+	t := i18n.T()
+	due := note.DueAt.Format("2006/01/02 15:04")
+	if note.IsOverdue(now) {
+		return fmt.Sprintf(t.Reminder.Overdue, due)
+	}
+	return fmt.Sprintf(t.Reminder.Due, due)
+}
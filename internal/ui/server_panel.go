@@ -0,0 +1,109 @@
+package ui
+
+import (
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/curtisnewbie/nota/internal/i18n"
+)
+
+// ServerPanel lets the user start and stop the API server and copy its token
+type ServerPanel struct {
+	serverHandler ServerHandler
+	window        fyne.Window
+	updating      bool
+	token         string
+	enabledCheck  *widget.Check
+	addrEntry     *widget.Entry
+	originEntry   *widget.Entry
+	tokenLabel    *widget.Label
+	statusLabel   *widget.Label
+	dialog        dialog.Dialog
+}
+
+// NewServerPanel creates a new server panel
+func NewServerPanel(serverHandler ServerHandler, window fyne.Window) *ServerPanel {
+	return &ServerPanel{
+		serverHandler: serverHandler,
+		window:        window,
+	}
+}
+
+// Show shows the state of the API server in a dialog, runningAddr is empty when the server is not running
+func (p *ServerPanel) Show(addr string, origin string, runningAddr string, token string) {
+	t := i18n.T()
+
+	p.addrEntry = widget.NewEntry()
+	p.originEntry = widget.NewEntry()
+	p.originEntry.SetPlaceHolder(t.Server.OriginPlaceholder)
+	p.enabledCheck = widget.NewCheck(t.Server.Enabled, func(enabled bool) {
+		if p.updating {
+			return
+		}
+		p.serverHandler.OnToggleServer(enabled, p.addrEntry.Text, p.originEntry.Text)
+	})
+
+	p.tokenLabel = widget.NewLabel("")
+	p.tokenLabel.Selectable = true
+	p.tokenLabel.Wrapping = fyne.TextWrapBreak
+	copyBtn := widget.NewButtonWithIcon(t.Server.Copy, theme.ContentCopyIcon(), func() {
+		fyne.CurrentApp().Clipboard().SetContent(p.token)
+	})
+	resetBtn := widget.NewButtonWithIcon(t.Server.ResetToken, theme.ViewRefreshIcon(), func() {
+		dialog.ShowConfirm(t.Server.ResetToken, t.Dialog.SureResetToken, func(confirmed bool) {
+			if confirmed {
+				p.serverHandler.OnResetServerToken()
+			}
+		}, p.window)
+	})
+
+	p.statusLabel = widget.NewLabel("")
+	p.statusLabel.Importance = widget.LowImportance
+
+	form := widget.NewForm(
+		widget.NewFormItem(t.Server.Address, p.addrEntry),
+		widget.NewFormItem(t.Server.Origin, p.originEntry),
+		widget.NewFormItem(t.Server.Token, p.tokenLabel),
+	)
+	content := container.NewVBox(
+		p.enabledCheck,
+		form,
+		container.NewHBox(copyBtn, resetBtn),
+		p.statusLabel,
+	)
+
+	p.dialog = dialog.NewCustom(t.Server.Title, t.Editor.Exit, content, p.window)
+	p.dialog.Resize(fyne.NewSize(520, 0))
+	p.SetState(addr, origin, runningAddr, token)
+	p.dialog.Show()
+}
+
+// SetState updates the address, the allowed origin, the running state and the token shown in the panel
+func (p *ServerPanel) SetState(addr string, origin string, runningAddr string, token string) {
+	if p.enabledCheck == nil {
+		return
+	}
+
+	t := i18n.T()
+	p.updating = true
+	defer func() { p.updating = false }()
+
+	p.token = token
+	p.tokenLabel.SetText(token)
+	p.addrEntry.SetText(addr)
+	p.originEntry.SetText(origin)
+	p.enabledCheck.SetChecked(runningAddr != "")
+	if runningAddr != "" {
+		p.addrEntry.Disable()
+		p.originEntry.Disable()
+		p.statusLabel.SetText(fmt.Sprintf(t.Server.Running, runningAddr))
+	} else {
+		p.addrEntry.Enable()
+		p.originEntry.Enable()
+		p.statusLabel.SetText(t.Server.Stopped)
+	}
+}
@@ -0,0 +1,193 @@
+package ui
+
+import (
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/curtisnewbie/nota/internal/domain"
+	"github.com/curtisnewbie/nota/internal/i18n"
+)
+
+// TagManager lets the user rename, merge and delete tags
+type TagManager struct {
+	tagHandler TagHandler
+	window     fyne.Window
+	tags       []*domain.Tag
+	selected   int
+	tagList    *widget.List
+	emptyLabel *widget.Label
+	renameBtn  *widget.Button
+	mergeBtn   *widget.Button
+	deleteBtn  *widget.Button
+	dialog     dialog.Dialog
+}
+
+// NewTagManager creates a new tag manager
+func NewTagManager(tagHandler TagHandler, window fyne.Window) *TagManager {
+	return &TagManager{
+		tagHandler: tagHandler,
+		window:     window,
+		selected:   -1,
+	}
+}
+
+// Show shows the tags in a dialog
+func (m *TagManager) Show(tags []*domain.Tag) {
+	t := i18n.T()
+
+	m.tagList = widget.NewList(
+		func() int { return len(m.tags) },
+		func() fyne.CanvasObject {
+			return widget.NewLabel("")
+		},
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			if id >= 0 && id < len(m.tags) {
+				obj.(*widget.Label).SetText(m.tags[id].Name)
+			}
+		},
+	)
+	m.tagList.OnSelected = func(id widget.ListItemID) {
+		m.selectTag(id)
+	}
+
+	m.emptyLabel = widget.NewLabel(t.Tags.NoTags)
+	m.emptyLabel.Alignment = fyne.TextAlignCenter
+
+	m.renameBtn = widget.NewButtonWithIcon(t.Tags.Rename, theme.DocumentCreateIcon(), func() {
+		m.showRenameDialog()
+	})
+	m.mergeBtn = widget.NewButtonWithIcon(t.Tags.Merge, theme.ContentPasteIcon(), func() {
+		m.showMergeDialog()
+	})
+	m.deleteBtn = widget.NewButtonWithIcon(t.Tags.Delete, theme.DeleteIcon(), func() {
+		m.onDeleteRequested()
+	})
+	m.deleteBtn.Importance = widget.DangerImportance
+
+	content := container.NewBorder(
+		nil,
+		container.NewHBox(m.renameBtn, m.mergeBtn, m.deleteBtn),
+		nil,
+		nil,
+		container.NewStack(m.tagList, m.emptyLabel),
+	)
+
+	m.dialog = dialog.NewCustom(t.Tags.Title, t.Editor.Exit, content, m.window)
+	m.dialog.Resize(fyne.NewSize(400, 450))
+	m.SetTags(tags)
+	m.dialog.Show()
+}
+
+// SetTags updates the tags shown in the tag manager
+func (m *TagManager) SetTags(tags []*domain.Tag) {
+	if m.tagList == nil {
+		return
+	}
+
+	m.tags = tags
+	m.tagList.UnselectAll()
+	m.tagList.Refresh()
+	m.selectTag(-1)
+
+	if len(tags) == 0 {
+		m.emptyLabel.Show()
+	} else {
+		m.emptyLabel.Hide()
+	}
+}
+
+// selectTag enables the actions for the selected tag
+func (m *TagManager) selectTag(index int) {
+	m.selected = index
+	if index < 0 || index >= len(m.tags) {
+		m.renameBtn.Disable()
+		m.mergeBtn.Disable()
+		m.deleteBtn.Disable()
+		return
+	}
+	m.renameBtn.Enable()
+	m.mergeBtn.Enable()
+	m.deleteBtn.Enable()
+}
+
+// selectedTag returns the selected tag or nil
+func (m *TagManager) selectedTag() *domain.Tag {
+	if m.selected < 0 || m.selected >= len(m.tags) {
+		return nil
+	}
+	return m.tags[m.selected]
+}
+
+// showRenameDialog asks for the new name of the selected tag
+func (m *TagManager) showRenameDialog() {
+	tag := m.selectedTag()
+	if tag == nil {
+		return
+	}
+
+	t := i18n.T()
+	nameEntry := widget.NewEntry()
+	nameEntry.SetText(tag.Name)
+
+	dialog.ShowForm(t.Tags.Rename, t.Editor.Save, t.Editor.Exit,
+		[]*widget.FormItem{widget.NewFormItem(t.Tags.Title, nameEntry)},
+		func(confirmed bool) {
+			if confirmed {
+				m.tagHandler.OnRenameTag(tag.ID, nameEntry.Text)
+			}
+		},
+		m.window,
+	)
+}
+
+// showMergeDialog asks for the tag that the selected tag is merged into
+func (m *TagManager) showMergeDialog() {
+	tag := m.selectedTag()
+	if tag == nil {
+		return
+	}
+
+	t := i18n.T()
+	targets := map[string]string{}
+	options := []string{}
+	for _, other := range m.tags {
+		if other.ID == tag.ID {
+			continue
+		}
+		targets[other.Name] = other.ID
+		options = append(options, other.Name)
+	}
+	if len(options) == 0 {
+		return
+	}
+	targetSelect := widget.NewSelect(options, nil)
+
+	dialog.ShowForm(fmt.Sprintf(t.Tags.MergeInto, tag.Name), t.Tags.Merge, t.Editor.Exit,
+		[]*widget.FormItem{widget.NewFormItem(t.Tags.Title, targetSelect)},
+		func(confirmed bool) {
+			if confirmed && targetSelect.Selected != "" {
+				m.tagHandler.OnMergeTags(tag.ID, targets[targetSelect.Selected])
+			}
+		},
+		m.window,
+	)
+}
+
+// onDeleteRequested asks for confirmation and deletes the selected tag
+func (m *TagManager) onDeleteRequested() {
+	tag := m.selectedTag()
+	if tag == nil {
+		return
+	}
+
+	t := i18n.T()
+	dialog.ShowConfirm(t.Dialog.DeleteTag, t.Dialog.SureDeleteTag, func(confirmed bool) {
+		if confirmed {
+			m.tagHandler.OnDeleteTag(tag.ID)
+		}
+	}, m.window)
+}
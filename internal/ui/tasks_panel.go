@@ -0,0 +1,190 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/curtisnewbie/nota/internal/domain"
+	"github.com/curtisnewbie/nota/internal/i18n"
+)
+
+// TasksPanel lists the tasks found across notes, ticking a task ticks it in its note
+type TasksPanel struct {
+	taskHandler TaskHandler
+	window      fyne.Window
+	tasks       []*domain.NoteTask
+	filter      domain.TaskFilter
+	taskList    *widget.List
+	emptyLabel  *widget.Label
+	tagSelect   *widget.Select
+	dialog      dialog.Dialog
+	shown       bool
+}
+
+// NewTasksPanel creates a new tasks panel
+func NewTasksPanel(taskHandler TaskHandler, window fyne.Window) *TasksPanel {
+	return &TasksPanel{
+		taskHandler: taskHandler,
+		window:      window,
+	}
+}
+
+// Show shows the open tasks in a dialog, the open dialog is refreshed instead
+func (p *TasksPanel) Show() {
+	if p.shown {
+		p.Refresh()
+		return
+	}
+
+	t := i18n.T()
+
+	p.taskList = widget.NewList(
+		func() int { return len(p.tasks) },
+		func() fyne.CanvasObject {
+			check := widget.NewCheck("", nil)
+			textLabel := widget.NewLabel("")
+			textLabel.Truncation = fyne.TextTruncateEllipsis
+			infoLabel := widget.NewLabel("")
+			infoLabel.TextStyle = fyne.TextStyle{Italic: true}
+			infoLabel.Truncation = fyne.TextTruncateEllipsis
+			openBtn := widget.NewButtonWithIcon("", theme.DocumentIcon(), nil)
+			openBtn.Importance = widget.LowImportance
+			return container.NewBorder(nil, nil, check, openBtn, container.NewVBox(textLabel, infoLabel))
+		},
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			if id < 0 || id >= len(p.tasks) {
+				return
+			}
+			task := p.tasks[id]
+			row := obj.(*fyne.Container)
+			texts := row.Objects[0].(*fyne.Container)
+			check := row.Objects[1].(*widget.Check)
+			openBtn := row.Objects[2].(*widget.Button)
+
+			texts.Objects[0].(*widget.Label).SetText(task.Text)
+			infoLabel := texts.Objects[1].(*widget.Label)
+			infoLabel.SetText(describeTask(task))
+			if task.DueDate != "" && !task.Done && task.DueDate <= time.Now().Format(domain.DailyDateLayout) {
+				infoLabel.Importance = widget.WarningImportance
+			} else {
+				infoLabel.Importance = widget.MediumImportance
+			}
+			infoLabel.Refresh()
+
+			// The row is reused for other tasks, it must not report the change made here
+			check.OnChanged = nil
+			check.SetChecked(task.Done)
+			check.OnChanged = func(done bool) {
+				p.taskHandler.OnSetTaskDone(task, done)
+			}
+			openBtn.OnTapped = func() {
+				p.dialog.Hide()
+				p.taskHandler.OnOpenTask(task)
+			}
+		},
+	)
+
+	p.emptyLabel = widget.NewLabel(t.Task.NoTasks)
+	p.emptyLabel.Wrapping = fyne.TextWrapWord
+
+	showDone := widget.NewCheck(t.Task.ShowDone, func(checked bool) {
+		p.filter.IncludeDone = checked
+		p.Refresh()
+	})
+	showDone.SetChecked(p.filter.IncludeDone)
+	p.tagSelect = widget.NewSelect(nil, func(selected string) {
+		if selected == t.Task.AllTags {
+			selected = ""
+		}
+		if p.filter.Tag != selected {
+			p.filter.Tag = selected
+			p.Refresh()
+		}
+	})
+	refreshBtn := widget.NewButtonWithIcon("", theme.ViewRefreshIcon(), func() {
+		p.Refresh()
+	})
+
+	content := container.NewBorder(
+		container.NewBorder(nil, nil, showDone, refreshBtn, p.tagSelect),
+		nil,
+		nil,
+		nil,
+		container.NewStack(p.taskList, container.NewVBox(p.emptyLabel)),
+	)
+
+	p.dialog = dialog.NewCustom(t.Task.Title, t.Editor.Exit, content, p.window)
+	p.dialog.Resize(fyne.NewSize(700, 500))
+	p.dialog.SetOnClosed(func() { p.shown = false })
+	p.shown = true
+	p.Refresh()
+	p.dialog.Show()
+}
+
+// Refresh lists the tasks again, e.g., after a task is ticked, nothing is done when the panel is not shown
+func (p *TasksPanel) Refresh() {
+	if !p.shown {
+		return
+	}
+
+	tasks, err := p.taskHandler.ListTasks(p.filter)
+	if err != nil {
+		dialog.ShowError(err, p.window)
+		return
+	}
+	p.tasks = tasks
+	p.taskList.UnselectAll()
+	p.taskList.Refresh()
+	if len(tasks) == 0 && p.filter.Tag == "" {
+		p.emptyLabel.Show()
+	} else {
+		p.emptyLabel.Hide()
+	}
+	p.refreshTags()
+}
+
+// refreshTags updates the tags to filter by with the tags of the listed tasks, keeping the selected one
+func (p *TasksPanel) refreshTags() {
+	t := i18n.T()
+	seen := map[string]bool{}
+	var tags []string
+	if p.filter.Tag != "" {
+		seen[p.filter.Tag] = true
+		tags = append(tags, p.filter.Tag)
+	}
+	for _, task := range p.tasks {
+		for _, tag := range task.TagNames() {
+			if !seen[tag] {
+				seen[tag] = true
+				tags = append(tags, tag)
+			}
+		}
+	}
+	sort.Strings(tags)
+
+	p.tagSelect.SetOptions(append([]string{t.Task.AllTags}, tags...))
+	if p.filter.Tag == "" {
+		p.tagSelect.SetSelected(t.Task.AllTags)
+	} else {
+		p.tagSelect.SetSelected(p.filter.Tag)
+	}
+}
+
+// describeTask describes the note of a task with the due date and tags of the task
+func describeTask(task *domain.NoteTask) string {
+	parts := []string{task.NoteTitle}
+	if task.DueDate != "" {
+		parts = append(parts, fmt.Sprintf(i18n.T().Task.Due, task.DueDate))
+	}
+	for _, tag := range task.TagNames() {
+		parts = append(parts, "#"+tag)
+	}
+	return strings.Join(parts, " · ")
+}
@@ -0,0 +1,112 @@
+package ui
+
+import (
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/curtisnewbie/nota/internal/domain"
+	"github.com/curtisnewbie/nota/internal/i18n"
+)
+
+// TemplatePicker asks for the template that a new note is created from and the values of its custom fields
+type TemplatePicker struct {
+	templateHandler TemplateHandler
+	window          fyne.Window
+	templates       []*domain.Note
+	dialog          dialog.Dialog
+}
+
+// NewTemplatePicker creates a new template picker
+func NewTemplatePicker(templateHandler TemplateHandler, window fyne.Window) *TemplatePicker {
+	return &TemplatePicker{
+		templateHandler: templateHandler,
+		window:          window,
+	}
+}
+
+// Show shows the templates in a dialog, picking one asks for its custom fields and creates the note
+func (p *TemplatePicker) Show(templates []*domain.Note) {
+	t := i18n.T()
+	if len(templates) == 0 {
+		dialog.ShowInformation(t.Template.Title, t.Template.NoTemplates, p.window)
+		return
+	}
+
+	p.templates = templates
+	templateList := widget.NewList(
+		func() int { return len(p.templates) },
+		func() fyne.CanvasObject {
+			titleLabel := widget.NewLabel("")
+			titleLabel.TextStyle = fyne.TextStyle{Bold: true}
+			titleLabel.Truncation = fyne.TextTruncateEllipsis
+			previewLabel := widget.NewLabel("")
+			previewLabel.Truncation = fyne.TextTruncateEllipsis
+			return container.NewVBox(titleLabel, previewLabel)
+		},
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			if id >= 0 && id < len(p.templates) {
+				template := p.templates[id]
+				container := obj.(*fyne.Container)
+				container.Objects[0].(*widget.Label).SetText(template.Title)
+				container.Objects[1].(*widget.Label).SetText(firstLine(template.Content))
+			}
+		},
+	)
+	templateList.OnSelected = func(id widget.ListItemID) {
+		if id < 0 || id >= len(p.templates) {
+			return
+		}
+		p.dialog.Hide()
+		p.askFields(p.templates[id])
+	}
+
+	p.dialog = dialog.NewCustom(t.Template.Title, t.Editor.Exit, templateList, p.window)
+	p.dialog.Resize(fyne.NewSize(500, 400))
+	p.dialog.Show()
+}
+
+// askFields asks for the values of the custom fields of the template, if any, and creates the note
+func (p *TemplatePicker) askFields(template *domain.Note) {
+	fields := domain.TemplateFields(template.Title, template.Content)
+	if len(fields) == 0 {
+		p.templateHandler.OnCreateFromTemplate(template.ID, nil)
+		return
+	}
+
+	t := i18n.T()
+	entries := make([]*widget.Entry, len(fields))
+	items := make([]*widget.FormItem, len(fields))
+	for i, field := range fields {
+		entries[i] = widget.NewEntry()
+		items[i] = widget.NewFormItem(field, entries[i])
+	}
+	form := dialog.NewForm(t.Template.Fields, t.Template.Create, t.Editor.Exit, items,
+		func(confirmed bool) {
+			if !confirmed {
+				return
+			}
+			values := make(map[string]string, len(fields))
+			for i, field := range fields {
+				values[field] = entries[i].Text
+			}
+			p.templateHandler.OnCreateFromTemplate(template.ID, values)
+		},
+		p.window,
+	)
+	form.Resize(fyne.NewSize(460, 0))
+	form.Show()
+	p.window.Canvas().Focus(entries[0])
+}
+
+// firstLine returns the first non-empty line of the text
+func firstLine(text string) string {
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
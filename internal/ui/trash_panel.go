@@ -0,0 +1,167 @@
+package ui
+
+import (
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/curtisnewbie/nota/internal/domain"
+	"github.com/curtisnewbie/nota/internal/i18n"
+)
+
+// TrashPanel shows soft-deleted notes with restore and permanent delete actions
+type TrashPanel struct {
+	trashHandler TrashHandler
+	window       fyne.Window
+	notes        []*domain.Note
+	selected     int
+	noteList     *widget.List
+	preview      *widget.Label
+	emptyLabel   *widget.Label
+	restoreBtn   *widget.Button
+	purgeBtn     *widget.Button
+	emptyBtn     *widget.Button
+	dialog       dialog.Dialog
+}
+
+// NewTrashPanel creates a new trash panel
+func NewTrashPanel(trashHandler TrashHandler, window fyne.Window) *TrashPanel {
+	return &TrashPanel{
+		trashHandler: trashHandler,
+		window:       window,
+		selected:     -1,
+	}
+}
+
+// Show shows the deleted notes in a dialog
+func (p *TrashPanel) Show(notes []*domain.Note) {
+	t := i18n.T()
+
+	p.noteList = widget.NewList(
+		func() int { return len(p.notes) },
+		func() fyne.CanvasObject {
+			titleLabel := widget.NewLabel("")
+			dateLabel := widget.NewLabel("")
+			dateLabel.TextStyle = fyne.TextStyle{Italic: true}
+			return container.NewVBox(titleLabel, dateLabel)
+		},
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			if id >= 0 && id < len(p.notes) {
+				note := p.notes[id]
+				container := obj.(*fyne.Container)
+				titleLabel := container.Objects[0].(*widget.Label)
+				dateLabel := container.Objects[1].(*widget.Label)
+				titleLabel.SetText(note.Title)
+				if note.DeletedAt != nil {
+					dateLabel.SetText(fmt.Sprintf(t.Trash.DeletedAt, note.DeletedAt.Format("2006/01/02 15:04")))
+				} else {
+					dateLabel.SetText("")
+				}
+			}
+		},
+	)
+	p.noteList.OnSelected = func(id widget.ListItemID) {
+		p.selectNote(id)
+	}
+
+	p.preview = widget.NewLabel("")
+	p.preview.Wrapping = fyne.TextWrapWord
+
+	p.emptyLabel = widget.NewLabel(t.Trash.Empty)
+	p.emptyLabel.Alignment = fyne.TextAlignCenter
+
+	p.restoreBtn = widget.NewButtonWithIcon(t.Trash.Restore, theme.ContentUndoIcon(), func() {
+		if p.selected >= 0 && p.selected < len(p.notes) {
+			p.trashHandler.OnRestoreNote(p.notes[p.selected].ID)
+		}
+	})
+	p.restoreBtn.Importance = widget.HighImportance
+
+	p.purgeBtn = widget.NewButtonWithIcon(t.Trash.Purge, theme.DeleteIcon(), func() {
+		p.onPurgeRequested()
+	})
+	p.purgeBtn.Importance = widget.DangerImportance
+
+	p.emptyBtn = widget.NewButton(t.Trash.EmptyTrash, func() {
+		dialog.ShowConfirm(t.Dialog.EmptyTrash, t.Dialog.SureEmptyTrash, func(confirmed bool) {
+			if confirmed {
+				p.trashHandler.OnEmptyTrash()
+			}
+		}, p.window)
+	})
+
+	rightPanel := container.NewBorder(
+		nil,
+		container.NewHBox(p.restoreBtn, p.purgeBtn),
+		nil,
+		nil,
+		container.NewScroll(p.preview),
+	)
+
+	split := container.NewHSplit(container.NewStack(p.noteList, p.emptyLabel), rightPanel)
+	split.SetOffset(0.35)
+
+	content := container.NewBorder(nil, container.NewHBox(p.emptyBtn), nil, nil, split)
+
+	p.dialog = dialog.NewCustom(t.Trash.Title, t.Editor.Exit, content, p.window)
+	p.dialog.Resize(fyne.NewSize(800, 550))
+	p.SetNotes(notes)
+	p.dialog.Show()
+}
+
+// SetNotes updates the deleted notes shown in the panel
+func (p *TrashPanel) SetNotes(notes []*domain.Note) {
+	if p.noteList == nil {
+		return
+	}
+
+	p.notes = notes
+	p.noteList.UnselectAll()
+	p.noteList.Refresh()
+	p.selectNote(-1)
+
+	if len(notes) == 0 {
+		p.emptyLabel.Show()
+		p.emptyBtn.Disable()
+	} else {
+		p.emptyLabel.Hide()
+		p.emptyBtn.Enable()
+	}
+}
+
+// selectNote previews the selected note
+func (p *TrashPanel) selectNote(index int) {
+	p.selected = index
+	if index < 0 || index >= len(p.notes) {
+		p.preview.SetText("")
+		p.restoreBtn.Disable()
+		p.purgeBtn.Disable()
+		return
+	}
+
+	if p.notes[index].Encrypted {
+		p.preview.SetText(i18n.T().Lock.Locked)
+	} else {
+		p.preview.SetText(p.notes[index].Content)
+	}
+	p.restoreBtn.Enable()
+	p.purgeBtn.Enable()
+}
+
+// onPurgeRequested asks for confirmation and permanently deletes the selected note
+func (p *TrashPanel) onPurgeRequested() {
+	if p.selected < 0 || p.selected >= len(p.notes) {
+		return
+	}
+
+	t := i18n.T()
+	id := p.notes[p.selected].ID
+	dialog.ShowConfirm(t.Dialog.PurgeNote, t.Dialog.SurePurge, func(confirmed bool) {
+		if confirmed {
+			p.trashHandler.OnPurgeNote(id)
+		}
+	}, p.window)
+}
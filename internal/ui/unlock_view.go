@@ -0,0 +1,65 @@
+package ui
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/curtisnewbie/nota/internal/i18n"
+)
+
+// UnlockView asks for the password of the encrypted database before the notes are shown
+type UnlockView struct {
+	unlockHandler UnlockHandler
+	passwordEntry *widget.Entry
+	errorLabel    *widget.Label
+}
+
+// NewUnlockView creates a new unlock view
+func NewUnlockView(unlockHandler UnlockHandler) *UnlockView {
+	return &UnlockView{
+		unlockHandler: unlockHandler,
+	}
+}
+
+// Build builds the unlock view
+func (v *UnlockView) Build() fyne.CanvasObject {
+	t := i18n.T()
+
+	message := widget.NewLabel(t.Database.Locked)
+	message.Alignment = fyne.TextAlignCenter
+
+	v.errorLabel = widget.NewLabel("")
+	v.errorLabel.Alignment = fyne.TextAlignCenter
+	v.errorLabel.Importance = widget.DangerImportance
+
+	unlock := func() {
+		password := v.passwordEntry.Text
+		v.passwordEntry.SetText("")
+		v.errorLabel.SetText("")
+		v.unlockHandler.OnUnlockDatabase(password)
+	}
+	v.passwordEntry = widget.NewPasswordEntry()
+	v.passwordEntry.SetPlaceHolder(t.Database.Password)
+	v.passwordEntry.OnSubmitted = func(string) { unlock() }
+	unlockBtn := widget.NewButtonWithIcon(t.Database.Unlock, theme.LoginIcon(), unlock)
+	unlockBtn.Importance = widget.HighImportance
+
+	form := container.NewBorder(nil, nil, nil, unlockBtn, v.passwordEntry)
+	return container.NewCenter(container.NewVBox(
+		widget.NewIcon(theme.VisibilityOffIcon()),
+		message,
+		container.NewGridWrap(fyne.NewSize(360, form.MinSize().Height), form),
+		v.errorLabel,
+	))
+}
+
+// Focus focuses the password entry
+func (v *UnlockView) Focus(window fyne.Window) {
+	window.Canvas().Focus(v.passwordEntry)
+}
+
+// ShowError shows why the database couldn't be unlocked
+func (v *UnlockView) ShowError(message string) {
+	v.errorLabel.SetText(message)
+}